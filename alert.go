@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAlertWindow time.Duration = 5 * time.Minute  // Default span of the window SLAs are evaluated over
+	minAlertSamples    int           = 10               // Probes in the window before an SLA can be breached
+	notifyTimeout      time.Duration = 10 * time.Second // Longest wait for a notifier to accept an alert
+)
+
+// Kinds of alert raised for a target
+const (
+	alertDown           string = "DOWN"              // Target stopped replying
+	alertUp             string = "UP"                // Target replies again
+	alertSLABreach      string = "SLA BREACH"        // Window statistics exceed an SLA threshold
+	alertSLARecovery    string = "SLA RECOVERY"      // Window statistics are back within the SLA
+	alertBudgetBurn     string = "SLO BURN"          // An SLO's error budget burns faster than the alerted rate
+	alertBudgetRecovery string = "SLO BURN RECOVERY" // The error budget burns slower again
+)
+
+// Alert raised for a target, with the rolling window statistics that triggered it
+type alertEvent struct {
+	Time   time.Time   `json:"time"`             // When the alert was raised
+	Kind   string      `json:"kind"`             // alertDown, alertUp, alertSLABreach, alertSLARecovery or a budget burn
+	Target string      `json:"target"`           // Hostname or IP address as given
+	IP     string      `json:"ip,omitempty"`     // Address last probed
+	Labels labels      `json:"labels,omitempty"` // Labels of the target
+	Reason string      `json:"reason,omitempty"` // Threshold breached, for SLA breaches and budget burns
+	Stats  windowStats `json:"stats"`            // Statistics of the rolling window
+}
+
+// Headline of the alert
+func (event alertEvent) subject() string {
+	target := event.Target
+	if len(event.Labels) > 0 {
+		target += " (" + event.Labels.String() + ")"
+	}
+	switch event.Kind {
+	case alertDown:
+		return fmt.Sprintf("goPing: %s is DOWN", target)
+	case alertUp:
+		return fmt.Sprintf("goPing: %s is UP again", target)
+	case alertSLABreach:
+		return fmt.Sprintf("goPing: %s breaches its SLA, %s", target, event.Reason)
+	case alertBudgetBurn:
+		return fmt.Sprintf("goPing: %s %s", target, event.Reason)
+	case alertBudgetRecovery:
+		return fmt.Sprintf("goPing: %s is back under the burn rate, %s", target, event.Reason)
+	default:
+		return fmt.Sprintf("goPing: %s is back within its SLA", target)
+	}
+}
+
+// Headline of the alert followed by its window statistics
+func (event alertEvent) String() string {
+	return event.subject() + "\n" + event.Stats.String()
+}
+
+// Destination of alerts, such as a chat webhook
+type notifier interface {
+	name() string                  // Name of the destination for logs
+	notify(event alertEvent) error // Deliver an alert
+}
+
+// Notifier posting a JSON message to an HTTP webhook
+type webhookNotifier struct {
+	service string                   // Name of the chat service
+	url     string                   // Webhook or API URL posted to
+	payload func(message string) any // JSON body carrying the message
+}
+
+// Name of the chat service
+func (webhook webhookNotifier) name() string {
+	return webhook.service
+}
+
+// Post the alert, failing on any unsuccessful status
+func (webhook webhookNotifier) notify(event alertEvent) error {
+	body, err := json.Marshal(webhook.payload(event.String()))
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: notifyTimeout}
+	response, err := client.Post(webhook.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("%s webhook returned %s", webhook.service, response.Status)
+	}
+	return nil
+}
+
+// Raises alerts on up/down transitions and SLA breaches of each target, evaluated over a rolling window
+type alerter struct {
+	settings  alertConfig                // Thresholds and window
+	notifiers []notifier                 // Destinations of every alert
+	mutex     sync.Mutex                 // Guards targets
+	targets   map[*statistic]*alertState // Alert state of each target seen
+}
+
+// Alert state of a target
+type alertState struct {
+	window   rollingWindow // Recent probe outcomes
+	state    targetState   // State last alerted on
+	breached bool          // Whether the SLA is currently breached
+}
+
+// Create an alerter with the chat, email and SNMP notifiers configured
+func newAlerter(settings alertConfig) (*alerter, error) {
+	if settings.Window <= 0 {
+		settings.Window = duration(defaultAlertWindow)
+	}
+	alerts := &alerter{settings: settings, targets: make(map[*statistic]*alertState)}
+	if settings.Slack != "" {
+		alerts.notifiers = append(alerts.notifiers, webhookNotifier{"Slack", settings.Slack, func(message string) any {
+			return map[string]string{"text": message}
+		}})
+	}
+	if settings.Discord != "" {
+		alerts.notifiers = append(alerts.notifiers, webhookNotifier{"Discord", settings.Discord, func(message string) any {
+			return map[string]string{"content": message}
+		}})
+	}
+	if telegram := settings.Telegram; telegram != nil {
+		if telegram.Token == "" || telegram.Chat == "" {
+			return nil, errors.New("Telegram alerts need both a bot token and a chat")
+		}
+		alerts.notifiers = append(alerts.notifiers, webhookNotifier{
+			"Telegram",
+			"https://api.telegram.org/bot" + telegram.Token + "/sendMessage",
+			func(message string) any { return map[string]string{"chat_id": telegram.Chat, "text": message} },
+		})
+	}
+	if settings.Email != nil {
+		email, err := newEmailNotifier(*settings.Email)
+		if err != nil {
+			return nil, err
+		}
+		alerts.notifiers = append(alerts.notifiers, email)
+	}
+	if settings.SNMP != nil {
+		snmp, err := newSNMPNotifier(*settings.SNMP)
+		if err != nil {
+			return nil, err
+		}
+		alerts.notifiers = append(alerts.notifiers, snmp)
+	}
+	return alerts, nil
+}
+
+// Names of the configured notifiers for logs
+func (alerts *alerter) names() string {
+	names := make([]string, len(alerts.notifiers))
+	for i, destination := range alerts.notifiers {
+		names[i] = destination.name()
+	}
+	if len(names) == 0 {
+		return "log only"
+	}
+	return strings.Join(names, ", ")
+}
+
+// Add a probe result of the target to its window, raising alerts if its state changed or its SLA
+// was breached or recovered
+func (alerts *alerter) observe(stats *statistic, result probeResult) {
+	stats.mutex.Lock()
+	state := stats.state
+	stats.mutex.Unlock()
+
+	alerts.mutex.Lock()
+	tracked, ok := alerts.targets[stats]
+	if !ok {
+		tracked = &alertState{window: rollingWindow{span: time.Duration(alerts.settings.Window)}}
+		alerts.targets[stats] = tracked
+	}
+	tracked.window.add(result)
+	summary := tracked.window.stats()
+	var kinds []string
+	var reason string
+	// Alert when going down, including from the start, and when coming back up
+	if state == stateDown && tracked.state != stateDown {
+		kinds = append(kinds, alertDown)
+	} else if state == stateUp && tracked.state == stateDown {
+		kinds = append(kinds, alertUp)
+	}
+	tracked.state = state
+	if summary.Sent >= minAlertSamples {
+		reason = alerts.breach(summary)
+		if reason != "" && !tracked.breached {
+			kinds = append(kinds, alertSLABreach)
+		} else if reason == "" && tracked.breached {
+			kinds = append(kinds, alertSLARecovery)
+		}
+		tracked.breached = reason != ""
+	}
+	alerts.mutex.Unlock()
+
+	for _, kind := range kinds {
+		event := alertEvent{
+			Time:   result.Time,
+			Kind:   kind,
+			Target: result.Target,
+			IP:     result.IP,
+			Labels: result.Labels,
+			Stats:  summary,
+		}
+		if kind == alertSLABreach {
+			event.Reason = reason
+		}
+		go alerts.send(event)
+	}
+}
+
+// Describe the SLA threshold the window statistics exceed, empty if within the SLA
+func (alerts *alerter) breach(summary windowStats) string {
+	switch {
+	case alerts.settings.MaxLoss > 0 && summary.Loss > alerts.settings.MaxLoss:
+		return fmt.Sprintf("loss %.2f%% > %.2f%%", summary.Loss, alerts.settings.MaxLoss)
+	case alerts.settings.MaxRTT > 0 && summary.AvgRTT > time.Duration(alerts.settings.MaxRTT):
+		return fmt.Sprintf("average RTT %s > %s", display(summary.AvgRTT), time.Duration(alerts.settings.MaxRTT))
+	default:
+		return ""
+	}
+}
+
+// Log the alert and deliver it to every notifier
+func (alerts *alerter) send(event alertEvent) {
+	slog.Warn(event.subject())
+	for _, destination := range alerts.notifiers {
+		if err := destination.notify(event); err != nil {
+			slog.Warn(fmt.Sprintf("Failed to send %s alert: %v", destination.name(), err))
+		}
+	}
+}
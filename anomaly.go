@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	anomalyWindow   int     = 30     // Number of recent RTTs forming the baseline of the anomaly detector
+	anomalyMinimum  int     = 10     // RTTs needed in the baseline before anomalies are flagged
+	madScale        float64 = 1.4826 // Scales the median absolute deviation to a standard deviation for normal data
+	defaultAnomalyZ float64 = 3.5    // Default robust z-score above which an RTT is anomalous
+)
+
+var anomalyThreshold float64 = defaultAnomalyZ // Robust z-score above which an RTT is anomalous (-anomaly) flag, 0 to disable
+
+// Online detector of RTT spikes against a rolling baseline of recent RTTs,
+// using the median and median absolute deviation so earlier spikes don't skew it
+type anomalyDetector struct {
+	window []time.Duration // Recent RTTs, oldest first
+}
+
+// Whether the RTT is a spike above the baseline, after which it joins the baseline so level shifts
+// are learnt rather than flagged forever
+func (detector *anomalyDetector) observe(rtt time.Duration) bool {
+	anomalous := false
+	if anomalyThreshold > 0 && len(detector.window) >= anomalyMinimum {
+		median := medianOf(detector.window)
+		deviations := make([]time.Duration, len(detector.window))
+		for i, previous := range detector.window {
+			deviations[i] = previous - median
+			if deviations[i] < 0 {
+				deviations[i] = -deviations[i]
+			}
+		}
+		// Floor the spread at the display precision, so differences too small to show aren't flagged
+		spread := madScale * float64(medianOf(deviations))
+		if spread < float64(precision) {
+			spread = float64(precision)
+		}
+		anomalous = float64(rtt-median)/spread > anomalyThreshold
+	}
+	detector.window = append(detector.window, rtt)
+	if len(detector.window) > anomalyWindow {
+		detector.window = detector.window[1:]
+	}
+	return anomalous
+}
+
+// Median of the durations
+func medianOf(durations []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
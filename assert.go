@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	assertP95        time.Duration // p95 RTT every target must stay below (-assert-rtt-p95-below) flag, 0 for none
+	assertAvg        time.Duration // Mean RTT every target must stay below (-assert-rtt-avg-below) flag, 0 for none
+	assertLoss       float64       // Percent loss every target must stay below (-assert-loss-below) flag, negative for none
+	assertionsFailed bool          // Whether an assertion failed, making goPing exit with 1
+)
+
+// Parse a percentage such as 1% or 0.5, between 0 and 100
+func parsePercent(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("%q is not a percentage between 0 and 100", value)
+	}
+	return percent, nil
+}
+
+// Whether any -assert-* threshold was given
+func assertionsGiven() bool {
+	return assertP95 > 0 || assertAvg > 0 || assertLoss >= 0
+}
+
+// Hold every target to the -assert-* thresholds at termination, printing each assertion's verdict
+// and recording a failure so goPing exits with 1, as a network SLA gate of a CI pipeline
+func checkAssertions(allStats []*statistic) {
+	if !assertionsGiven() {
+		return
+	}
+	fmt.Fprintln(noticeOutput(), "\n----------------------------| Assertions |----------------------------")
+	for _, stats := range allStats {
+		summary := stats.summary()
+		stats.mutex.Lock()
+		p95 := percentile(stats.rttAll, 95)
+		stats.mutex.Unlock()
+		// Targets that never replied fail RTT assertions, and ones never probed every assertion
+		replied := summary.Sent > summary.Lost
+		if assertP95 > 0 && replied {
+			stats.judgeAssertion(p95 < assertP95, fmt.Sprintf("p95 RTT %s below %s", display(p95), assertP95))
+		} else if assertP95 > 0 {
+			stats.judgeAssertion(false, fmt.Sprintf("p95 RTT below %s, but no replies", assertP95))
+		}
+		if assertAvg > 0 && replied {
+			stats.judgeAssertion(summary.AvgRTT < assertAvg, fmt.Sprintf("mean RTT %s below %s", display(summary.AvgRTT), assertAvg))
+		} else if assertAvg > 0 {
+			stats.judgeAssertion(false, fmt.Sprintf("mean RTT below %s, but no replies", assertAvg))
+		}
+		if assertLoss >= 0 && summary.Sent > 0 {
+			stats.judgeAssertion(summary.Loss < assertLoss, fmt.Sprintf("loss %.2f%% below %.2f%%", summary.Loss, assertLoss))
+		} else if assertLoss >= 0 {
+			stats.judgeAssertion(false, fmt.Sprintf("loss below %.2f%%, but no probes completed", assertLoss))
+		}
+	}
+	if assertionsFailed {
+		fmt.Fprintln(noticeOutput(), "Assertions failed")
+	}
+}
+
+// Print whether the target passed an assertion, recording a failure
+func (stats *statistic) judgeAssertion(passed bool, assertion string) {
+	outcome := "PASS"
+	if !passed {
+		outcome = "FAIL"
+		assertionsFailed = true
+	}
+	fmt.Fprintf(noticeOutput(), "%s\t%s%s: %s\n", outcome, stats.target.address, stats.labels.suffix(), assertion)
+}
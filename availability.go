@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Time a target spent UP and DOWN by its hysteresis state, from which its availability is derived.
+// Time between probes is credited to the state the target was in, leaving out paused time.
+type availability struct {
+	last time.Time     // When the last probe was sent, zero before the first or after a gap not measured
+	up   time.Duration // Time spent up
+	down time.Duration // Time spent down
+}
+
+// Credit the time since the last probe to the state the target was in until the probe sent now.
+// Must be called with stats.mutex held, before the probe's outcome updates the state.
+func (stats *statistic) observeAvailability(sent time.Time) {
+	tracked := &stats.uptime
+	if !tracked.last.IsZero() && sent.After(tracked.last) {
+		elapsed := sent.Sub(tracked.last) - probing.pausedBetween(tracked.last, sent)
+		switch stats.state {
+		case stateUp:
+			tracked.up += max(elapsed, 0)
+		case stateDown:
+			tracked.down += max(elapsed, 0)
+		}
+	}
+	tracked.last = sent
+}
+
+// Stop crediting time until the next probe, for gaps such as those outside the probing schedule
+func (stats *statistic) skipAvailability() {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	stats.uptime.last = time.Time{}
+}
+
+// Percentage of the measured time the target was up, 0 if no time was measured yet
+func (tracked availability) percent() float64 {
+	if tracked.up+tracked.down == 0 {
+		return 0
+	}
+	return float64(tracked.up) / float64(tracked.up+tracked.down) * 100
+}
+
+// Describe the availability and total downtime, e.g. "99.95% (downtime 12s)"
+func (tracked availability) String() string {
+	return fmt.Sprintf("%.2f%% (downtime %s)", tracked.percent(), display(tracked.down))
+}
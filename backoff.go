@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+var backoffMax time.Duration // Cap of the probe interval of targets that are down (-backoff) flag, 0 to keep probing every interval
+
+// Interval until the next probe of a target with -backoff: doubling from the normal interval with
+// every probe while the target is down up to backoffMax, with equal jitter so many dead targets
+// don't fire together, and back to the normal interval as soon as it replies.
+// Only called by the target's probing goroutine.
+func (stats *statistic) backoffInterval(replied bool) time.Duration {
+	stats.mutex.Lock()
+	down := stats.state == stateDown
+	stats.mutex.Unlock()
+	if replied || !down {
+		if stats.backoff > 0 && replied {
+			slog.Info(fmt.Sprintf("%s replies again. Resuming normal probing...", stats.target.address))
+		}
+		stats.backoff = 0
+		return nextInterval(stats.probeEvery())
+	}
+	if stats.backoff == 0 {
+		slog.Info(fmt.Sprintf("%s is down. Backing off probes up to every %s...", stats.target.address, backoffMax))
+		stats.backoff = stats.probeEvery()
+	}
+	stats.backoff = min(2*stats.backoff, backoffMax)
+	return stats.backoff/2 + rand.N(stats.backoff/2+1)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	_ "net/http/pprof" // Registers the profiling handlers served with -pprof
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/alvihabib/goPing/pinger"
+)
+
+const (
+	defaultBenchDuration time.Duration = 10 * time.Second       // Length of goping bench unless -duration is given
+	defaultBenchPingers  int           = 64                     // Concurrent pingers of goping bench unless -bench-pingers is given
+	defaultBenchInterval time.Duration = time.Millisecond       // Interval of each pinger of goping bench unless -bench-interval is given
+	benchTimeout         time.Duration = 100 * time.Millisecond // Deadline of replies from localhost
+)
+
+// Serve the net/http/pprof handlers on the address (-pprof) until the process exits
+func servePprof(address string) {
+	slog.Info(fmt.Sprintf("Serving profiles on http://%s/debug/pprof/...", address))
+	if err := http.ListenAndServe(address, nil); err != nil {
+		slog.Error(fmt.Sprintf("Could not serve profiles: %s", err))
+	}
+}
+
+// Probe localhost with many concurrent pingers at a high rate for the duration (goping bench),
+// then report the probe rate achieved, CPU time and allocations per probe
+func runBench(duration time.Duration, pingers int, interval time.Duration) error {
+	target := "127.0.0.1"
+	if wantIPv6 {
+		target = "::1"
+	}
+	slog.Info(fmt.Sprintf("Benchmarking %d pingers probing %s every %s for %s...", pingers, target, interval, duration))
+
+	all := make([]*pinger.Pinger, pingers)
+	for i := range all {
+		var err error
+		all[i], err = pinger.New(target, pinger.WithIPv6(wantIPv6), pinger.WithInterval(interval), pinger.WithTimeout(benchTimeout), pinger.WithTTL(ttl))
+		if err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	cpuBefore, cpuKnown := processCPU()
+	started := time.Now()
+	failed := make(chan error, pingers)
+	var wg sync.WaitGroup
+	for _, probing := range all {
+		wg.Add(1)
+		go func(probing *pinger.Pinger) {
+			defer wg.Done()
+			if err := probing.Run(ctx); err != nil {
+				failed <- err
+			}
+		}(probing)
+	}
+	wg.Wait()
+	elapsed := time.Since(started)
+	cpuAfter, _ := processCPU()
+	runtime.ReadMemStats(&after)
+	close(failed)
+	if err := <-failed; err != nil {
+		return err
+	}
+
+	var sent, received int
+	var totalRTT time.Duration
+	for _, probing := range all {
+		statistics := probing.Statistics()
+		sent += statistics.Sent
+		received += statistics.Received
+		totalRTT += statistics.AvgRTT * time.Duration(statistics.Received)
+	}
+	if sent == 0 {
+		return errors.New("No probes were sent")
+	}
+	fmt.Println("\n----------------------------| Benchmark |----------------------------")
+	fmt.Printf("Probes sent: %d\t\tReplies: %d\t\tLoss: %.2f%%\n", sent, received, float64(sent-received)/float64(sent)*100)
+	fmt.Printf("Rate: %.0f probes/s\t\tElapsed: %s\n", float64(sent)/elapsed.Seconds(), display(elapsed))
+	if received > 0 {
+		fmt.Printf("Average RTT: %s\n", display(totalRTT/time.Duration(received)))
+	}
+	if cpuKnown {
+		cpu := cpuAfter - cpuBefore
+		fmt.Printf("CPU: %s per probe\t\tUtilization: %.1f%% of one core\n", cpu/time.Duration(sent), float64(cpu)/float64(elapsed)*100)
+	} else {
+		fmt.Println("CPU: not measured on this platform")
+	}
+	fmt.Printf(
+		"Allocations: %.2f per probe\t\tBytes: %.1f per probe\t\tGC cycles: %d\n",
+		float64(after.Mallocs-before.Mallocs)/float64(sent),
+		float64(after.TotalAlloc-before.TotalAlloc)/float64(sent),
+		after.NumGC-before.NumGC)
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const defaultBenchmarkCount int = 20 // Probes of each target of goping benchmark-dns and benchmark-cdn unless -c is given
+
+var benchmarkSort string // Column the benchmark ranks targets by: latency, jitter or loss (-sort) flag
+
+// Popular public resolvers ranked by goping benchmark-dns
+var benchmarkDNSTargets = []wellKnownTarget{
+	{"1.1.1.1", "2606:4700:4700::1111", "cloudflare"},
+	{"8.8.8.8", "2001:4860:4860::8888", "google"},
+	{"9.9.9.9", "2620:fe::fe", "quad9"},
+	{"208.67.222.222", "2620:119:35::35", "opendns"},
+	{"94.140.14.14", "2a10:50c0::ad1:ff", "adguard"},
+	{"76.76.2.0", "2606:1a40::", "controld"},
+	{"185.228.168.9", "2a0d:2a00:1::2", "cleanbrowsing"},
+}
+
+// Popular CDNs ranked by goping benchmark-cdn, by hostnames so each resolves to its nearest edge
+var benchmarkCDNTargets = []wellKnownTarget{
+	{"www.cloudflare.com", "www.cloudflare.com", "cloudflare"},
+	{"www.fastly.com", "www.fastly.com", "fastly"},
+	{"www.akamai.com", "www.akamai.com", "akamai"},
+	{"d1.awsstatic.com", "d1.awsstatic.com", "cloudfront"},
+	{"www.gstatic.com", "www.gstatic.com", "google"},
+	{"cdn.jsdelivr.net", "cdn.jsdelivr.net", "jsdelivr"},
+}
+
+// Result of a benchmarked target, as ranked
+type benchmarkResult struct {
+	Rank   int           `json:"rank"`       // Position in the ranking, 1 being the best
+	Median time.Duration `json:"median_rtt"` // Median RTT
+	targetSummary
+}
+
+// Probe every target concurrently for the given number of probes (goping benchmark-dns and
+// benchmark-cdn), then print them ranked by -sort
+func runBenchmark(allStats []*statistic, count int) {
+	slog.Info(fmt.Sprintf("Probing %d targets %d times each...", len(allStats), count))
+	var wg sync.WaitGroup
+	for _, stats := range allStats {
+		wg.Add(1)
+		go func(stats *statistic) {
+			defer wg.Done()
+			stats.run(count, nil, func(probeResult) {})
+		}(stats)
+	}
+	wg.Wait()
+	showBenchmark(allStats)
+}
+
+// Rank the targets by -sort, breaking ties by loss and then median RTT, targets that never
+// replied coming last
+func rankBenchmark(allStats []*statistic) []benchmarkResult {
+	results := make([]benchmarkResult, len(allStats))
+	for i, stats := range allStats {
+		results[i].targetSummary = stats.summary()
+		stats.mutex.Lock()
+		if len(stats.rttAll) > 0 {
+			results[i].Median = medianOf(stats.rttAll)
+		}
+		stats.mutex.Unlock()
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if repliedA, repliedB := a.Sent > a.Lost, b.Sent > b.Lost; repliedA != repliedB {
+			return repliedA
+		}
+		switch {
+		case benchmarkSort == "jitter" && a.Jitter != b.Jitter:
+			return a.Jitter < b.Jitter
+		case benchmarkSort == "loss" && a.Loss != b.Loss:
+			return a.Loss < b.Loss
+		case a.Loss != b.Loss:
+			return a.Loss < b.Loss
+		default:
+			return a.Median < b.Median
+		}
+	})
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+	return results
+}
+
+// Print the ranking as a table, or as one JSON document with -o json
+func showBenchmark(allStats []*statistic) {
+	results := rankBenchmark(allStats)
+	if output.format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(results)
+		return
+	}
+	fmt.Printf("\n----------------------------| Ranked by %s |----------------------------\n", benchmarkSort)
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "RANK\tTARGET\tLABELS\tSENT\tLOSS\tMEDIAN\tP95\tJITTER")
+	for _, result := range results {
+		if result.Sent > result.Lost {
+			fmt.Fprintf(writer, "%d\t%s\t%s\t%d\t%.2f%%\t%s\t%s\t%s\n", result.Rank, result.Target, result.Labels, result.Sent, result.Loss, display(result.Median), display(result.P95RTT), display(result.Jitter))
+		} else {
+			fmt.Fprintf(writer, "%d\t%s\t%s\t%d\t%.2f%%\t\t\t\n", result.Rank, result.Target, result.Labels, result.Sent, result.Loss)
+		}
+	}
+	writer.Flush()
+}
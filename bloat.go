@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBloatDuration time.Duration = 10 * time.Second       // Length of each phase of goping bloat unless -bloat-duration is given
+	defaultBloatStreams  int           = 4                      // Parallel transfers loading the link unless -bloat-streams is given
+	bloatInterval        time.Duration = 200 * time.Millisecond // Interval between probes of either phase
+	bloatTimeout         time.Duration = 2 * time.Second        // Deadline of each reply, long enough for a badly bloated queue
+	bloatRetry           time.Duration = time.Second            // Pause before restarting a transfer that failed
+)
+
+// Highest latency increase under load earning each grade, best first, as common bufferbloat tests grade
+var bloatGrades = []struct {
+	limit time.Duration // Largest increase of median RTT of the grade
+	grade string        // Grade given
+}{
+	{5 * time.Millisecond, "A+"},
+	{30 * time.Millisecond, "A"},
+	{60 * time.Millisecond, "B"},
+	{200 * time.Millisecond, "C"},
+	{400 * time.Millisecond, "D"},
+}
+
+// Bytes moved by the load of goping bloat in each direction
+type bloatLoad struct {
+	downloaded atomic.Int64 // Bytes received by downloads
+	uploaded   atomic.Int64 // Bytes sent by uploads
+	failure    sync.Once    // Warns once of transfers failing
+}
+
+// Measure the target's RTT idle for the duration, then again for as long while streams parallel
+// transfers download from, upload to or both the URL (goping bloat), and grade the increase
+// of latency under load, the symptom of bufferbloat in the queues of the bottleneck link
+func runBloat(target *resolution, url string, streams int, direction string, duration time.Duration) error {
+	ip, err := target.current()
+	if err != nil {
+		return err
+	}
+	idle := &statistic{target: target, id: nextEchoID(), timeout: bloatTimeout}
+	loaded := &statistic{target: target, id: nextEchoID(), timeout: bloatTimeout}
+	defer idle.closeSocket()
+	defer loaded.closeSocket()
+	load := new(bloatLoad)
+	var loadTime time.Duration
+	closeHandler(func() { showBloat(idle, loaded, load, loadTime) })
+
+	slog.Info(fmt.Sprintf("Measuring idle RTT to %s for %s...", displayAddress(ip), duration))
+	idle.pingFor(ip, "idle", duration)
+
+	streamsDescribed := fmt.Sprintf("%d %s", streams, direction)
+	if direction == "both" {
+		streamsDescribed = fmt.Sprintf("%d download and %d upload", streams, streams)
+	}
+	slog.Info(fmt.Sprintf("Measuring RTT under load of %s streams with %s for %s...", streamsDescribed, url, duration))
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < streams; i++ {
+		if direction == "download" || direction == "both" {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				load.transfer(ctx, url, false)
+			}()
+		}
+		if direction == "upload" || direction == "both" {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				load.transfer(ctx, url, true)
+			}()
+		}
+	}
+	started := time.Now()
+	loaded.pingFor(ip, "loaded", duration)
+	loadTime = time.Since(started)
+	cancel()
+	wg.Wait()
+
+	showBloat(idle, loaded, load, loadTime)
+	return nil
+}
+
+// Probe the address every bloatInterval until the duration has passed, printing each RTT with the phase
+func (stats *statistic) pingFor(ip *net.IPAddr, phase string, duration time.Duration) {
+	for deadline := time.Now().Add(duration); time.Now().Before(deadline); {
+		probing.wait(nil)
+		sent := time.Now()
+		err := stats.ping(ip)
+		stats.tally(err == nil, sent)
+		if err != nil {
+			slog.Info(fmt.Sprintf("Phase: %s\t\tSeq: %d\t\tLost", phase, stats.count), "phase", phase, "seq", stats.count, "error", err)
+		} else {
+			slog.Info(fmt.Sprintf("Phase: %s\t\tSeq: %d\t\tRTT: %s", phase, stats.count, display(stats.rtt)), "phase", phase, "seq", stats.count, "rtt", stats.rtt)
+		}
+		time.Sleep(bloatInterval)
+	}
+}
+
+// Download from, or upload endless zeros to, the URL over and over until the context is done
+func (load *bloatLoad) transfer(ctx context.Context, url string, upload bool) {
+	for ctx.Err() == nil {
+		var request *http.Request
+		var err error
+		if upload {
+			request, err = http.NewRequestWithContext(ctx, http.MethodPost, url, &countingReader{count: &load.uploaded})
+		} else {
+			request, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		}
+		if err != nil {
+			load.warn(err)
+			return
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err == nil {
+			if response.StatusCode/100 != 2 {
+				err = fmt.Errorf("%s answered %s", url, response.Status)
+			} else if !upload {
+				_, err = io.Copy(&countingWriter{count: &load.downloaded}, response.Body)
+			}
+			response.Body.Close()
+		}
+		if err != nil && ctx.Err() == nil {
+			load.warn(err)
+			time.Sleep(bloatRetry)
+		}
+	}
+}
+
+// Warn of the first failing transfer
+func (load *bloatLoad) warn(err error) {
+	load.failure.Do(func() {
+		slog.Warn(fmt.Sprintf("Load transfer failed (%s). Retrying...", err))
+	})
+}
+
+// Reader of endless zeros counting the bytes read, as the body of uploads
+type countingReader struct {
+	count *atomic.Int64 // Bytes read so far
+}
+
+// Fill the buffer with zeros
+func (reader *countingReader) Read(buffer []byte) (int, error) {
+	clear(buffer)
+	reader.count.Add(int64(len(buffer)))
+	return len(buffer), nil
+}
+
+// Writer discarding downloads while counting their bytes
+type countingWriter struct {
+	count *atomic.Int64 // Bytes written so far
+}
+
+// Discard the bytes, counting them
+func (writer *countingWriter) Write(buffer []byte) (int, error) {
+	writer.count.Add(int64(len(buffer)))
+	return len(buffer), nil
+}
+
+// Print idle and loaded RTT and loss with the throughput of the load, and grade the latency increase
+func showBloat(idle *statistic, loaded *statistic, load *bloatLoad, loadTime time.Duration) {
+	fmt.Println("\n----------------------------| Bufferbloat |----------------------------")
+	for _, phase := range []struct {
+		name  string
+		stats *statistic
+	}{{"Idle", idle}, {"Loaded", loaded}} {
+		summary := phase.stats.summary()
+		phase.stats.mutex.Lock()
+		median, p95 := time.Duration(0), percentile(phase.stats.rttAll, 95)
+		if len(phase.stats.rttAll) > 0 {
+			median = medianOf(phase.stats.rttAll)
+		}
+		phase.stats.mutex.Unlock()
+		fmt.Printf("%s:\t\tSent: %d\t\tLoss: %.2f%%\t\tMedian RTT: %s\t\tp95 RTT: %s\n", phase.name, summary.Sent, summary.Loss, display(median), display(p95))
+	}
+	if loadTime > 0 {
+		seconds := loadTime.Seconds()
+		fmt.Printf("Load:\t\tDownload: %.2f Mbit/s\t\tUpload: %.2f Mbit/s\n", float64(load.downloaded.Load())*8/seconds/1e6, float64(load.uploaded.Load())*8/seconds/1e6)
+		if load.downloaded.Load() == 0 && load.uploaded.Load() == 0 {
+			fmt.Println("No load was generated, check that -bloat-url can be downloaded from or uploaded to")
+			return
+		}
+	}
+
+	idle.mutex.Lock()
+	loaded.mutex.Lock()
+	defer idle.mutex.Unlock()
+	defer loaded.mutex.Unlock()
+	if len(idle.rttAll) == 0 || len(loaded.rttAll) == 0 {
+		fmt.Println("Grade: not enough replies to grade")
+		return
+	}
+	increase := medianOf(loaded.rttAll) - medianOf(idle.rttAll)
+	grade := "F"
+	for _, candidate := range bloatGrades {
+		if increase < candidate.limit {
+			grade = candidate.grade
+			break
+		}
+	}
+	fmt.Printf("Latency increase under load: %s\t\tGrade: %s\n", display(increase), grade)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+const broadcastWindow time.Duration = time.Second // How long replies to a broadcast or multicast probe (-b) are collected
+
+// Host replying to a broadcast or multicast probe (-b)
+type responder struct {
+	IP  string        `json:"ip"`  // Address of the host
+	RTT time.Duration `json:"rtt"` // Round trip time of its reply
+}
+
+// Replies of one host over the run of a broadcast or multicast target
+type responderStatistic struct {
+	replies  int           // Number of probes the host replied to
+	totalRTT time.Duration // Sum of its RTTs for averaging
+}
+
+// Whether the address is multicast, the limited broadcast address, or the broadcast address
+// of a subnet of one of our interfaces
+func isBroadcast(ip net.IP) bool {
+	if ip.IsMulticast() || ip.Equal(net.IPv4bcast) {
+		return true
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return false
+	}
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, address := range addresses {
+		subnet, ok := address.(*net.IPNet)
+		if !ok || subnet.IP.To4() == nil || len(subnet.Mask) != net.IPv4len {
+			continue
+		}
+		// Skip /31 and /32 subnets, which have no broadcast address
+		if ones, bits := subnet.Mask.Size(); bits-ones < 2 {
+			continue
+		}
+		broadcast := make(net.IP, net.IPv4len)
+		for i := range broadcast {
+			broadcast[i] = subnet.IP.To4()[i] | ^subnet.Mask[i]
+		}
+		if ip4.Equal(broadcast) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count the replies of each host to the last probe, remembering the order hosts first replied in
+func (stats *statistic) tallyResponders() {
+	if stats.responderStats == nil {
+		stats.responderStats = make(map[string]*responderStatistic)
+	}
+	for _, reply := range stats.responders {
+		replies, ok := stats.responderStats[reply.IP]
+		if !ok {
+			replies = new(responderStatistic)
+			stats.responderStats[reply.IP] = replies
+			stats.responderOrder = append(stats.responderOrder, reply.IP)
+		}
+		replies.replies++
+		replies.totalRTT += reply.RTT
+	}
+}
+
+// Print each host that replied to the broadcast or multicast target, with its average RTT
+func (stats *statistic) showResponders() {
+	fmt.Fprintf(noticeOutput(), "Responders: %d\n", len(stats.responderOrder))
+	for _, ip := range stats.responderOrder {
+		replies := stats.responderStats[ip]
+		fmt.Fprintf(
+			noticeOutput(),
+			"  %s\t\tReplies: %d\t\tAvg RTT: %s\n",
+			displayAddress(&net.IPAddr{IP: net.ParseIP(ip)}),
+			replies.replies,
+			display(replies.totalRTT/time.Duration(replies.replies)))
+	}
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+// Allow sending to broadcast addresses on the socket
+func enableBroadcast(rawConn syscall.RawConn) error {
+	var sockErr error
+	err := rawConn.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
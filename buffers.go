@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const packetBufferSize int = 1000 // Size of pooled packet buffers, fitting any request sent and reply read
+
+// Buffers requests are encoded in and replies read into, reused across probes and targets so steady
+// probing doesn't allocate a buffer per packet
+var packetBuffers = sync.Pool{New: func() any {
+	buffer := make([]byte, packetBufferSize)
+	return &buffer
+}}
+
+// Packets the replies of awaited probes are read into, reused like the buffers as each keeps the
+// source and decoded echo of the last reply read into it
+var replyPackets = sync.Pool{New: func() any {
+	return &packet{buffer: make([]byte, packetBufferSize)}
+}}
+
+var echoPayload = []byte("PLS-GIB-INTERNSHIP") // Payload of every echo request
+
+// Take a packet buffer from the pool, to be returned with putBuffer once nothing refers to it
+func getBuffer() *[]byte {
+	return packetBuffers.Get().(*[]byte)
+}
+
+// Return a packet buffer to the pool
+func putBuffer(buffer *[]byte) {
+	packetBuffers.Put(buffer)
+}
+
+// Encode an echo request into the buffer as icmp.Message.Marshal would, without allocating.
+// The checksum of IPv6 requests is left to the kernel, which fills it in on raw ICMPv6 sockets.
+func encodeEcho(buffer []byte, messageType icmp.Type, id int, seq int, data []byte) []byte {
+	var typeCode byte
+	switch messageType := messageType.(type) {
+	case ipv4.ICMPType:
+		typeCode = byte(messageType)
+	case ipv6.ICMPType:
+		typeCode = byte(messageType)
+	}
+	buffer = append(buffer[:0], typeCode, 0, 0, 0)
+	buffer = binary.BigEndian.AppendUint16(buffer, uint16(id))
+	buffer = binary.BigEndian.AppendUint16(buffer, uint16(seq))
+	buffer = append(buffer, data...)
+	if _, isIPv4 := messageType.(ipv4.ICMPType); isIPv4 {
+		binary.BigEndian.PutUint16(buffer[2:4], icmpChecksum(buffer))
+	}
+	return buffer
+}
+
+// Parse the ICMP message of a packet read. Echo messages are decoded into the packet's own, their
+// data left in its buffer, so the replies of steady probing don't allocate as icmp.ParseMessage does.
+func (read *packet) parse() (*icmp.Message, error) {
+	message := read.buffer[:read.n]
+	if len(message) >= 8 {
+		var messageType icmp.Type
+		switch {
+		case wantIPv6 && (message[0] == byte(ipv6.ICMPTypeEchoReply) || message[0] == byte(ipv6.ICMPTypeEchoRequest)):
+			messageType = ipv6.ICMPType(message[0])
+		case !wantIPv6 && (message[0] == byte(ipv4.ICMPTypeEchoReply) || message[0] == byte(ipv4.ICMPTypeEcho)):
+			messageType = ipv4.ICMPType(message[0])
+		}
+		if messageType != nil {
+			read.echo = icmp.Echo{
+				ID:   int(binary.BigEndian.Uint16(message[4:6])),
+				Seq:  int(binary.BigEndian.Uint16(message[6:8])),
+				Data: message[8:],
+			}
+			read.message = icmp.Message{Type: messageType, Code: int(message[1]), Checksum: int(binary.BigEndian.Uint16(message[2:4])), Body: &read.echo}
+			return &read.message, nil
+		}
+	}
+	protocolICMP := protocolICMP4
+	if wantIPv6 {
+		protocolICMP = protocolICMP6
+	}
+	return icmp.ParseMessage(protocolICMP, message)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/net/ipv4"
+)
+
+func BenchmarkEncodeEcho(b *testing.B) {
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+	b.ReportAllocs()
+	for seq := 0; b.Loop(); seq++ {
+		encodeEcho(*buffer, ipv4.ICMPTypeEcho, 0x1234, seq, echoPayload)
+	}
+}
+
+func BenchmarkParseEchoReply(b *testing.B) {
+	read := packet{buffer: make([]byte, packetBufferSize)}
+	read.n = len(encodeEcho(read.buffer, ipv4.ICMPTypeEchoReply, 0x1234, 1, echoPayload))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := read.parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
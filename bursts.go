@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Runs of consecutive lost probes, since bursts of loss hurt applications very differently than random loss
+type lossBursts struct {
+	lengths         map[int]int   // Number of bursts of each length in probes
+	longest         int           // Length of the longest burst in probes
+	longestOutage   time.Duration // Duration of the longest burst
+	current         int           // Length of the ongoing burst, 0 if the last probe replied
+	currentStart    time.Time     // Send time of the first probe of the ongoing burst
+	currentLastSent time.Time     // Send time of the last probe of the ongoing burst
+}
+
+// Track the outcome of a probe sent at the given time
+func (bursts *lossBursts) observe(replied bool, sent time.Time) {
+	if !replied {
+		if bursts.current == 0 {
+			bursts.currentStart = sent
+		}
+		bursts.current++
+		bursts.currentLastSent = sent
+		return
+	}
+	if bursts.current > 0 {
+		// The outage lasted until the probe that replied
+		bursts.end(sent.Sub(bursts.currentStart))
+	}
+}
+
+// Close the ongoing burst after the given outage
+func (bursts *lossBursts) end(outage time.Duration) {
+	if bursts.lengths == nil {
+		bursts.lengths = make(map[int]int)
+	}
+	bursts.lengths[bursts.current]++
+	if bursts.current > bursts.longest {
+		bursts.longest = bursts.current
+	}
+	if outage > bursts.longestOutage {
+		bursts.longestOutage = outage
+	}
+	bursts.current = 0
+}
+
+// Summarize the bursts, counting an ongoing burst as lasting until its last probe,
+// e.g. "3		Longest: 7 packets / 7s		Lengths: 1x2 7x1"
+func (bursts lossBursts) String() string {
+	if bursts.current > 0 {
+		ongoing := bursts
+		ongoing.lengths = make(map[int]int, len(bursts.lengths)+1)
+		for length, count := range bursts.lengths {
+			ongoing.lengths[length] = count
+		}
+		ongoing.end(bursts.currentLastSent.Sub(bursts.currentStart))
+		return ongoing.String()
+	}
+	total := 0
+	distribution := make([]int, 0, len(bursts.lengths))
+	for length, count := range bursts.lengths {
+		total += count
+		distribution = append(distribution, length)
+	}
+	sort.Ints(distribution)
+	counts := make([]string, len(distribution))
+	for i, length := range distribution {
+		counts[i] = fmt.Sprintf("%dx%d", length, bursts.lengths[length])
+	}
+	return fmt.Sprintf(
+		"%d\t\tLongest: %d packets / %s\t\tLengths: %s",
+		total,
+		bursts.longest,
+		display(bursts.longestOutage),
+		strings.Join(counts, " "))
+}
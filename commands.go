@@ -0,0 +1,321 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Subcommand of goping, given ahead of its flags
+type command struct {
+	name     string   // Name given as the first argument
+	usage    string   // Arguments following the flags
+	summary  string   // What the command does, shown in help
+	operands string   // What its arguments are completed as: hosts, files, commands, shells, sweeps, or none if empty
+	owned    []string // Flags only the commands listing them here take
+	common   []string // Flags shared with other commands this one takes, nil for all of them
+}
+
+// Flags every command takes, controlling logging and profiling
+var globalFlags = []string{"v", "vv", "log-file", "log-file-size", "journald", "pprof"}
+
+// Commands of goping in the order listed by goping help, ping being the default
+var commands = []command{
+	{
+		name:     "ping",
+		usage:    "address[=label][?settings] ...",
+		summary:  "Ping targets until interrupted or -c probes are sent (the default command)",
+		operands: "hosts",
+		owned:    []string{"daemon", "config-watch", "split-path", "heartbeat-url", "remote-write-url", "metrics-file", "assert-rtt-p95-below", "assert-rtt-avg-below", "assert-loss-below", "summary-only"},
+	},
+	{
+		name:     "trace",
+		usage:    "address",
+		summary:  "Trace the path to a target mtr style, probing every TTL up to it each round",
+		operands: "hosts",
+		owned:    []string{"max-hops", "export", "whois"},
+		common:   []string{"c", "ipv", "n", "mark", "vrf", "resolver", "doh", "dot", "probe", "pcap", "debug-packets", "interval-jitter", "precision", "timestamping", "flow-label", "ecn", "geoip"},
+	},
+	{
+		name:     "sweep",
+		usage:    "size|ttl [flags] address",
+		summary:  "Sweep a target across payload sizes or TTLs, goping sweep size and sweep ttl being sweep-size and sweep-ttl",
+		operands: "sweeps",
+		owned:    []string{"min", "max", "step", "first-ttl", "max-hops"},
+		common:   []string{"c", "ipv", "ttl", "n", "mark", "vrf", "resolver", "doh", "dot", "pcap", "debug-packets", "precision", "timestamping"},
+	},
+	{
+		name:     "sweep-size",
+		usage:    "address",
+		summary:  "Probe a target across a range of payload sizes, reporting RTT and loss per size to reveal MTU black holes",
+		operands: "hosts",
+		owned:    []string{"min", "max", "step"},
+		common:   []string{"c", "ipv", "ttl", "n", "mark", "vrf", "resolver", "doh", "dot", "pcap", "debug-packets", "precision", "timestamping"},
+	},
+	{
+		name:     "sweep-ttl",
+		usage:    "address",
+		summary:  "Probe a target with every TTL up to it, tabulating time exceeded and echo replies to find filtering and rate limiting hops",
+		operands: "hosts",
+		owned:    []string{"first-ttl", "max-hops"},
+		common:   []string{"c", "ipv", "n", "mark", "vrf", "resolver", "doh", "dot", "pcap", "debug-packets", "precision", "timestamping"},
+	},
+	{
+		name:     "bloat",
+		usage:    "address",
+		summary:  "Measure RTT idle and then under parallel HTTP load, grading the latency increase caused by bufferbloat",
+		operands: "hosts",
+		owned:    []string{"bloat-url", "bloat-streams", "bloat-direction", "bloat-duration"},
+		common:   []string{"ipv", "ttl", "n", "mark", "vrf", "resolver", "doh", "dot", "pcap", "precision", "timestamping"},
+	},
+	{
+		name:     "compare",
+		usage:    "addressA addressB | -via interfaceA,interfaceB address",
+		summary:  "Ping two targets in lockstep, or one over two interfaces, and judge which is faster",
+		operands: "hosts",
+		owned:    []string{"via"},
+	},
+	{
+		name:     "replay",
+		usage:    "file",
+		summary:  "Replay a -record file through the output, summary, report and heatmap",
+		operands: "files",
+		common:   []string{"o", "format", "precision", "report", "heatmap", "label"},
+	},
+	{
+		name:     "diff",
+		usage:    "before after",
+		summary:  "Compare two -record files for regressions",
+		operands: "files",
+		common:   []string{"precision"},
+	},
+	{
+		name:     "history",
+		usage:    "file|URL",
+		summary:  "Print the last results of a -record file, or of the -history kept by goping serve at an http:// URL",
+		operands: "files",
+		owned:    []string{"last", "api-token"},
+		common:   []string{"o", "format", "precision"},
+	},
+	{
+		name:     "report",
+		usage:    "file",
+		summary:  "Render the HTML report of a -record file to -report, or to the file's name ending .html",
+		operands: "files",
+		common:   []string{"report", "precision"},
+	},
+	{
+		name:     "whois",
+		usage:    "address|ASnumber ...",
+		summary:  "Look up the owner of IP addresses or AS numbers over RDAP, to tell whose network a hop belongs to",
+		operands: "hosts",
+		common:   []string{},
+	},
+	{
+		name:    "quicktest",
+		usage:   "",
+		summary: "Probe a few well-known anycast resolvers to gauge the local connection",
+		common:  []string{"c", "ipv", "ttl", "n", "mark", "vrf", "o", "format", "precision", "table", "timestamping", "label"},
+	},
+	{
+		name:     "benchmark-dns",
+		usage:    "[address[=label] ...]",
+		summary:  "Rank popular public resolvers, or the targets given, by latency, jitter or loss to choose a DNS server",
+		operands: "hosts",
+		owned:    []string{"sort"},
+		common:   []string{"c", "ipv", "ttl", "n", "mark", "vrf", "o", "precision", "timestamping", "label"},
+	},
+	{
+		name:     "benchmark-cdn",
+		usage:    "[address[=label] ...]",
+		summary:  "Rank popular CDNs, or the targets given, by latency, jitter or loss from their nearest edges",
+		operands: "hosts",
+		owned:    []string{"sort"},
+		common:   []string{"c", "ipv", "ttl", "n", "mark", "vrf", "o", "precision", "timestamping", "label"},
+	},
+	{
+		name:    "serve",
+		usage:   "",
+		summary: "Run ping jobs started and stopped remotely over gRPC and HTTP",
+		owned:   []string{"grpc", "http", "api-token"},
+	},
+	{
+		name:    "bench",
+		usage:   "",
+		summary: "Measure the probe rate, CPU and allocations achievable against localhost",
+		owned:   []string{"bench-pingers", "bench-interval"},
+		common:  []string{"duration", "ipv", "ttl"},
+	},
+	{
+		name:     "healthcheck",
+		usage:    "address",
+		summary:  "Probe a target a few times and exit 0 if it is healthy or 1 if not, for container healthchecks",
+		operands: "hosts",
+		owned:    []string{"healthcheck-max-loss", "healthcheck-max-rtt"},
+		common:   []string{"c", "ipv", "ttl", "resolver", "precision"},
+	},
+	{
+		name:    "service",
+		usage:   "install|uninstall|start|stop [flags] [address[=label] ...]",
+		summary: "Install, uninstall, start or stop the Windows service running -daemon with the flags and targets given to install",
+		common:  []string{},
+	},
+	{
+		name:     "help",
+		usage:    "[command]",
+		summary:  "List the commands, or show the flags of one",
+		operands: "commands",
+		common:   []string{},
+	},
+	{
+		name:     "completion",
+		usage:    "bash|zsh|fish|powershell",
+		summary:  "Print a script completing the commands, flags and targets of goping in a shell",
+		operands: "shells",
+		common:   []string{},
+	},
+}
+
+// Command of the given name, nil if there is none
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// Whether the flag is owned by a command, rather than shared between commands
+func ownedFlag(name string) bool {
+	for _, cmd := range commands {
+		if slices.Contains(cmd.owned, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the command takes the flag
+func (cmd *command) takes(name string) bool {
+	switch {
+	case slices.Contains(globalFlags, name), slices.Contains(cmd.owned, name):
+		return true
+	case ownedFlag(name):
+		return false
+	case cmd.common == nil:
+		return true
+	default:
+		return slices.Contains(cmd.common, name)
+	}
+}
+
+// What goping sweep sweeps, each run as the sweep-<kind> command
+var sweepKinds = []string{"size", "ttl"}
+
+// Take the command given ahead of the flags, defaulting to ping, and have -h print its help
+func parseCommand() *command {
+	cmd := findCommand("ping")
+	if len(os.Args) > 1 {
+		if named := findCommand(os.Args[1]); named != nil {
+			cmd = named
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		}
+	}
+	// Run goping sweep size and sweep ttl as sweep-size and sweep-ttl, their flags following the kind
+	if cmd.name == "sweep" && len(os.Args) > 1 && slices.Contains(sweepKinds, os.Args[1]) {
+		cmd = findCommand("sweep-" + os.Args[1])
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	flag.Usage = func() { cmd.printHelp() }
+	return cmd
+}
+
+// Fail with the command's help on flags given that it doesn't take, as flag.Parse does on
+// flags that aren't defined, or on goping sweep missing what to sweep
+func (cmd *command) rejectForeignFlags() {
+	output := flag.CommandLine.Output()
+	foreign := false
+	flag.Visit(func(given *flag.Flag) {
+		if !cmd.takes(given.Name) {
+			fmt.Fprintf(output, "flag -%s doesn't apply to goping %s\n", given.Name, cmd.name)
+			foreign = true
+		}
+	})
+	if cmd.name == "sweep" {
+		fmt.Fprintf(output, "goping sweep needs what to sweep ahead of the flags: %s\n", strings.Join(sweepKinds, " or "))
+		foreign = true
+	}
+	if foreign {
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// Print the commands of goping (goping help), or the help of the named command
+func runHelp(arguments []string) {
+	if len(arguments) > 0 {
+		cmd := findCommand(arguments[0])
+		if cmd == nil {
+			slog.Error(fmt.Sprintf("Unknown command %q, see goping help", arguments[0]))
+			os.Exit(1)
+		}
+		cmd.printHelp()
+		return
+	}
+	output := flag.CommandLine.Output()
+	fmt.Fprintf(output, "Usage: %s [command] [flags] [arguments]\n\nCommands:\n", os.Args[0])
+	width := 0
+	for _, cmd := range commands {
+		width = max(width, len(cmd.name))
+	}
+	for _, cmd := range commands {
+		fmt.Fprintf(output, "  %-*s  %s\n", width, cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(output, "\nRun '%s help <command>' for the flags of a command.\n", os.Args[0])
+}
+
+// Print the usage, summary and flags of the command
+func (cmd *command) printHelp() {
+	output := flag.CommandLine.Output()
+	// Put the flags ahead of the arguments, unless they follow a word given first as with service and sweep
+	synopsis := fmt.Sprintf("%s %s [flags] %s", os.Args[0], cmd.name, cmd.usage)
+	if strings.Contains(cmd.usage, "[flags]") {
+		synopsis = fmt.Sprintf("%s %s %s", os.Args[0], cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(output, "Usage: %s\n\n%s.\n\nFlags:\n", strings.TrimSpace(synopsis), cmd.summary)
+	// Print only the command's flags, in the style of flag.PrintDefaults
+	taken := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	taken.SetOutput(output)
+	for _, defined := range cmd.flags() {
+		taken.Var(defined.Value, defined.Name, defined.Usage)
+		taken.Lookup(defined.Name).DefValue = defined.DefValue
+	}
+	taken.PrintDefaults()
+	if cmd.name == "ping" {
+		fmt.Fprintf(output, "\nOther commands: %s. Run '%s help' for a list.\n", strings.Join(commandNames()[1:], ", "), os.Args[0])
+	}
+}
+
+// Flags the command takes, sorted by name
+func (cmd *command) flags() []*flag.Flag {
+	var taken []*flag.Flag
+	flag.VisitAll(func(defined *flag.Flag) {
+		if cmd.takes(defined.Name) {
+			taken = append(taken, defined)
+		}
+	})
+	return taken
+}
+
+// Names of every command
+func commandNames() []string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.name
+	}
+	return names
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+// The command is taken from ahead of the flags, goping sweep <kind> running as the sweep-<kind> command
+func TestParseCommand(t *testing.T) {
+	for _, test := range []struct {
+		arguments []string
+		command   string
+		rest      []string
+	}{
+		{[]string{"example.com"}, "ping", []string{"example.com"}},
+		{[]string{"trace", "-c", "3", "example.com"}, "trace", []string{"-c", "3", "example.com"}},
+		{[]string{"sweep", "size", "-max", "1472", "example.com"}, "sweep-size", []string{"-max", "1472", "example.com"}},
+		{[]string{"sweep", "ttl", "example.com"}, "sweep-ttl", []string{"example.com"}},
+		{[]string{"sweep", "-c", "3", "size", "example.com"}, "sweep", []string{"-c", "3", "size", "example.com"}},
+		{[]string{"sweep-size", "example.com"}, "sweep-size", []string{"example.com"}},
+	} {
+		arguments := os.Args
+		os.Args = append([]string{"goping"}, test.arguments...)
+		cmd := parseCommand()
+		rest := os.Args[1:]
+		os.Args = arguments
+		if cmd.name != test.command || !slices.Equal(rest, test.rest) {
+			t.Errorf("%q parsed as %s %q, want %s %q", test.arguments, cmd.name, rest, test.command, test.rest)
+		}
+	}
+}
+
+// A flag owned by one command isn't taken by others, which reject it
+func TestTakes(t *testing.T) {
+	for _, test := range []struct {
+		command string
+		flag    string
+		takes   bool
+	}{
+		{"sweep-size", "min", true},
+		{"sweep", "min", true},
+		{"sweep", "first-ttl", true},
+		{"healthcheck", "min", false},
+		{"sweep-ttl", "min", false},
+		{"trace", "max-hops", true},
+		{"healthcheck", "v", true},
+	} {
+		if takes := findCommand(test.command).takes(test.flag); takes != test.takes {
+			t.Errorf("goping %s taking -%s is %t, want %t", test.command, test.flag, takes, test.takes)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// |z| above which a difference between the two targets is reported as significant (~95% confidence)
+const significanceZ float64 = 1.96
+
+// Probe both targets of goping compare in lockstep, printing each pair of RTTs and their delta
+// Can be infinite or finite
+func runCompare(statsA *statistic, statsB *statistic, pingCount int) {
+	defer statsA.closeSocket()
+	defer statsB.closeSocket()
+	for i := 0; i != pingCount; i++ {
+		probing.wait(nil)
+		var (
+			wg               sync.WaitGroup
+			resultA, resultB probeResult
+		)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			resultA = statsA.probe()
+		}()
+		go func() {
+			defer wg.Done()
+			resultB = statsB.probe()
+		}()
+		wg.Wait()
+
+		// Print both RTTs every message, with their delta if both replied
+		delta := "-"
+		if resultA.Error == "" && resultB.Error == "" {
+			delta = display(resultA.RTT - resultB.RTT).String()
+		}
+		slog.Info(
+			fmt.Sprintf(
+				"Seq: %d\t\tA: %s\t\tB: %s\t\tDelta: %s",
+				resultA.Seq,
+				compareRTT(resultA),
+				compareRTT(resultB),
+				delta),
+			"seq", resultA.Seq,
+			"a", resultA.Target,
+			"b", resultB.Target,
+			"rttA", resultA.RTT,
+			"rttB", resultB.RTT)
+		time.Sleep(nextInterval(probeInterval)) // Sleep for the interval
+	}
+}
+
+// Describe one side's RTT for the compare output, or why it was lost
+func compareRTT(result probeResult) string {
+	if result.Error != "" {
+		slog.Debug("Probe lost", "target", result.Target, "error", result.Error)
+		return "lost"
+	}
+	return display(result.RTT).String()
+}
+
+// Print the summary of both targets followed by the verdict of the comparison
+func showComparison(statsA *statistic, statsB *statistic) {
+	showSummary([]*statistic{statsA, statsB})
+	fmt.Println("\n----------------------------| Comparison |----------------------------")
+
+	// Latency: Welch's t statistic, normally approximated for the verdict
+	meanA, deviationA := meanDeviation(statsA.rttAll)
+	meanB, deviationB := meanDeviation(statsB.rttAll)
+	fmt.Printf(
+		"Mean RTT A: %s ± %s\t\tMean RTT B: %s ± %s\n",
+		display(time.Duration(meanA)),
+		display(time.Duration(deviationA)),
+		display(time.Duration(meanB)),
+		display(time.Duration(deviationB)))
+	if len(statsA.rttAll) > 1 && len(statsB.rttAll) > 1 {
+		standardError := math.Sqrt(deviationA*deviationA/float64(len(statsA.rttAll)) + deviationB*deviationB/float64(len(statsB.rttAll)))
+		fmt.Printf("Faster: %s\n", verdict(meanB-meanA, standardError))
+	} else {
+		fmt.Println("Faster: not enough replies to compare")
+	}
+
+	// Loss: two proportion z-test
+	fmt.Printf("Relative loss (A - B): %.2f%%\n", statsA.loss-statsB.loss)
+	if statsA.count > 0 && statsB.count > 0 {
+		pooled := float64(statsA.lost+statsB.lost) / float64(statsA.count+statsB.count)
+		standardError := math.Sqrt(pooled * (1 - pooled) * (1/float64(statsA.count) + 1/float64(statsB.count)))
+		fmt.Printf("Less lossy: %s\n", verdict((statsB.loss-statsA.loss)/100, standardError))
+	}
+
+	// Stability: lower jitter, also checking the spread of RTTs with an F-like ratio of variances
+	statsA.calculateJitter()
+	statsB.calculateJitter()
+	stable := "no clear difference"
+	if len(statsA.rttAll) > 1 && len(statsB.rttAll) > 1 && deviationA > 0 && deviationB > 0 {
+		ratio := (deviationA * deviationA) / (deviationB * deviationB)
+		// Require the variances to differ by a factor of two as well as jitter to agree
+		switch {
+		case ratio > 2 && statsA.jitter > statsB.jitter:
+			stable = "B"
+		case ratio < 0.5 && statsA.jitter < statsB.jitter:
+			stable = "A"
+		}
+	}
+	fmt.Printf("Jitter A: %s\t\tJitter B: %s\t\tMore stable: %s\n", display(statsA.jitter), display(statsB.jitter), stable)
+
+	// Overhead of the first interface over the second with -via, such as that of a VPN
+	if statsA.device != "" && len(statsA.rttAll) > 0 && len(statsB.rttAll) > 0 {
+		overhead := time.Duration(meanA - meanB)
+		fmt.Printf(
+			"Overhead of %s over %s: %s mean RTT (%+.1f%%)\t\t%+.2f%% loss\n",
+			statsA.device,
+			statsB.device,
+			display(overhead),
+			100*(meanA-meanB)/meanB,
+			statsA.loss-statsB.loss)
+	}
+}
+
+// Name the side favoured by a positive (A) or negative (B) difference, if it is significant
+func verdict(difference float64, standardError float64) string {
+	if difference == 0 {
+		return "no difference"
+	}
+	z := zScore(difference, standardError)
+	switch {
+	case z > significanceZ:
+		return fmt.Sprintf("A (significant, z = %.2f)", z)
+	case z < -significanceZ:
+		return fmt.Sprintf("B (significant, z = %.2f)", -z)
+	default:
+		return fmt.Sprintf("no significant difference (z = %.2f)", math.Abs(z))
+	}
+}
+
+// Difference in units of its standard error, infinite if there is no spread at all
+func zScore(difference float64, standardError float64) float64 {
+	if standardError == 0 {
+		return math.Copysign(math.Inf(1), difference)
+	}
+	return difference / standardError
+}
+
+// Mean and sample standard deviation of the RTTs in nanoseconds
+func meanDeviation(rtts []time.Duration) (float64, float64) {
+	if len(rtts) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, rtt := range rtts {
+		sum += float64(rtt)
+	}
+	mean := sum / float64(len(rtts))
+	if len(rtts) == 1 {
+		return mean, 0
+	}
+	var squares float64
+	for _, rtt := range rtts {
+		squares += (float64(rtt) - mean) * (float64(rtt) - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(rtts)-1))
+}
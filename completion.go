@@ -0,0 +1,270 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Shells goping completion prints scripts for
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// Print the completion script of the shell (goping completion), generated from the commands and their flags
+func writeCompletion(writer io.Writer, shell string) error {
+	program := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	switch shell {
+	case "bash":
+		writeBashCompletion(writer, program)
+	case "zsh":
+		writeZshCompletion(writer, program)
+	case "fish":
+		writeFishCompletion(writer, program)
+	case "powershell":
+		writePowerShellCompletion(writer, program)
+	default:
+		return fmt.Errorf("Unknown shell %q, expected one of %s", shell, strings.Join(completionShells, ", "))
+	}
+	return nil
+}
+
+// Whether the flag is given without a value, like -v
+func boolFlag(defined *flag.Flag) bool {
+	value, ok := defined.Value.(interface{ IsBoolFlag() bool })
+	return ok && value.IsBoolFlag()
+}
+
+// Whether the flag's value is a file, completed as one
+func fileFlag(defined *flag.Flag) bool {
+	name, _ := flag.UnquoteUsage(defined)
+	return name == "file" || name == "path"
+}
+
+// Words the operands of the command are completed from, nil for hosts, files or none
+func (cmd *command) operandWords() []string {
+	switch cmd.operands {
+	case "commands":
+		return commandNames()
+	case "shells":
+		return completionShells
+	case "sweeps":
+		return sweepKinds
+	}
+	return nil
+}
+
+// Bash completion, completing commands, flags, file values of flags and recent targets, hosts or files as operands
+func writeBashCompletion(writer io.Writer, program string) {
+	fmt.Fprintf(writer, "# bash completion for %s, load with: source <(%s completion bash)\n", program, program)
+	fmt.Fprintf(writer, "_goping() {\n")
+	fmt.Fprintf(writer, "    local cur=${COMP_WORDS[COMP_CWORD]} previous=${COMP_WORDS[COMP_CWORD-1]} command=ping\n")
+	fmt.Fprintf(writer, "    if [[ $COMP_CWORD -gt 1 ]]; then\n")
+	fmt.Fprintf(writer, "        case ${COMP_WORDS[1]} in\n")
+	fmt.Fprintf(writer, "            %s) command=${COMP_WORDS[1]} ;;\n", strings.Join(commandNames(), "|"))
+	fmt.Fprintf(writer, "        esac\n")
+	fmt.Fprintf(writer, "    fi\n")
+	fmt.Fprintf(writer, "    local flags values files operands words\n")
+	fmt.Fprintf(writer, "    COMPREPLY=()\n")
+	fmt.Fprintf(writer, "    case $command in\n")
+	for _, cmd := range commands {
+		var flags, values, files []string
+		for _, defined := range cmd.flags() {
+			flags = append(flags, "-"+defined.Name)
+			if !boolFlag(defined) {
+				values = append(values, "-"+defined.Name)
+			}
+			if fileFlag(defined) {
+				files = append(files, "-"+defined.Name)
+			}
+		}
+		fmt.Fprintf(writer, "        %s)\n", cmd.name)
+		fmt.Fprintf(writer, "            flags=%q\n", strings.Join(flags, " "))
+		fmt.Fprintf(writer, "            values=%q\n", strings.Join(values, " "))
+		fmt.Fprintf(writer, "            files=%q\n", strings.Join(files, " "))
+		fmt.Fprintf(writer, "            operands=%s\n", cmd.operands)
+		fmt.Fprintf(writer, "            words=%q\n", strings.Join(cmd.operandWords(), " "))
+		fmt.Fprintf(writer, "            ;;\n")
+	}
+	fmt.Fprintf(writer, "    esac\n")
+	fmt.Fprintf(writer, "    # Complete the value of a flag taking one, as a file if it takes a file\n")
+	fmt.Fprintf(writer, "    if [[ \" $values \" == *\" $previous \"* ]]; then\n")
+	fmt.Fprintf(writer, "        if [[ \" $files \" == *\" $previous \"* ]]; then\n")
+	fmt.Fprintf(writer, "            COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+	fmt.Fprintf(writer, "        fi\n")
+	fmt.Fprintf(writer, "        return\n")
+	fmt.Fprintf(writer, "    fi\n")
+	fmt.Fprintf(writer, "    if [[ $cur == -* ]]; then\n")
+	fmt.Fprintf(writer, "        COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	fmt.Fprintf(writer, "        return\n")
+	fmt.Fprintf(writer, "    fi\n")
+	fmt.Fprintf(writer, "    if [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(writer, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	fmt.Fprintf(writer, "    fi\n")
+	fmt.Fprintf(writer, "    case $operands in\n")
+	fmt.Fprintf(writer, "        hosts) COMPREPLY+=($(compgen -W \"$(\"${COMP_WORDS[0]}\" completion --targets 2>/dev/null)\" -- \"$cur\") $(compgen -A hostname -- \"$cur\")) ;;\n")
+	fmt.Fprintf(writer, "        files) COMPREPLY+=($(compgen -f -- \"$cur\")) ;;\n")
+	fmt.Fprintf(writer, "        commands|shells|sweeps) COMPREPLY+=($(compgen -W \"$words\" -- \"$cur\")) ;;\n")
+	fmt.Fprintf(writer, "    esac\n")
+	fmt.Fprintf(writer, "}\n")
+	fmt.Fprintf(writer, "complete -F _goping %s\n", program)
+}
+
+// Zsh completion, describing commands and flags with their help
+func writeZshCompletion(writer io.Writer, program string) {
+	fmt.Fprintf(writer, "#compdef %s\n\n", program)
+	fmt.Fprintf(writer, "# zsh completion for %s, install with: %s completion zsh > \"${fpath[1]}/_%s\"\n", program, program, program)
+	fmt.Fprintf(writer, "# Complete the targets recently pinged, then hosts known to the shell\n")
+	fmt.Fprintf(writer, "_goping_targets() {\n")
+	fmt.Fprintf(writer, "    local -a recent=(${(f)\"$($executable completion --targets 2>/dev/null)\"})\n")
+	fmt.Fprintf(writer, "    _alternative 'recent:recent target:compadd -a recent' 'hosts:host:_hosts'\n")
+	fmt.Fprintf(writer, "}\n\n")
+	fmt.Fprintf(writer, "_goping() {\n")
+	fmt.Fprintf(writer, "    local command=ping executable=${words[1]}\n")
+	fmt.Fprintf(writer, "    local -a commands=(\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(writer, "        '%s:%s'\n", cmd.name, zshQuote(cmd.summary))
+	}
+	fmt.Fprintf(writer, "    )\n")
+	fmt.Fprintf(writer, "    if (( CURRENT > 2 )) && [[ -n ${(M)commands:#${(b)words[2]}:*} ]]; then\n")
+	fmt.Fprintf(writer, "        command=${words[2]}\n")
+	fmt.Fprintf(writer, "        shift words\n")
+	fmt.Fprintf(writer, "        (( CURRENT-- ))\n")
+	fmt.Fprintf(writer, "    elif (( CURRENT == 2 )) && [[ $PREFIX != -* ]]; then\n")
+	fmt.Fprintf(writer, "        _describe -t commands command commands\n")
+	fmt.Fprintf(writer, "    fi\n")
+	fmt.Fprintf(writer, "    case $command in\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(writer, "        %s)\n", cmd.name)
+		fmt.Fprintf(writer, "            _arguments")
+		for _, defined := range cmd.flags() {
+			name, usage := flag.UnquoteUsage(defined)
+			spec := fmt.Sprintf("-%s[%s]", defined.Name, zshQuote(usage))
+			switch {
+			case fileFlag(defined):
+				spec += fmt.Sprintf(":%s:_files", name)
+			case !boolFlag(defined):
+				spec += fmt.Sprintf(":%s: ", zshQuote(name))
+			}
+			fmt.Fprintf(writer, " \\\n                '%s'", spec)
+		}
+		switch cmd.operands {
+		case "hosts":
+			fmt.Fprintf(writer, " \\\n                '*:host:_goping_targets'")
+		case "files":
+			fmt.Fprintf(writer, " \\\n                '*:file:_files'")
+		case "commands", "shells", "sweeps":
+			fmt.Fprintf(writer, " \\\n                '*:%s:(%s)'", strings.TrimSuffix(cmd.operands, "s"), strings.Join(cmd.operandWords(), " "))
+		}
+		fmt.Fprintf(writer, "\n            ;;\n")
+	}
+	fmt.Fprintf(writer, "    esac\n")
+	fmt.Fprintf(writer, "}\n\n")
+	// Run when autoloaded from fpath, or register when sourced
+	fmt.Fprintf(writer, "if [[ $funcstack[1] == _goping ]]; then\n")
+	fmt.Fprintf(writer, "    _goping \"$@\"\n")
+	fmt.Fprintf(writer, "else\n")
+	fmt.Fprintf(writer, "    compdef _goping %s\n", program)
+	fmt.Fprintf(writer, "fi\n")
+}
+
+// Quote text for a single-quoted zsh _arguments spec or _describe entry
+func zshQuote(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, `:`, `\:`, `'`, `'\''`).Replace(text)
+}
+
+// Fish completion, describing commands and flags with their help
+func writeFishCompletion(writer io.Writer, program string) {
+	fmt.Fprintf(writer, "# fish completion for %s, load with: %s completion fish | source\n", program, program)
+	fmt.Fprintf(writer, "complete -c %s -f\n", program)
+	for _, cmd := range commands {
+		fmt.Fprintf(writer, "complete -c %s -n __fish_use_subcommand -a %s -d '%s'\n", program, cmd.name, fishQuote(cmd.summary))
+	}
+	for _, cmd := range commands {
+		// Flags of ping apply until another command is given, ping being the default
+		condition := "__fish_seen_subcommand_from " + cmd.name
+		if cmd.name == "ping" {
+			condition = "not __fish_seen_subcommand_from " + strings.Join(commandNames()[1:], " ")
+		}
+		for _, defined := range cmd.flags() {
+			_, usage := flag.UnquoteUsage(defined)
+			option := "-o " + defined.Name
+			if len(defined.Name) == 1 {
+				option = "-s " + defined.Name
+			}
+			switch {
+			case fileFlag(defined):
+				option += " -r -F"
+			case !boolFlag(defined):
+				option += " -r"
+			}
+			fmt.Fprintf(writer, "complete -c %s -n '%s' %s -d '%s'\n", program, condition, option, fishQuote(usage))
+		}
+		switch cmd.operands {
+		case "hosts":
+			fmt.Fprintf(writer, "complete -c %s -n '%s' -a '(%s completion --targets 2>/dev/null) (__fish_print_hostnames)'\n", program, condition, program)
+		case "files":
+			fmt.Fprintf(writer, "complete -c %s -n '%s' -F\n", program, condition)
+		case "commands", "shells", "sweeps":
+			fmt.Fprintf(writer, "complete -c %s -n '%s' -a '%s'\n", program, condition, strings.Join(cmd.operandWords(), " "))
+		}
+	}
+}
+
+// Quote text for a single-quoted fish argument
+func fishQuote(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(text)
+}
+
+// PowerShell completion, completing commands, flags and the targets recently pinged, and falling back to files for operands
+func writePowerShellCompletion(writer io.Writer, program string) {
+	quote := strings.NewReplacer(`'`, `''`)
+	list := func(words []string) string {
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			quoted[i] = "'" + quote.Replace(word) + "'"
+		}
+		return "@(" + strings.Join(quoted, ", ") + ")"
+	}
+	fmt.Fprintf(writer, "# PowerShell completion for %s, load with: %s completion powershell | Out-String | Invoke-Expression\n", program, program)
+	fmt.Fprintf(writer, "Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {\n", program)
+	fmt.Fprintf(writer, "    param($wordToComplete, $commandAst, $cursorPosition)\n")
+	fmt.Fprintf(writer, "    $commands = %s\n", list(commandNames()))
+	fmt.Fprintf(writer, "    $flags = @{\n")
+	for _, cmd := range commands {
+		var flags []string
+		for _, defined := range cmd.flags() {
+			flags = append(flags, "-"+defined.Name)
+		}
+		fmt.Fprintf(writer, "        '%s' = %s\n", cmd.name, list(flags))
+	}
+	fmt.Fprintf(writer, "    }\n")
+	fmt.Fprintf(writer, "    $operands = @{\n")
+	for _, cmd := range commands {
+		if words := cmd.operandWords(); words != nil {
+			fmt.Fprintf(writer, "        '%s' = %s\n", cmd.name, list(words))
+		}
+	}
+	fmt.Fprintf(writer, "    }\n")
+	var hosts []string
+	for _, cmd := range commands {
+		if cmd.operands == "hosts" {
+			hosts = append(hosts, cmd.name)
+		}
+	}
+	fmt.Fprintf(writer, "    $hosts = %s\n", list(hosts))
+	fmt.Fprintf(writer, "    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })\n")
+	fmt.Fprintf(writer, "    $first = $words.Count -eq 0 -or ($words.Count -eq 1 -and $wordToComplete -ne '')\n")
+	fmt.Fprintf(writer, "    $command = 'ping'\n")
+	fmt.Fprintf(writer, "    if (-not $first -and $flags.ContainsKey($words[0])) { $command = $words[0] }\n")
+	fmt.Fprintf(writer, "    if ($wordToComplete -like '-*') { $candidates = $flags[$command] }\n")
+	fmt.Fprintf(writer, "    elseif ($first) { $candidates = $commands }\n")
+	fmt.Fprintf(writer, "    elseif ($operands.ContainsKey($command)) { $candidates = $operands[$command] }\n")
+	fmt.Fprintf(writer, "    elseif ($hosts -contains $command) { $candidates = @(& '%s' completion --targets 2>$null) }\n", quote.Replace(program))
+	fmt.Fprintf(writer, "    else { return }\n")
+	fmt.Fprintf(writer, "    $candidates | Where-Object { $_ -like \"$wordToComplete*\" } | ForEach-Object {\n")
+	fmt.Fprintf(writer, "        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)\n")
+	fmt.Fprintf(writer, "    }\n")
+	fmt.Fprintf(writer, "}\n")
+}
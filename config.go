@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Settings read from the -config file, a JSON object such as
+// {"targets": ["1.1.1.1=cloudflare", "example.com"], "labels": {"site": "lab"}, "alerts": {...}}
+type config struct {
+	Targets  []string         `json:"targets"`  // Hostnames or IP addresses to ping, each optionally labelled as host=label
+	Default  string           `json:"default"`  // Hostname or IP address to ping when no targets are given anywhere, empty for none
+	Labels   labels           `json:"labels"`   // Labels attached to every target, under any given with -label
+	Alerts   *alertConfig     `json:"alerts"`   // Alerting on up/down transitions and SLA breaches, nil for none
+	SLO      *sloConfig       `json:"slo"`      // Service level objectives tracked, nil for none
+	Schedule []scheduleWindow `json:"schedule"` // Windows of local time probes may be sent in, empty for any time
+}
+
+// Read and check the config file, rejecting unknown keys so typos aren't silently ignored
+func loadConfig(path string) (*config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	var settings config
+	if err := decoder.Decode(&settings); err != nil {
+		return nil, fmt.Errorf("Invalid config file %s: %w", path, err)
+	}
+	return &settings, nil
+}
+
+// Merge the config labels under the -label tags, which take precedence
+func (settings *config) tags(global labels) labels {
+	merged := make(labels, len(settings.Labels)+len(global))
+	for key, value := range settings.Labels {
+		merged[key] = value
+	}
+	for key, value := range global {
+		merged[key] = value
+	}
+	return merged
+}
+
+// Keys of the config read only at start whose settings differ in the other config, which a reload
+// can't apply: alerting and SLO thresholds, and the schedule
+func (settings *config) startupChanges(other *config) []string {
+	var changed []string
+	for _, key := range []struct {
+		name          string
+		before, after any
+	}{
+		{"alerts", settings.Alerts, other.Alerts},
+		{"slo", settings.SLO, other.SLO},
+		{"schedule", settings.Schedule, other.Schedule},
+	} {
+		before, _ := json.Marshal(key.before)
+		after, _ := json.Marshal(key.after)
+		if !bytes.Equal(before, after) {
+			changed = append(changed, key.name)
+		}
+	}
+	return changed
+}
+
+// Alerting settings of the config file
+type alertConfig struct {
+	Window   duration        `json:"window"`   // Span of the rolling window SLAs are evaluated over, default 5m
+	MaxLoss  float64         `json:"max_loss"` // Loss percentage over the window breaching the SLA, 0 for none
+	MaxRTT   duration        `json:"max_rtt"`  // Average RTT over the window breaching the SLA, 0 for none
+	Slack    string          `json:"slack"`    // Slack incoming webhook URL
+	Discord  string          `json:"discord"`  // Discord webhook URL
+	Telegram *telegramConfig `json:"telegram"` // Telegram bot and chat to message
+	Email    *emailConfig    `json:"email"`    // SMTP server and addresses to mail
+	SNMP     *snmpConfig     `json:"snmp"`     // SNMP manager to send traps to
+}
+
+// Telegram bot messaging a chat
+type telegramConfig struct {
+	Token string `json:"token"` // Bot token from @BotFather
+	Chat  string `json:"chat"`  // Chat ID or @channel name
+}
+
+// SMTP delivery of alerts
+type emailConfig struct {
+	Server   string   `json:"server"`   // SMTP server host[:port], port 587 by default and 465 for implicit TLS
+	Username string   `json:"username"` // User to authenticate as, empty for none
+	Password string   `json:"password"` // Password of the user
+	From     string   `json:"from"`     // Sender address
+	To       []string `json:"to"`       // Recipient addresses
+	Subject  string   `json:"subject"`  // text/template of the subject, default {{.Subject}}
+	Body     string   `json:"body"`     // text/template of the body, default the subject and window statistics
+}
+
+// SNMPv2c traps of alerts
+type snmpConfig struct {
+	Manager    string `json:"manager"`    // SNMP manager host[:port] receiving traps, port 162 by default
+	Community  string `json:"community"`  // Community string, default public
+	Enterprise string `json:"enterprise"` // OID traps and their objects are numbered under, default 1.3.6.1.4.1.8072.9999.9999
+}
+
+// Service level objectives tracked per target
+type sloConfig struct {
+	Objectives []objectiveConfig `json:"objectives"` // Objectives every target is held to
+	Report     duration          `json:"report"`     // Interval of SLO reports, default 1h
+	BurnRate   float64           `json:"burn_rate"`  // Error budget burn rate over 1/24 of the window alerted on, default 10
+}
+
+// Objective on RTT percentile or loss over a window, e.g. p95 RTT < 80ms over 24h
+type objectiveConfig struct {
+	Name       string   `json:"name"`       // Name in reports and alerts, default rtt or loss
+	RTT        duration `json:"rtt"`        // RTT the percentile must stay under, for RTT objectives
+	Percentile float64  `json:"percentile"` // Percentile of RTT objectives, default 95
+	Loss       float64  `json:"loss"`       // Loss percentage to stay under, for loss objectives
+	Window     duration `json:"window"`     // Span compliance is measured over, default 24h
+}
+
+// Duration written in config files as a string such as "5m" or "150ms"
+type duration time.Duration
+
+// Parse a duration string
+func (length *duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("Duration must be a string such as \"5m\", got %s", data)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*length = duration(parsed)
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// Alerts, SLOs and the schedule are reported changed on reload, as only a restart applies them
+func TestStartupChanges(t *testing.T) {
+	started := &config{
+		Targets:  []string{"1.1.1.1"},
+		Alerts:   &alertConfig{MaxLoss: 5, Slack: "https://hooks.slack.com/x"},
+		Schedule: []scheduleWindow{{Days: "mon-fri", From: "09:00", To: "18:00"}},
+	}
+	for _, test := range []struct {
+		name     string
+		reloaded *config
+		changed  []string
+	}{
+		{"unchanged", &config{
+			Targets:  []string{"1.1.1.1"},
+			Alerts:   &alertConfig{MaxLoss: 5, Slack: "https://hooks.slack.com/x"},
+			Schedule: []scheduleWindow{{Days: "mon-fri", From: "09:00", To: "18:00"}},
+		}, nil},
+		{"targets and labels", &config{
+			Targets:  []string{"8.8.8.8"},
+			Labels:   labels{"site": "lab"},
+			Alerts:   &alertConfig{MaxLoss: 5, Slack: "https://hooks.slack.com/x"},
+			Schedule: []scheduleWindow{{Days: "mon-fri", From: "09:00", To: "18:00"}},
+		}, nil},
+		{"alert threshold", &config{
+			Targets:  []string{"1.1.1.1"},
+			Alerts:   &alertConfig{MaxLoss: 1, Slack: "https://hooks.slack.com/x"},
+			Schedule: []scheduleWindow{{Days: "mon-fri", From: "09:00", To: "18:00"}},
+		}, []string{"alerts"}},
+		{"slo added and schedule removed", &config{
+			Targets: []string{"1.1.1.1"},
+			Alerts:  &alertConfig{MaxLoss: 5, Slack: "https://hooks.slack.com/x"},
+			SLO:     &sloConfig{Objectives: []objectiveConfig{{Loss: 1}}},
+		}, []string{"slo", "schedule"}},
+	} {
+		if changed := started.startupChanges(test.reloaded); !slices.Equal(changed, test.changed) {
+			t.Errorf("%s: got %q changed, want %q", test.name, changed, test.changed)
+		}
+	}
+}
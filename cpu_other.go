@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "time"
+
+// CPU time isn't measured here, so goping bench reports only rates and allocations
+func processCPU() (time.Duration, bool) {
+	return 0, false
+}
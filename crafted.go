@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+var (
+	craftedType int = -1 // ICMP type of probes (-icmp-type) flag with -expert, negative for that of the probe type
+	craftedCode int      // ICMP code of probes (-icmp-code) flag with -expert
+)
+
+// Whether probes carry an ICMP type or code of the user's choosing (-expert)
+func craftedProbe() bool {
+	return craftedType >= 0 || craftedCode != 0
+}
+
+// Rewrite the type and code of an encoded probe to the crafted ones, keeping its identifier,
+// sequence and payload, and refresh the checksum of IPv4 (the kernel computes that of ICMPv6)
+func craftProbe(encoded []byte) {
+	if craftedType >= 0 {
+		encoded[0] = byte(craftedType)
+	}
+	encoded[1] = byte(craftedCode)
+	if !wantIPv6 {
+		binary.BigEndian.PutUint16(encoded[2:4], icmpChecksum(encoded))
+	}
+}
+
+// Whether a reply the probe type doesn't expect answers a crafted probe, as replies to types such
+// as information requests parse as raw bodies carrying the request's identifier and sequence.
+// The raw socket's copy of the probe itself, as seen on loopback, isn't an answer.
+func craftedAnswer(reply *icmp.Message, id int, seq int) bool {
+	body, ok := reply.Body.(*icmp.RawBody)
+	if !craftedProbe() || !ok || len(body.Data) < 4 || typeNumber(reply.Type) == craftedType {
+		return false
+	}
+	return int(binary.BigEndian.Uint16(body.Data[0:2])) == id && int(binary.BigEndian.Uint16(body.Data[2:4])) == seq
+}
+
+// Number of an ICMP or ICMPv6 message type
+func typeNumber(kind icmp.Type) int {
+	switch kind := kind.(type) {
+	case ipv4.ICMPType:
+		return int(kind)
+	case ipv6.ICMPType:
+		return int(kind)
+	}
+	return -1
+}
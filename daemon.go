@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Long-lived monitoring service (-daemon) pinging the targets of the -config file and the
+// command line, each as its own engine job so they can be added and removed on reload
+type daemon struct {
+	server     *engine           // Engine running a job per target
+	configPath string            // Config file re-read on SIGHUP, empty for none
+	watch      time.Duration     // Interval the config file is checked for changes at (-config-watch), 0 for none
+	watched    []byte            // Contents of the config file when last checked
+	started    *config           // Config the daemon started with, whose alerts, slo and schedule stay in effect
+	arguments  []string          // Targets given on the command line, always pinged
+	global     labels            // Labels given with -label
+	count      int               // Times to ping each target, -1 for forever
+	jobs       map[string]string // Job ID of each target by its argument and labels
+}
+
+// Create a daemon pinging the command line targets and those of the config file, checked for changes every watch if positive
+func newDaemon(configPath string, watch time.Duration, arguments []string, global labels, count int) *daemon {
+	return &daemon{
+		server:     newEngine(),
+		configPath: configPath,
+		watch:      watch,
+		arguments:  arguments,
+		global:     global,
+		count:      count,
+		jobs:       make(map[string]string),
+	}
+}
+
+// Start the targets, report readiness to systemd, then reload the config on every SIGHUP, and
+// whenever it changes if watched, until terminated, keeping the current targets if a reload fails
+func (service *daemon) run() error {
+	if err := service.reload(); err != nil {
+		return err
+	}
+	service.notifyReady()
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	var changes <-chan time.Time
+	if service.watch > 0 && service.configPath != "" {
+		service.changed()
+		ticker := time.NewTicker(service.watch)
+		defer ticker.Stop()
+		changes = ticker.C
+	}
+	for {
+		select {
+		case <-hangups:
+			slog.Info("SIGHUP received. Reloading config...")
+		case <-changes:
+			if !service.changed() {
+				continue
+			}
+			slog.Info("Config file changed. Reloading config...")
+		}
+		sdNotify("RELOADING=1")
+		if err := service.reload(); err != nil {
+			slog.Error(err.Error() + ". Keeping the current targets...")
+		}
+		service.notifyReady()
+	}
+}
+
+// Whether the config file's contents changed since last checked. Contents are compared rather than
+// modification times, as a Kubernetes ConfigMap volume swaps in a new file through a symlink.
+func (service *daemon) changed() bool {
+	contents, err := os.ReadFile(service.configPath)
+	if err != nil || bytes.Equal(contents, service.watched) {
+		return false
+	}
+	service.watched = contents
+	return true
+}
+
+// Re-read the config, stopping targets no longer listed and starting new ones. Targets still
+// listed with the same labels keep running, so their statistics carry over. Alerts, SLOs and the
+// schedule are set up once at start, so changes to them are warned of until restarted.
+func (service *daemon) reload() error {
+	settings := new(config)
+	if service.configPath != "" {
+		var err error
+		if settings, err = loadConfig(service.configPath); err != nil {
+			return err
+		}
+	}
+	if service.started == nil {
+		service.started = settings
+	} else if changed := service.started.startupChanges(settings); len(changed) > 0 {
+		slog.Warn(fmt.Sprintf("Config %s changed, which only applies on restart. Keeping the settings goping started with...", strings.Join(changed, ", ")))
+	}
+	tags := settings.tags(service.global)
+	wanted := make(map[string]string) // Target argument by key
+	var added []string                // Keys of targets to start, in config order
+	arguments, err := expandKeywords(append(append([]string(nil), service.arguments...), settings.Targets...))
+	if err != nil {
+		return err
+	}
+	for _, argument := range arguments {
+		key := argument + " " + tags.String()
+		if _, listed := wanted[key]; listed {
+			continue
+		}
+		wanted[key] = argument
+		if _, running := service.jobs[key]; !running {
+			added = append(added, key)
+		}
+	}
+	if len(wanted) == 0 {
+		return errors.New("Please enter IPs/hostnames to ping in the config file or on the command line")
+	}
+
+	// Stop all removed targets at once, then wait for each to finish its current probe
+	var removed []string
+	for key, id := range service.jobs {
+		if _, listed := wanted[key]; !listed {
+			service.server.stop(id)
+			removed = append(removed, key)
+		}
+	}
+	for _, key := range removed {
+		service.server.remove(service.jobs[key])
+		delete(service.jobs, key)
+	}
+	// Skip targets that can't start, such as a mistyped hostname, so the rest still run. They are
+	// retried by the next reload.
+	started := 0
+	for _, key := range added {
+		startedJob, err := service.server.start([]string{wanted[key]}, service.count, tags)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Skipping target %s: %s", wanted[key], err))
+			continue
+		}
+		service.jobs[key] = startedJob.id
+		started++
+	}
+	slog.Info(fmt.Sprintf("Pinging %d targets, %d added and %d removed...", len(service.jobs), started, len(removed)))
+	return nil
+}
+
+// Tell systemd the daemon is ready, with a status line shown by systemctl status
+func (service *daemon) notifyReady() {
+	if err := sdNotify(fmt.Sprintf("READY=1\nSTATUS=Pinging %d targets", len(service.jobs))); err != nil {
+		slog.Warn("Failed to notify systemd: " + err.Error())
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var desktopNotifications bool // Native desktop notifications of outages (-notify-desktop) flag
+
+// Notifier showing alerts as native desktop notifications, with notify-send, osascript or a toast
+type desktopNotifier struct{}
+
+// Check the platform's notification command is installed
+func newDesktopNotifier() (desktopNotifier, error) {
+	_, err := exec.LookPath(desktopCommand)
+	return desktopNotifier{}, err
+}
+
+// Name of the destination for logs
+func (desktopNotifier) name() string {
+	return "desktop"
+}
+
+// Show the alert's headline with its window statistics, urgently when the target went down
+func (desktopNotifier) notify(event alertEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	title := strings.TrimPrefix(event.subject(), "goPing: ")
+	output, err := desktopCommandFor(ctx, title, event.Stats.String(), event.Kind == alertDown).CombinedOutput()
+	if err != nil && len(output) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return err
+}
@@ -0,0 +1,19 @@
+//go:build darwin
+
+package main
+
+import (
+	"context"
+	"os/exec"
+)
+
+const desktopCommand string = "osascript" // Runs the AppleScript showing notifications in Notification Center
+
+// Command showing a notification with osascript, passing the text as arguments so it needs no quoting
+func desktopCommandFor(ctx context.Context, title string, body string, urgent bool) *exec.Cmd {
+	script := "display notification (item 2 of argv) with title \"goPing\" subtitle (item 1 of argv)"
+	if urgent {
+		script += " sound name \"Basso\""
+	}
+	return exec.CommandContext(ctx, desktopCommand, "-e", "on run argv", "-e", script, "-e", "end run", title, body)
+}
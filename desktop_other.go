@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package main
+
+import (
+	"context"
+	"os/exec"
+)
+
+const desktopCommand string = "notify-send" // Not found on this platform, so -notify-desktop is ignored
+
+// Desktop notifications are only shown on Unix, macOS and Windows
+func desktopCommandFor(ctx context.Context, title string, body string, urgent bool) *exec.Cmd {
+	return exec.CommandContext(ctx, desktopCommand, title, body)
+}
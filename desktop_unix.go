@@ -0,0 +1,32 @@
+//go:build unix && !darwin
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+const desktopCommand string = "notify-send" // Shows desktop notifications through the session's notification daemon
+
+// Command showing a notification with notify-send. Run through sudo, as raw sockets often are, it
+// runs as the invoking user on their session bus, which root can't reach.
+func desktopCommandFor(ctx context.Context, title string, body string, urgent bool) *exec.Cmd {
+	urgency := "normal"
+	if urgent {
+		urgency = "critical"
+	}
+	cmd := exec.CommandContext(ctx, desktopCommand, "--app-name=goPing", "--urgency="+urgency, title, body)
+	uid, uidErr := strconv.Atoi(os.Getenv("SUDO_UID"))
+	gid, gidErr := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if os.Geteuid() != 0 || uidErr != nil || gidErr != nil || uid == 0 {
+		return cmd
+	}
+	runtimeDir := "/run/user/" + strconv.Itoa(uid)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}}
+	cmd.Env = append(os.Environ(), "XDG_RUNTIME_DIR="+runtimeDir, "DBUS_SESSION_BUS_ADDRESS=unix:path="+runtimeDir+"/bus")
+	return cmd
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+const desktopCommand string = "powershell.exe" // Runs the script showing toast notifications
+
+// Toast of the text in GOPING_TITLE and GOPING_BODY, shown as PowerShell's since toasts need a registered app ID
+const toastScript string = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:GOPING_TITLE)) | Out-Null
+$text.Item(1).AppendChild($template.CreateTextNode($env:GOPING_BODY)) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe').Show($toast)
+`
+
+// Command showing a toast notification with PowerShell, passing the text in the environment so it needs no quoting
+func desktopCommandFor(ctx context.Context, title string, body string, urgent bool) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, desktopCommand, "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(), "GOPING_TITLE="+title, "GOPING_BODY="+body)
+	return cmd
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Compare the probe results of two recordings made with -record, per target they share,
+// or as a whole if they share none (goping diff)
+func diffSessions(pathA string, pathB string) error {
+	headerA, resultsA, err := readRecording(pathA)
+	if err != nil {
+		return err
+	}
+	headerB, resultsB, err := readRecording(pathB)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("A: %s, %d probes recorded %s\n", pathA, len(resultsA), headerA.Start.Format(time.RFC1123))
+	fmt.Printf("B: %s, %d probes recorded %s\n", pathB, len(resultsB), headerB.Start.Format(time.RFC1123))
+
+	targetsA, byTargetA := groupResults(resultsA)
+	_, byTargetB := groupResults(resultsB)
+	shared := false
+	for _, target := range targetsA {
+		if results, ok := byTargetB[target]; ok {
+			fmt.Printf("\n----------------------------| %s |----------------------------\n", target)
+			showDiff(byTargetA[target], results)
+			shared = true
+		}
+	}
+	if !shared {
+		fmt.Println("\n----------------------------| All targets |----------------------------")
+		showDiff(resultsA, resultsB)
+	}
+	return nil
+}
+
+// Split probe results by target, keeping the order targets first appear in
+func groupResults(results []probeResult) ([]string, map[string][]probeResult) {
+	var targets []string
+	byTarget := make(map[string][]probeResult)
+	for _, result := range results {
+		if _, ok := byTarget[result.Target]; !ok {
+			targets = append(targets, result.Target)
+		}
+		byTarget[result.Target] = append(byTarget[result.Target], result)
+	}
+	return targets, byTarget
+}
+
+// Print the change from A to B of median and p95 RTT, loss and jitter, marking significant changes
+func showDiff(resultsA []probeResult, resultsB []probeResult) {
+	rttsA, lostA := repliedRTTs(resultsA)
+	rttsB, lostB := repliedRTTs(resultsB)
+
+	// Median: Mann-Whitney U test, which doesn't assume normally distributed RTTs
+	fmt.Printf(
+		"Median RTT: %s -> %s\t\t%s\n",
+		display(percentile(rttsA, 50)),
+		display(percentile(rttsB, 50)),
+		significance(mannWhitney(rttsA, rttsB)))
+	// p95: no test, as a tail percentile of a short run is itself noisy
+	fmt.Printf(
+		"p95 RTT: %s -> %s\t\tChange: %s\n",
+		display(percentile(rttsA, 95)),
+		display(percentile(rttsB, 95)),
+		display(percentile(rttsB, 95)-percentile(rttsA, 95)))
+
+	// Loss: two proportion z-test
+	lossA := float64(lostA) / math.Max(float64(len(resultsA)), 1)
+	lossB := float64(lostB) / math.Max(float64(len(resultsB)), 1)
+	z := math.NaN()
+	switch {
+	case len(resultsA) == 0 || len(resultsB) == 0:
+	case lossA == lossB:
+		z = 0
+	default:
+		pooled := float64(lostA+lostB) / float64(len(resultsA)+len(resultsB))
+		z = zScore(lossB-lossA, math.Sqrt(pooled*(1-pooled)*(1/float64(len(resultsA))+1/float64(len(resultsB)))))
+	}
+	fmt.Printf("Loss: %.2f%% -> %.2f%%\t\t%s\n", lossA*100, lossB*100, significance(z))
+
+	// Jitter: Welch's t statistic on the differences between subsequent RTTs, whose mean is the jitter
+	differencesA, differencesB := successiveDifferences(rttsA), successiveDifferences(rttsB)
+	jitterA, deviationA := meanDeviation(differencesA)
+	jitterB, deviationB := meanDeviation(differencesB)
+	z = math.NaN()
+	switch {
+	case len(differencesA) < 2 || len(differencesB) < 2:
+	case jitterA == jitterB:
+		z = 0
+	default:
+		z = zScore(jitterB-jitterA, math.Sqrt(deviationA*deviationA/float64(len(differencesA))+deviationB*deviationB/float64(len(differencesB))))
+	}
+	fmt.Printf("Jitter: %s -> %s\t\t%s\n", display(time.Duration(jitterA)), display(time.Duration(jitterB)), significance(z))
+}
+
+// Describe a change from A to B given its z statistic, positive being worse, NaN if it can't be tested
+func significance(z float64) string {
+	switch {
+	case math.IsNaN(z):
+		return "not enough data"
+	case z > significanceZ:
+		return fmt.Sprintf("worse (significant, z = %.2f)", z)
+	case z < -significanceZ:
+		return fmt.Sprintf("better (significant, z = %.2f)", -z)
+	default:
+		return fmt.Sprintf("no significant change (z = %.2f)", math.Abs(z))
+	}
+}
+
+// RTTs of the probes that replied, and the number that were lost
+func repliedRTTs(results []probeResult) ([]time.Duration, int) {
+	var rtts []time.Duration
+	lost := 0
+	for _, result := range results {
+		if result.Error != "" {
+			lost++
+			continue
+		}
+		rtts = append(rtts, result.RTT)
+	}
+	return rtts, lost
+}
+
+// Absolute differences between subsequent RTTs
+func successiveDifferences(rtts []time.Duration) []time.Duration {
+	var differences []time.Duration
+	for i := 1; i < len(rtts); i++ {
+		diff := rtts[i] - rtts[i-1]
+		if diff < 0 {
+			diff = -diff
+		}
+		differences = append(differences, diff)
+	}
+	return differences
+}
+
+// Nearest rank percentile of the RTTs, 0 if there are none
+func percentile(rtts []time.Duration, p float64) time.Duration {
+	if len(rtts) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), rtts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// z statistic of the Mann-Whitney U test that RTTs of B tend to be larger than those of A,
+// normally approximated with ties given their average rank, NaN if either side is empty
+func mannWhitney(rttsA []time.Duration, rttsB []time.Duration) float64 {
+	n1, n2 := float64(len(rttsA)), float64(len(rttsB))
+	if n1 == 0 || n2 == 0 {
+		return math.NaN()
+	}
+	// Rank both samples together
+	type sample struct {
+		rtt   time.Duration
+		fromB bool
+	}
+	samples := make([]sample, 0, len(rttsA)+len(rttsB))
+	for _, rtt := range rttsA {
+		samples = append(samples, sample{rtt, false})
+	}
+	for _, rtt := range rttsB {
+		samples = append(samples, sample{rtt, true})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].rtt < samples[j].rtt })
+	var rankSumB, tieCorrection float64
+	for i := 0; i < len(samples); {
+		j := i
+		for j < len(samples) && samples[j].rtt == samples[i].rtt {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // Average of ranks i+1 to j
+		for k := i; k < j; k++ {
+			if samples[k].fromB {
+				rankSumB += rank
+			}
+		}
+		ties := float64(j - i)
+		tieCorrection += ties*ties*ties - ties
+		i = j
+	}
+	u := rankSumB - n2*(n2+1)/2
+	n := n1 + n2
+	deviation := math.Sqrt(n1 * n2 / 12 * ((n + 1) - tieCorrection/(n*(n-1))))
+	if deviation == 0 {
+		return 0
+	}
+	return (u - n1*n2/2) / deviation
+}
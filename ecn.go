@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	ecnNotECT byte = 0 // Not ECN-capable transport (RFC 3168)
+	ecnECT1   byte = 1 // ECN-capable transport, codepoint 1
+	ecnECT0   byte = 2 // ECN-capable transport, codepoint 0
+	ecnCE     byte = 3 // Congestion experienced, set by routers marking instead of dropping
+	ecnMask   byte = 3 // ECN bits of the IPv4 TOS or IPv6 traffic class
+)
+
+// Names of ECN codepoints as displayed, by value
+var ecnNames = [4]string{"Not-ECT", "ECT(1)", "ECT(0)", "CE"}
+
+// Codepoints of -ecn by name
+var ecnCodepoints = map[string]byte{"ect0": ecnECT0, "ect1": ecnECT1, "ce": ecnCE}
+
+var ecnProbe byte // ECN codepoint of marked probes (-ecn) flag, Not-ECT when not testing ECN
+
+// ECN outcome of a target's probes, marked ones alternating with unmarked controls
+type ecnStatistic struct {
+	marked       int    // Number of probes sent with the codepoint
+	markedLost   int    // Number of those lost
+	controls     int    // Number of unmarked probes sent
+	controlsLost int    // Number of those lost
+	replies      [4]int // Replies to marked probes by the codepoint they carried
+}
+
+// ECN codepoint of the probe with the given sequence number, every other probe being an unmarked
+// control so loss of marked probes can be told apart from loss of any probe
+func probeECN(seq int) byte {
+	if seq%2 == 1 {
+		return ecnNotECT
+	}
+	return ecnProbe
+}
+
+// Codepoint of a probe quoted in an ICMP error, as the router sending it received the probe
+func quotedECN(data []byte) (byte, bool) {
+	if wantIPv6 {
+		if len(data) < ipv6.HeaderLen {
+			return 0, false
+		}
+		return (data[1] >> 4) & ecnMask, true
+	}
+	if len(data) < ipv4.HeaderLen {
+		return 0, false
+	}
+	return data[1] & ecnMask, true
+}
+
+// Count the outcome of the last probe under its codepoint, when testing ECN
+func (stats *statistic) tallyECN(replied bool) {
+	if ecnProbe == ecnNotECT {
+		return
+	}
+	if stats.ecn == ecnNotECT {
+		stats.ecnStats.controls++
+		if !replied {
+			stats.ecnStats.controlsLost++
+		}
+		return
+	}
+	stats.ecnStats.marked++
+	if !replied {
+		stats.ecnStats.markedLost++
+	} else if stats.replyECN >= 0 {
+		stats.ecnStats.replies[stats.replyECN]++
+	}
+}
+
+// Print what became of the marking of ECN probes: whether replies kept it, the path or target bleached
+// it to Not-ECT or marked congestion, and whether marked probes were lost more than the controls
+func (stats *statistic) showECN() {
+	counts := stats.ecnStats
+	fmt.Fprintf(
+		noticeOutput(),
+		"ECN probes: %d %s\t\tLost: %d\t\tNot-ECT controls: %d\t\tLost: %d\n",
+		counts.marked,
+		ecnNames[ecnProbe],
+		counts.markedLost,
+		counts.controls,
+		counts.controlsLost)
+	var replies []string
+	answered := 0
+	for codepoint, count := range counts.replies {
+		if count > 0 {
+			replies = append(replies, fmt.Sprintf("%s %d", ecnNames[codepoint], count))
+			answered += count
+		}
+	}
+	verdict := "no replies to ECN probes"
+	switch {
+	case counts.marked > 0 && counts.markedLost == counts.marked && counts.controlsLost < counts.controls:
+		verdict = "ECN probes dropped, while unmarked probes replied"
+	case answered == 0:
+	case counts.replies[ecnProbe] == answered:
+		verdict = "ECN marking survived the path"
+	case counts.replies[ecnCE] > 0 && ecnProbe != ecnCE:
+		verdict = "congestion experienced marked on the path"
+	case counts.replies[ecnNotECT] > 0:
+		verdict = "ECN marking bleached on the path, or not echoed by the target"
+	default:
+		verdict = "ECN marking rewritten on the path"
+	}
+	if len(replies) > 0 {
+		fmt.Fprintf(noticeOutput(), "ECN of replies: %s\t\t%s\n", strings.Join(replies, ", "), verdict)
+	} else {
+		fmt.Fprintf(noticeOutput(), "ECN of replies: none\t\t%s\n", verdict)
+	}
+	// Loss of marked probes beyond that of the controls points at middleboxes discarding ECN-capable packets
+	if counts.marked > 0 && counts.controls > 0 {
+		markedLoss := 100 * float64(counts.markedLost) / float64(counts.marked)
+		controlLoss := 100 * float64(counts.controlsLost) / float64(counts.controls)
+		if markedLoss > controlLoss && counts.markedLost < counts.marked {
+			fmt.Fprintf(noticeOutput(), "ECN probes lost more often than unmarked ones: %.2f%% vs %.2f%%\n", markedLoss, controlLoss)
+		}
+	}
+}
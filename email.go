@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultEmailSubject string = "{{.Subject}}"                 // Subject template of alert emails
+	defaultEmailBody    string = "{{.Subject}}\n\n{{.Stats}}\n" // Body template of alert emails
+	smtpsPort           string = "465"                          // Port of SMTP servers speaking implicit TLS
+)
+
+// Data of the email subject and body templates: the alert's fields, e.g. {{.Target}}, {{.Kind}},
+// {{.Reason}} and {{.Stats.Loss}}, plus its headline
+type alertMessage struct {
+	alertEvent
+	Subject string // Headline of the alert
+}
+
+// Notifier mailing alerts through an SMTP server
+type emailNotifier struct {
+	settings emailConfig        // Server, credentials and addresses
+	subject  *template.Template // Template of the subject line
+	body     *template.Template // Template of the message body
+}
+
+// Create an email notifier, checking its settings and parsing its templates
+func newEmailNotifier(settings emailConfig) (*emailNotifier, error) {
+	if settings.Server == "" || settings.From == "" || len(settings.To) == 0 {
+		return nil, errors.New("Email alerts need a server, a from address and at least one to address")
+	}
+	if _, _, err := net.SplitHostPort(settings.Server); err != nil {
+		settings.Server = net.JoinHostPort(settings.Server, "587")
+	}
+	if settings.Subject == "" {
+		settings.Subject = defaultEmailSubject
+	}
+	if settings.Body == "" {
+		settings.Body = defaultEmailBody
+	}
+	subject, err := template.New("subject").Parse(settings.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid email subject template: %w", err)
+	}
+	body, err := template.New("body").Parse(settings.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid email body template: %w", err)
+	}
+	return &emailNotifier{settings: settings, subject: subject, body: body}, nil
+}
+
+// Name of the destination
+func (email *emailNotifier) name() string {
+	return "email"
+}
+
+// Render the alert through the templates and send it
+func (email *emailNotifier) notify(event alertEvent) error {
+	data := alertMessage{alertEvent: event, Subject: event.subject()}
+	var subject, body bytes.Buffer
+	if err := email.subject.Execute(&subject, data); err != nil {
+		return err
+	}
+	if err := email.body.Execute(&body, data); err != nil {
+		return err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", email.settings.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(email.settings.To, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&message, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+	return email.send(message.Bytes())
+}
+
+// Deliver the message, over implicit TLS on port 465 and otherwise upgrading with STARTTLS
+// when the server offers it
+func (email *emailNotifier) send(message []byte) error {
+	host, port, _ := net.SplitHostPort(email.settings.Server)
+	var auth smtp.Auth
+	if email.settings.Username != "" {
+		auth = smtp.PlainAuth("", email.settings.Username, email.settings.Password, host)
+	}
+	if port != smtpsPort {
+		return smtp.SendMail(email.settings.Server, auth, email.settings.From, email.settings.To, message)
+	}
+
+	dialer := &net.Dialer{Timeout: notifyTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", email.settings.Server, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(email.settings.From); err != nil {
+		return err
+	}
+	for _, to := range email.settings.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := data.Write(message); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	environmentPrefix  string = "GOPING_"        // Prefix of the environment variable of every flag
+	environmentTargets string = "GOPING_TARGETS" // Targets used when none are given on the command line
+)
+
+// Flags that may be repeated, their environment variables holding comma-separated values
+var repeatedFlags = []string{"label"}
+
+// Environment variable of a flag, e.g. GOPING_LOG_FILE for -log-file
+func environmentVariable(name string) string {
+	return environmentPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Set the flags the command takes from their environment variables, unless given on the command
+// line, so flags take precedence over the environment and the environment over the config file
+func (cmd *command) applyEnvironment() error {
+	given := make(map[string]bool)
+	flag.Visit(func(set *flag.Flag) { given[set.Name] = true })
+	for _, defined := range cmd.flags() {
+		variable := environmentVariable(defined.Name)
+		value, ok := os.LookupEnv(variable)
+		if !ok || given[defined.Name] {
+			continue
+		}
+		values := []string{value}
+		if slices.Contains(repeatedFlags, defined.Name) {
+			values = strings.Split(value, ",")
+		}
+		for _, value := range values {
+			if err := flag.Set(defined.Name, strings.TrimSpace(value)); err != nil {
+				return fmt.Errorf("Invalid %s %q: %v", variable, value, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Targets given on the command line, or in GOPING_TARGETS separated by spaces or commas if none are
+func commandLineTargets() []string {
+	if flag.NArg() > 0 {
+		return flag.Args()
+	}
+	return strings.FieldsFunc(os.Getenv(environmentTargets), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/icmp"
+)
+
+// Roles of the interface an RFC 5837 interface information object describes, by the top bits of its C-Type
+var interfaceRoles = [4]string{"Incoming", "Sub-IP", "Outgoing", "Next hop"}
+
+// Extensions of a time exceeded or destination unreachable message (RFC 4884), nil for other messages
+func messageExtensions(message *icmp.Message) []icmp.Extension {
+	switch body := message.Body.(type) {
+	case *icmp.TimeExceeded:
+		return body.Extensions
+	case *icmp.DstUnreach:
+		return body.Extensions
+	}
+	return nil
+}
+
+// Describe ICMP extensions of a router's reply for display: MPLS label stacks (RFC 4950) of the
+// LSP the probe took, and the interfaces the probe arrived and left on (RFC 5837)
+func describeExtensions(extensions []icmp.Extension) []string {
+	var described []string
+	for _, extension := range extensions {
+		switch object := extension.(type) {
+		case *icmp.MPLSLabelStack:
+			labels := make([]string, len(object.Labels))
+			for i, label := range object.Labels {
+				labels[i] = fmt.Sprintf("%d (TC %d, TTL %d)", label.Label, label.TC, label.TTL)
+			}
+			described = append(described, "MPLS labels: "+strings.Join(labels, ", "))
+		case *icmp.InterfaceInfo:
+			var details []string
+			if object.Interface != nil {
+				if object.Interface.Index != 0 {
+					details = append(details, fmt.Sprintf("ifIndex %d", object.Interface.Index))
+				}
+				if object.Interface.Name != "" {
+					details = append(details, fmt.Sprintf("name %s", object.Interface.Name))
+				}
+				if object.Interface.MTU != 0 {
+					details = append(details, fmt.Sprintf("MTU %d", object.Interface.MTU))
+				}
+			}
+			if object.Addr != nil {
+				details = append(details, fmt.Sprintf("address %s", object.Addr.IP))
+			}
+			described = append(described, fmt.Sprintf("%s interface: %s", interfaceRoles[object.Type>>6&3], strings.Join(details, ", ")))
+		}
+	}
+	return described
+}
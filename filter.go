@@ -0,0 +1,66 @@
+package main
+
+import (
+	"golang.org/x/net/bpf"
+)
+
+// Classic BPF program passing only packets a probe of the identifier may be answered by: echo and
+// timestamp replies carrying it, and ICMP errors quoting a request carrying it. IPv4 raw sockets see
+// the IP header ahead of the ICMP message, ICMPv6 ones the message alone.
+func replyFilter(id int) ([]bpf.RawInstruction, error) {
+	accept, drop := bpf.RetConstant{Val: 0xffff}, bpf.RetConstant{Val: 0}
+	echoID := bpf.JumpIf{Cond: bpf.JumpEqual, Val: uint32(id & 0xffff), SkipFalse: 1} // Accept if equal, else drop
+	var program []bpf.Instruction
+	if wantIPv6 {
+		program = []bpf.Instruction{
+			bpf.LoadAbsolute{Off: 0, Size: 1},                      // Type
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: 129, SkipTrue: 6}, // Echo reply
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: 1, SkipTrue: 3},   // Destination unreachable
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: 2, SkipTrue: 2},   // Packet too big
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: 3, SkipTrue: 1},   // Time exceeded
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: 4, SkipFalse: 5},  // Parameter problem
+			bpf.LoadAbsolute{Off: 8 + 40 + 4, Size: 2},             // Identifier of the quoted request
+			bpf.Jump{Skip: 1},
+			bpf.LoadAbsolute{Off: 4, Size: 2}, // Identifier of the reply
+			echoID,
+			accept,
+			drop,
+		}
+	} else {
+		program = []bpf.Instruction{
+			bpf.LoadMemShift{Off: 0},                                // X = IP header length
+			bpf.LoadIndirect{Off: 0, Size: 1},                       // Type
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0, SkipTrue: 11},   // Echo reply
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: 14, SkipTrue: 10},  // Timestamp reply
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: 3, SkipTrue: 2},    // Destination unreachable
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: 11, SkipTrue: 1},   // Time exceeded
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: 12, SkipFalse: 10}, // Parameter problem
+			bpf.LoadIndirect{Off: 8, Size: 1},                       // X += quoted IP header length
+			bpf.ALUOpConstant{Op: bpf.ALUOpAnd, Val: 0x0f},
+			bpf.ALUOpConstant{Op: bpf.ALUOpShiftLeft, Val: 2},
+			bpf.ALUOpX{Op: bpf.ALUOpAdd},
+			bpf.TAX{},
+			bpf.LoadIndirect{Off: 8 + 4, Size: 2}, // Identifier of the quoted request
+			bpf.Jump{Skip: 1},
+			bpf.LoadIndirect{Off: 4, Size: 2}, // Identifier of the reply
+			echoID,
+			accept,
+			drop,
+		}
+	}
+	return bpf.Assemble(program)
+}
+
+// Have the kernel drop ICMP traffic not answering the identifier's probes before it reaches
+// userspace, since raw sockets otherwise receive every ICMP packet of the host. Only Linux
+// supports filters through the socket options used.
+func (sock *icmpSocket) filterReplies(id int) error {
+	filter, err := replyFilter(id)
+	if err != nil {
+		return err
+	}
+	if sock.ipv6Conn != nil {
+		return sock.ipv6Conn.SetBPF(filter)
+	}
+	return sock.ipv4Conn.SetBPF(filter)
+}
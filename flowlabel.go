@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const maxFlowLabel uint32 = 0x7ffff // Largest flow label that can be leased, the high bit being reserved for stateless labels
+
+var (
+	flowLabel       uint32 // IPv6 flow label of probes (-flow-label) flag, 0 for the kernel's choice
+	flowLabelRotate int    // Number of consecutive flow labels probes cycle through (-flow-label-rotate) flag, 0 or 1 to keep one
+)
+
+// Probes of a target sent with one flow label, since ECMP may hash each label onto a different path
+type flowLabelStatistic struct {
+	count int             // Number of probes sent with the label
+	lost  int             // Number of those lost
+	rtts  []time.Duration // RTTs of those that replied
+}
+
+// Flow label of the probe with the given sequence number, cycling through -flow-label-rotate labels
+func probeFlowLabel(seq int) uint32 {
+	if flowLabelRotate < 2 {
+		return flowLabel
+	}
+	base := flowLabel
+	if base == 0 {
+		base = 1
+	}
+	return (base-1+uint32(seq%flowLabelRotate))%maxFlowLabel + 1
+}
+
+// Count the outcome of the last probe under its flow label, when rotating labels
+func (stats *statistic) tallyFlowLabel(label uint32, replied bool) {
+	if flowLabelRotate < 2 {
+		return
+	}
+	if stats.flowStats == nil {
+		stats.flowStats = make(map[uint32]*flowLabelStatistic)
+	}
+	flow, ok := stats.flowStats[label]
+	if !ok {
+		flow = new(flowLabelStatistic)
+		stats.flowStats[label] = flow
+		stats.flowOrder = append(stats.flowOrder, label)
+	}
+	flow.count++
+	if replied {
+		flow.rtts = append(flow.rtts, stats.rtt)
+	} else {
+		flow.lost++
+	}
+}
+
+// Print statistics per flow label, and whether the fastest and slowest differ significantly,
+// which suggests ECMP is hashing them onto unequal-cost paths
+func (stats *statistic) showFlowLabels() {
+	var fastest, slowest *flowLabelStatistic
+	var fastestMean, slowestMean float64
+	for _, label := range stats.flowOrder {
+		flow := stats.flowStats[label]
+		mean, _ := meanDeviation(flow.rtts)
+		fmt.Fprintf(
+			noticeOutput(),
+			"  Flow label: 0x%05x\t\tSent: %d\t\tLost: %d\t\tAvg RTT: %s\n",
+			label,
+			flow.count,
+			flow.lost,
+			display(time.Duration(mean)))
+		if len(flow.rtts) == 0 {
+			continue
+		}
+		if fastest == nil || mean < fastestMean {
+			fastest, fastestMean = flow, mean
+		}
+		if slowest == nil || mean > slowestMean {
+			slowest, slowestMean = flow, mean
+		}
+	}
+	if fastest == nil || fastest == slowest {
+		return
+	}
+	_, fastDeviation := meanDeviation(fastest.rtts)
+	_, slowDeviation := meanDeviation(slowest.rtts)
+	verdict := "no significant difference between flow labels"
+	if len(fastest.rtts) > 1 && len(slowest.rtts) > 1 {
+		standardError := math.Sqrt(fastDeviation*fastDeviation/float64(len(fastest.rtts)) + slowDeviation*slowDeviation/float64(len(slowest.rtts)))
+		if z := zScore(slowestMean-fastestMean, standardError); z > significanceZ {
+			verdict = fmt.Sprintf("possible unequal-cost paths (significant, z = %.2f)", z)
+		}
+	}
+	fmt.Fprintf(noticeOutput(), "Flow label RTT spread: %s\t\t%s\n", display(time.Duration(slowestMean-fastestMean)), verdict)
+}
@@ -0,0 +1,51 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Flow label socket options of linux/in6.h, missing from x/sys/unix
+const (
+	ipv6FlowInfo         int    = 11  // IPV6_FLOWINFO control message carrying the flow information of a packet
+	ipv6FlowLabelMgr     int    = 32  // IPV6_FLOWLABEL_MGR socket option leasing flow labels
+	ipv6FlowLabelGet     uint8  = 0   // IPV6_FL_A_GET action
+	ipv6FlowShareAny     uint8  = 255 // IPV6_FL_S_ANY sharing
+	ipv6FlowFlagCreate   uint16 = 1   // IPV6_FL_F_CREATE flag
+	flowLabelRequestSize int    = 32  // Size of struct in6_flowlabel_req
+)
+
+// Lease the flow label for the destination on the socket, which Linux requires before sending with it
+func leaseFlowLabel(rawConn syscall.RawConn, destination net.IP, label uint32) error {
+	request := make([]byte, flowLabelRequestSize)
+	copy(request[0:16], destination.To16())
+	binary.BigEndian.PutUint32(request[16:20], label)
+	request[20] = ipv6FlowLabelGet
+	request[21] = ipv6FlowShareAny
+	binary.NativeEndian.PutUint16(request[22:24], ipv6FlowFlagCreate)
+	var sockErr error
+	err := rawConn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptString(int(fd), unix.IPPROTO_IPV6, ipv6FlowLabelMgr, string(request))
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// Control message setting the flow label of a sent packet
+func flowLabelControl(label uint32) []byte {
+	oob := make([]byte, unix.CmsgSpace(4))
+	header := (*unix.Cmsghdr)(unsafe.Pointer(&oob[0]))
+	header.Level = unix.IPPROTO_IPV6
+	header.Type = int32(ipv6FlowInfo)
+	header.SetLen(unix.CmsgLen(4))
+	binary.BigEndian.PutUint32(oob[unix.CmsgLen(0):], label)
+	return oob
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// Setting flow labels is only implemented on Linux
+func leaseFlowLabel(rawConn syscall.RawConn, destination net.IP, label uint32) error {
+	return errors.New("Flow labels are not supported on this platform")
+}
+
+// No control message sets the flow label on this platform
+func flowLabelControl(label uint32) []byte {
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+const (
+	gatewayKeyword string = "gateway" // Target standing for the default gateway
+	dnsKeyword     string = "dns"     // Target standing for the configured resolvers
+)
+
+// Replace the gateway and dns keywords among the targets with the addresses discovered from the OS,
+// labelled with the keyword unless given a label, keeping any settings of the keyword
+func expandKeywords(arguments []string) ([]string, error) {
+	var expanded []string
+	for _, argument := range arguments {
+		rest, settings, _ := strings.Cut(argument, "?")
+		keyword, label, _ := strings.Cut(rest, "=")
+		if keyword != gatewayKeyword && keyword != dnsKeyword {
+			expanded = append(expanded, argument)
+			continue
+		}
+		var addresses []string
+		var err error
+		if keyword == gatewayKeyword {
+			addresses, err = defaultGateways()
+		} else {
+			addresses, err = systemNameservers()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Could not discover the addresses of target %s: %w", keyword, err)
+		}
+		if label == "" {
+			label = keyword
+		}
+		if settings != "" {
+			settings = "?" + settings
+		}
+		for _, address := range addresses {
+			expanded = append(expanded, address+"="+label+settings)
+		}
+	}
+	return expanded, nil
+}
+
+// Addresses of every nameserver of the system resolver configuration of the IP version probed
+func systemNameservers() ([]string, error) {
+	file, err := os.Open(resolvConf)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var addresses []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+		host, _, _ := strings.Cut(fields[1], "%")
+		if ip := net.ParseIP(host); ip != nil && (ip.To4() == nil) == wantIPv6 {
+			addresses = append(addresses, fields[1])
+		}
+	}
+	if len(addresses) == 0 {
+		version := "IPv4"
+		if wantIPv6 {
+			version = "IPv6"
+		}
+		return nil, fmt.Errorf("No %s nameserver found in %s", version, resolvConf)
+	}
+	return addresses, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"strings"
+)
+
+const (
+	routeTable4 string = "/proc/net/route"      // Kernel IPv4 routing table
+	routeTable6 string = "/proc/net/ipv6_route" // Kernel IPv6 routing table
+)
+
+// Next hops of the default routes of the IP version probed, read from the kernel routing table
+func defaultGateways() ([]string, error) {
+	path := routeTable4
+	if wantIPv6 {
+		path = routeTable6
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var gateways []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if wantIPv6 {
+			// destination, prefix length, source, source prefix length, next hop, metric, refcount, use, flags and device
+			if len(fields) < 10 || fields[1] != "00" || strings.Trim(fields[0], "0") != "" || strings.Trim(fields[4], "0") == "" {
+				continue
+			}
+			hop, err := hex.DecodeString(fields[4])
+			if err != nil || len(hop) != net.IPv6len {
+				continue
+			}
+			gateway := net.IP(hop).String()
+			// Link-local next hops are only reachable through their interface
+			if net.IP(hop).IsLinkLocalUnicast() {
+				gateway += "%" + fields[9]
+			}
+			gateways = append(gateways, gateway)
+			continue
+		}
+		// Interface, destination, gateway, flags, ..., mask, in host byte order hex
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" || fields[2] == "00000000" {
+			continue
+		}
+		hop, err := hex.DecodeString(fields[2])
+		if err != nil || len(hop) != net.IPv4len {
+			continue
+		}
+		gateway := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(gateway, binary.LittleEndian.Uint32(hop))
+		gateways = append(gateways, gateway.String())
+	}
+	if len(gateways) == 0 {
+		return nil, errors.New("No default route found in " + path)
+	}
+	return gateways, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// Discovering the default gateway is only implemented on Linux
+func defaultGateways() ([]string, error) {
+	return nil, errors.New("Discovering the default gateway is not supported on this platform")
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+const (
+	gcTuningMemoryLimit  int64   = 512 << 20                        // Soft memory limit with -gc-tuning, unless GOMEMLIMIT is set
+	gcTuningMemoryShare  float64 = 0.5                              // Share of the cgroup's or system's memory the limit is capped at
+	gcCyclesMetric       string  = "/gc/cycles/total:gc-cycles"     // Completed GC cycles
+	gcPausesMetric       string  = "/sched/pauses/total/gc:seconds" // Histogram of GC stop-the-world pauses
+	schedLatenciesMetric string  = "/sched/latencies:seconds"       // Histogram of goroutines' waits to run once runnable
+)
+
+var (
+	gcTuning     bool              // Tune the garbage collector for measurement-sensitive sessions (-gc-tuning) flag
+	runtimeStart = sampleRuntime() // Runtime metrics when goPing started, subtracted for those of the run
+)
+
+// GC pauses and scheduler latencies of the Go runtime at one moment
+type runtimeSample struct {
+	pauseTotal time.Duration             // Total GC pause time so far
+	pauses     *metrics.Float64Histogram // GC pauses so far
+	latencies  *metrics.Float64Histogram // Scheduler latencies so far
+}
+
+// Read the runtime's GC pauses and scheduler latencies so far
+func sampleRuntime() runtimeSample {
+	samples := []metrics.Sample{{Name: gcPausesMetric}, {Name: schedLatenciesMetric}}
+	metrics.Read(samples)
+	var gcStats debug.GCStats
+	debug.ReadGCStats(&gcStats)
+	sample := runtimeSample{pauseTotal: gcStats.PauseTotal}
+	if samples[0].Value.Kind() == metrics.KindFloat64Histogram {
+		sample.pauses = samples[0].Value.Float64Histogram()
+	}
+	if samples[1].Value.Kind() == metrics.KindFloat64Histogram {
+		sample.latencies = samples[1].Value.Float64Histogram()
+	}
+	return sample
+}
+
+// Collect only as memory nears a soft limit, the GOMEMLIMIT or 512MiB capped at half the memory of
+// the cgroup or system, so GC pauses rarely land within probes' RTTs without the heap outgrowing a
+// container. Collects once first so probing starts from a clean heap. GOGC, if set, still takes precedence.
+func tuneGC() {
+	limit := debug.SetMemoryLimit(-1)
+	if os.Getenv("GOMEMLIMIT") == "" {
+		limit = gcTuningMemoryLimit
+		available, source, err := memoryAvailable()
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Could not read the memory available (%s). Limiting memory to %dMiB, which may exceed it...", err, limit>>20))
+		} else if capped := int64(float64(available) * gcTuningMemoryShare); capped < limit {
+			slog.Debug("Capped the GC memory limit", "limit", capped, "available", available, "source", source)
+			limit = capped
+		}
+		debug.SetMemoryLimit(limit)
+	}
+	if os.Getenv("GOGC") == "" {
+		debug.SetGCPercent(-1)
+	}
+	runtime.GC()
+	slog.Info(fmt.Sprintf("Tuned GC to collect only as memory nears %dMiB...", limit>>20))
+}
+
+// Number of GC cycles the runtime has completed, read cheaply so probes only look for the pauses
+// they overlapped when a cycle ran
+func gcCycles() uint64 {
+	samples := []metrics.Sample{{Name: gcCyclesMetric}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return samples[0].Value.Uint64()
+}
+
+// Time the runtime stopped the world for GC between start and end, of its last 256 cycles.
+// Each cycle's pauses are taken as one ending when it did, so the overlap is approximate.
+func gcPauseDuring(start, end time.Time) time.Duration {
+	var gcStats debug.GCStats
+	debug.ReadGCStats(&gcStats)
+	var paused time.Duration
+	// Pauses are listed most recent first
+	for i, pauseEnd := range gcStats.PauseEnd {
+		if pauseEnd.Before(start) {
+			break
+		}
+		pauseStart := pauseEnd.Add(-gcStats.Pause[i])
+		if pauseStart.After(end) {
+			continue
+		}
+		if pauseEnd.After(end) {
+			pauseEnd = end
+		}
+		if pauseStart.Before(start) {
+			pauseStart = start
+		}
+		paused += pauseEnd.Sub(pauseStart)
+	}
+	return paused
+}
+
+// Find the GC pause time overlapping the RTT of the last reply, if a GC cycle ran while probing
+func (stats *statistic) tallyGCPause(collected bool) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	stats.gcPause = 0
+	if !collected {
+		return
+	}
+	if stats.gcPause = gcPauseDuring(stats.sent, stats.sent.Add(stats.rtt)); stats.gcPause > 0 {
+		stats.gcProbes++
+		stats.gcPauseTotal += stats.gcPause
+	}
+}
+
+// Count of samples in a histogram since a baseline of it with the same buckets, and the upper bound
+// of the bucket holding the quantile, 0 if there were none
+func histogramQuantile(now, base *metrics.Float64Histogram, quantile float64) (uint64, time.Duration) {
+	if now == nil {
+		return 0, 0
+	}
+	counts := append([]uint64(nil), now.Counts...)
+	if base != nil && len(base.Counts) == len(counts) {
+		for i := range counts {
+			counts[i] -= base.Counts[i]
+		}
+	}
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	rank := uint64(math.Ceil(quantile * float64(total)))
+	var seen uint64
+	for i, count := range counts {
+		if seen += count; seen >= rank {
+			bound := now.Buckets[i+1]
+			// The last bucket is unbounded, so take its lower bound
+			if math.IsInf(bound, 1) {
+				bound = now.Buckets[i]
+			}
+			return total, time.Duration(bound * float64(time.Second))
+		}
+	}
+	return total, 0
+}
+
+// Print the GC pauses and scheduler latencies of the run, if any probe overlapped a pause or with -gc-tuning
+func showRuntime(allStats []*statistic) {
+	overlapped := 0
+	for _, stats := range allStats {
+		overlapped += stats.gcProbes
+	}
+	if overlapped == 0 && !gcTuning {
+		return
+	}
+	now := sampleRuntime()
+	pauses, pauseP99 := histogramQuantile(now.pauses, runtimeStart.pauses, 0.99)
+	_, latencyP50 := histogramQuantile(now.latencies, runtimeStart.latencies, 0.5)
+	_, latencyP99 := histogramQuantile(now.latencies, runtimeStart.latencies, 0.99)
+	fmt.Fprintf(noticeOutput(), "GC pauses: %d\t\tTotal: %s\t\tp99: %s\t\tProbes overlapped: %d\n", pauses, display(now.pauseTotal-runtimeStart.pauseTotal), display(pauseP99), overlapped)
+	fmt.Fprintf(noticeOutput(), "Scheduler latency: p50 %s\t\tp99 %s\n", display(latencyP50), display(latencyP99))
+}
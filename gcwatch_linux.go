@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	cgroupMemoryMax string = "/sys/fs/cgroup/memory.max" // Memory limit of goPing's cgroup v2, "max" for none
+	procMeminfo     string = "/proc/meminfo"             // Memory of the system, MemAvailable among it
+)
+
+// Memory goPing may use in bytes, the memory.max of its cgroup or else the system's MemAvailable,
+// with the file it was read from
+func memoryAvailable() (int64, string, error) {
+	if contents, err := os.ReadFile(cgroupMemoryMax); err == nil {
+		if limit, err := strconv.ParseInt(strings.TrimSpace(string(contents)), 10, 64); err == nil {
+			return limit, cgroupMemoryMax, nil
+		}
+	}
+	file, err := os.Open(procMeminfo)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// MemAvailable:  5470788 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "MemAvailable:" && fields[2] == "kB" {
+			available, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, "", fmt.Errorf("Invalid MemAvailable in %s: %w", procMeminfo, err)
+			}
+			return available << 10, procMeminfo, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, "", err
+	}
+	return 0, "", errors.New("No MemAvailable in " + procMeminfo)
+}
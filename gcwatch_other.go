@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// Reading the memory available is only implemented on Linux
+func memoryAvailable() (int64, string, error) {
+	return 0, "", errors.New("Reading the memory available is not supported on this platform")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+var geoDatabases []*maxminddb.Reader // MaxMind DB files addresses are located in (-geoip) flag, nil for none
+
+// Location and network of an address, as found in the -geoip databases
+type geoLocation struct {
+	Country string `json:"country,omitempty"` // ISO 3166 country code
+	City    string `json:"city,omitempty"`    // City name in English
+	ASN     uint   `json:"asn,omitempty"`     // Number of the autonomous system announcing the address
+	ASName  string `json:"as_name,omitempty"` // Organization of the autonomous system
+}
+
+// Fields of City, Country and ASN databases, each holding some of them
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ASN    uint   `maxminddb:"autonomous_system_number"`
+	ASName string `maxminddb:"autonomous_system_organization"`
+}
+
+// Open the comma-separated MaxMind DB files, e.g. a City and an ASN database
+func openGeoDatabases(paths string) error {
+	for _, path := range strings.Split(paths, ",") {
+		database, err := maxminddb.Open(path)
+		if err != nil {
+			return fmt.Errorf("Could not open GeoIP database %s: %w", path, err)
+		}
+		geoDatabases = append(geoDatabases, database)
+	}
+	return nil
+}
+
+// Locate the address in every -geoip database, the first to know a field giving it,
+// nil if none are open or none know the address
+func geoLookup(ip net.IP) *geoLocation {
+	address, ok := netip.AddrFromSlice(ip)
+	if len(geoDatabases) == 0 || !ok {
+		return nil
+	}
+	var location geoLocation
+	for _, database := range geoDatabases {
+		var record geoRecord
+		if err := database.Lookup(address.Unmap()).Decode(&record); err != nil {
+			continue
+		}
+		location.Country = cmp.Or(location.Country, record.Country.ISOCode)
+		location.City = cmp.Or(location.City, record.City.Names["en"])
+		location.ASName = cmp.Or(location.ASName, record.ASName)
+		location.ASN = cmp.Or(location.ASN, record.ASN)
+	}
+	if location == (geoLocation{}) {
+		return nil
+	}
+	return &location
+}
+
+// Format the location as "country, city, ASn name", leaving out what isn't known
+func (location *geoLocation) String() string {
+	if location == nil {
+		return ""
+	}
+	var parts []string
+	if location.Country != "" {
+		parts = append(parts, location.Country)
+	}
+	if location.City != "" {
+		parts = append(parts, location.City)
+	}
+	if location.ASN != 0 {
+		parts = append(parts, strings.TrimSpace(fmt.Sprintf("AS%d %s", location.ASN, location.ASName)))
+	}
+	return strings.Join(parts, ", ")
+}
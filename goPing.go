@@ -13,16 +13,129 @@
 // 7) Supports finite number of pings (with flag)
 // 8) Supports calculating jitter
 // 9) Supports displaying summary of statistics upon termination
+// 10) Detects and counts replies sourced from addresses other than the target
+// 11) Displays reverse DNS names of the target and responders (disable with flag)
+// 12) Supports resolving the target against a custom DNS server (flag)
+// 13) Supports re-resolving the target during long runs, logging address changes (flag)
+// 14) Supports pinging all resolved addresses of a hostname concurrently (flag)
+// 15) Supports resolving the target over DNS-over-HTTPS or DNS-over-TLS (flag)
+// 16) Supports resolving .local hostnames via multicast DNS
+// 17) Supports labelling targets and tagging every output line (flag)
+// 18) Supports pinging several targets concurrently, with a live table display (flag)
+// 19) Supports structured, leveled logging with verbosity and rotated log file (flags)
+// 20) Supports hex dumping packets with decoded ICMP headers (flag)
+// 21) Supports capturing probe traffic to a pcap file (flag)
+// 22) Supports kernel receive timestamps for precise RTT (flag)
+// 23) Supports configurable RTT display precision down to nanoseconds (flag)
+// 24) Supports comparing two targets in lockstep with a statistical verdict (compare subcommand)
+// 25) Supports recording probe results and replaying them later in text, JSON or CSV (flag, replay subcommand)
+// 26) Supports comparing two recorded sessions for regressions with significance (diff subcommand)
+// 27) Supports flagging and counting RTT spikes against a rolling baseline (flag)
+// 28) Reports loss bursts and the longest outage in the summary
+// 29) Detects possible route changes from shifts in reply TTL or time exceeded responder
+// 30) Supports ICMP timestamp probes estimating clock offset and one-way delay (flag)
+// 31) Supports pinging broadcast and multicast addresses, listing every responder (flag)
+// 32) Supports IPv4 record route and timestamp options (flags)
+// 33) Supports setting or rotating the IPv6 flow label with per-label statistics (flags)
+// 34) Supports marking probes for policy routing with SO_MARK (flag)
+// 35) Supports probing inside a Linux VRF (flag)
+// 36) Supports a server mode with a gRPC API to start and stop jobs and stream results (serve subcommand)
+// 37) Supports an HTTP API adding and removing targets at runtime in server mode (flag)
+// 38) Supports streaming live results over Server-Sent Events in server mode
+// 39) Supports a built-in web dashboard of live RTT, loss and jitter in server mode
+// 40) Supports running as a systemd service with readiness notification, SIGHUP config reload and journald logging (flags)
+// 41) Supports publishing probe results to NATS or Kafka event pipelines (flag)
+// 42) Supports alerting up/down transitions and SLA breaches to Slack, Discord or Telegram (config)
+// 43) Supports mailing alerts through SMTP with templated subject and body (config)
+// 44) Supports SLO tracking with periodic compliance reports and error budget burn alerts (config)
+// 45) Supports writing a self-contained HTML report with charts at termination, or from a recording (flag, report subcommand)
+// 46) Supports exporting RTT over time as a PNG or SVG latency heatmap (flag)
+// 47) Supports tracing the path to a target with per-hop loss and RTT, exporting the hop graph as DOT or JSON (trace subcommand)
+// 48) Supports randomizing probe intervals to avoid synchronization (flag)
+// 49) Supports an adaptive RTO-style timeout from smoothed RTT and variance (flags)
+// 50) Supports exponential backoff of probes to targets that are down (flag)
+// 51) Supports a fixed run duration and scheduled probing windows (flag, config)
+// 52) Supports pausing and resuming probing via SIGUSR1 or the HTTP API, keeping statistics
+// 53) Resets statistics at runtime via SIGUSR2 or the HTTP API, printing those of the ending epoch
+// 54) Tracks availability and total downtime of each target from its up/down state
+// 55) Provides a pinger library package configured with functional options
+// 56) Calls library callbacks on each probe sent, reply, timeout and when finished
+// 57) Streams library probe results over a channel
+// 58) Shares one raw socket between concurrent library pingers, demultiplexing replies by echo identifier and sequence
+// 59) Sends and reads library probes in batches with sendmmsg and recvmmsg on Linux
+// 60) Reuses pooled packet buffers so steady probing doesn't allocate per packet
+// 61) Benchmarks the probe engine against localhost (bench subcommand) and serves pprof profiles (flag)
+// 62) Organizes the CLI into commands with per-command flags and help (help subcommand)
+// 63) Generates shell completion scripts for bash, zsh, fish and PowerShell (completion subcommand)
+// 64) Supports configuring every flag and the targets through GOPING_* environment variables (env)
+// 65) Supports container healthchecks with loss and RTT thresholds and exit codes (healthcheck subcommand)
+// 66) Supports reconciling daemon targets whenever the config file changes, e.g. a Kubernetes ConfigMap (flag)
+// 67) Supports custom result lines through a Go template (flag)
+// 68) Supports sending probe results and state changes to local or remote syslog (flag)
+// 69) Supports logging natively to the systemd journal with structured fields on Linux (flag)
+// 70) Supports running the daemon as a native Windows service (service subcommand)
+// 71) Displays MPLS label stacks and interface information of ICMP extensions (RFC 4884) in trace
+// 72) Loose source routing of IPv4 probes through chosen gateways (-g)
+// 73) ECN testing, reporting whether ECN markings survive the path, are bleached or dropped (-ecn)
+// 74) Packet-size sweeps revealing MTU black holes and size-dependent rate limiting (sweep-size subcommand)
+// 75) TTL sweeps tabulating time exceeded and echo replies per TTL to find filtering and rate limiting hops (sweep-ttl subcommand)
+// 76) Bufferbloat grading of RTT under parallel HTTP load against idle RTT (bloat subcommand)
+// 77) Reply timeouts longer than the probe interval in the pinger library, tracking probes in flight by sequence number
+// 78) Reports replies arriving after their timeout as late, optionally counting them as received (flag)
+// 79) Counts malformed and truncated replies, hex dumping them in debug mode
+// 80) Counts echo replies with corrupted payloads and the bits flipped
+// 81) Supports per-target TTL, interval and timeout overrides with host?ttl=10&interval=200ms
+// 82) Discovers the default gateway and resolvers for the gateway and dns targets
+// 83) Splits loss and latency between the first hop and upstream (flag)
+// 84) Re-resolves targets when interfaces, addresses or routes change
+// 85) Compares one target over two interfaces to quantify VPN overhead (compare -via)
+// 86) Annotates targets and traced hops with GeoIP country, city and AS (flag)
+// 87) Looks up the owners of addresses and AS numbers over RDAP (whois subcommand, trace -whois)
+// 88) Corrects ICMP timestamp one-way delays against an NTP server to report path asymmetry (flag)
+// 89) Pings a healthchecks.io or Cronitor dead man's switch while targets are up (flag)
+// 90) Sends alerts as SNMPv2c traps to a manager (config)
+// 91) Pushes RTT histograms and loss counters to Prometheus remote write (flag)
+// 92) Writes OpenMetrics snapshots for the node_exporter textfile collector (flag)
+// 93) Asserts p95 RTT, mean RTT and loss thresholds at termination for CI gates (flags)
+// 94) Prints only the summary, optionally as one JSON document, with a breakdown of loss causes (flag)
+// 95) Shows cumulative min/avg/max RTT on every line, fping -l style (flag)
+// 96) Warns of loss patterns consistent with ICMP rate limiting in the summary
+// 97) Keeps probing the last resolved address while retrying failed re-resolution in the background
+// 98) Resolves targets and checks socket privileges before probing, failing fast at startup
+// 99) Probes well-known anycast resolvers to gauge the local connection (quicktest subcommand)
+// 100) Ranks popular resolvers or CDNs by latency, jitter or loss (benchmark-dns and benchmark-cdn subcommands)
+// 101) Crafts probes with arbitrary ICMP types and codes behind a guard flag (expert flag)
+// 102) Filters replies in the kernel with classic BPF on Linux, sparing userspace the host's other ICMP traffic
+// 103) Measures wire-level RTT with eBPF programs at the tc hooks on Linux, falling back to kernel timestamps (flag)
+// 104) Timestamps requests and replies by the NIC where supported, reporting each probe's clock (flag)
+// 105) Raises probing threads to real-time priority and pins them to a CPU on Linux (flags)
+// 106) Notes probes whose RTT overlapped a Go GC pause, summarizing GC pauses and scheduler latency, with -gc-tuning to hold off collection during measurement-sensitive sessions (flag)
+// 107) Keeps the last probe results in a ring buffer, shown by typing show last N while pinging or with GET /results in server mode (flag, history subcommand)
+// 108) Interactive console with keyboard controls to pause, change the interval, view a target, reset statistics and quit (flag)
+// 109) Shows native desktop notifications when a target goes down or recovers (flag)
 
 package main
 
 import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/bits"
+	"math/rand/v2"
 	"net"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -40,223 +153,2485 @@ const (
 	resolveNetwork6 string = "ip6"           // Resolve network for IPv6
 	protocolICMP4   int    = 1               // ICMP protocol for IPv4 for ParseMessage
 	protocolICMP6   int    = 58              // ICMP protocol for IPv6 for ParseMessage
+
+	defaultPrecision time.Duration = 10 * time.Microsecond // Default display precision of durations
+	probeInterval    time.Duration = time.Second           // Interval between probes of a target
 )
 
-var (
-	wantIPv6 bool // Is IPv6 desired?
-	ttl      int  // Time-To-Live (-ttl) flag
-)
+var (
+	wantIPv6           bool          // Is IPv6 desired?
+	ttl                int           // Time-To-Live (-ttl) flag
+	numeric            bool          // Skip reverse DNS lookups (-n) flag
+	showTable          bool          // Live table display (-table) flag
+	loopStats          bool          // Cumulative RTTs on every probe's line (-loop-stats) flag
+	debugPackets       bool          // Hex dump every packet (-debug-packets) flag
+	probeTypeTimestamp bool          // Send ICMP timestamp requests instead of echo requests (-probe timestamp) flag
+	broadcast          bool          // Allow broadcast and multicast targets, collecting every responder (-b) flag
+	socketMark         int           // Firewall mark of probes for policy routing (-mark) flag, 0 for none
+	vrfDevice          string        // VRF device probes are bound to (-vrf) flag, empty for the default VRF
+	intervalJitter     float64       // Percentage band each interval is randomized within (-interval-jitter) flag, 0 for none
+	runFinished        chan struct{} // Closed once the run duration (-duration) flag is reached, nil for no limit
+
+	packetCapture   *capture      // Capture file of sent and received packets (-pcap) flag, nil if not capturing
+	alerts          *alerter      // Alerting configured in the -config file, nil if none
+	slos            *sloTracker   // SLO tracking configured in the -config file, nil if none
+	runReport       *htmlReport   // HTML report written at termination (-report) flag, nil if none
+	latencyHeatmap  *heatmap      // RTT heatmap written at termination (-heatmap) flag, nil if none
+	sessionRecorder *recorder     // Recording of every probe result (-record) flag, nil if not recording
+	output          *resultWriter // Writer of probe results in the output format (-o) flag
+
+	precision            time.Duration = defaultPrecision // Display precision of durations (-precision) flag
+	kernelTimestamping   bool                             // Timestamp replies in the kernel (-timestamping kernel) flag
+	timestampFallback    sync.Once                        // Warns once when kernel timestamps are unavailable
+	hardwareTimestamping bool                             // Timestamp requests and replies by the NIC (-timestamping hardware) flag
+	hardwareFallback     sync.Once                        // Warns once when hardware timestamps are unavailable
+	ipOptionsFallback    sync.Once                        // Warns once when IP options can't be set
+	flowLabelFallback    sync.Once                        // Warns once when flow labels can't be set
+	wireTiming           *wireTimer                       // Timer of requests and replies on the wire (-timestamping ebpf) flag, nil if not timing
+	replyFilterFallback  sync.Once                        // Logs once when replies can't be filtered in the kernel
+
+	resolver        *net.Resolver // Custom DNS resolver (-resolver) flag, nil for the system resolver
+	resolverAddress string        // Address of the custom DNS resolver
+	dohURL          string        // DNS-over-HTTPS endpoint (-doh) flag
+	dotAddress      string        // DNS-over-TLS server (-dot) flag
+	dnsExchange     = udpExchange // Transport for direct DNS queries, replaced by -doh or -dot
+	resolveEvery    int           // Re-resolve the target every N probes (-resolve N), 0 to resolve once
+	resolveTTL      bool          // Re-resolve the target when its DNS TTL expires (-resolve ttl)
+
+	reverseNames      = make(map[string]string) // Cache of reverse DNS lookups by IP address
+	reverseNamesMutex sync.Mutex                // Guards reverseNames across concurrent targets
+
+	echoIDs atomic.Int32 // Number of echo identifiers handed out by nextEchoID
+)
+
+type statistic struct {
+	target              *resolution                    // Target being pinged
+	labels              labels                         // Labels attached to every output line of this target
+	id                  int                            // ICMP echo identifier used for this target
+	count               int                            // Number of packets sent
+	lost                int                            // Number of packets lost
+	probeState                                         // Outcome of the last probe to finish
+	loss                float64                        // Percent loss at iteration
+	mismatched          int                            // Number of replies sourced from an address other than the target
+	malformed           int                            // Number of the target's replies failing to parse or shorter than their request
+	truncated           int                            // Number of those that were echo replies carrying less payload than sent
+	corrupted           int                            // Number of echo replies whose payload differed from the request's
+	corruptedBits       int                            // Number of bits flipped across those payloads
+	lastRTT             time.Duration                  // RTT of the last probe, kept for the table as rtt is reset while probing
+	lastIP              string                         // Address of the last probe, kept for summaries read while probing
+	totalRTT            time.Duration                  // Sum of all RTTs for averaging
+	histogram           rttHistogram                   // Cumulative histogram of all RTTs, pushed by -remote-write-url and written by -metrics-file
+	state               targetState                    // Up/down state of the target
+	consecutive         int                            // Consecutive probes disagreeing with the current state
+	baseline            anomalyDetector                // Rolling baseline of recent RTTs for anomaly detection
+	anomalies           int                            // Number of RTTs flagged as anomalous
+	bursts              lossBursts                     // Runs of consecutive lost probes
+	route               routeTracker                   // Reply TTLs and responders watched for route changes
+	clockSamples        []icmpTimestamps               // Timestamps of every ICMP timestamp reply, for the clock summary
+	lossCauses          map[string]int                 // Number of lost probes of each cause, e.g. timeout
+	rateLimit           rateLimitEvidence              // Loss patterns pointing at ICMP rate limiting
+	responderStats      map[string]*responderStatistic // Replies of each host to a broadcast or multicast target by IP
+	responderOrder      []string                       // IPs of responderStats in the order they first replied
+	ecnStats            ecnStatistic                   // Probes by ECN codepoint when testing ECN (-ecn)
+	flowStats           map[uint32]*flowLabelStatistic // Probes by flow label when rotating labels (-flow-label-rotate)
+	flowOrder           []uint32                       // Labels of flowStats in the order they were first sent
+	hopLimit            int                            // TTL of probes overriding -ttl, for a hop of goping trace or a target's ?ttl=, 0 for -ttl
+	late                lateReplies                    // Replies arriving after their probes were declared lost
+	payload             []byte                         // Payload of echo requests overriding echoPayload, for a size of goping sweep-size, nil for the default
+	timeout             time.Duration                  // Deadline of replies overriding the default, 0 for the default
+	interval            time.Duration                  // Interval between probes overriding the default (a target's ?interval=), 0 for the default
+	device              string                         // Interface probes are bound to overriding -vrf, for a side of goping compare -via, empty for -vrf
+	sock                *icmpSocket                    // Socket configured for the target's address, nil until the first probe, used only by the probing goroutine
+	lastSent            *flight                        // Last probe sent, whose timestamps the kernel and NIC report after it leaves (-timestamping hardware)
+	rto                 rtoEstimator                   // Adaptive timeout of probes (-adaptive-timeout), updated only by the probing goroutine
+	backoff             time.Duration                  // Current probe interval of a down target (-backoff), 0 when probing normally
+	uptime              availability                   // Time spent up and down for the availability percentage
+	gcPause             time.Duration                  // GC pause time overlapping the last reply's RTT, 0 if none did
+	gcProbes            int                            // Number of replies whose RTT overlapped a GC pause
+	gcPauseTotal        time.Duration                  // GC pause time overlapping those RTTs
+	resetting           resetRequest                   // Reset of the counters requested with SIGUSR2 or POST /reset (reset.go)
+	mutex               sync.Mutex                     // Guards the fields above read by the table while probing
+	rttAll              []time.Duration                // All RTTs in a slice for jitter calculation
+	totalDifferencesRTT time.Duration                  // Differences between subsequent RTTs for jitter calculation
+	jitter              time.Duration                  // Jitter
+}
+
+func main() {
+	// Take a subcommand given ahead of the flags, ping by default
+	cmd := parseCommand()
+	command := cmd.name
+
+	// List the targets recently pinged for the completion scripts (goping completion --targets),
+	// ahead of the flags so it stays out of help
+	if command == "completion" && len(os.Args) == 2 && (os.Args[1] == "--targets" || os.Args[1] == "-targets") {
+		for _, address := range recentTargets() {
+			fmt.Println(address)
+		}
+		return
+	}
+
+	// Parse flags to variables
+	ipVersion := flag.Int(
+		"ipv",
+		4,
+		"4 or 6, corresponding to which IP version to use")
+	pingCount := flag.Int(
+		"c",
+		-1,
+		"Finite number of times to ping, -1 being infinite")
+	timeToLive := flag.Int(
+		"ttl",
+		64,
+		"Time-to-live before package expires")
+	flag.IntVar(
+		&socketMark,
+		"mark",
+		0,
+		"Set this firewall `mark` (SO_MARK) on probes so policy routing can steer them, Linux only")
+	geoIP := flag.String(
+		"geoip",
+		"",
+		"Locate targets and traced hops in these MaxMind DB `files`, comma-separated, e.g. GeoLite2-City.mmdb,GeoLite2-ASN.mmdb")
+	flag.StringVar(
+		&vrfDevice,
+		"vrf",
+		"",
+		"Bind probes to this VRF device `name`, probing inside its routing instance, Linux only")
+	flag.BoolVar(
+		&numeric,
+		"n",
+		false,
+		"Numeric output only, skipping reverse DNS lookups")
+	flag.StringVar(
+		&resolverAddress,
+		"resolver",
+		"",
+		"DNS server `ip[:port]` to resolve the target against instead of the system resolver")
+	flag.StringVar(
+		&dohURL,
+		"doh",
+		"",
+		"DNS-over-HTTPS `url` to resolve the target with, e.g. https://cloudflare-dns.com/dns-query")
+	flag.StringVar(
+		&dotAddress,
+		"dot",
+		"",
+		"DNS-over-TLS server `host[:port]` to resolve the target with, e.g. 1.1.1.1")
+	resolvePolicy := flag.String(
+		"resolve",
+		"once",
+		"When to resolve the target: once, every N probes, or ttl to honor the DNS TTL")
+	allIPs := flag.Bool(
+		"all-ips",
+		false,
+		"Ping every address the hostname resolves to concurrently, with per-IP statistics")
+	globalLabels := make(labels)
+	flag.Var(
+		globalLabels,
+		"label",
+		"`key=value` label attached to every output line, may be repeated")
+	flag.BoolVar(
+		&broadcast,
+		"b",
+		false,
+		"Allow pinging broadcast and multicast addresses, listing every host that replies")
+	recordRoute := flag.Bool(
+		"R",
+		false,
+		"Record the route of IPv4 probes in the record route option, up to 9 hops")
+	timestampFlag := flag.String(
+		"T",
+		"",
+		"Record hop timestamps of IPv4 probes in the timestamp option: tsonly, or tsandaddr with addresses")
+	gatewaysFlag := flag.String(
+		"g",
+		"",
+		"Loose source route IPv4 probes through these comma-separated `gateway,...` hops, up to 9")
+	ecnFlag := flag.String(
+		"ecn",
+		"",
+		"Mark every other probe with this ECN `codepoint`, ect0, ect1 or ce, reporting whether the marking survives the path")
+	flowLabelFlag := flag.Uint(
+		"flow-label",
+		0,
+		"IPv6 flow `label` of probes, 0 for the kernel's choice")
+	flag.IntVar(
+		&flowLabelRotate,
+		"flow-label-rotate",
+		0,
+		"Cycle probes through this many consecutive IPv6 flow labels from -flow-label, reporting RTT per label")
+	runDuration := flag.Duration(
+		"duration",
+		0,
+		"Stop after running for this long, e.g. 2h, showing the summary")
+	flag.Float64Var(
+		&intervalJitter,
+		"interval-jitter",
+		0,
+		"Randomize each interval between probes within this `percent` band, e.g. 10 for 0.9s to 1.1s")
+	flag.BoolVar(
+		&showTable,
+		"table",
+		false,
+		"Show a live table of all targets refreshed in place instead of scrolling lines")
+	flag.BoolVar(
+		&interactiveTUI,
+		"tui",
+		false,
+		"Run an interactive console: space pauses and resumes, + and - change the interval, arrows and enter show a target, r resets statistics, q quits")
+	flag.BoolVar(
+		&debugPackets,
+		"debug-packets",
+		false,
+		"Hex dump every request and reply with decoded ICMP header fields")
+	pcapFile := flag.String(
+		"pcap",
+		"",
+		"Write all sent and received ICMP packets to this pcap `file`")
+	probeType := flag.String(
+		"probe",
+		"echo",
+		"ICMP probe `type`: echo, or timestamp to send IPv4 timestamp requests and estimate the target's clock offset")
+	flag.IntVar(
+		&realtimePriority,
+		"rt-priority",
+		0,
+		"SCHED_FIFO `priority` of probing threads from 1 to 99, reducing jitter on loaded machines (Linux, needs CAP_SYS_NICE), 0 for normal scheduling")
+	flag.IntVar(
+		&cpuAffinity,
+		"cpu-affinity",
+		-1,
+		"`CPU` to pin probing threads to, reducing jitter on loaded machines (Linux), -1 for any")
+	flag.BoolVar(
+		&gcTuning,
+		"gc-tuning",
+		false,
+		"Collect garbage only as memory nears a soft limit (GOMEMLIMIT, or 512MiB capped at half the cgroup's or system's memory), so GC pauses rarely inflate RTTs of measurement-sensitive sessions")
+	icmpType := flag.Int(
+		"icmp-type",
+		-1,
+		"ICMP `type` of probes instead of the probe type's, e.g. 15 for information requests, with -expert")
+	icmpCode := flag.Int(
+		"icmp-code",
+		0,
+		"ICMP `code` of probes, e.g. non-zero for echo requests, with -expert")
+	expert := flag.Bool(
+		"expert",
+		false,
+		"Allow crafting probes with -icmp-type and -icmp-code to test firewall and IDS behavior")
+	ntpServer := flag.String(
+		"ntp",
+		"",
+		"Correct the one-way delays of -probe timestamp against this NTP `server`, reporting forward and return path asymmetry")
+	sinkURL := flag.String(
+		"sink",
+		"",
+		"Publish every probe result as a JSON event to this `url`: nats://host[:port]/subject or kafka://broker[,broker...]/topic")
+	flag.StringVar(
+		&metricsFile,
+		"metrics-file",
+		"",
+		"Write an OpenMetrics snapshot of every target to this `file` every 15s and at every summary, e.g. for the node_exporter textfile collector")
+	flag.BoolVar(
+		&loopStats,
+		"loop-stats",
+		false,
+		"Show the cumulative min/avg/max RTT on every probe's line, like fping -l")
+	historySize := flag.Int(
+		"history",
+		defaultHistorySize,
+		"Keep the last `n` probe results in memory, shown by typing show last N while pinging or by GET /results in server mode, 0 to keep none")
+	historyLast := flag.Int(
+		"last",
+		defaultShowLast,
+		"Print the last `n` results with goping history")
+	flag.BoolVar(
+		&summaryOnly,
+		"summary-only",
+		false,
+		"Print no per-probe output, only the summary at exit, as a single JSON document with -o json")
+	flag.StringVar(
+		&benchmarkSort,
+		"sort",
+		"latency",
+		"Column goping benchmark-dns and benchmark-cdn rank targets by: latency, jitter or loss")
+	remoteWriteFlag := flag.String(
+		"remote-write-url",
+		"",
+		"Push RTT histograms and loss counters of every target to this Prometheus remote write `url` every 15s")
+	flag.DurationVar(
+		&assertP95,
+		"assert-rtt-p95-below",
+		0,
+		"Exit with 1 at termination unless every target's p95 RTT is below this `duration`")
+	flag.DurationVar(
+		&assertAvg,
+		"assert-rtt-avg-below",
+		0,
+		"Exit with 1 at termination unless every target's mean RTT is below this `duration`")
+	assertLossFlag := flag.String(
+		"assert-loss-below",
+		"",
+		"Exit with 1 at termination unless every target's loss is below this `percent`, e.g. 1%")
+	flag.BoolVar(
+		&desktopNotifications,
+		"notify-desktop",
+		false,
+		"Show a desktop notification (notify-send, osascript or a Windows toast) when a target goes down or recovers")
+	heartbeatFlag := flag.String(
+		"heartbeat-url",
+		"",
+		"Ping this dead man's switch `url` (e.g. of healthchecks.io or Cronitor) every minute while every target is up")
+	syslogAddress := flag.String(
+		"syslog",
+		"",
+		"Send every probe result and up/down state change to syslog at this `address`: local, udp://host[:port] or tcp://host[:port]")
+	syslogFacility := flag.String(
+		"syslog-facility",
+		"daemon",
+		"Syslog `facility` of -syslog messages, e.g. daemon, user or local0")
+	syslogSeverity := flag.String(
+		"syslog-severity",
+		"info",
+		"Syslog `severity` of probe results sent with -syslog, e.g. info or notice")
+	reportFile := flag.String(
+		"report",
+		"",
+		"Write a self-contained HTML report with RTT, loss and histogram charts to this `file` at termination")
+	heatmapFile := flag.String(
+		"heatmap",
+		"",
+		"Render RTT over time as a density heatmap to this .png or .svg `file` at termination, also from goping replay")
+	recordFile := flag.String(
+		"record",
+		"",
+		"Record every probe result to this `file` for goping replay")
+	outputFormat := flag.String(
+		"o",
+		"text",
+		"Output `format` of probe results: text, json or csv")
+	lineFormat := flag.String(
+		"format",
+		"",
+		"Go `template` of each probe result's line in place of -o, e.g. '{{.Seq}} {{.Target}} {{.IP}} {{.RTT}} {{.TTL}} {{.Loss}} {{.Time.Unix}}'")
+	timestamping := flag.String(
+		"timestamping",
+		"kernel",
+		"Where replies are timestamped for RTT: kernel (falling back to userspace if unsupported), userspace, hardware to time requests and replies by the NIC (Linux, falling back to kernel), or ebpf to time them on the wire (Linux, falling back to kernel)")
+	flag.DurationVar(
+		&precision,
+		"precision",
+		defaultPrecision,
+		"Display precision of RTTs and other durations, down to 1ns")
+	flag.Float64Var(
+		&anomalyThreshold,
+		"anomaly",
+		defaultAnomalyZ,
+		"Robust z-score above the recent RTT baseline at which a probe is flagged as an anomaly, 0 to disable")
+	maxHops := flag.Int(
+		"max-hops",
+		defaultMaxHops,
+		"Highest TTL probed by goping trace and sweep-ttl")
+	firstTTL := flag.Int(
+		"first-ttl",
+		1,
+		"Lowest TTL probed by goping sweep-ttl")
+	compareVia := flag.String(
+		"via",
+		"",
+		"Compare one target of goping compare over two `interfaces`, e.g. wg0,eth0, binding each side's probes to one of them")
+	flag.BoolVar(
+		&whoisHops,
+		"whois",
+		false,
+		"Name the owner of each hop's network in goping trace, looked up over RDAP")
+	exportFormat := flag.String(
+		"export",
+		"",
+		"Print the hop graph of goping trace in this `format` instead of the hop table: dot or json")
+	sweepMin := flag.Int(
+		"min",
+		defaultSweepMin,
+		"Smallest payload `size` in bytes probed by goping sweep-size")
+	sweepMax := flag.Int(
+		"max",
+		defaultSweepMax,
+		"Largest payload `size` in bytes probed by goping sweep-size")
+	sweepStep := flag.Int(
+		"step",
+		defaultSweepStep,
+		"Increment in bytes between payload sizes probed by goping sweep-size")
+	bloatURL := flag.String(
+		"bloat-url",
+		"",
+		"HTTP `url` goping bloat downloads from or uploads to, to load the link")
+	bloatStreams := flag.Int(
+		"bloat-streams",
+		defaultBloatStreams,
+		"Parallel transfers in each direction loading the link with goping bloat")
+	bloatDirection := flag.String(
+		"bloat-direction",
+		"download",
+		"Direction goping bloat loads the link in: download, upload or both")
+	bloatDuration := flag.Duration(
+		"bloat-duration",
+		defaultBloatDuration,
+		"Length of each of the idle and loaded phases of goping bloat")
+	benchPingers := flag.Int(
+		"bench-pingers",
+		defaultBenchPingers,
+		"Concurrent pingers probing localhost with goping bench")
+	benchInterval := flag.Duration(
+		"bench-interval",
+		defaultBenchInterval,
+		"Interval between probes of each pinger of goping bench")
+	healthcheckMaxLoss := flag.Float64(
+		"healthcheck-max-loss",
+		defaultHealthcheckMaxLoss,
+		"Highest `percent` loss goping healthcheck judges healthy")
+	healthcheckMaxRTT := flag.Duration(
+		"healthcheck-max-rtt",
+		defaultHealthcheckMaxRTT,
+		"Highest mean RTT goping healthcheck judges healthy")
+	pprofAddress := flag.String(
+		"pprof",
+		"",
+		"Serve net/http/pprof profiles on this `address`, e.g. :6060")
+	grpcAddress := flag.String(
+		"grpc",
+		defaultGRPCAddress,
+		"Serve the gRPC control and results API on this `address` with goping serve, e.g. :7070 for every interface, empty for none")
+	flag.StringVar(
+		&apiToken,
+		"api-token",
+		"",
+		"Bearer `token` clients of goping serve must present, and goping history presents to it, best given as GOPING_API_TOKEN to keep it out of the process list")
+	httpAddress := flag.String(
+		"http",
+		defaultHTTPAddress,
+		"Serve the HTTP control API on this `address` with goping serve, e.g. :8080 for every interface, empty for none")
+	configFile := flag.String(
+		"config",
+		"",
+		"Read targets and labels from this JSON config `file`, re-read on SIGHUP with -daemon")
+	configWatch := flag.Duration(
+		"config-watch",
+		0,
+		"Reload the -config file of -daemon whenever it changes, checking this often, e.g. 10s for a mounted Kubernetes ConfigMap")
+	daemonMode := flag.Bool(
+		"daemon",
+		false,
+		"Run as a long-lived service: notify systemd when ready, reload -config on SIGHUP, log for journald")
+	flag.DurationVar(
+		&backoffMax,
+		"backoff",
+		0,
+		"Back off probes of targets that are down exponentially up to this interval, e.g. 1m, resuming once they reply")
+	flag.BoolVar(
+		&splitPath,
+		"split-path",
+		false,
+		"Ping the default gateway alongside the targets, telling whether their loss and latency arise locally, up to the first hop, or upstream")
+	flag.BoolVar(
+		&countLate,
+		"count-late",
+		false,
+		"Count replies arriving after their probe's timeout as received in the loss figure, rather than only reporting them as late")
+	flag.BoolVar(
+		&adaptiveTimeout,
+		"adaptive-timeout",
+		false,
+		"Derive each probe's timeout from the smoothed RTT and RTT variance like TCP's RTO instead of waiting 10s")
+	flag.DurationVar(
+		&rtoMin,
+		"rto-min",
+		defaultRTOMin,
+		"Lower bound of adaptive timeouts")
+	flag.DurationVar(
+		&rtoMax,
+		"rto-max",
+		defaultRTOMax,
+		"Upper bound of adaptive timeouts, also the timeout until the first reply")
+	verbose := flag.Bool(
+		"v",
+		false,
+		"Verbose logging of socket setup and resolution")
+	veryVerbose := flag.Bool(
+		"vv",
+		false,
+		"Very verbose logging, adding raw ICMP details")
+	logFile := flag.String(
+		"log-file",
+		"",
+		"Also write logs to this `path`, rotating it as it grows")
+	journald := flag.Bool(
+		"journald",
+		false,
+		"Log to the systemd journal natively with structured fields such as TARGET=, SEQ= and RTT_USEC= in place of the console, Linux only")
+	logFileMiB := flag.Int(
+		"log-file-size",
+		defaultLogFileMiB,
+		"Size in MiB at which the -log-file is rotated")
+	flag.Parse()
+
+	// Set flags not given on the command line from their GOPING_* environment variables
+	if err := cmd.applyEnvironment(); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+
+	// Set up logging at the requested verbosity (-v, -vv, -log-file)
+	verbosity := 0
+	if *veryVerbose {
+		verbosity = 2
+	} else if *verbose {
+		verbosity = 1
+	}
+	if *logFileMiB < 1 {
+		slog.Warn("Log file size must be at least 1 MiB. Defaulting to 10...")
+		*logFileMiB = defaultLogFileMiB
+	}
+	if err := setupLogging(verbosity, *logFile, *logFileMiB, *daemonMode && command == "ping", *journald); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+
+	// Fail on flags the command doesn't take, and list the commands (goping help)
+	cmd.rejectForeignFlags()
+	if command == "help" {
+		runHelp(flag.Args())
+		return
+	}
+
+	// Print a shell completion script (goping completion)
+	if command == "completion" {
+		if flag.NArg() != 1 {
+			slog.Error("Please enter the shell to complete: bash, zsh, fish or powershell")
+			os.Exit(1)
+		}
+		if err := writeCompletion(os.Stdout, flag.Arg(0)); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Install, uninstall, start or stop the Windows service (goping service)
+	if command == "service" {
+		if flag.NArg() < 1 {
+			slog.Error("Please enter the service action: install, uninstall, start or stop")
+			os.Exit(1)
+		}
+		if err := controlService(flag.Arg(0), flag.Args()[1:]); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Error check assertions (-assert-*) input, exiting with 1 once deferred writers are flushed if one failed
+	assertLoss = -1
+	if *assertLossFlag != "" {
+		if percent, err := parsePercent(*assertLossFlag); err != nil {
+			slog.Warn(fmt.Sprintf("Loss assertion %s. Ignoring -assert-loss-below...", err))
+		} else {
+			assertLoss = percent
+		}
+	}
+	if assertP95 < 0 || assertAvg < 0 {
+		slog.Warn("RTT assertions must be positive durations. Ignoring negative ones...")
+		assertP95, assertAvg = max(assertP95, 0), max(assertAvg, 0)
+	}
+	defer func() {
+		if assertionsFailed {
+			os.Exit(1)
+		}
+	}()
+
+	// Serve profiles (-pprof) if given
+	if *pprofAddress != "" {
+		go servePprof(*pprofAddress)
+	}
+
+	// Open packet capture (-pcap) if given
+	if *pcapFile != "" {
+		var err error
+		if packetCapture, err = openCapture(*pcapFile); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		defer packetCapture.close()
+		slog.Info(fmt.Sprintf("Capturing packets to %s...", *pcapFile))
+	}
+
+	// Error check outputFormat (-o) input
+	switch *outputFormat {
+	case "text", "json", "csv":
+	default:
+		slog.Warn("Output format must be text, json or csv. Defaulting to text...")
+		*outputFormat = "text"
+	}
+	output = newResultWriter(*outputFormat)
+
+	// Parse the template of result lines (-format) if given, printed in place of the output format
+	if *lineFormat != "" {
+		if *outputFormat != "text" {
+			slog.Warn("Results are printed through -format. Ignoring -o...")
+		}
+		if err := output.useTemplate(*lineFormat); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// Error check probeType (-probe) input
+	switch *probeType {
+	case "echo":
+	case "timestamp":
+		if *ipVersion == 6 {
+			slog.Warn("ICMP timestamp probes are IPv4 only. Defaulting to echo...")
+		} else {
+			probeTypeTimestamp = true
+		}
+	default:
+		slog.Warn("Probe type must be echo or timestamp. Defaulting to echo...")
+	}
+
+	// Error check real-time priority (-rt-priority) input
+	if realtimePriority < 0 || realtimePriority > 99 {
+		slog.Warn("Real-time priority must be between 1 and 99. Ignoring -rt-priority...")
+		realtimePriority = 0
+	}
+
+	// Tune the garbage collector before probing (-gc-tuning)
+	if gcTuning {
+		tuneGC()
+	}
+
+	// Error check crafted ICMP type and code (-icmp-type, -icmp-code) input, which need -expert
+	if (*icmpType >= 0 || *icmpCode != 0) && !*expert {
+		slog.Warn("Crafting probes needs -expert. Ignoring -icmp-type and -icmp-code...")
+	} else if *icmpType > 255 || *icmpCode < 0 || *icmpCode > 255 {
+		slog.Warn("ICMP type and code must be between 0 and 255. Ignoring -icmp-type and -icmp-code...")
+	} else if *icmpType >= 0 || *icmpCode != 0 {
+		craftedType, craftedCode = *icmpType, *icmpCode
+		crafted := fmt.Sprintf("code %d", craftedCode)
+		if craftedType >= 0 {
+			crafted = fmt.Sprintf("type %d %s", craftedType, crafted)
+		}
+		slog.Warn(fmt.Sprintf("Sending probes with crafted ICMP %s. Targets may not answer them, and firewalls or IDSs may log or block them as an attack...", crafted))
+	}
+
+	// Error check NTP server (-ntp) input, querying it for our clock's offset
+	if *ntpServer != "" && !probeTypeTimestamp {
+		slog.Warn("An NTP reference only applies to -probe timestamp. Ignoring -ntp...")
+	} else if *ntpServer != "" {
+		offset, roundTrip, err := queryNTP(*ntpServer)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Could not query NTP server %s (%s). Ignoring -ntp...", *ntpServer, err))
+		} else {
+			ntpReference, localClockOffset = true, offset
+			slog.Debug("Queried NTP server", "server", *ntpServer, "offset", offset, "rtt", roundTrip)
+		}
+	}
+
+	// Error check IP options (-R, -T, -g) input
+	optionsChosen := 0
+	for _, chosen := range []bool{*recordRoute, *timestampFlag != "", *gatewaysFlag != ""} {
+		if chosen {
+			optionsChosen++
+		}
+	}
+	if optionsChosen > 0 && *ipVersion == 6 {
+		slog.Warn("Record route, timestamp and source route options are IPv4 only. Ignoring -R, -T and -g...")
+	} else if optionsChosen > 1 {
+		slog.Error("Please choose only one of -R, -T and -g, as IPv4 options only fit one")
+		os.Exit(1)
+	} else if *gatewaysFlag != "" {
+		var gateways []net.IP
+		for _, gateway := range strings.Split(*gatewaysFlag, ",") {
+			address, err := net.ResolveIPAddr("ip4", strings.TrimSpace(gateway))
+			if err != nil {
+				slog.Error(fmt.Sprintf("Invalid gateway %q of -g: %s", gateway, err))
+				os.Exit(1)
+			}
+			gateways = append(gateways, address.IP)
+		}
+		option, err := looseSourceRouteOption(gateways)
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		requestIPOptions = option
+	} else if *recordRoute {
+		requestIPOptions = recordRouteOption()
+	} else {
+		switch *timestampFlag {
+		case "":
+		case "tsonly":
+			requestIPOptions = timestampOption(timestampsOnly)
+		case "tsandaddr":
+			requestIPOptions = timestampOption(timestampsAndAddrs)
+		default:
+			slog.Warn("Timestamp option must be tsonly or tsandaddr. Defaulting to tsonly...")
+			requestIPOptions = timestampOption(timestampsOnly)
+		}
+	}
+
+	// Error check ECN codepoint (-ecn) input
+	if *ecnFlag != "" {
+		codepoint, ok := ecnCodepoints[*ecnFlag]
+		if !ok {
+			slog.Warn("ECN codepoint must be ect0, ect1 or ce. Defaulting to ect0...")
+			codepoint = ecnECT0
+		}
+		ecnProbe = codepoint
+	}
+
+	// Error check flow label (-flow-label, -flow-label-rotate) input
+	if *flowLabelFlag > uint(maxFlowLabel) {
+		slog.Warn("Flow label must be at most 0x7ffff. Defaulting to the kernel's choice...")
+		*flowLabelFlag = 0
+	}
+	flowLabel = uint32(*flowLabelFlag)
+	if flowLabelRotate < 0 {
+		slog.Warn("Flow labels to rotate must be positive. Defaulting to one...")
+		flowLabelRotate = 0
+	}
+	if (flowLabel != 0 || flowLabelRotate > 1) && *ipVersion != 6 {
+		slog.Warn("Flow labels are IPv6 only. Ignoring -flow-label and -flow-label-rotate...")
+		flowLabel, flowLabelRotate = 0, 0
+	}
+
+	// Error check socketMark (-mark) input
+	if socketMark < 0 {
+		slog.Warn("Mark must be positive. Defaulting to none...")
+		socketMark = 0
+	}
+
+	// Open the GeoIP databases (-geoip) if given
+	if *geoIP != "" {
+		if err := openGeoDatabases(*geoIP); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// Error check vrfDevice (-vrf) input
+	if vrfDevice != "" {
+		if _, err := net.InterfaceByName(vrfDevice); err != nil {
+			slog.Error(fmt.Sprintf("Invalid VRF %q: %s", vrfDevice, err))
+			os.Exit(1)
+		}
+		slog.Info(fmt.Sprintf("Probing in VRF %s...", vrfDevice))
+	}
+
+	// Error check timestamping (-timestamping) input
+	switch *timestamping {
+	case "kernel":
+		kernelTimestamping = true
+	case "userspace":
+	case "hardware":
+		kernelTimestamping, hardwareTimestamping = true, true
+		defer restoreHardwareTimestamping()
+	case "ebpf":
+		// Time requests and replies on the wire, falling back to kernel timestamps where eBPF can't
+		kernelTimestamping = true
+		var err error
+		if wireTiming, err = openWireTimer(); err != nil {
+			slog.Warn(fmt.Sprintf("Could not timestamp on the wire with eBPF (%s). Falling back to kernel...", err))
+		} else {
+			defer wireTiming.close()
+		}
+	default:
+		slog.Warn("Timestamping must be kernel, userspace, hardware or ebpf. Defaulting to kernel...")
+		kernelTimestamping = true
+	}
+
+	// Error check precision (-precision) input
+	if precision < time.Nanosecond {
+		slog.Warn("Precision must be at least 1ns. Defaulting to 10µs...")
+		precision = defaultPrecision
+	}
+
+	// Error check history size (-history) input, keeping the last results if given
+	if *historySize < 0 {
+		slog.Warn(fmt.Sprintf("History size must be a positive integer or 0. Defaulting to %d...", defaultHistorySize))
+		*historySize = defaultHistorySize
+	}
+	if *historySize > 0 {
+		recentResults = newResultHistory(*historySize)
+	}
+
+	// Render a latency heatmap (-heatmap) at termination if given
+	if *heatmapFile != "" {
+		var err error
+		if latencyHeatmap, err = newHeatmap(*heatmapFile); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// Re-render a recording (goping replay) without pinging
+	if command == "replay" {
+		if flag.NArg() != 1 {
+			slog.Error("Please enter the recording file to replay")
+			os.Exit(1)
+		}
+		if err := replay(flag.Arg(0), output); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		if *reportFile != "" {
+			if err := reportRecording(flag.Arg(0), *reportFile); err != nil {
+				slog.Error("Failed to write report: " + err.Error())
+			}
+		}
+		writeExports()
+		return
+	}
+
+	// Compare two recordings (goping diff) without pinging
+	if command == "diff" {
+		if flag.NArg() != 2 {
+			slog.Error("Please enter the two recording files to compare")
+			os.Exit(1)
+		}
+		if err := diffSessions(flag.Arg(0), flag.Arg(1)); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Print the last results of a recording or a server's history (goping history) without pinging
+	if command == "history" {
+		if flag.NArg() != 1 {
+			slog.Error("Please enter the recording file or server URL to print the history of")
+			os.Exit(1)
+		}
+		if *historyLast < 1 {
+			slog.Error("Number of results to print must be a positive integer")
+			os.Exit(1)
+		}
+		if err := printHistory(flag.Arg(0), *historyLast, output); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Render the HTML report of a recording (goping report) without pinging
+	if command == "report" {
+		if flag.NArg() != 1 {
+			slog.Error("Please enter the recording file to report on")
+			os.Exit(1)
+		}
+		path := *reportFile
+		if path == "" {
+			path = strings.TrimSuffix(flag.Arg(0), filepath.Ext(flag.Arg(0))) + ".html"
+		}
+		if err := reportRecording(flag.Arg(0), path); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Look up the owners of addresses or AS numbers (goping whois) without pinging
+	if command == "whois" {
+		if flag.NArg() == 0 {
+			slog.Error("Please enter the IP addresses or AS numbers to look up")
+			os.Exit(1)
+		}
+		if !runWhois(flag.Args()) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Error check intervalJitter (-interval-jitter) input
+	if intervalJitter < 0 || intervalJitter >= 100 {
+		slog.Warn("Interval jitter must be a percentage from 0 to below 100. Defaulting to 0...")
+		intervalJitter = 0
+	}
+
+	// Error check backoffMax (-backoff) input
+	if backoffMax != 0 && backoffMax < 2*probeInterval {
+		slog.Warn("Backoff must be at least 2s, or 0 to disable. Defaulting to 0...")
+		backoffMax = 0
+	}
+
+	// Error check adaptive timeout bounds (-rto-min, -rto-max) input
+	if rtoMin <= 0 || rtoMax < rtoMin {
+		slog.Warn("Adaptive timeout bounds must be positive with -rto-min at most -rto-max. Defaulting to 100ms and 10s...")
+		rtoMin, rtoMax = defaultRTOMin, defaultRTOMax
+	}
+
+	// Error check anomalyThreshold (-anomaly) input
+	if anomalyThreshold < 0 {
+		slog.Warn("Anomaly threshold must be positive, or 0 to disable. Defaulting to 3.5...")
+		anomalyThreshold = defaultAnomalyZ
+	}
+
+	// Error check pingCount (-c) input
+	if *pingCount < -1 {
+		slog.Warn("Times to ping must be positive int, or -1 for infinite. Defaulting to infinite...")
+		*pingCount = -1
+	}
+
+	// Error check timeToLive (-ttl) input
+	if *timeToLive < 0 {
+		slog.Warn("Invalid TTL. Defaulting to 64...")
+		*timeToLive = 64
+	}
+	ttl = *timeToLive
+
+	// Error check resolvePolicy (-resolve) input
+	switch *resolvePolicy {
+	case "once":
+	case "ttl":
+		resolveTTL = true
+	default:
+		if n, err := strconv.Atoi(*resolvePolicy); err == nil && n > 0 {
+			resolveEvery = n
+		} else {
+			slog.Warn("Resolve policy must be once, ttl or a positive int. Defaulting to once...")
+		}
+	}
+
+	// Set up custom resolver (-resolver) if given
+	if resolverAddress != "" {
+		if net.ParseIP(resolverAddress) != nil {
+			resolverAddress = net.JoinHostPort(resolverAddress, "53")
+		} else if _, _, err := net.SplitHostPort(resolverAddress); err != nil {
+			slog.Error(fmt.Sprintf("Invalid resolver %q, expected ip[:port]", resolverAddress))
+			os.Exit(1)
+		}
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, resolverAddress)
+			},
+		}
+		slog.Info(fmt.Sprintf("Resolving via %s...", resolverAddress))
+	}
+
+	// Set up encrypted DNS resolution (-doh or -dot) if given
+	if (resolverAddress != "" && encryptedDNS()) || (dohURL != "" && dotAddress != "") {
+		slog.Error("Please choose only one of -resolver, -doh and -dot")
+		os.Exit(1)
+	}
+	if dohURL != "" {
+		if parsed, err := url.Parse(dohURL); err != nil || parsed.Scheme != "https" {
+			slog.Error(fmt.Sprintf("Invalid DNS-over-HTTPS URL %q, expected https://...", dohURL))
+			os.Exit(1)
+		}
+		dnsExchange = httpsExchange
+		slog.Info(fmt.Sprintf("Resolving via %s...", resolverName("")))
+	}
+	if dotAddress != "" {
+		if _, _, err := net.SplitHostPort(dotAddress); err != nil {
+			dotAddress = net.JoinHostPort(dotAddress, "853")
+		}
+		dnsExchange = tlsExchange
+		slog.Info(fmt.Sprintf("Resolving via %s...", resolverName("")))
+	}
+
+	// Establish IP Version
+	if wantIPv6 = *ipVersion == 6; wantIPv6 {
+		slog.Info("Using IPv6...")
+	} else {
+		slog.Info("Using IPv4...")
+	}
+
+	// Read targets, labels and alerting from the config file (-config) if given
+	settings := new(config)
+	if *configFile != "" {
+		var err error
+		if settings, err = loadConfig(*configFile); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// Only probe within the schedule of the config file if given
+	if len(settings.Schedule) > 0 {
+		var err error
+		if probeSchedule, err = parseSchedule(settings.Schedule); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// Pause and resume probing on SIGUSR1, and reset statistics on SIGUSR2
+	go listenForPause()
+	go listenForReset()
+
+	// Stop after the run duration (-duration) if given
+	if *runDuration < 0 {
+		slog.Warn("Duration must be positive, or 0 for no limit. Defaulting to no limit...")
+		*runDuration = 0
+	}
+	if *runDuration > 0 {
+		runFinished = make(chan struct{})
+		time.AfterFunc(*runDuration, func() { close(runFinished) })
+	}
+
+	// Raise alerts on up/down transitions and SLA breaches if configured
+	if settings.Alerts != nil {
+		var err error
+		if alerts, err = newAlerter(*settings.Alerts); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		slog.Info(fmt.Sprintf("Alerting via %s...", alerts.names()))
+	}
+
+	// Show outages as desktop notifications (-notify-desktop), along with any configured alerts
+	if desktopNotifications {
+		if desktop, err := newDesktopNotifier(); err != nil {
+			slog.Warn(fmt.Sprintf("Could not find %s (%s). Ignoring -notify-desktop...", desktopCommand, err))
+		} else {
+			if alerts == nil {
+				alerts, _ = newAlerter(alertConfig{})
+			}
+			alerts.notifiers = append(alerts.notifiers, desktop)
+			slog.Info("Notifying outages on the desktop...")
+		}
+	}
+
+	// Track SLO compliance if configured, alerting budget burns along with other alerts
+	if settings.SLO != nil {
+		burnAlerts := alerts
+		if burnAlerts == nil {
+			burnAlerts, _ = newAlerter(alertConfig{})
+		}
+		var err error
+		if slos, err = newSLOTracker(*settings.SLO, burnAlerts); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		go slos.reportPeriodically()
+		slog.Info(fmt.Sprintf("Tracking %d SLOs, reporting every %s...", len(slos.settings.Objectives), time.Duration(slos.settings.Report)))
+	}
+
+	// Collect results for the HTML report (-report) if given
+	if *reportFile != "" {
+		runReport = newReport(*reportFile)
+	}
+
+	// Publish probe results to the event sink (-sink) if given
+	if *sinkURL != "" {
+		var err error
+		if sink, err = openSink(*sinkURL); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		defer sink.close()
+		redacted, _ := url.Parse(*sinkURL)
+		slog.Info(fmt.Sprintf("Publishing results to %s...", redacted.Redacted()))
+	}
+
+	// Ping a dead man's switch while targets are up (-heartbeat-url) if given
+	if *heartbeatFlag != "" {
+		parsed, err := url.Parse(*heartbeatFlag)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			slog.Warn("Heartbeat URL must be an http or https URL. Ignoring -heartbeat-url...")
+		} else {
+			heartbeatURL = parsed
+			slog.Info(fmt.Sprintf("Sending heartbeats to %s while targets are up...", parsed.Host))
+		}
+	}
+
+	// Push metrics to a Prometheus remote write endpoint (-remote-write-url) if given
+	if *remoteWriteFlag != "" {
+		parsed, err := url.Parse(*remoteWriteFlag)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			slog.Warn("Remote write URL must be an http or https URL. Ignoring -remote-write-url...")
+		} else {
+			remoteWriteURL = parsed
+			slog.Info(fmt.Sprintf("Pushing metrics to %s...", parsed.Redacted()))
+		}
+	}
+
+	// Send probe results and state changes to syslog (-syslog) if given
+	if *syslogAddress != "" {
+		var err error
+		if syslogOutput, err = openSyslog(*syslogAddress, *syslogFacility, *syslogSeverity); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		defer syslogOutput.close()
+		slog.Info(fmt.Sprintf("Sending results to syslog at %s...", syslogOutput.address))
+	}
+
+	// Rank benchmarked targets by a column of the results (-sort)
+	if benchmarkSort != "latency" && benchmarkSort != "jitter" && benchmarkSort != "loss" {
+		slog.Warn("Sort must be latency, jitter or loss. Defaulting to latency...")
+		benchmarkSort = "latency"
+	}
+
+	// Leave only the summary at exit (-summary-only), which the live table would overwrite
+	if summaryOnly && showTable {
+		slog.Warn("The table isn't shown with -summary-only. Ignoring -table...")
+		showTable = false
+	}
+
+	// Take over the terminal with the interactive console (-tui), which shows its own table
+	if interactiveTUI && summaryOnly {
+		slog.Warn("The interactive console isn't shown with -summary-only. Ignoring -tui...")
+		interactiveTUI = false
+	} else if interactiveTUI && !interactive() {
+		slog.Warn("The interactive console needs a terminal. Ignoring -tui...")
+		interactiveTUI = false
+	}
+	if interactiveTUI && showTable {
+		slog.Warn("The interactive console shows its own table. Ignoring -table...")
+		showTable = false
+	}
+
+	// Run as a monitoring service (-daemon) until terminated, ignored by commands other than ping
+	if *configWatch != 0 && !*daemonMode {
+		slog.Warn("The config file is only watched with -daemon. Ignoring -config-watch...")
+	}
+	if *daemonMode && command == "ping" {
+		if splitPath {
+			slog.Warn("The path isn't split with -daemon. Ignoring -split-path...")
+			splitPath = false
+		}
+		if summaryOnly {
+			slog.Warn("A daemon prints no summary until terminated. Ignoring -summary-only...")
+			summaryOnly = false
+		}
+		if showTable {
+			slog.Warn("The table isn't shown with -daemon. Ignoring -table...")
+			showTable = false
+		}
+		if interactiveTUI {
+			slog.Warn("A daemon has no interactive console. Ignoring -tui...")
+			interactiveTUI = false
+		}
+		if *configWatch < 0 || (*configWatch > 0 && *configFile == "") {
+			slog.Warn("Config watching needs a -config file and a positive interval. Ignoring -config-watch...")
+			*configWatch = 0
+		}
+		watchNetwork()
+		service := newDaemon(*configFile, *configWatch, commandLineTargets(), globalLabels, *pingCount)
+		service.server.echo = output.write
+		currentStats = service.server.allStats
+		if heartbeatURL != nil {
+			go sendHeartbeats()
+		}
+		if remoteWriteURL != nil {
+			go pushRemoteWrite()
+		}
+		if metricsFile != "" {
+			go writeMetricsPeriodically()
+		}
+		closeHandler(func() {
+			sdNotify("STOPPING=1")
+			showSummary(service.server.allStats())
+		})
+		if err := runDaemon(service); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Probe a target a few times and exit by whether it is healthy (goping healthcheck)
+	if command == "healthcheck" {
+		targets := commandLineTargets()
+		if len(targets) != 1 {
+			slog.Error("Please enter exactly one IP/hostname to healthcheck")
+			os.Exit(1)
+		}
+		count := *pingCount
+		if count == -1 {
+			count = defaultHealthcheckCount
+		}
+		if *healthcheckMaxLoss < 0 || *healthcheckMaxLoss > 100 {
+			slog.Warn(fmt.Sprintf("Healthcheck max loss must be a percentage from 0 to 100. Defaulting to %.0f...", defaultHealthcheckMaxLoss))
+			*healthcheckMaxLoss = defaultHealthcheckMaxLoss
+		}
+		if *healthcheckMaxRTT <= 0 {
+			slog.Warn(fmt.Sprintf("Healthcheck max RTT must be positive. Defaulting to %s...", defaultHealthcheckMaxRTT))
+			*healthcheckMaxRTT = defaultHealthcheckMaxRTT
+		}
+		healthy, err := runHealthcheck(targets[0], count, *healthcheckMaxLoss, *healthcheckMaxRTT)
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		if !healthy {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Measure the probe rate, CPU and allocations achievable against localhost (goping bench)
+	if command == "bench" {
+		if *benchPingers < 1 {
+			slog.Warn(fmt.Sprintf("Bench pingers must be positive. Defaulting to %d...", defaultBenchPingers))
+			*benchPingers = defaultBenchPingers
+		}
+		if *benchInterval <= 0 {
+			slog.Warn(fmt.Sprintf("Bench interval must be positive. Defaulting to %s...", defaultBenchInterval))
+			*benchInterval = defaultBenchInterval
+		}
+		duration := *runDuration
+		if duration == 0 {
+			duration = defaultBenchDuration
+		}
+		if err := runBench(duration, *benchPingers, *benchInterval); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Run ping jobs driven remotely (goping serve) until terminated
+	if command == "serve" {
+		if *grpcAddress == "" && *httpAddress == "" {
+			slog.Error("Please enter an address to serve on with -grpc or -http")
+			os.Exit(1)
+		}
+		server := newEngine()
+		currentStats = server.allStats
+		closeHandler(func() { showSummary(server.allStats()) })
+		// Serve each API until one fails
+		failed := make(chan error, 2)
+		if *grpcAddress != "" {
+			slog.Info(fmt.Sprintf("Serving gRPC API on %s...", *grpcAddress))
+			warnIfExposed("gRPC", *grpcAddress)
+			go func() { failed <- serveGRPC(*grpcAddress, server) }()
+		}
+		if *httpAddress != "" {
+			slog.Info(fmt.Sprintf("Serving HTTP API on %s...", *httpAddress))
+			warnIfExposed("HTTP", *httpAddress)
+			go func() { failed <- serveHTTP(*httpAddress, server) }()
+		}
+		slog.Error((<-failed).Error())
+		os.Exit(1)
+	}
+
+	// Establish hostnames/IP addresses, each optionally labelled as host=label
+	arguments := commandLineTargets() // Store hostnames or IP addresses, or those of GOPING_TARGETS
+	// Take targets and labels from the config file (-config), the command line and environment taking precedence
+	globalLabels = settings.tags(globalLabels)
+	// Rank popular resolvers or CDNs unless targets are given (goping benchmark-dns and benchmark-cdn)
+	if command == "benchmark-dns" || command == "benchmark-cdn" {
+		if len(arguments) == 0 && command == "benchmark-dns" {
+			arguments = wellKnownArguments(benchmarkDNSTargets)
+		} else if len(arguments) == 0 {
+			arguments = wellKnownArguments(benchmarkCDNTargets)
+		}
+		if *pingCount == -1 {
+			*pingCount = defaultBenchmarkCount
+		}
+	}
+	if len(arguments) == 0 {
+		arguments = settings.Targets
+	}
+	// Probe well-known anycast targets a few times (goping quicktest)
+	if command == "quicktest" {
+		if flag.NArg() > 0 {
+			slog.Warn("goping quicktest probes its own targets. Ignoring the addresses given...")
+		}
+		arguments = wellKnownArguments(quicktestTargets)
+		if *pingCount == -1 {
+			*pingCount = defaultQuicktestCount
+		}
+	}
+	if len(arguments) == 0 && settings.Default != "" {
+		slog.Info(fmt.Sprintf("No IP/hostname specified. Pinging the config's default %s...", settings.Default))
+		arguments = []string{settings.Default}
+	}
+	if len(arguments) == 0 {
+		slog.Error("No IP/hostname specified. Please enter one, set a default in the -config file, or run goping quicktest")
+		flag.Usage()
+		os.Exit(1)
+	}
+	// Ping the default gateway first to split the path to the targets (-split-path)
+	if splitPath {
+		gateways, err := defaultGateways()
+		if err != nil {
+			slog.Error(fmt.Sprintf("Could not discover the gateway to split the path at: %s", err))
+			os.Exit(1)
+		}
+		arguments = append([]string{gateways[0] + "=" + gatewayKeyword}, arguments...)
+	}
+	// Discover the addresses of the gateway and dns targets
+	arguments, err := expandKeywords(arguments)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	if *allIPs && (resolveTTL || resolveEvery > 0) {
+		slog.Warn("Addresses are only resolved once with -all-ips. Ignoring -resolve...")
+		resolveTTL, resolveEvery = false, 0
+	}
+
+	// Remember the targets given for shell completion
+	if cmd.operands == "hosts" && flag.NArg() > 0 {
+		rememberTargets(flag.Args())
+	}
+
+	// Trace the path to a single target (goping trace)
+	if command == "trace" {
+		if len(arguments) != 1 {
+			slog.Error("Please enter exactly one IP/hostname to trace")
+			os.Exit(1)
+		}
+		if *maxHops < 1 || *maxHops > 255 {
+			slog.Warn("Max hops must be between 1 and 255. Defaulting to 30...")
+			*maxHops = defaultMaxHops
+		}
+		if *exportFormat != "" && *exportFormat != "dot" && *exportFormat != "json" {
+			slog.Error(fmt.Sprintf("Invalid export format %q, expected dot or json", *exportFormat))
+			os.Exit(1)
+		}
+		address, _, _ := parseTarget(arguments[0], nil)
+		if err := runTrace(&resolution{address: address}, *maxHops, *pingCount, *exportFormat); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Probe a single target across payload sizes (goping sweep-size)
+	if command == "sweep-size" {
+		if len(arguments) != 1 {
+			slog.Error("Please enter exactly one IP/hostname to sweep")
+			os.Exit(1)
+		}
+		if *sweepMin < 0 || *sweepMin > maxSweepSize {
+			slog.Warn(fmt.Sprintf("Min size must be between 0 and %d. Defaulting to %d...", maxSweepSize, defaultSweepMin))
+			*sweepMin = defaultSweepMin
+		}
+		if *sweepMax < *sweepMin || *sweepMax > maxSweepSize {
+			slog.Warn(fmt.Sprintf("Max size must be between -min and %d. Defaulting to %d...", maxSweepSize, max(defaultSweepMax, *sweepMin)))
+			*sweepMax = max(defaultSweepMax, *sweepMin)
+		}
+		if *sweepStep < 1 {
+			slog.Warn(fmt.Sprintf("Step must be positive. Defaulting to %d...", defaultSweepStep))
+			*sweepStep = defaultSweepStep
+		}
+		count := *pingCount
+		if count == -1 {
+			count = defaultSweepCount
+		}
+		address, _, _ := parseTarget(arguments[0], nil)
+		if err := runSizeSweep(&resolution{address: address}, *sweepMin, *sweepMax, *sweepStep, count); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Probe a single target across TTLs (goping sweep-ttl)
+	if command == "sweep-ttl" {
+		if len(arguments) != 1 {
+			slog.Error("Please enter exactly one IP/hostname to sweep")
+			os.Exit(1)
+		}
+		if *maxHops < 1 || *maxHops > 255 {
+			slog.Warn("Max hops must be between 1 and 255. Defaulting to 30...")
+			*maxHops = defaultMaxHops
+		}
+		if *firstTTL < 1 || *firstTTL > *maxHops {
+			slog.Warn("First TTL must be between 1 and -max-hops. Defaulting to 1...")
+			*firstTTL = 1
+		}
+		count := *pingCount
+		if count == -1 {
+			count = defaultTTLSweepCount
+		}
+		address, _, _ := parseTarget(arguments[0], nil)
+		if err := runTTLSweep(&resolution{address: address}, *firstTTL, *maxHops, count); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Grade RTT under load against idle RTT for a single target (goping bloat)
+	if command == "bloat" {
+		if len(arguments) != 1 {
+			slog.Error("Please enter exactly one IP/hostname to measure bufferbloat to")
+			os.Exit(1)
+		}
+		if *bloatURL == "" {
+			slog.Error("Please enter a URL to load the link with using -bloat-url")
+			os.Exit(1)
+		}
+		if *bloatStreams < 1 {
+			slog.Warn(fmt.Sprintf("Bloat streams must be positive. Defaulting to %d...", defaultBloatStreams))
+			*bloatStreams = defaultBloatStreams
+		}
+		if *bloatDirection != "download" && *bloatDirection != "upload" && *bloatDirection != "both" {
+			slog.Warn("Bloat direction must be download, upload or both. Defaulting to download...")
+			*bloatDirection = "download"
+		}
+		if *bloatDuration <= 0 {
+			slog.Warn(fmt.Sprintf("Bloat duration must be positive. Defaulting to %s...", defaultBloatDuration))
+			*bloatDuration = defaultBloatDuration
+		}
+		address, _, _ := parseTarget(arguments[0], nil)
+		if err := runBloat(&resolution{address: address}, *bloatURL, *bloatStreams, *bloatDirection, *bloatDuration); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Create a statistics client per target, one per resolved address if -all-ips is given,
+	// each with its own echo identifier
+	var allStats []*statistic
+	for _, argument := range arguments {
+		address, targetLabels, overrides := parseTarget(argument, globalLabels)
+		addresses := []string{address}
+		if *allIPs {
+			ipAddresses, err := resolveAll(address)
+			if err != nil {
+				slog.Error(err.Error(), "address", address)
+				os.Exit(1)
+			}
+			slog.Info(fmt.Sprintf("Resolved %s to %d addresses...", address, len(ipAddresses)))
+			addresses = addresses[:0]
+			for _, ipAddress := range ipAddresses {
+				addresses = append(addresses, ipAddress.String())
+			}
+		}
+		for _, address := range addresses {
+			allStats = append(allStats, &statistic{
+				target:   &resolution{address: address},
+				labels:   targetLabels,
+				id:       nextEchoID(),
+				hopLimit: overrides.ttl,
+				interval: overrides.interval,
+				timeout:  overrides.timeout,
+			})
+		}
+	}
+
+	if splitPath {
+		splitGateway = allStats[0]
+	}
+
+	// Record every probe result (-record) if given
+	if *recordFile != "" {
+		var err error
+		if sessionRecorder, err = openRecorder(*recordFile); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		defer sessionRecorder.close()
+		slog.Info(fmt.Sprintf("Recording probes to %s...", *recordFile))
+	}
+
+	// Compare one target over two interfaces (goping compare -via), as two sides bound to each
+	if command == "compare" && *compareVia != "" {
+		devices := strings.Split(*compareVia, ",")
+		if len(devices) != 2 || len(allStats) != 1 {
+			slog.Error("Please enter exactly one IP/hostname and two interfaces to compare it over")
+			os.Exit(1)
+		}
+		target := allStats[0]
+		allStats = nil
+		for _, device := range devices {
+			if _, err := net.InterfaceByName(device); err != nil {
+				slog.Error(fmt.Sprintf("Invalid interface %q: %s", device, err))
+				os.Exit(1)
+			}
+			sideLabels := make(labels, len(target.labels)+1)
+			for key, value := range target.labels {
+				sideLabels[key] = value
+			}
+			sideLabels["via"] = device
+			allStats = append(allStats, &statistic{
+				target:   &resolution{address: target.target.address},
+				labels:   sideLabels,
+				id:       nextEchoID(),
+				hopLimit: target.hopLimit,
+				interval: target.interval,
+				timeout:  target.timeout,
+				device:   device,
+			})
+		}
+	}
 
-type statistic struct {
-	count               int             // Number of packets sent
-	lost                int             // Number of packets lost
-	rtt                 time.Duration   // Round trip time for each packet
-	loss                float64         // Percent loss at iteration
-	rttAll              []time.Duration // All RTTs in a slice for jitter calculation
-	totalDifferencesRTT time.Duration   // Differences between subsequent RTTs for jitter calculation
-	jitter              time.Duration   // Jitter
-}
+	// Resolve every target and open a socket once up front, exiting on what would fail every probe
+	if err := checkSocket(); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	resolveErrs := resolveTargets(allStats)
+	for _, err := range resolveErrs {
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	}
+	if errors.Join(resolveErrs...) != nil {
+		os.Exit(1)
+	}
 
-func main() {
-	// Remove timestamp from log
-	log.SetFlags(log.Flags() &^ (log.Ldate | log.Ltime))
+	// Rank the targets once probed (goping benchmark-dns and benchmark-cdn)
+	if command == "benchmark-dns" || command == "benchmark-cdn" {
+		currentStats = func() []*statistic { return allStats }
+		closeHandler(func() { showBenchmark(allStats) })
+		runBenchmark(allStats, *pingCount)
+		return
+	}
+
+	// Compare two targets in lockstep (goping compare)
+	if command == "compare" {
+		if len(allStats) != 2 {
+			slog.Error("Please enter exactly two IPs/hostnames to compare")
+			os.Exit(1)
+		}
+		currentStats = func() []*statistic { return allStats }
+		closeHandler(func() { showComparison(allStats[0], allStats[1]) })
+		runCompare(allStats[0], allStats[1], *pingCount)
+		showComparison(allStats[0], allStats[1])
+		writeExports()
+		return
+	}
+
+	// Re-resolve targets when the network changes
+	watchNetwork()
 
-	// Create statistics client
-	stats := new(statistic)
+	// Take commands such as show last N typed while pinging, unless the interactive console reads keys
+	if recentResults != nil && interactive() && !interactiveTUI {
+		go readConsole()
+	}
 
 	// Listen for ctrl-c termination
-	stats.closeHandler()
+	currentStats = func() []*statistic { return allStats }
+	if heartbeatURL != nil {
+		go sendHeartbeats()
+	}
+	if remoteWriteURL != nil {
+		go pushRemoteWrite()
+	}
+	if metricsFile != "" {
+		go writeMetricsPeriodically()
+	}
+	closeHandler(func() { showFinalSummary(allStats) })
+	runStarted = time.Now()
 
-	// Parse flags to variables
-	ipVersion := flag.Int(
-		"ipv",
-		4,
-		"4 or 6, corresponding to which IP version to use")
-	pingCount := flag.Int(
-		"c",
-		-1,
-		"Finite number of times to ping, -1 being infinite")
-	timeToLive := flag.Int(
-		"ttl",
-		64,
-		"Time-to-live before package expires")
-	flag.Parse()
+	// Ping all targets concurrently
+	var wg sync.WaitGroup
+	for _, stats := range allStats {
+		wg.Add(1)
+		go func(stats *statistic) {
+			defer wg.Done()
+			stats.run(*pingCount, nil, func(result probeResult) {
+				if recentResults != nil {
+					recentResults.add(result)
+				}
+				// The table and interactive console show statistics in place of scrolling lines, and -summary-only none
+				if !showTable && !interactiveTUI && !summaryOnly {
+					output.write(result)
+				}
+			})
+		}(stats)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	// Refresh the table in place until all targets are done, if -table is given
+	if showTable {
+		refreshTable(allStats, done)
+	}
+	// Run the interactive console (-tui) until all targets are done, or summarize them so far if quit
+	if interactiveTUI {
+		quit, err := runTUI(allStats, done)
+		if err != nil {
+			slog.Error("Could not run the interactive console: " + err.Error())
+		}
+		if quit {
+			showFinalSummary(allStats)
+			writeExports()
+			return
+		}
+	}
+	<-done
+	// Show summary if finite pings reached
+	showFinalSummary(allStats)
+	writeExports()
+}
 
-	// Error check pingCount (-c) input
-	if *pingCount < -1 {
-		log.Printf("Times to ping must be positive int, or -1 for infinite. Defaulting to infinite...")
-		*pingCount = -1
+// Write the HTML report (-report) and latency heatmap (-heatmap) of the run if given
+func writeExports() {
+	if runReport != nil {
+		if err := runReport.write(); err != nil {
+			slog.Error("Failed to write report: " + err.Error())
+		} else {
+			slog.Info(fmt.Sprintf("Wrote report to %s...", runReport.path))
+		}
 	}
+	if latencyHeatmap != nil {
+		if err := latencyHeatmap.write(); err != nil {
+			slog.Error("Failed to write heatmap: " + err.Error())
+		} else {
+			slog.Info(fmt.Sprintf("Wrote heatmap to %s...", latencyHeatmap.path))
+		}
+	}
+}
 
-	// Error check timeToLive (-ttl) input
-	if *timeToLive < 0 {
-		log.Printf("Invalid TTL. Defaulting to 64...")
-		*timeToLive = 64
+// Main ping loop for a single target, passing each result to emit until stop is closed.
+// A probe is sent every interval while earlier ones still await their replies, which are read
+// as they arrive, so a timeout longer than the interval doesn't slow probing. Each probe is
+// emitted as it finishes, so a lost one follows replies to the probes sent after it.
+func (stats *statistic) run(pingCount int, stop <-chan struct{}, emit func(probeResult)) {
+	tuneProbeThread()
+	stats.target.stop = stop
+	defer stats.closeSocket()
+	var (
+		flights  []*flight          // Probes in flight, oldest first
+		held     *flight            // Probe due but held back until those in flight finish, nil if none
+		reading  *listener          // Reader of the target's socket, nil until a probe is sent on it
+		packets  <-chan *packet     // Packets read by it, nil while there is none
+		launched int                // Probes sent or lost before sending
+		due      bool               // Whether the next probe is due
+		replied  = true             // Whether the last probe to finish was replied to, for -backoff
+		finished []*flight          // Probes finished by the last packet or deadline
+		next     = time.NewTimer(0) // Time until the next probe is due
+		expiry   = time.NewTimer(0) // Deadline of the probe in flight expiring first
+	)
+	expiry.Stop()
+	defer next.Stop()
+	defer expiry.Stop()
+	// Emit the probes finished, oldest first, no longer awaiting their replies
+	finish := func() {
+		for _, probe := range finished {
+			flights = slices.DeleteFunc(flights, func(inFlight *flight) bool { return inFlight == probe })
+			result := stats.complete(probe)
+			replied = result.Error == ""
+			emit(result)
+		}
+		finished = finished[:0]
+	}
+	// Handle a packet read, first finishing probes whose deadline passed before it arrived
+	handle := func(read *packet) {
+		finished = stats.expire(flights, read.received, finished)
+		if probe := stats.handle(stats.sock, read, flights); probe != nil {
+			finished = append(finished, probe)
+		}
+		reading.free <- read
 	}
-	ttl = *timeToLive
 
-	// Establish IP Version
-	if wantIPv6 = *ipVersion == 6; wantIPv6 {
-		log.Printf("Using IPv6...\n")
-	} else {
-		log.Printf("Using IPv4...\n")
+	for launched != pingCount || len(flights) > 0 {
+		select {
+		case <-stop:
+			return
+		case <-next.C:
+			due = true
+		case read, ok := <-packets:
+			if !ok {
+				reading, packets = nil, nil
+				break
+			}
+			handle(read)
+		case <-expiry.C:
+			// Take replies already read before counting their probes lost
+		drained:
+			for {
+				select {
+				case read, ok := <-packets:
+					if !ok {
+						reading, packets = nil, nil
+						break drained
+					}
+					handle(read)
+				default:
+					break drained
+				}
+			}
+			finished = stats.expire(flights, time.Now(), finished)
+		}
+		finish()
+
+		// Start the probe due, unless pausing, a schedule or a reset must wait for those in flight to finish
+		if due && held == nil && launched != pingCount && (len(flights) == 0 || !stats.holding()) {
+			if len(flights) == 0 {
+				if !stats.waitForSchedule(stop) || !probing.wait(stop) {
+					return
+				}
+				stats.resetIfRequested()
+			}
+			held = stats.begin(stats.count + len(flights))
+		}
+		// Send it, unless it is to a new address whose socket replaces the one those in flight await replies on
+		if held != nil && (len(flights) == 0 || !stats.reopens(held.ip)) {
+			probe := held
+			held, due = nil, false
+			launched++
+			if probe.resolved {
+				stats.launch(probe)
+			} else {
+				// Count a target that didn't resolve as lost at once, there being no socket to await replies on
+				probe.finish(probe.err)
+			}
+			if probe.done {
+				finished = append(finished, probe)
+				finish()
+			} else {
+				flights = append(flights, probe)
+				if reading == nil || reading.sock != stats.sock {
+					reading = listen(stats.sock, stats.replySize())
+					packets = reading.packets
+				}
+			}
+			// Send the next probe an interval later, backing off while the target is down with -backoff
+			interval := nextInterval(stats.probeEvery())
+			if backoffMax > 0 {
+				interval = stats.backoffInterval(replied)
+			}
+			next.Reset(interval)
+		}
+		// Wake at the deadline of the probe in flight expiring first
+		expiry.Stop()
+		if len(flights) > 0 {
+			earliest := flights[0].deadline
+			for _, probe := range flights[1:] {
+				if probe.deadline.Before(earliest) {
+					earliest = probe.deadline
+				}
+			}
+			expiry.Reset(time.Until(earliest))
+		}
 	}
+}
 
-	// Establish hostname/IP address
-	var address string // Store hostname or IP address
-	if flag.NArg() == 0 {
-		log.Printf("No IP/hostname specified. Defaulting to cloudflare.com...\n")
-		address = "cloudflare.com"
-	} else if flag.NArg() > 1 {
-		log.Printf("Please enter only one IP/hostname as a positional argument\n")
-		os.Exit(1)
+// Echo identifier for the next target, distinct for each target of this process
+func nextEchoID() int {
+	return (os.Getpid() + int(echoIDs.Add(1)) - 1) & 0xffff
+}
+
+// Outcome of a single probe as its replies are read, that of the last probe to finish kept in its
+// statistics. Probes in flight at once each have their own.
+type probeState struct {
+	seq        int             // Sequence number of the probe, the number of probes sent before it
+	sent       time.Time       // When the request was sent, for the GC pauses its RTT overlapped
+	rtt        time.Duration   // Round trip time of the probe
+	replyTTL   int             // TTL or hop limit of the reply, 0 if unknown
+	responder  net.IP          // Source of the time exceeded message answering it, nil if none did
+	answer     icmp.Type       // Type of the ICMP message answering it, nil if none did
+	answerer   net.IP          // Source of that message
+	extensions []string        // ICMP extensions of the error message answering it (RFC 4884) as displayed, nil if it had none
+	timestamps *icmpTimestamps // Timestamps of the ICMP timestamp reply, nil if there was none
+	responders []responder     // Hosts replying to a probe of a broadcast or multicast target (-b)
+	ipOptions  *ipOptions      // Record route or timestamp option of the reply (-R or -T), nil if there was none
+	flowLabel  uint32          // IPv6 flow label of the probe, 0 for the kernel's choice
+	ecn        byte            // ECN codepoint of the probe (-ecn)
+	replyECN   int             // ECN field of the reply, or of the probe quoted by an error, -1 if unknown
+	clock      string          // Source of the timestamps of the reply's RTT: hardware, kernel, userspace or ebpf, empty if lost
+}
+
+// Probe being sent or awaiting its reply
+type flight struct {
+	probeState               // Outcome of the probe so far
+	started      time.Time   // When the probe started, before resolving the target
+	ip           *net.IPAddr // Address probed, nil if the target didn't resolve
+	resolved     bool        // Whether the target resolved
+	cycles       uint64      // GC cycles completed when the probe started
+	deadline     time.Time   // When the probe is lost unless answered
+	sentSoftware time.Time   // When the kernel sent the request (-timestamping hardware), zero if unknown
+	sentHardware time.Time   // When the NIC sent the request (-timestamping hardware), zero if unknown
+	mismatched   bool        // Whether it was answered from an address other than the target
+	truncated    bool        // Whether its echo reply carried less payload than sent
+	corrupted    bool        // Whether its echo reply's payload differed from the request's
+	done         bool        // Whether the probe was answered or lost
+	err          error       // Why the probe was lost, nil if it was answered
+}
+
+// Finish the probe, lost if there was an error
+func (probe *flight) finish(err error) {
+	probe.done, probe.err = true, err
+}
+
+// Start a probe with the sequence number, resolving the target first
+func (stats *statistic) begin(seq int) *flight {
+	probe := &flight{probeState: probeState{seq: seq, replyECN: -1}, started: time.Now(), cycles: gcCycles()}
+	probe.ip, probe.err = stats.target.current()
+	probe.resolved = probe.err == nil
+	return probe
+}
+
+// Resolve and ping the target once, updating statistics and recording (-record) the outcome
+func (stats *statistic) probe() probeResult {
+	stats.resetIfRequested()
+	probe := stats.begin(stats.count)
+	if probe.resolved {
+		stats.await(probe)
+	}
+	return stats.complete(probe)
+}
+
+// Whether probing must wait for the probes in flight to finish before starting the next: while
+// paused or outside the schedule, as they are counted before probing stops, and before a reset
+func (stats *statistic) holding() bool {
+	return probing.status().Paused || (probeSchedule != nil && !probeSchedule.allows(time.Now())) || stats.resetting.pending.Load()
+}
+
+// Update statistics with the finished probe and record (-record) its outcome
+func (stats *statistic) complete(probe *flight) probeResult {
+	// Keep the probe's outcome as the target's last
+	stats.probeState = probe.probeState
+	logIPAddress, logErr := probe.ip, probe.err
+	stats.tallyGCPause(logErr == nil && gcCycles() != probe.cycles)
+	if adaptiveTimeout {
+		var netErr net.Error
+		stats.rto.observe(errors.As(logErr, &netErr) && netErr.Timeout(), stats.rtt)
+	}
+	anomalous := stats.tally(logErr == nil, probe.started)
+	if logErr != nil {
+		stats.tallyLossCause(lossCause(logErr, probe.resolved))
+	}
+	stats.tallyResponders()
+	stats.tallyFlowLabel(stats.flowLabel, logErr == nil)
+	stats.tallyECN(logErr == nil)
+	stats.trackRoute(stats.replyTTL, stats.responder, probe.started)
+
+	result := probeResult{
+		Time:       probe.started,
+		Target:     stats.target.address,
+		Name:       displayAddress(logIPAddress),
+		Seq:        probe.seq + 1,
+		RTT:        stats.rtt,
+		Loss:       stats.loss,
+		Labels:     stats.labels,
+		Mismatched: probe.mismatched,
+		Truncated:  probe.truncated,
+		Corrupted:  probe.corrupted,
+		Anomaly:    anomalous,
+		TTL:        stats.replyTTL,
+		Timestamps: stats.timestamps,
+		Responders: stats.responders,
+		IPOptions:  stats.ipOptions,
+		FlowLabel:  stats.flowLabel,
+		LostAt:     stats.lossSide(logErr == nil),
+		Clock:      stats.clock,
+		GCPause:    stats.gcPause,
+	}
+	if loopStats {
+		result.Running = stats.running()
+	}
+	if ecnProbe != ecnNotECT {
+		result.ECN = ecnNames[stats.ecn]
+		if logErr == nil && stats.replyECN >= 0 {
+			result.ReplyECN = ecnNames[stats.replyECN]
+		}
+	}
+	if logIPAddress != nil {
+		result.IP = logIPAddress.String()
+		stats.mutex.Lock()
+		stats.lastIP = result.IP
+		stats.mutex.Unlock()
+	}
+	if logErr != nil {
+		result.Error = logErr.Error()
+	}
+	if sessionRecorder != nil {
+		if err := sessionRecorder.write(result); err != nil {
+			slog.Debug("Could not record probe", "error", err)
+		}
+	}
+	if sink != nil {
+		if err := sink.publish(result); err != nil {
+			slog.Warn("Could not publish probe to sink: " + err.Error())
+		}
+	}
+	if syslogOutput != nil {
+		if err := syslogOutput.observe(stats, result); err != nil {
+			slog.Warn("Could not send probe to syslog: " + err.Error())
+		}
+	}
+	if alerts != nil {
+		alerts.observe(stats, result)
+	}
+	if slos != nil {
+		slos.observe(stats, result)
+	}
+	if runReport != nil {
+		runReport.add(stats, result)
+	}
+	if latencyHeatmap != nil {
+		latencyHeatmap.add(result)
+	}
+	return result
+}
+
+// Update statistics with the outcome of a probe sent at the given time whose RTT is in stats.rtt,
+// returning whether the RTT is anomalous against the recent baseline
+func (stats *statistic) tally(replied bool, sent time.Time) bool {
+	stats.mutex.Lock()
+	anomalous := false
+	if !replied {
+		stats.count++
+		stats.lost++
 	} else {
-		address = flag.Arg(0)
-	}
-
-	// Main ping loop
-	// Can be infinite or finite
-	for i := 0; i != *pingCount; i++ {
-		logIPAddress, logErr := stats.ping(address)
-		// Update statistics
-		if logErr != nil {
-			stats.count++
-			stats.lost++
-			log.Printf("ERROR: %s\n", logErr)
-		} else {
-			stats.count++
-			stats.rttAll = append(stats.rttAll, stats.rtt)
-		}
-		stats.loss = (float64(stats.lost) / float64(stats.count)) * 100.0
-		// Pring statistics every message
-		log.Printf(
-			"Seq: %d\t\tPinging: %s\t\tRTT: %s\t\tLoss: %.2f%%\n",
-			stats.count,
-			logIPAddress,
-			stats.rtt,
-			stats.loss)
-		time.Sleep(time.Second) // Sleep for 1 second
+		stats.count++
+		stats.rttAll = append(stats.rttAll, stats.rtt)
+		stats.totalRTT += stats.rtt
+		stats.histogram.observe(stats.rtt)
+		if anomalous = stats.baseline.observe(stats.rtt); anomalous {
+			stats.anomalies++
+		}
 	}
-	// Show summary if finite pings reached
-	stats.showStatistics()
+	stats.loss = (float64(stats.lost) / float64(stats.count)) * 100.0
+	stats.lastRTT = stats.rtt
+	stats.bursts.observe(replied, sent)
+	stats.rateLimit.observe(replied, sent)
+	stats.observeAvailability(sent)
+	stats.updateState(replied)
+	stats.mutex.Unlock()
+	return anomalous
 }
 
-// Ping the address, receiving a pointer to the statistics client
-func (stats *statistic) ping(address string) (*net.IPAddr, error) {
-	stats.rtt = 0 // Reset rtt in case error causes return before update
+// Ping the resolved IP address once, leaving the outcome in the statistics for the caller to tally
+func (stats *statistic) ping(ipAddress *net.IPAddr) error {
+	probe := &flight{probeState: probeState{seq: stats.count, replyECN: -1}, ip: ipAddress, resolved: true}
+	stats.await(probe)
+	stats.probeState = probe.probeState
+	return probe.err
+}
 
-	var (
-		listenNetwork  string    // listenNetwork for ListenPacket
-		listenAddress  string    // listenAddress for ListenPacket
-		resolveNetwork string    // resolveNetwork for ResolveIPAddr
-		messageType    icmp.Type // messageType for icmp.Message
-		protocolICMP   int       // protocolICMP for ParseMessage
-	)
+// Send the probe and read replies until one answers it or its deadline passes
+func (stats *statistic) await(probe *flight) {
+	sock := stats.launch(probe)
+	if probe.done {
+		return
+	}
+	read := replyPackets.Get().(*packet)
+	defer replyPackets.Put(read)
+	// Make room for replies to payloads larger than pooled buffers
+	if size := stats.replySize(); size > len(read.buffer) {
+		read.buffer = make([]byte, size)
+	}
+	if err := sock.setReadDeadline(probe.deadline); err != nil {
+		probe.finish(err)
+		return
+	}
+	flights := []*flight{probe}
+	for !probe.done {
+		if err := sock.read(read); err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				stats.expire(flights, probe.deadline, nil)
+				return
+			}
+			probe.rtt = read.received.Sub(probe.sent)
+			probe.finish(err)
+			return
+		}
+		stats.handle(sock, read, flights)
+	}
+}
+
+// Size of buffers replies are read into, fitting the echo of the largest probe with an IPv4 header of up to 60 bytes
+func (stats *statistic) replySize() int {
+	return max(packetBufferSize, 8+len(stats.requestPayload())+60)
+}
+
+// Payload of the target's echo requests
+func (stats *statistic) requestPayload() []byte {
+	if stats.payload != nil {
+		return stats.payload
+	}
+	return echoPayload
+}
 
-	// Set parameters according to IPv4 or IPv6
+// Send the probe on the target's socket, returning the socket, the probe finishing at once if it
+// couldn't be sent
+func (stats *statistic) launch(probe *flight) *icmpSocket {
+	ipAddress := probe.ip
+	var messageType icmp.Type // messageType for icmp.Message
+
+	// Set parameters according to IPv4 or IPv6, and the probe type (-probe)
 	if wantIPv6 {
-		listenNetwork = listenNetwork6
-		listenAddress = listenAddress6
-		resolveNetwork = resolveNetwork6
 		messageType = ipv6.ICMPTypeEchoRequest
-		protocolICMP = protocolICMP6
-
+	} else if probeTypeTimestamp {
+		messageType = ipv4.ICMPTypeTimestamp
 	} else {
-		listenNetwork = listenNetwork4
-		listenAddress = listenAddress4
-		resolveNetwork = resolveNetwork4
 		messageType = ipv4.ICMPTypeEcho
-		protocolICMP = protocolICMP4
 	}
 
-	// Listen for reply packets
-	listenPacket, err := icmp.ListenPacket(listenNetwork, listenAddress)
+	// Take the target's socket, configured for the address when first probing it
+	sock, err := stats.socket(ipAddress)
 	if err != nil {
-		return nil, err
+		probe.finish(err)
+		return nil
 	}
-	defer listenPacket.Close()
 
-	// Set TTL deadlines
-	if wantIPv6 {
-		listenPacket.IPv6PacketConn().SetHopLimit(ttl)
-	} else {
-		listenPacket.IPv4PacketConn().SetTTL(ttl)
+	// Mark every other probe with the ECN codepoint (-ecn)
+	probe.ecn = probeECN(probe.seq)
+	if ecnProbe != ecnNotECT {
+		if err := sock.setTrafficClass(int(probe.ecn)); err != nil {
+			slog.Debug("Could not set ECN codepoint", "ecn", ecnNames[probe.ecn], "error", err)
+		}
 	}
 
-	// Resolve hostname to IP address
-	ipAddress, err := net.ResolveIPAddr(resolveNetwork, address)
-	if err != nil {
-		return nil, err
+	// Set the IPv6 flow label, rotating it with -flow-label-rotate
+	if label := probeFlowLabel(probe.seq); wantIPv6 && label != 0 {
+		if err := sock.setFlowLabel(ipAddress, label); err != nil {
+			flowLabelFallback.Do(func() {
+				slog.Warn(fmt.Sprintf("Could not set flow label (%s). Sending with the kernel's choice...", err))
+			})
+		} else {
+			probe.flowLabel = label
+		}
 	}
 
-	// Create ICMP echo request packet
-	request := icmp.Message{
-		Type: messageType,
-		Code: 0,
-		Body: &icmp.Echo{
-			ID:   os.Getpid() & 0xffff,
-			Seq:  stats.count,
-			Data: []byte("PLS-GIB-INTERNSHIP"),
-		},
+	// Encode ICMP echo request packet into a pooled buffer, or marshal a timestamp request with -probe timestamp
+	requestBuffer := getBuffer()
+	defer putBuffer(requestBuffer)
+	requestEncoded := encodeEcho(*requestBuffer, messageType, stats.id, probe.seq, stats.requestPayload())
+	if probeTypeTimestamp {
+		request := icmp.Message{Type: messageType, Code: 0, Body: timestampRequest(stats.id, probe.seq, time.Now())}
+		if requestEncoded, err = request.Marshal(nil); err != nil {
+			probe.finish(err)
+			return sock
+		}
 	}
-	requestEncoded, err := request.Marshal(nil)
-	if err != nil {
-		return ipAddress, err
+	// Craft the type and code of the probe with -expert
+	if craftedProbe() {
+		craftProbe(requestEncoded)
+	}
+
+	// Take the timestamps of the previous request sent before this one's join them (-timestamping hardware)
+	if sock.sentTimestamps {
+		stats.collectSent(sock)
+	}
+	stats.lastSent = probe
+
+	// Set timeout to read reply, or to collect replies from every host with -b
+	timeout := 10 * time.Second
+	if broadcast {
+		timeout = broadcastWindow
+	} else if stats.timeout > 0 {
+		timeout = stats.timeout
+	} else if adaptiveTimeout {
+		timeout = stats.rto.timeout()
+		trace("Adaptive timeout", "rto", timeout)
 	}
 
 	// Send packet
-	timeSent := time.Now()
-	if _, err := listenPacket.WriteTo(requestEncoded, ipAddress); err != nil {
-		return ipAddress, err
+	probe.sent = time.Now()
+	probe.deadline = probe.sent.Add(timeout)
+	if err := sock.writeTo(requestEncoded, ipAddress); err != nil {
+		probe.finish(err)
+		return sock
 	}
-	replyEncoded := make([]byte, 1000)
-	// Set timeout to read reply
-	err = listenPacket.SetReadDeadline(time.Now().Add(10 * time.Second))
-	if err != nil {
-		return ipAddress, err
+	if tracing() {
+		trace("Sent echo request", "to", ipAddress, "id", stats.id, "seq", probe.seq, "bytes", len(requestEncoded), "raw", hex.EncodeToString(requestEncoded))
+	}
+	if debugPackets {
+		dumpPacket(true, ipAddress, requestEncoded, true)
+	}
+	if packetCapture != nil {
+		if err := packetCapture.write(probe.sent, sock.source, ipAddress.IP, stats.probeTTL(), requestEncoded); err != nil {
+			slog.Debug("Could not capture packet", "error", err)
+		}
+	}
+	return sock
+}
+
+// Finish the probes in flight whose deadline passed by the given time, appending them to finished.
+// A probe of a broadcast target (-b) succeeds if any host replied within the window.
+func (stats *statistic) expire(flights []*flight, now time.Time, finished []*flight) []*flight {
+	for _, probe := range flights {
+		if probe.done || now.Before(probe.deadline) {
+			continue
+		}
+		if len(probe.responders) > 0 {
+			probe.rtt = probe.responders[0].RTT
+			probe.finish(nil)
+		} else {
+			stats.lose(probe, stats.sock.timeoutError())
+		}
+		finished = append(finished, probe)
 	}
+	return finished
+}
+
+// Lose the probe to its read timing out
+func (stats *statistic) lose(probe *flight, timeout error) {
+	probe.rtt = time.Since(probe.sent)
+	// Watch for the reply arriving late, while later probes await theirs
+	if !broadcast {
+		stats.late.expect(probe.seq, probe.sent)
+	}
+	probe.finish(timeout)
+}
+
+// Take the software and hardware timestamps the kernel and NIC took of the last request sent
+// (-timestamping hardware), which arrive shortly after it leaves, so before the next is sent
+func (stats *statistic) collectSent(sock *icmpSocket) {
+	if stats.lastSent == nil {
+		return
+	}
+	sentSoftware, sentHardware := sock.sent()
+	if !sentSoftware.IsZero() {
+		stats.lastSent.sentSoftware = sentSoftware
+	}
+	if !sentHardware.IsZero() {
+		stats.lastSent.sentHardware = sentHardware
+	}
+}
 
-	// Read echo reply
-	replyRead, _, err := listenPacket.ReadFrom(replyEncoded)
-	stats.rtt = time.Since(timeSent).Round(10 * time.Microsecond)
+// Handle a packet read from the target's socket for the probes in flight, returning the probe it
+// finished, nil if none. The raw socket sees all ICMP traffic, so many packets answer no probe.
+func (stats *statistic) handle(sock *icmpSocket, read *packet, flights []*flight) *flight {
+	replyEncoded := read.buffer[:read.n]
+	if packetCapture != nil {
+		if err := packetCapture.write(read.received, read.peer.IP, sock.source, read.hopLimit, replyEncoded); err != nil {
+			slog.Debug("Could not capture packet", "error", err)
+		}
+	}
 
 	// Parse echo reply
-	reply, err := icmp.ParseMessage(protocolICMP, replyEncoded[:replyRead])
+	reply, err := read.parse()
 	if err != nil {
-		return ipAddress, err
+		if debugPackets {
+			dumpPacket(false, read.peer, replyEncoded, false)
+		}
+		// Count the target's unparseable replies as malformed and keep waiting for a sound one
+		if read.peer.IP.Equal(sock.destination.IP) {
+			stats.malformed++
+			slog.Debug("Malformed reply", "from", read.peer, "bytes", read.n, "error", err, "raw", hex.EncodeToString(replyEncoded))
+		}
+		return nil
+	}
+	// Skip replies to other requests, such as those of other targets or pings
+	// This also keeps IPv6 discovery from counting as error (https://www.sharetechnote.com/html/IP_Network_IPv6.html)
+	probe := stats.answered(reply, flights)
+	if tracing() {
+		trace("Received ICMP message", "from", read.peer, "type", reply.Type, "code", reply.Code, "bytes", read.n, "matched", probe != nil, "raw", hex.EncodeToString(replyEncoded))
+	}
+	if debugPackets {
+		dumpPacket(false, read.peer, replyEncoded, probe != nil)
+	}
+	if probe == nil {
+		// Count replies to probes already declared lost as late
+		if echo, ok := reply.Body.(*icmp.Echo); ok && echo.ID == stats.id && (reply.Type == ipv4.ICMPTypeEchoReply || reply.Type == ipv6.ICMPTypeEchoReply) {
+			stats.observeLate(echo.Seq, read.peer, sock.destination, read.received)
+		}
+		return nil
+	}
+	probe.rtt = read.received.Sub(probe.sent)
+	probe.answer, probe.answerer = reply.Type, read.source(probe.ip.IP)
+	// Keep the reply's TTL, and the router's address if the request expired, to watch for route changes
+	if reply.Type == ipv4.ICMPTypeEchoReply || reply.Type == ipv6.ICMPTypeEchoReply || reply.Type == ipv4.ICMPTypeTimestampReply {
+		probe.replyTTL = read.hopLimit
+		if requestIPOptions != nil {
+			probe.ipOptions = parseIPOptions(read.options)
+		}
+		if read.trafficClass >= 0 {
+			probe.replyECN = read.trafficClass & int(ecnMask)
+		}
+	} else if reply.Type == ipv4.ICMPTypeTimeExceeded || reply.Type == ipv6.ICMPTypeTimeExceeded {
+		probe.responder = read.source(nil)
+		// The quoted probe shows its ECN field as the router received it, for goping trace -ecn
+		if body, ok := reply.Body.(*icmp.TimeExceeded); ok {
+			if codepoint, ok := quotedECN(body.Data); ok {
+				probe.replyECN = int(codepoint)
+			}
+		}
+	}
+	// Keep ICMP extensions of errors from routers (RFC 4884), such as MPLS labels, for goping trace
+	probe.extensions = describeExtensions(messageExtensions(reply))
+	// Collect every host replying to a broadcast or multicast target (-b) until the window closes
+	if broadcast && (reply.Type == ipv4.ICMPTypeEchoReply || reply.Type == ipv6.ICMPTypeEchoReply) {
+		probe.responders = append(probe.responders, responder{IP: read.peer.IP.String(), RTT: probe.rtt})
+		return nil
+	}
+	// Replies from intermediate routers or NAT devices are not the target's
+	if !read.peer.IP.Equal(probe.ip.IP) {
+		stats.mismatched++
+		probe.mismatched = true
+		probe.finish(&replyError{kind: reply.Type, from: &net.IPAddr{IP: read.source(nil), Zone: read.peer.Zone}, want: probe.ip})
+		return probe
 	}
 	// Determine return based on reply type
 	switch reply.Type {
-	// Let IPv6 discovery not count as error (https://www.sharetechnote.com/html/IP_Network_IPv6.html)
-	case ipv6.ICMPTypeNeighborSolicitation, ipv6.ICMPTypeNeighborAdvertisement:
-		fallthrough
 	case ipv4.ICMPTypeEchoReply, ipv6.ICMPTypeEchoReply:
-		return ipAddress, nil
+		probe.clock = "userspace"
+		if read.kernel {
+			probe.clock = "kernel"
+		}
+		// Take the RTT between the NIC's timestamps with -timestamping hardware, or else between the kernel's
+		if sock.sentTimestamps {
+			stats.collectSent(sock)
+			if !probe.sentHardware.IsZero() && !read.hardware.IsZero() {
+				probe.rtt, probe.clock = read.hardware.Sub(probe.sentHardware), "hardware"
+			} else if !probe.sentSoftware.IsZero() && read.kernel {
+				probe.rtt = read.received.Sub(probe.sentSoftware)
+			}
+		}
+		// Take the RTT on the wire with -timestamping ebpf, free of scheduling delay on both ends
+		if wireTiming != nil {
+			if rtt, ok := wireTiming.rtt(stats.id, probe.seq&0xffff); ok {
+				probe.rtt, probe.clock = rtt, "ebpf"
+			}
+		}
+		// Echo replies must return the whole payload, or something on the path cut them short
+		payload := stats.requestPayload()
+		echo := reply.Body.(*icmp.Echo)
+		if len(echo.Data) < len(payload) {
+			stats.malformed++
+			stats.truncated++
+			probe.truncated = true
+			slog.Debug("Truncated reply", "from", read.peer, "payload", len(echo.Data), "sent", len(payload), "raw", hex.EncodeToString(replyEncoded))
+		}
+		// Count bits of the echoed payload differing from those sent, as faulty links corrupt without dropping
+		if flipped := flippedBits(payload, echo.Data); flipped > 0 {
+			stats.corrupted++
+			stats.corruptedBits += flipped
+			probe.corrupted = true
+			slog.Debug("Corrupted reply", "from", read.peer, "bits", flipped, "raw", hex.EncodeToString(replyEncoded))
+		}
+		probe.finish(nil)
+	case ipv4.ICMPTypeTimestampReply:
+		probe.timestamps, err = parseTimestampReply(reply.Body.(*icmp.RawBody).Data, read.received)
+		if err == nil {
+			stats.clockSamples = append(stats.clockSamples, *probe.timestamps)
+		}
+		probe.finish(err)
 	default:
-		return ipAddress, fmt.Errorf("Received %s instead of echo reply", reply.Type)
+		// Any answer to a crafted probe from the target counts as its reply
+		if craftedAnswer(reply, stats.id, probe.seq&0xffff) {
+			probe.finish(nil)
+		} else {
+			probe.finish(&replyError{kind: reply.Type})
+		}
+	}
+	return probe
+}
+
+// Number of bits differing between the payload sent and as much of it as was echoed
+func flippedBits(sent []byte, echoed []byte) int {
+	flipped := 0
+	for i := range min(len(sent), len(echoed)) {
+		flipped += bits.OnesCount8(sent[i] ^ echoed[i])
+	}
+	return flipped
+}
+
+// Probe in flight the reply answers, either as an echo reply carrying its identifier and sequence,
+// or as an ICMP error quoting it, nil if it answers none
+func (stats *statistic) answered(reply *icmp.Message, flights []*flight) *flight {
+	var id, seq int
+	switch body := reply.Body.(type) {
+	case *icmp.Echo:
+		if reply.Type != ipv4.ICMPTypeEchoReply && reply.Type != ipv6.ICMPTypeEchoReply {
+			return nil
+		}
+		id, seq = body.ID, body.Seq
+	case *icmp.RawBody:
+		if len(body.Data) < 4 {
+			return nil
+		}
+		id, seq = int(binary.BigEndian.Uint16(body.Data[0:2])), int(binary.BigEndian.Uint16(body.Data[2:4]))
+		if !craftedAnswer(reply, id, seq) && reply.Type != ipv4.ICMPTypeTimestampReply {
+			return nil
+		}
+	case *icmp.TimeExceeded:
+		id, seq = quotedEcho(body.Data)
+	case *icmp.DstUnreach:
+		id, seq = quotedEcho(body.Data)
+	case *icmp.PacketTooBig:
+		id, seq = quotedEcho(body.Data)
+	case *icmp.ParamProb:
+		id, seq = quotedEcho(body.Data)
+	default:
+		return nil
+	}
+	if id != stats.id {
+		return nil
+	}
+	for _, probe := range flights {
+		if !probe.done && probe.seq&0xffff == seq {
+			return probe
+		}
+	}
+	return nil
+}
+
+// Extract the echo identifier and sequence of the original request quoted in an ICMP error message,
+// returning -1 for both if the quoted packet is too short
+func quotedEcho(data []byte) (int, int) {
+	// Skip the quoted IP header
+	if wantIPv6 {
+		if len(data) < ipv6.HeaderLen {
+			return -1, -1
+		}
+		data = data[ipv6.HeaderLen:]
+	} else {
+		if len(data) < ipv4.HeaderLen || len(data) < int(data[0]&0x0f)*4 {
+			return -1, -1
+		}
+		data = data[int(data[0]&0x0f)*4:]
+	}
+	if len(data) < 8 {
+		return -1, -1
+	}
+	return int(binary.BigEndian.Uint16(data[4:6])), int(binary.BigEndian.Uint16(data[6:8]))
+}
+
+// Round a duration to the display precision (-precision),
+// keeping full resolution values for statistics
+func display(duration time.Duration) time.Duration {
+	return duration.Round(precision)
+}
 
+// Interval until the next probe, randomized within the -interval-jitter band so probes of
+// several goPing instances don't stay in step with each other or with periodic network events
+func nextInterval(interval time.Duration) time.Duration {
+	if intervalJitter == 0 {
+		return interval
 	}
+	spread := float64(interval) * intervalJitter / 100
+	return interval + time.Duration((rand.Float64()*2-1)*spread)
 }
 
-// Listen for ctrl-c type signal interrupt and exit after displaying summary
-func (stats *statistic) closeHandler() {
-	c := make(chan os.Signal)
+// Listen for ctrl-c type signal interrupt, or the end of the -duration, and exit after displaying summary
+func closeHandler(summary func()) {
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	go func(stats *statistic) {
-		<-c
-		fmt.Println(": Signal Interrupt received... ")
+	go func() {
+		select {
+		case <-c:
+			fmt.Fprintln(noticeOutput(), ": Signal Interrupt received... ")
+		case <-runFinished:
+			fmt.Fprintln(noticeOutput(), ": Run duration reached... ")
+		}
 		// Print statistics now
-		stats.showStatistics()
+		summary()
+		writeExports()
+		// Deliver results still buffered for the sink
+		if sink != nil {
+			sink.close()
+		}
+		restoreHardwareTimestamping()
+		if assertionsFailed {
+			os.Exit(1)
+		}
 		os.Exit(0)
-	}(stats)
+	}()
+}
+
+// Print statistics of all targets at program termination
+func showSummary(allStats []*statistic) {
+	fmt.Fprintln(noticeOutput(), "\n----------------------------| Statistics |----------------------------")
+	for _, stats := range allStats {
+		// Name each target only when there are several
+		if len(allStats) > 1 {
+			fmt.Fprintf(noticeOutput(), "Address: %s%s\n", stats.target.address, stats.labels.suffix())
+		} else if len(stats.labels) > 0 {
+			fmt.Fprintf(noticeOutput(), "Labels: %s\n", stats.labels)
+		}
+		stats.showStatistics()
+	}
+	// Judge where loss and latency arise against the gateway (-split-path)
+	if splitGateway != nil {
+		showSplitPath(allStats)
+	}
+	showPauses()
+	showRuntime(allStats)
+	// Snapshot the statistics summarized for the textfile collector (-metrics-file)
+	if metricsFile != "" {
+		writeMetricsFile(allStats)
+	}
 }
 
-// Print statistics at program termination
+// Print statistics of a single target
 func (stats *statistic) showStatistics() {
-	fmt.Println("\n----------------------------| Statistics |----------------------------")
+	stats.calculateJitter()
+	fmt.Fprintf(
+		noticeOutput(),
+		"Packets sent: %d\t\tPackets lost: %d\t\tLoss: %.2f%%\t\tJitter: %s\n",
+		stats.count,
+		stats.lost,
+		stats.loss,
+		display(stats.jitter))
+	// Locate the target in the -geoip databases
+	if location := stats.summary().Geo; location != nil {
+		fmt.Fprintf(noticeOutput(), "Location: %s\n", location)
+	}
+	// Only mention mismatched sources if any were seen
+	if stats.mismatched > 0 {
+		fmt.Fprintf(noticeOutput(), "Replies from other sources: %d\n", stats.mismatched)
+	}
+	// Only mention malformed replies if any were seen
+	if stats.malformed > 0 {
+		fmt.Fprintf(noticeOutput(), "Malformed replies: %d\t\tTruncated: %d\n", stats.malformed, stats.truncated)
+	}
+	// Only mention corrupted payloads if any were echoed
+	if stats.corrupted > 0 {
+		fmt.Fprintf(noticeOutput(), "Corrupted replies: %d\t\tBits flipped: %d\n", stats.corrupted, stats.corruptedBits)
+	}
+	// Only mention availability once time in a state was measured
+	if stats.uptime.up+stats.uptime.down > 0 {
+		fmt.Fprintf(noticeOutput(), "Availability: %s\n", stats.uptime)
+	}
+	// Only mention GC pauses if any overlapped an RTT
+	if stats.gcProbes > 0 {
+		fmt.Fprintf(noticeOutput(), "RTTs overlapping GC pauses: %d\t\tPaused: %s\n", stats.gcProbes, display(stats.gcPauseTotal))
+	}
+	// Only describe loss bursts and their causes if any probes were lost
+	if stats.lost > 0 {
+		fmt.Fprintf(noticeOutput(), "Loss bursts: %s\n", stats.bursts)
+		fmt.Fprintf(noticeOutput(), "Loss causes: %s\n", describeLossCauses(stats.lossCauses))
+	}
+	// Warn of loss patterns consistent with ICMP rate limiting, with the evidence
+	if evidence := stats.rateLimit.patterns(stats.count, stats.lost); len(evidence) > 0 {
+		fmt.Fprintln(noticeOutput(), "Possible ICMP rate limiting:")
+		for _, pattern := range evidence {
+			fmt.Fprintf(noticeOutput(), "  %s\n", pattern)
+		}
+	}
+	// Estimate the target's clock offset and one-way delays with -probe timestamp
+	if len(stats.clockSamples) > 0 {
+		stats.showClock()
+	}
+	// Only mention late replies if any arrived
+	if len(stats.late.rtts) > 0 {
+		stats.showLate()
+	}
+	// List the hosts that replied to a broadcast or multicast target
+	if len(stats.responderOrder) > 0 {
+		stats.showResponders()
+	}
+	// Break down probes by flow label when rotating them
+	if len(stats.flowOrder) > 0 {
+		stats.showFlowLabels()
+	}
+	// Judge whether ECN markings survived when testing ECN
+	if ecnProbe != ecnNotECT {
+		stats.showECN()
+	}
+	// Only mention route changes if any were seen
+	if stats.route.changes > 0 {
+		fmt.Fprintf(noticeOutput(), "Possible route changes: %d\n", stats.route.changes)
+	}
+	// Only mention anomalies if any were flagged
+	if stats.anomalies > 0 {
+		fmt.Fprintf(noticeOutput(), "Anomalous RTTs: %d\n", stats.anomalies)
+	}
+}
+
+// Calculate jitter from all RTTs so far
+func (stats *statistic) calculateJitter() {
 	// Calculate jitter only 2 or more pings stored
 	if len(stats.rttAll) > 1 {
 		// Formula derived from https://www.pingman.com/kb/article/what-is-jitter-57.html
+		stats.totalDifferencesRTT = 0
 		for val := range stats.rttAll[:len(stats.rttAll)-1] {
 			diff := stats.rttAll[val] - stats.rttAll[val+1]
 			if diff < 0 {
@@ -267,10 +2642,4 @@ func (stats *statistic) showStatistics() {
 		// https://stackoverflow.com/questions/54777109/dividing-a-time-duration-in-golang
 		stats.jitter = time.Duration(int64(stats.totalDifferencesRTT) / int64(len(stats.rttAll)-1))
 	}
-	fmt.Printf(
-		"Packets sent: %d\t\tPackets lost: %d\t\tLoss: %.2f%%\t\tJitter: %s\n",
-		stats.count,
-		stats.lost,
-		stats.loss,
-		stats.jitter)
 }
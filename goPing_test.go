@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+// IP protocol the fake target is probed over, set aside for experiments (RFC 3692), so the kernel
+// doesn't answer the echo requests itself as it does over ICMP
+const fakeNetwork string = "ip4:253"
+
+// Target at 127.0.0.1 whose socket is answered by a fake peer, replying to each sequence after its
+// delay and never to a sequence without one
+func fakeTarget(t *testing.T, delays map[int]time.Duration) *statistic {
+	t.Helper()
+	loopback := &net.IPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	conn, err := net.ListenPacket(fakeNetwork, loopback.IP.String())
+	if err != nil {
+		t.Skip(err)
+	}
+	sock := &icmpSocket{conn: conn.(*net.IPConn), oob: make([]byte, controlMessageSize), sentTraffic: -1, destination: loopback, closed: make(chan struct{})}
+	sock.ipv4Conn = ipv4.NewPacketConn(sock.conn)
+	if err := sock.prepareCalls(); err != nil {
+		sock.close()
+		t.Fatal(err)
+	}
+	peer, err := net.ListenPacket(fakeNetwork, loopback.IP.String())
+	if err != nil {
+		sock.close()
+		t.Skip(err)
+	}
+	t.Cleanup(func() { peer.Close() })
+	stats := &statistic{target: &resolution{address: loopback.IP.String()}, id: nextEchoID(), sock: sock}
+
+	// Answer the target's echo requests, skipping the peer's own replies looped back to it
+	go func() {
+		request := make([]byte, packetBufferSize)
+		for {
+			n, _, err := peer.ReadFrom(request)
+			if err != nil {
+				return
+			}
+			if n < 8 || request[0] != byte(ipv4.ICMPTypeEcho) || int(binary.BigEndian.Uint16(request[4:6])) != stats.id {
+				continue
+			}
+			seq := int(binary.BigEndian.Uint16(request[6:8]))
+			delay, ok := delays[seq]
+			if !ok {
+				continue
+			}
+			reply := encodeEcho(make([]byte, packetBufferSize), ipv4.ICMPTypeEchoReply, stats.id, seq, slices.Clone(request[8:n]))
+			time.AfterFunc(delay, func() { peer.WriteTo(reply, loopback) })
+		}
+	}()
+	return stats
+}
+
+// Have every lookup fail at once, as for a hostname that doesn't exist, without querying any DNS server
+func failLookups(t *testing.T) {
+	t.Helper()
+	systemResolver := resolver
+	resolver = &net.Resolver{PreferGo: true, Dial: func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("No DNS server in tests")
+	}}
+	t.Cleanup(func() { resolver = systemResolver })
+}
+
+// Probes are emitted as they finish, a lost one after replies to later probes, and replies
+// arriving after their probe's timeout are counted late
+func TestRun(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		delays   map[int]time.Duration // Delay of the reply to each sequence, none for a lost probe
+		count    int
+		interval time.Duration
+		timeout  time.Duration
+		seqs     []int // Sequence numbers emitted, in order
+		lost     []int // Sequence numbers lost
+		late     int   // Replies arriving late
+	}{
+		{"replied", map[int]time.Duration{0: 0, 1: 0, 2: 0}, 3, 50 * time.Millisecond, time.Second, []int{1, 2, 3}, nil, 0},
+		{"timeout", map[int]time.Duration{0: 0, 2: 0}, 3, 100 * time.Millisecond, 300 * time.Millisecond, []int{1, 3, 2}, []int{2}, 0},
+		{"late", map[int]time.Duration{0: 200 * time.Millisecond, 1: 0, 2: 0}, 3, 300 * time.Millisecond, 100 * time.Millisecond, []int{1, 2, 3}, []int{1}, 1},
+		{"out of order", map[int]time.Duration{0: 300 * time.Millisecond, 1: 0}, 2, 100 * time.Millisecond, time.Second, []int{2, 1}, nil, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			stats := fakeTarget(t, test.delays)
+			stats.interval, stats.timeout = test.interval, test.timeout
+			var seqs, lost []int
+			stats.run(test.count, make(chan struct{}), func(result probeResult) {
+				seqs = append(seqs, result.Seq)
+				if result.Error != "" {
+					lost = append(lost, result.Seq)
+					if !strings.Contains(result.Error, "timeout") {
+						t.Errorf("Seq %d lost to %q, want a timeout", result.Seq, result.Error)
+					}
+				}
+			})
+			if !slices.Equal(seqs, test.seqs) {
+				t.Errorf("Emitted seqs %v, want %v", seqs, test.seqs)
+			}
+			if !slices.Equal(lost, test.lost) {
+				t.Errorf("Lost seqs %v, want %v", lost, test.lost)
+			}
+			if stats.count != test.count || stats.lost != len(test.lost) {
+				t.Errorf("%d sent and %d lost, want %d and %d", stats.count, stats.lost, test.count, len(test.lost))
+			}
+			if len(stats.late.rtts) != test.late {
+				t.Errorf("%d late replies, want %d", len(stats.late.rtts), test.late)
+			}
+		})
+	}
+}
+
+// Probes of a target that doesn't resolve are lost at once rather than awaited on a socket never opened
+func TestRunUnresolvedTarget(t *testing.T) {
+	failLookups(t)
+	stats := &statistic{target: &resolution{address: "nonexistent.invalid"}, id: nextEchoID(), interval: 10 * time.Millisecond}
+	var results []probeResult
+	stats.run(3, make(chan struct{}), func(result probeResult) { results = append(results, result) })
+	if len(results) != 3 {
+		t.Fatalf("Got %d results, want 3", len(results))
+	}
+	for _, result := range results {
+		if result.Error == "" || result.IP != "" {
+			t.Errorf("Seq %d got IP %q and error %q, want it lost unresolved", result.Seq, result.IP, result.Error)
+		}
+	}
+	if stats.count != 3 || stats.lost != 3 || stats.lossCauses["unresolved"] != 3 {
+		t.Errorf("%d sent, %d lost and %d unresolved, want 3 of each", stats.count, stats.lost, stats.lossCauses["unresolved"])
+	}
+	if stats.sock != nil {
+		t.Error("Socket opened for a target that didn't resolve")
+	}
+}
@@ -0,0 +1,4 @@
+// Package gopingpb holds the protobuf messages and gRPC service of goping serve, generated from goping.proto
+package gopingpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative goping.proto
@@ -0,0 +1,1387 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: goping.proto
+
+package gopingpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StartRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Targets       []string               `protobuf:"bytes,1,rep,name=targets,proto3" json:"targets,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartRequest) Reset() {
+	*x = StartRequest{}
+	mi := &file_goping_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartRequest) ProtoMessage() {}
+
+func (x *StartRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartRequest.ProtoReflect.Descriptor instead.
+func (*StartRequest) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StartRequest) GetTargets() []string {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+func (x *StartRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *StartRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type JobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobRequest) Reset() {
+	*x = JobRequest{}
+	mi := &file_goping_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobRequest) ProtoMessage() {}
+
+func (x *JobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobRequest.ProtoReflect.Descriptor instead.
+func (*JobRequest) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *JobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type LastRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Last          int32                  `protobuf:"varint,2,opt,name=last,proto3" json:"last,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LastRequest) Reset() {
+	*x = LastRequest{}
+	mi := &file_goping_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LastRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LastRequest) ProtoMessage() {}
+
+func (x *LastRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LastRequest.ProtoReflect.Descriptor instead.
+func (*LastRequest) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LastRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LastRequest) GetLast() int32 {
+	if x != nil {
+		return x.Last
+	}
+	return 0
+}
+
+type JobStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Running       bool                   `protobuf:"varint,2,opt,name=running,proto3" json:"running,omitempty"`
+	Targets       []*TargetSummary       `protobuf:"bytes,3,rep,name=targets,proto3" json:"targets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobStatus) Reset() {
+	*x = JobStatus{}
+	mi := &file_goping_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobStatus) ProtoMessage() {}
+
+func (x *JobStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobStatus.ProtoReflect.Descriptor instead.
+func (*JobStatus) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *JobStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *JobStatus) GetRunning() bool {
+	if x != nil {
+		return x.Running
+	}
+	return false
+}
+
+func (x *JobStatus) GetTargets() []*TargetSummary {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+type StatsReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*JobStatus           `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsReply) Reset() {
+	*x = StatsReply{}
+	mi := &file_goping_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsReply) ProtoMessage() {}
+
+func (x *StatsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsReply.ProtoReflect.Descriptor instead.
+func (*StatsReply) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StatsReply) GetJobs() []*JobStatus {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+type ResultsReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*ProbeResult         `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResultsReply) Reset() {
+	*x = ResultsReply{}
+	mi := &file_goping_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResultsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultsReply) ProtoMessage() {}
+
+func (x *ResultsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultsReply.ProtoReflect.Descriptor instead.
+func (*ResultsReply) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ResultsReply) GetResults() []*ProbeResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type TargetSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        string                 `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Ip            string                 `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Sent          int64                  `protobuf:"varint,4,opt,name=sent,proto3" json:"sent,omitempty"`
+	Lost          int64                  `protobuf:"varint,5,opt,name=lost,proto3" json:"lost,omitempty"`
+	Loss          float64                `protobuf:"fixed64,6,opt,name=loss,proto3" json:"loss,omitempty"`
+	MinRtt        *durationpb.Duration   `protobuf:"bytes,7,opt,name=min_rtt,json=minRtt,proto3" json:"min_rtt,omitempty"`
+	AvgRtt        *durationpb.Duration   `protobuf:"bytes,8,opt,name=avg_rtt,json=avgRtt,proto3" json:"avg_rtt,omitempty"`
+	P95Rtt        *durationpb.Duration   `protobuf:"bytes,9,opt,name=p95_rtt,json=p95Rtt,proto3" json:"p95_rtt,omitempty"`
+	MaxRtt        *durationpb.Duration   `protobuf:"bytes,10,opt,name=max_rtt,json=maxRtt,proto3" json:"max_rtt,omitempty"`
+	Jitter        *durationpb.Duration   `protobuf:"bytes,11,opt,name=jitter,proto3" json:"jitter,omitempty"`
+	State         string                 `protobuf:"bytes,12,opt,name=state,proto3" json:"state,omitempty"`
+	Anomalies     int64                  `protobuf:"varint,13,opt,name=anomalies,proto3" json:"anomalies,omitempty"`
+	Late          int64                  `protobuf:"varint,14,opt,name=late,proto3" json:"late,omitempty"`
+	Malformed     int64                  `protobuf:"varint,15,opt,name=malformed,proto3" json:"malformed,omitempty"`
+	Corrupted     int64                  `protobuf:"varint,16,opt,name=corrupted,proto3" json:"corrupted,omitempty"`
+	Errors        map[string]int64       `protobuf:"bytes,17,rep,name=errors,proto3" json:"errors,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	RateLimiting  []string               `protobuf:"bytes,18,rep,name=rate_limiting,json=rateLimiting,proto3" json:"rate_limiting,omitempty"`
+	Availability  float64                `protobuf:"fixed64,19,opt,name=availability,proto3" json:"availability,omitempty"`
+	Downtime      *durationpb.Duration   `protobuf:"bytes,20,opt,name=downtime,proto3" json:"downtime,omitempty"`
+	Geo           *GeoLocation           `protobuf:"bytes,21,opt,name=geo,proto3" json:"geo,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TargetSummary) Reset() {
+	*x = TargetSummary{}
+	mi := &file_goping_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TargetSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TargetSummary) ProtoMessage() {}
+
+func (x *TargetSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TargetSummary.ProtoReflect.Descriptor instead.
+func (*TargetSummary) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TargetSummary) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *TargetSummary) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *TargetSummary) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *TargetSummary) GetSent() int64 {
+	if x != nil {
+		return x.Sent
+	}
+	return 0
+}
+
+func (x *TargetSummary) GetLost() int64 {
+	if x != nil {
+		return x.Lost
+	}
+	return 0
+}
+
+func (x *TargetSummary) GetLoss() float64 {
+	if x != nil {
+		return x.Loss
+	}
+	return 0
+}
+
+func (x *TargetSummary) GetMinRtt() *durationpb.Duration {
+	if x != nil {
+		return x.MinRtt
+	}
+	return nil
+}
+
+func (x *TargetSummary) GetAvgRtt() *durationpb.Duration {
+	if x != nil {
+		return x.AvgRtt
+	}
+	return nil
+}
+
+func (x *TargetSummary) GetP95Rtt() *durationpb.Duration {
+	if x != nil {
+		return x.P95Rtt
+	}
+	return nil
+}
+
+func (x *TargetSummary) GetMaxRtt() *durationpb.Duration {
+	if x != nil {
+		return x.MaxRtt
+	}
+	return nil
+}
+
+func (x *TargetSummary) GetJitter() *durationpb.Duration {
+	if x != nil {
+		return x.Jitter
+	}
+	return nil
+}
+
+func (x *TargetSummary) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *TargetSummary) GetAnomalies() int64 {
+	if x != nil {
+		return x.Anomalies
+	}
+	return 0
+}
+
+func (x *TargetSummary) GetLate() int64 {
+	if x != nil {
+		return x.Late
+	}
+	return 0
+}
+
+func (x *TargetSummary) GetMalformed() int64 {
+	if x != nil {
+		return x.Malformed
+	}
+	return 0
+}
+
+func (x *TargetSummary) GetCorrupted() int64 {
+	if x != nil {
+		return x.Corrupted
+	}
+	return 0
+}
+
+func (x *TargetSummary) GetErrors() map[string]int64 {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *TargetSummary) GetRateLimiting() []string {
+	if x != nil {
+		return x.RateLimiting
+	}
+	return nil
+}
+
+func (x *TargetSummary) GetAvailability() float64 {
+	if x != nil {
+		return x.Availability
+	}
+	return 0
+}
+
+func (x *TargetSummary) GetDowntime() *durationpb.Duration {
+	if x != nil {
+		return x.Downtime
+	}
+	return nil
+}
+
+func (x *TargetSummary) GetGeo() *GeoLocation {
+	if x != nil {
+		return x.Geo
+	}
+	return nil
+}
+
+type GeoLocation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Country       string                 `protobuf:"bytes,1,opt,name=country,proto3" json:"country,omitempty"`
+	City          string                 `protobuf:"bytes,2,opt,name=city,proto3" json:"city,omitempty"`
+	Asn           uint32                 `protobuf:"varint,3,opt,name=asn,proto3" json:"asn,omitempty"`
+	AsName        string                 `protobuf:"bytes,4,opt,name=as_name,json=asName,proto3" json:"as_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GeoLocation) Reset() {
+	*x = GeoLocation{}
+	mi := &file_goping_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GeoLocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeoLocation) ProtoMessage() {}
+
+func (x *GeoLocation) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeoLocation.ProtoReflect.Descriptor instead.
+func (*GeoLocation) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GeoLocation) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *GeoLocation) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *GeoLocation) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *GeoLocation) GetAsName() string {
+	if x != nil {
+		return x.AsName
+	}
+	return ""
+}
+
+type ProbeResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Job           string                 `protobuf:"bytes,2,opt,name=job,proto3" json:"job,omitempty"`
+	Target        string                 `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+	Ip            string                 `protobuf:"bytes,4,opt,name=ip,proto3" json:"ip,omitempty"`
+	Name          string                 `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+	Seq           int64                  `protobuf:"varint,6,opt,name=seq,proto3" json:"seq,omitempty"`
+	Rtt           *durationpb.Duration   `protobuf:"bytes,7,opt,name=rtt,proto3" json:"rtt,omitempty"`
+	Loss          float64                `protobuf:"fixed64,8,opt,name=loss,proto3" json:"loss,omitempty"`
+	Error         string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,10,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Mismatched    bool                   `protobuf:"varint,11,opt,name=mismatched,proto3" json:"mismatched,omitempty"`
+	Anomaly       bool                   `protobuf:"varint,12,opt,name=anomaly,proto3" json:"anomaly,omitempty"`
+	Truncated     bool                   `protobuf:"varint,13,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	Corrupted     bool                   `protobuf:"varint,14,opt,name=corrupted,proto3" json:"corrupted,omitempty"`
+	LostAt        string                 `protobuf:"bytes,15,opt,name=lost_at,json=lostAt,proto3" json:"lost_at,omitempty"`
+	Ttl           int32                  `protobuf:"varint,16,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Timestamps    *ICMPTimestamps        `protobuf:"bytes,17,opt,name=timestamps,proto3" json:"timestamps,omitempty"`
+	Responders    []*Responder           `protobuf:"bytes,18,rep,name=responders,proto3" json:"responders,omitempty"`
+	IpOptions     *IPOptions             `protobuf:"bytes,19,opt,name=ip_options,json=ipOptions,proto3" json:"ip_options,omitempty"`
+	FlowLabel     uint32                 `protobuf:"varint,20,opt,name=flow_label,json=flowLabel,proto3" json:"flow_label,omitempty"`
+	Ecn           string                 `protobuf:"bytes,21,opt,name=ecn,proto3" json:"ecn,omitempty"`
+	ReplyEcn      string                 `protobuf:"bytes,22,opt,name=reply_ecn,json=replyEcn,proto3" json:"reply_ecn,omitempty"`
+	Running       *RunningStats          `protobuf:"bytes,23,opt,name=running,proto3" json:"running,omitempty"`
+	Clock         string                 `protobuf:"bytes,24,opt,name=clock,proto3" json:"clock,omitempty"`
+	GcPause       *durationpb.Duration   `protobuf:"bytes,25,opt,name=gc_pause,json=gcPause,proto3" json:"gc_pause,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbeResult) Reset() {
+	*x = ProbeResult{}
+	mi := &file_goping_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeResult) ProtoMessage() {}
+
+func (x *ProbeResult) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeResult.ProtoReflect.Descriptor instead.
+func (*ProbeResult) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ProbeResult) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *ProbeResult) GetJob() string {
+	if x != nil {
+		return x.Job
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetRtt() *durationpb.Duration {
+	if x != nil {
+		return x.Rtt
+	}
+	return nil
+}
+
+func (x *ProbeResult) GetLoss() float64 {
+	if x != nil {
+		return x.Loss
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *ProbeResult) GetMismatched() bool {
+	if x != nil {
+		return x.Mismatched
+	}
+	return false
+}
+
+func (x *ProbeResult) GetAnomaly() bool {
+	if x != nil {
+		return x.Anomaly
+	}
+	return false
+}
+
+func (x *ProbeResult) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+func (x *ProbeResult) GetCorrupted() bool {
+	if x != nil {
+		return x.Corrupted
+	}
+	return false
+}
+
+func (x *ProbeResult) GetLostAt() string {
+	if x != nil {
+		return x.LostAt
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetTtl() int32 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetTimestamps() *ICMPTimestamps {
+	if x != nil {
+		return x.Timestamps
+	}
+	return nil
+}
+
+func (x *ProbeResult) GetResponders() []*Responder {
+	if x != nil {
+		return x.Responders
+	}
+	return nil
+}
+
+func (x *ProbeResult) GetIpOptions() *IPOptions {
+	if x != nil {
+		return x.IpOptions
+	}
+	return nil
+}
+
+func (x *ProbeResult) GetFlowLabel() uint32 {
+	if x != nil {
+		return x.FlowLabel
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetEcn() string {
+	if x != nil {
+		return x.Ecn
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetReplyEcn() string {
+	if x != nil {
+		return x.ReplyEcn
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetRunning() *RunningStats {
+	if x != nil {
+		return x.Running
+	}
+	return nil
+}
+
+func (x *ProbeResult) GetClock() string {
+	if x != nil {
+		return x.Clock
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetGcPause() *durationpb.Duration {
+	if x != nil {
+		return x.GcPause
+	}
+	return nil
+}
+
+type RunningStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinRtt        *durationpb.Duration   `protobuf:"bytes,1,opt,name=min_rtt,json=minRtt,proto3" json:"min_rtt,omitempty"`
+	AvgRtt        *durationpb.Duration   `protobuf:"bytes,2,opt,name=avg_rtt,json=avgRtt,proto3" json:"avg_rtt,omitempty"`
+	MaxRtt        *durationpb.Duration   `protobuf:"bytes,3,opt,name=max_rtt,json=maxRtt,proto3" json:"max_rtt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunningStats) Reset() {
+	*x = RunningStats{}
+	mi := &file_goping_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunningStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunningStats) ProtoMessage() {}
+
+func (x *RunningStats) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunningStats.ProtoReflect.Descriptor instead.
+func (*RunningStats) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RunningStats) GetMinRtt() *durationpb.Duration {
+	if x != nil {
+		return x.MinRtt
+	}
+	return nil
+}
+
+func (x *RunningStats) GetAvgRtt() *durationpb.Duration {
+	if x != nil {
+		return x.AvgRtt
+	}
+	return nil
+}
+
+func (x *RunningStats) GetMaxRtt() *durationpb.Duration {
+	if x != nil {
+		return x.MaxRtt
+	}
+	return nil
+}
+
+type Responder struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	Rtt           *durationpb.Duration   `protobuf:"bytes,2,opt,name=rtt,proto3" json:"rtt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Responder) Reset() {
+	*x = Responder{}
+	mi := &file_goping_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Responder) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Responder) ProtoMessage() {}
+
+func (x *Responder) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Responder.ProtoReflect.Descriptor instead.
+func (*Responder) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Responder) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *Responder) GetRtt() *durationpb.Duration {
+	if x != nil {
+		return x.Rtt
+	}
+	return nil
+}
+
+type ICMPTimestamps struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Originate     uint32                 `protobuf:"varint,1,opt,name=originate,proto3" json:"originate,omitempty"`
+	Receive       uint32                 `protobuf:"varint,2,opt,name=receive,proto3" json:"receive,omitempty"`
+	Transmit      uint32                 `protobuf:"varint,3,opt,name=transmit,proto3" json:"transmit,omitempty"`
+	Offset        *durationpb.Duration   `protobuf:"bytes,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Forward       *durationpb.Duration   `protobuf:"bytes,5,opt,name=forward,proto3" json:"forward,omitempty"`
+	Return        *durationpb.Duration   `protobuf:"bytes,6,opt,name=return,proto3" json:"return,omitempty"`
+	Asymmetry     *durationpb.Duration   `protobuf:"bytes,7,opt,name=asymmetry,proto3" json:"asymmetry,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ICMPTimestamps) Reset() {
+	*x = ICMPTimestamps{}
+	mi := &file_goping_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ICMPTimestamps) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ICMPTimestamps) ProtoMessage() {}
+
+func (x *ICMPTimestamps) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ICMPTimestamps.ProtoReflect.Descriptor instead.
+func (*ICMPTimestamps) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ICMPTimestamps) GetOriginate() uint32 {
+	if x != nil {
+		return x.Originate
+	}
+	return 0
+}
+
+func (x *ICMPTimestamps) GetReceive() uint32 {
+	if x != nil {
+		return x.Receive
+	}
+	return 0
+}
+
+func (x *ICMPTimestamps) GetTransmit() uint32 {
+	if x != nil {
+		return x.Transmit
+	}
+	return 0
+}
+
+func (x *ICMPTimestamps) GetOffset() *durationpb.Duration {
+	if x != nil {
+		return x.Offset
+	}
+	return nil
+}
+
+func (x *ICMPTimestamps) GetForward() *durationpb.Duration {
+	if x != nil {
+		return x.Forward
+	}
+	return nil
+}
+
+func (x *ICMPTimestamps) GetReturn() *durationpb.Duration {
+	if x != nil {
+		return x.Return
+	}
+	return nil
+}
+
+func (x *ICMPTimestamps) GetAsymmetry() *durationpb.Duration {
+	if x != nil {
+		return x.Asymmetry
+	}
+	return nil
+}
+
+type IPOptions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Route         []string               `protobuf:"bytes,1,rep,name=route,proto3" json:"route,omitempty"`
+	Source        []string               `protobuf:"bytes,2,rep,name=source,proto3" json:"source,omitempty"`
+	Stamps        []*OptionStamp         `protobuf:"bytes,3,rep,name=stamps,proto3" json:"stamps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IPOptions) Reset() {
+	*x = IPOptions{}
+	mi := &file_goping_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IPOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPOptions) ProtoMessage() {}
+
+func (x *IPOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPOptions.ProtoReflect.Descriptor instead.
+func (*IPOptions) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *IPOptions) GetRoute() []string {
+	if x != nil {
+		return x.Route
+	}
+	return nil
+}
+
+func (x *IPOptions) GetSource() []string {
+	if x != nil {
+		return x.Source
+	}
+	return nil
+}
+
+func (x *IPOptions) GetStamps() []*OptionStamp {
+	if x != nil {
+		return x.Stamps
+	}
+	return nil
+}
+
+type OptionStamp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Ms            uint32                 `protobuf:"varint,2,opt,name=ms,proto3" json:"ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OptionStamp) Reset() {
+	*x = OptionStamp{}
+	mi := &file_goping_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OptionStamp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OptionStamp) ProtoMessage() {}
+
+func (x *OptionStamp) ProtoReflect() protoreflect.Message {
+	mi := &file_goping_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OptionStamp.ProtoReflect.Descriptor instead.
+func (*OptionStamp) Descriptor() ([]byte, []int) {
+	return file_goping_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *OptionStamp) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *OptionStamp) GetMs() uint32 {
+	if x != nil {
+		return x.Ms
+	}
+	return 0
+}
+
+var File_goping_proto protoreflect.FileDescriptor
+
+const file_goping_proto_rawDesc = "" +
+	"\n" +
+	"\fgoping.proto\x12\x06goping\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb3\x01\n" +
+	"\fStartRequest\x12\x18\n" +
+	"\atargets\x18\x01 \x03(\tR\atargets\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\x128\n" +
+	"\x06labels\x18\x03 \x03(\v2 .goping.StartRequest.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x1c\n" +
+	"\n" +
+	"JobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"1\n" +
+	"\vLastRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04last\x18\x02 \x01(\x05R\x04last\"f\n" +
+	"\tJobStatus\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\arunning\x18\x02 \x01(\bR\arunning\x12/\n" +
+	"\atargets\x18\x03 \x03(\v2\x15.goping.TargetSummaryR\atargets\"3\n" +
+	"\n" +
+	"StatsReply\x12%\n" +
+	"\x04jobs\x18\x01 \x03(\v2\x11.goping.JobStatusR\x04jobs\"=\n" +
+	"\fResultsReply\x12-\n" +
+	"\aresults\x18\x01 \x03(\v2\x13.goping.ProbeResultR\aresults\"\x8d\a\n" +
+	"\rTargetSummary\x12\x16\n" +
+	"\x06target\x18\x01 \x01(\tR\x06target\x12\x0e\n" +
+	"\x02ip\x18\x02 \x01(\tR\x02ip\x129\n" +
+	"\x06labels\x18\x03 \x03(\v2!.goping.TargetSummary.LabelsEntryR\x06labels\x12\x12\n" +
+	"\x04sent\x18\x04 \x01(\x03R\x04sent\x12\x12\n" +
+	"\x04lost\x18\x05 \x01(\x03R\x04lost\x12\x12\n" +
+	"\x04loss\x18\x06 \x01(\x01R\x04loss\x122\n" +
+	"\amin_rtt\x18\a \x01(\v2\x19.google.protobuf.DurationR\x06minRtt\x122\n" +
+	"\aavg_rtt\x18\b \x01(\v2\x19.google.protobuf.DurationR\x06avgRtt\x122\n" +
+	"\ap95_rtt\x18\t \x01(\v2\x19.google.protobuf.DurationR\x06p95Rtt\x122\n" +
+	"\amax_rtt\x18\n" +
+	" \x01(\v2\x19.google.protobuf.DurationR\x06maxRtt\x121\n" +
+	"\x06jitter\x18\v \x01(\v2\x19.google.protobuf.DurationR\x06jitter\x12\x14\n" +
+	"\x05state\x18\f \x01(\tR\x05state\x12\x1c\n" +
+	"\tanomalies\x18\r \x01(\x03R\tanomalies\x12\x12\n" +
+	"\x04late\x18\x0e \x01(\x03R\x04late\x12\x1c\n" +
+	"\tmalformed\x18\x0f \x01(\x03R\tmalformed\x12\x1c\n" +
+	"\tcorrupted\x18\x10 \x01(\x03R\tcorrupted\x129\n" +
+	"\x06errors\x18\x11 \x03(\v2!.goping.TargetSummary.ErrorsEntryR\x06errors\x12#\n" +
+	"\rrate_limiting\x18\x12 \x03(\tR\frateLimiting\x12\"\n" +
+	"\favailability\x18\x13 \x01(\x01R\favailability\x125\n" +
+	"\bdowntime\x18\x14 \x01(\v2\x19.google.protobuf.DurationR\bdowntime\x12%\n" +
+	"\x03geo\x18\x15 \x01(\v2\x13.goping.GeoLocationR\x03geo\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a9\n" +
+	"\vErrorsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"f\n" +
+	"\vGeoLocation\x12\x18\n" +
+	"\acountry\x18\x01 \x01(\tR\acountry\x12\x12\n" +
+	"\x04city\x18\x02 \x01(\tR\x04city\x12\x10\n" +
+	"\x03asn\x18\x03 \x01(\rR\x03asn\x12\x17\n" +
+	"\aas_name\x18\x04 \x01(\tR\x06asName\"\xf0\x06\n" +
+	"\vProbeResult\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x10\n" +
+	"\x03job\x18\x02 \x01(\tR\x03job\x12\x16\n" +
+	"\x06target\x18\x03 \x01(\tR\x06target\x12\x0e\n" +
+	"\x02ip\x18\x04 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04name\x18\x05 \x01(\tR\x04name\x12\x10\n" +
+	"\x03seq\x18\x06 \x01(\x03R\x03seq\x12+\n" +
+	"\x03rtt\x18\a \x01(\v2\x19.google.protobuf.DurationR\x03rtt\x12\x12\n" +
+	"\x04loss\x18\b \x01(\x01R\x04loss\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\x127\n" +
+	"\x06labels\x18\n" +
+	" \x03(\v2\x1f.goping.ProbeResult.LabelsEntryR\x06labels\x12\x1e\n" +
+	"\n" +
+	"mismatched\x18\v \x01(\bR\n" +
+	"mismatched\x12\x18\n" +
+	"\aanomaly\x18\f \x01(\bR\aanomaly\x12\x1c\n" +
+	"\ttruncated\x18\r \x01(\bR\ttruncated\x12\x1c\n" +
+	"\tcorrupted\x18\x0e \x01(\bR\tcorrupted\x12\x17\n" +
+	"\alost_at\x18\x0f \x01(\tR\x06lostAt\x12\x10\n" +
+	"\x03ttl\x18\x10 \x01(\x05R\x03ttl\x126\n" +
+	"\n" +
+	"timestamps\x18\x11 \x01(\v2\x16.goping.ICMPTimestampsR\n" +
+	"timestamps\x121\n" +
+	"\n" +
+	"responders\x18\x12 \x03(\v2\x11.goping.ResponderR\n" +
+	"responders\x120\n" +
+	"\n" +
+	"ip_options\x18\x13 \x01(\v2\x11.goping.IPOptionsR\tipOptions\x12\x1d\n" +
+	"\n" +
+	"flow_label\x18\x14 \x01(\rR\tflowLabel\x12\x10\n" +
+	"\x03ecn\x18\x15 \x01(\tR\x03ecn\x12\x1b\n" +
+	"\treply_ecn\x18\x16 \x01(\tR\breplyEcn\x12.\n" +
+	"\arunning\x18\x17 \x01(\v2\x14.goping.RunningStatsR\arunning\x12\x14\n" +
+	"\x05clock\x18\x18 \x01(\tR\x05clock\x124\n" +
+	"\bgc_pause\x18\x19 \x01(\v2\x19.google.protobuf.DurationR\agcPause\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xaa\x01\n" +
+	"\fRunningStats\x122\n" +
+	"\amin_rtt\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\x06minRtt\x122\n" +
+	"\aavg_rtt\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\x06avgRtt\x122\n" +
+	"\amax_rtt\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x06maxRtt\"H\n" +
+	"\tResponder\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12+\n" +
+	"\x03rtt\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\x03rtt\"\xb8\x02\n" +
+	"\x0eICMPTimestamps\x12\x1c\n" +
+	"\toriginate\x18\x01 \x01(\rR\toriginate\x12\x18\n" +
+	"\areceive\x18\x02 \x01(\rR\areceive\x12\x1a\n" +
+	"\btransmit\x18\x03 \x01(\rR\btransmit\x121\n" +
+	"\x06offset\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\x06offset\x123\n" +
+	"\aforward\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\aforward\x121\n" +
+	"\x06return\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\x06return\x127\n" +
+	"\tasymmetry\x18\a \x01(\v2\x19.google.protobuf.DurationR\tasymmetry\"f\n" +
+	"\tIPOptions\x12\x14\n" +
+	"\x05route\x18\x01 \x03(\tR\x05route\x12\x16\n" +
+	"\x06source\x18\x02 \x03(\tR\x06source\x12+\n" +
+	"\x06stamps\x18\x03 \x03(\v2\x13.goping.OptionStampR\x06stamps\"7\n" +
+	"\vOptionStamp\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x0e\n" +
+	"\x02ms\x18\x02 \x01(\rR\x02ms2\x83\x02\n" +
+	"\x06Pinger\x120\n" +
+	"\x05Start\x12\x14.goping.StartRequest\x1a\x11.goping.JobStatus\x12-\n" +
+	"\x04Stop\x12\x12.goping.JobRequest\x1a\x11.goping.JobStatus\x12/\n" +
+	"\x05Stats\x12\x12.goping.JobRequest\x1a\x12.goping.StatsReply\x121\n" +
+	"\x04Last\x12\x13.goping.LastRequest\x1a\x14.goping.ResultsReply\x124\n" +
+	"\aResults\x12\x12.goping.JobRequest\x1a\x13.goping.ProbeResult0\x01B&Z$github.com/alvihabib/goPing/gopingpbb\x06proto3"
+
+var (
+	file_goping_proto_rawDescOnce sync.Once
+	file_goping_proto_rawDescData []byte
+)
+
+func file_goping_proto_rawDescGZIP() []byte {
+	file_goping_proto_rawDescOnce.Do(func() {
+		file_goping_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_goping_proto_rawDesc), len(file_goping_proto_rawDesc)))
+	})
+	return file_goping_proto_rawDescData
+}
+
+var file_goping_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_goping_proto_goTypes = []any{
+	(*StartRequest)(nil),          // 0: goping.StartRequest
+	(*JobRequest)(nil),            // 1: goping.JobRequest
+	(*LastRequest)(nil),           // 2: goping.LastRequest
+	(*JobStatus)(nil),             // 3: goping.JobStatus
+	(*StatsReply)(nil),            // 4: goping.StatsReply
+	(*ResultsReply)(nil),          // 5: goping.ResultsReply
+	(*TargetSummary)(nil),         // 6: goping.TargetSummary
+	(*GeoLocation)(nil),           // 7: goping.GeoLocation
+	(*ProbeResult)(nil),           // 8: goping.ProbeResult
+	(*RunningStats)(nil),          // 9: goping.RunningStats
+	(*Responder)(nil),             // 10: goping.Responder
+	(*ICMPTimestamps)(nil),        // 11: goping.ICMPTimestamps
+	(*IPOptions)(nil),             // 12: goping.IPOptions
+	(*OptionStamp)(nil),           // 13: goping.OptionStamp
+	nil,                           // 14: goping.StartRequest.LabelsEntry
+	nil,                           // 15: goping.TargetSummary.LabelsEntry
+	nil,                           // 16: goping.TargetSummary.ErrorsEntry
+	nil,                           // 17: goping.ProbeResult.LabelsEntry
+	(*durationpb.Duration)(nil),   // 18: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil), // 19: google.protobuf.Timestamp
+}
+var file_goping_proto_depIdxs = []int32{
+	14, // 0: goping.StartRequest.labels:type_name -> goping.StartRequest.LabelsEntry
+	6,  // 1: goping.JobStatus.targets:type_name -> goping.TargetSummary
+	3,  // 2: goping.StatsReply.jobs:type_name -> goping.JobStatus
+	8,  // 3: goping.ResultsReply.results:type_name -> goping.ProbeResult
+	15, // 4: goping.TargetSummary.labels:type_name -> goping.TargetSummary.LabelsEntry
+	18, // 5: goping.TargetSummary.min_rtt:type_name -> google.protobuf.Duration
+	18, // 6: goping.TargetSummary.avg_rtt:type_name -> google.protobuf.Duration
+	18, // 7: goping.TargetSummary.p95_rtt:type_name -> google.protobuf.Duration
+	18, // 8: goping.TargetSummary.max_rtt:type_name -> google.protobuf.Duration
+	18, // 9: goping.TargetSummary.jitter:type_name -> google.protobuf.Duration
+	16, // 10: goping.TargetSummary.errors:type_name -> goping.TargetSummary.ErrorsEntry
+	18, // 11: goping.TargetSummary.downtime:type_name -> google.protobuf.Duration
+	7,  // 12: goping.TargetSummary.geo:type_name -> goping.GeoLocation
+	19, // 13: goping.ProbeResult.time:type_name -> google.protobuf.Timestamp
+	18, // 14: goping.ProbeResult.rtt:type_name -> google.protobuf.Duration
+	17, // 15: goping.ProbeResult.labels:type_name -> goping.ProbeResult.LabelsEntry
+	11, // 16: goping.ProbeResult.timestamps:type_name -> goping.ICMPTimestamps
+	10, // 17: goping.ProbeResult.responders:type_name -> goping.Responder
+	12, // 18: goping.ProbeResult.ip_options:type_name -> goping.IPOptions
+	9,  // 19: goping.ProbeResult.running:type_name -> goping.RunningStats
+	18, // 20: goping.ProbeResult.gc_pause:type_name -> google.protobuf.Duration
+	18, // 21: goping.RunningStats.min_rtt:type_name -> google.protobuf.Duration
+	18, // 22: goping.RunningStats.avg_rtt:type_name -> google.protobuf.Duration
+	18, // 23: goping.RunningStats.max_rtt:type_name -> google.protobuf.Duration
+	18, // 24: goping.Responder.rtt:type_name -> google.protobuf.Duration
+	18, // 25: goping.ICMPTimestamps.offset:type_name -> google.protobuf.Duration
+	18, // 26: goping.ICMPTimestamps.forward:type_name -> google.protobuf.Duration
+	18, // 27: goping.ICMPTimestamps.return:type_name -> google.protobuf.Duration
+	18, // 28: goping.ICMPTimestamps.asymmetry:type_name -> google.protobuf.Duration
+	13, // 29: goping.IPOptions.stamps:type_name -> goping.OptionStamp
+	0,  // 30: goping.Pinger.Start:input_type -> goping.StartRequest
+	1,  // 31: goping.Pinger.Stop:input_type -> goping.JobRequest
+	1,  // 32: goping.Pinger.Stats:input_type -> goping.JobRequest
+	2,  // 33: goping.Pinger.Last:input_type -> goping.LastRequest
+	1,  // 34: goping.Pinger.Results:input_type -> goping.JobRequest
+	3,  // 35: goping.Pinger.Start:output_type -> goping.JobStatus
+	3,  // 36: goping.Pinger.Stop:output_type -> goping.JobStatus
+	4,  // 37: goping.Pinger.Stats:output_type -> goping.StatsReply
+	5,  // 38: goping.Pinger.Last:output_type -> goping.ResultsReply
+	8,  // 39: goping.Pinger.Results:output_type -> goping.ProbeResult
+	35, // [35:40] is the sub-list for method output_type
+	30, // [30:35] is the sub-list for method input_type
+	30, // [30:30] is the sub-list for extension type_name
+	30, // [30:30] is the sub-list for extension extendee
+	0,  // [0:30] is the sub-list for field type_name
+}
+
+func init() { file_goping_proto_init() }
+func file_goping_proto_init() {
+	if File_goping_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_goping_proto_rawDesc), len(file_goping_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_goping_proto_goTypes,
+		DependencyIndexes: file_goping_proto_depIdxs,
+		MessageInfos:      file_goping_proto_msgTypes,
+	}.Build()
+	File_goping_proto = out.File
+	file_goping_proto_goTypes = nil
+	file_goping_proto_depIdxs = nil
+}
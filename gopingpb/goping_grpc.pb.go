@@ -0,0 +1,277 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: goping.proto
+
+package gopingpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Pinger_Start_FullMethodName   = "/goping.Pinger/Start"
+	Pinger_Stop_FullMethodName    = "/goping.Pinger/Stop"
+	Pinger_Stats_FullMethodName   = "/goping.Pinger/Stats"
+	Pinger_Last_FullMethodName    = "/goping.Pinger/Last"
+	Pinger_Results_FullMethodName = "/goping.Pinger/Results"
+)
+
+// PingerClient is the client API for Pinger service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PingerClient interface {
+	Start(ctx context.Context, in *StartRequest, opts ...grpc.CallOption) (*JobStatus, error)
+	Stop(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*JobStatus, error)
+	Stats(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*StatsReply, error)
+	Last(ctx context.Context, in *LastRequest, opts ...grpc.CallOption) (*ResultsReply, error)
+	Results(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProbeResult], error)
+}
+
+type pingerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPingerClient(cc grpc.ClientConnInterface) PingerClient {
+	return &pingerClient{cc}
+}
+
+func (c *pingerClient) Start(ctx context.Context, in *StartRequest, opts ...grpc.CallOption) (*JobStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatus)
+	err := c.cc.Invoke(ctx, Pinger_Start_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pingerClient) Stop(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*JobStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatus)
+	err := c.cc.Invoke(ctx, Pinger_Stop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pingerClient) Stats(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (*StatsReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatsReply)
+	err := c.cc.Invoke(ctx, Pinger_Stats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pingerClient) Last(ctx context.Context, in *LastRequest, opts ...grpc.CallOption) (*ResultsReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResultsReply)
+	err := c.cc.Invoke(ctx, Pinger_Last_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pingerClient) Results(ctx context.Context, in *JobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProbeResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Pinger_ServiceDesc.Streams[0], Pinger_Results_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[JobRequest, ProbeResult]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Pinger_ResultsClient = grpc.ServerStreamingClient[ProbeResult]
+
+// PingerServer is the server API for Pinger service.
+// All implementations must embed UnimplementedPingerServer
+// for forward compatibility.
+type PingerServer interface {
+	Start(context.Context, *StartRequest) (*JobStatus, error)
+	Stop(context.Context, *JobRequest) (*JobStatus, error)
+	Stats(context.Context, *JobRequest) (*StatsReply, error)
+	Last(context.Context, *LastRequest) (*ResultsReply, error)
+	Results(*JobRequest, grpc.ServerStreamingServer[ProbeResult]) error
+	mustEmbedUnimplementedPingerServer()
+}
+
+// UnimplementedPingerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPingerServer struct{}
+
+func (UnimplementedPingerServer) Start(context.Context, *StartRequest) (*JobStatus, error) {
+	return nil, status.Error(codes.Unimplemented, "method Start not implemented")
+}
+func (UnimplementedPingerServer) Stop(context.Context, *JobRequest) (*JobStatus, error) {
+	return nil, status.Error(codes.Unimplemented, "method Stop not implemented")
+}
+func (UnimplementedPingerServer) Stats(context.Context, *JobRequest) (*StatsReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedPingerServer) Last(context.Context, *LastRequest) (*ResultsReply, error) {
+	return nil, status.Error(codes.Unimplemented, "method Last not implemented")
+}
+func (UnimplementedPingerServer) Results(*JobRequest, grpc.ServerStreamingServer[ProbeResult]) error {
+	return status.Error(codes.Unimplemented, "method Results not implemented")
+}
+func (UnimplementedPingerServer) mustEmbedUnimplementedPingerServer() {}
+func (UnimplementedPingerServer) testEmbeddedByValue()                {}
+
+// UnsafePingerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PingerServer will
+// result in compilation errors.
+type UnsafePingerServer interface {
+	mustEmbedUnimplementedPingerServer()
+}
+
+func RegisterPingerServer(s grpc.ServiceRegistrar, srv PingerServer) {
+	// If the following call panics, it indicates UnimplementedPingerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Pinger_ServiceDesc, srv)
+}
+
+func _Pinger_Start_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PingerServer).Start(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pinger_Start_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PingerServer).Start(ctx, req.(*StartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pinger_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PingerServer).Stop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pinger_Stop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PingerServer).Stop(ctx, req.(*JobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pinger_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PingerServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pinger_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PingerServer).Stats(ctx, req.(*JobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pinger_Last_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LastRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PingerServer).Last(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pinger_Last_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PingerServer).Last(ctx, req.(*LastRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pinger_Results_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(JobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PingerServer).Results(m, &grpc.GenericServerStream[JobRequest, ProbeResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Pinger_ResultsServer = grpc.ServerStreamingServer[ProbeResult]
+
+// Pinger_ServiceDesc is the grpc.ServiceDesc for Pinger service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Pinger_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "goping.Pinger",
+	HandlerType: (*PingerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Start",
+			Handler:    _Pinger_Start_Handler,
+		},
+		{
+			MethodName: "Stop",
+			Handler:    _Pinger_Stop_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _Pinger_Stats_Handler,
+		},
+		{
+			MethodName: "Last",
+			Handler:    _Pinger_Last_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Results",
+			Handler:       _Pinger_Results_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "goping.proto",
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"net"
+
+	"github.com/alvihabib/goPing/gopingpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defaultGRPCAddress string = "127.0.0.1:7070" // Address the gRPC API is served on, loopback unless -grpc exposes it
+
+// Codec encoding the messages of goping.proto in its JSON mapping, for clients with no generated code
+// asking for the content type application/grpc+json. Clients default to binary protobuf.
+type jsonCodec struct{}
+
+// Let clients ask for JSON, registered before the server starts as gRPC requires
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// Encode a message
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return protojson.MarshalOptions{UseProtoNames: true}.Marshal(v.(proto.Message))
+}
+
+// Decode a message
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return protojson.Unmarshal(data, v.(proto.Message))
+}
+
+// Name the codec as the content subtype of application/grpc+json
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+// gRPC service driving the engine (goping serve -grpc), as described by gopingpb/goping.proto
+type pingerService struct {
+	gopingpb.UnimplementedPingerServer
+	server *engine // Engine running the jobs
+}
+
+// Start a job
+func (service pingerService) Start(_ context.Context, request *gopingpb.StartRequest) (*gopingpb.JobStatus, error) {
+	count := int(request.Count)
+	if count == 0 {
+		count = -1
+	}
+	started, err := service.server.start(request.Targets, count, request.Labels)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return started.status().message(), nil
+}
+
+// Stop a job
+func (service pingerService) Stop(_ context.Context, request *gopingpb.JobRequest) (*gopingpb.JobStatus, error) {
+	stopped, err := service.server.stop(request.Id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return stopped.status().message(), nil
+}
+
+// Fetch the statistics of a job, or of all jobs
+func (service pingerService) Stats(_ context.Context, request *gopingpb.JobRequest) (*gopingpb.StatsReply, error) {
+	statuses := service.server.statuses()
+	if request.Id != "" {
+		found, err := service.server.job(request.Id)
+		if err != nil {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		statuses = []jobStatus{found.status()}
+	}
+	reply := &gopingpb.StatsReply{}
+	for _, jobStatus := range statuses {
+		reply.Jobs = append(reply.Jobs, jobStatus.message())
+	}
+	return reply, nil
+}
+
+// Fetch the last results of a job, or of all jobs
+func (service pingerService) Last(_ context.Context, request *gopingpb.LastRequest) (*gopingpb.ResultsReply, error) {
+	count := int(request.Last)
+	if count == 0 {
+		count = defaultShowLast
+	}
+	if count < 0 {
+		return nil, status.Error(codes.InvalidArgument, "Number of results must be a positive integer")
+	}
+	results, err := service.server.last(count, request.Id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	reply := &gopingpb.ResultsReply{}
+	for _, result := range results {
+		reply.Results = append(reply.Results, result.message())
+	}
+	return reply, nil
+}
+
+// Stream the results of a job, or of all jobs, until the client cancels
+func (service pingerService) Results(request *gopingpb.JobRequest, stream grpc.ServerStreamingServer[gopingpb.ProbeResult]) error {
+	results, unsubscribe, err := service.server.subscribe(request.Id)
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	defer unsubscribe()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case result := <-results:
+			if err := stream.Send(result.message()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Reject a call unless its authorization metadata presents the -api-token, if one is required
+func authorizeCall(ctx context.Context) error {
+	if apiToken == "" {
+		return nil
+	}
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) == 1 && authorized(values[0]) {
+		return nil
+	}
+	return status.Error(codes.Unauthenticated, "Missing or invalid API token, expected authorization: Bearer <token>")
+}
+
+// Protobuf message of the job status
+func (listed jobStatus) message() *gopingpb.JobStatus {
+	message := &gopingpb.JobStatus{Id: listed.ID, Running: listed.Running}
+	for _, summary := range listed.Targets {
+		message.Targets = append(message.Targets, summary.message())
+	}
+	return message
+}
+
+// Protobuf message of the target's statistics
+func (summary targetSummary) message() *gopingpb.TargetSummary {
+	message := &gopingpb.TargetSummary{
+		Target:       summary.Target,
+		Ip:           summary.IP,
+		Labels:       summary.Labels,
+		Sent:         int64(summary.Sent),
+		Lost:         int64(summary.Lost),
+		Loss:         summary.Loss,
+		MinRtt:       durationpb.New(summary.MinRTT),
+		AvgRtt:       durationpb.New(summary.AvgRTT),
+		P95Rtt:       durationpb.New(summary.P95RTT),
+		MaxRtt:       durationpb.New(summary.MaxRTT),
+		Jitter:       durationpb.New(summary.Jitter),
+		State:        summary.State,
+		Anomalies:    int64(summary.Anomalies),
+		Late:         int64(summary.Late),
+		Malformed:    int64(summary.Malformed),
+		Corrupted:    int64(summary.Corrupted),
+		RateLimiting: summary.RateLimiting,
+		Availability: summary.Availability,
+		Downtime:     durationpb.New(summary.Downtime),
+	}
+	if len(summary.Errors) > 0 {
+		message.Errors = make(map[string]int64, len(summary.Errors))
+		for cause, count := range summary.Errors {
+			message.Errors[cause] = int64(count)
+		}
+	}
+	if geo := summary.Geo; geo != nil {
+		message.Geo = &gopingpb.GeoLocation{Country: geo.Country, City: geo.City, Asn: uint32(geo.ASN), AsName: geo.ASName}
+	}
+	return message
+}
+
+// Protobuf message of the probe result
+func (result probeResult) message() *gopingpb.ProbeResult {
+	message := &gopingpb.ProbeResult{
+		Time:       timestamppb.New(result.Time),
+		Job:        result.Job,
+		Target:     result.Target,
+		Ip:         result.IP,
+		Name:       result.Name,
+		Seq:        int64(result.Seq),
+		Rtt:        durationpb.New(result.RTT),
+		Loss:       result.Loss,
+		Error:      result.Error,
+		Labels:     result.Labels,
+		Mismatched: result.Mismatched,
+		Anomaly:    result.Anomaly,
+		Truncated:  result.Truncated,
+		Corrupted:  result.Corrupted,
+		LostAt:     result.LostAt,
+		Ttl:        int32(result.TTL),
+		FlowLabel:  result.FlowLabel,
+		Ecn:        result.ECN,
+		ReplyEcn:   result.ReplyECN,
+		Clock:      result.Clock,
+		GcPause:    durationpb.New(result.GCPause),
+	}
+	if timestamps := result.Timestamps; timestamps != nil {
+		message.Timestamps = &gopingpb.ICMPTimestamps{
+			Originate: timestamps.Originate,
+			Receive:   timestamps.Receive,
+			Transmit:  timestamps.Transmit,
+			Offset:    durationpb.New(timestamps.Offset),
+			Forward:   durationpb.New(timestamps.Forward),
+			Return:    durationpb.New(timestamps.Return),
+			Asymmetry: durationpb.New(timestamps.Asymmetry),
+		}
+	}
+	for _, host := range result.Responders {
+		message.Responders = append(message.Responders, &gopingpb.Responder{Ip: host.IP, Rtt: durationpb.New(host.RTT)})
+	}
+	if options := result.IPOptions; options != nil {
+		message.IpOptions = &gopingpb.IPOptions{Route: options.Route, Source: options.Source}
+		for _, stamp := range options.Stamps {
+			message.IpOptions.Stamps = append(message.IpOptions.Stamps, &gopingpb.OptionStamp{Address: stamp.Address, Ms: stamp.Milliseconds})
+		}
+	}
+	if running := result.Running; running != nil {
+		message.Running = &gopingpb.RunningStats{MinRtt: durationpb.New(running.MinRTT), AvgRtt: durationpb.New(running.AvgRTT), MaxRtt: durationpb.New(running.MaxRTT)}
+	}
+	return message
+}
+
+// Serve the gRPC API of the engine on the address until it fails, with reflection so clients such
+// as grpcurl discover the service without goping.proto
+func serveGRPC(address string, server *engine) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, request any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := authorizeCall(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, request)
+		}),
+		grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := authorizeCall(stream.Context()); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}))
+	gopingpb.RegisterPingerServer(grpcServer, pingerService{server: server})
+	reflection.Register(grpcServer)
+	return grpcServer.Serve(listener)
+}
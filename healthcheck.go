@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alvihabib/goPing/pinger"
+)
+
+const (
+	defaultHealthcheckCount   int           = 3                      // Probes of goping healthcheck unless -c is given
+	defaultHealthcheckMaxLoss float64       = 50                     // Percent loss tolerated unless -healthcheck-max-loss is given
+	defaultHealthcheckMaxRTT  time.Duration = time.Second            // Mean RTT tolerated unless -healthcheck-max-rtt is given
+	healthcheckInterval       time.Duration = 200 * time.Millisecond // Interval between probes, short so the check finishes quickly
+	healthcheckTimeout        time.Duration = 2 * time.Second        // Deadline of each reply
+)
+
+// Probe the target a few times and judge it healthy if loss and mean RTT are within the thresholds
+// (goping healthcheck), printing a one-line verdict. Probes use an unprivileged ICMP socket so
+// non-root containers can run the check, falling back to a raw socket where that is denied.
+func runHealthcheck(target string, count int, maxLoss float64, maxRTT time.Duration) (bool, error) {
+	options := []pinger.Option{
+		pinger.WithCount(count),
+		pinger.WithInterval(healthcheckInterval),
+		pinger.WithTimeout(healthcheckTimeout),
+		pinger.WithIPv6(wantIPv6),
+		pinger.WithTTL(ttl),
+	}
+	if resolver != nil {
+		options = append(options, pinger.WithResolver(resolver))
+	}
+	probing, err := pinger.New(target, append(options, pinger.WithPrivileged(false))...)
+	if err != nil {
+		return false, err
+	}
+	err = probing.Run(context.Background())
+	if errors.Is(err, os.ErrPermission) {
+		if probing, err = pinger.New(target, append(options, pinger.WithPrivileged(true))...); err != nil {
+			return false, err
+		}
+		err = probing.Run(context.Background())
+	}
+	if err != nil {
+		return false, err
+	}
+
+	statistics := probing.Statistics()
+	var failures []string
+	if statistics.Received == 0 {
+		failures = append(failures, "no replies")
+	} else {
+		if statistics.Loss > maxLoss {
+			failures = append(failures, fmt.Sprintf("loss above %.2f%%", maxLoss))
+		}
+		if statistics.AvgRTT > maxRTT {
+			failures = append(failures, fmt.Sprintf("RTT above %s", maxRTT))
+		}
+	}
+	verdict := "Healthy"
+	if len(failures) > 0 {
+		verdict = "Unhealthy (" + strings.Join(failures, ", ") + ")"
+	}
+	fmt.Printf("%s: %s (%s) replied to %d/%d probes, Loss: %.2f%%, Avg RTT: %s\n",
+		verdict, target, statistics.IP, statistics.Received, statistics.Sent, statistics.Loss, display(statistics.AvgRTT))
+	return len(failures) == 0, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const heartbeatInterval time.Duration = time.Minute // Interval between pings of the -heartbeat-url endpoint
+
+var heartbeatURL *url.URL // Dead man's switch pinged while every target is up (-heartbeat-url) flag, nil for none
+
+// Ping the -heartbeat-url endpoint, e.g. of healthchecks.io or Cronitor, every heartbeatInterval while
+// every target of the running mode is up, so goPing itself going away raises an alert too
+func sendHeartbeats() {
+	client := http.Client{Timeout: notifyTimeout}
+	for range time.Tick(heartbeatInterval) {
+		if currentStats == nil || !allUp(currentStats()) {
+			slog.Debug("Skipping heartbeat, not every target is up")
+			continue
+		}
+		response, err := client.Get(heartbeatURL.String())
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Could not send heartbeat to %s (%s)", heartbeatURL.Host, err))
+			continue
+		}
+		response.Body.Close()
+		if response.StatusCode/100 != 2 {
+			slog.Warn(fmt.Sprintf("Heartbeat to %s failed: %s", heartbeatURL.Host, response.Status))
+		}
+	}
+}
+
+// Whether there are targets and every one of them is up
+func allUp(targets []*statistic) bool {
+	for _, stats := range targets {
+		stats.mutex.Lock()
+		state := stats.state
+		stats.mutex.Unlock()
+		if state != stateUp {
+			return false
+		}
+	}
+	return len(targets) > 0
+}
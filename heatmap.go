@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	heatmapColumns int = 240  // Time bins across the heatmap, fewer for short runs
+	heatmapRows    int = 60   // Logarithmic RTT buckets up the heatmap
+	heatmapWidth   int = 1200 // Width of the plot area in pixels
+	heatmapHeight  int = 480  // Height of the plot area in pixels
+	heatmapMargin  int = 60   // Room for axis labels around the plot area of SVG heatmaps
+)
+
+// Colour stops of the density scale, from empty to densest (viridis)
+var heatmapPalette = []color.RGBA{
+	{0x44, 0x01, 0x54, 0xff},
+	{0x3b, 0x52, 0x8b, 0xff},
+	{0x21, 0x90, 0x8d, 0xff},
+	{0x5d, 0xc9, 0x63, 0xff},
+	{0xfd, 0xe7, 0x25, 0xff},
+}
+
+// RTT-over-time heatmap of every reply rendered to a PNG or SVG file at termination (-heatmap)
+type heatmap struct {
+	path    string         // File the heatmap is written to, its extension choosing the format
+	mutex   sync.Mutex     // Guards samples
+	samples []windowSample // Outcome of every probe
+}
+
+// Create a heatmap written to the path, which must end in .png or .svg
+func newHeatmap(path string) (*heatmap, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".svg":
+		return &heatmap{path: path}, nil
+	default:
+		return nil, fmt.Errorf("Heatmap file %q must end in .png or .svg", path)
+	}
+}
+
+// Collect a probe result
+func (latency *heatmap) add(result probeResult) {
+	latency.mutex.Lock()
+	defer latency.mutex.Unlock()
+	latency.samples = append(latency.samples, windowSample{sent: result.Time, rtt: result.RTT, replied: result.Error == ""})
+}
+
+// Counts of replies per time bin and RTT bucket, with the bounds of the axes
+type heatmapGrid struct {
+	counts     [][]int       // Replies by column, then row from the lowest RTT bucket
+	start, end time.Time     // Time range of the columns
+	low, high  time.Duration // RTT range of the rows, spaced logarithmically
+}
+
+// Bin the replies by time and logarithmic RTT bucket
+func (latency *heatmap) grid() (heatmapGrid, error) {
+	var grid heatmapGrid
+	var replies []windowSample
+	for _, sample := range latency.samples {
+		if sample.replied && sample.rtt > 0 {
+			replies = append(replies, sample)
+		}
+	}
+	if len(replies) == 0 {
+		return grid, errors.New("No replies to draw a heatmap of")
+	}
+	grid.start, grid.end = replies[0].sent, replies[0].sent
+	grid.low, grid.high = replies[0].rtt, replies[0].rtt
+	for _, sample := range replies {
+		if sample.sent.Before(grid.start) {
+			grid.start = sample.sent
+		}
+		if sample.sent.After(grid.end) {
+			grid.end = sample.sent
+		}
+		grid.low, grid.high = min(grid.low, sample.rtt), max(grid.high, sample.rtt)
+	}
+	// Keep the RTT range at least one decade wide around a steady RTT
+	if grid.high < grid.low*10 {
+		middle := math.Sqrt(float64(grid.low) * float64(grid.high))
+		grid.low, grid.high = time.Duration(middle/math.Sqrt(10)), time.Duration(middle*math.Sqrt(10))
+	}
+
+	columns := min(heatmapColumns, len(replies))
+	grid.counts = make([][]int, columns)
+	for column := range grid.counts {
+		grid.counts[column] = make([]int, heatmapRows)
+	}
+	span := grid.end.Sub(grid.start)
+	decades := math.Log10(float64(grid.high) / float64(grid.low))
+	for _, sample := range replies {
+		column := 0
+		if span > 0 {
+			column = min(int(float64(sample.sent.Sub(grid.start))/float64(span)*float64(columns)), columns-1)
+		}
+		row := int(math.Log10(float64(sample.rtt)/float64(grid.low)) / decades * float64(heatmapRows))
+		grid.counts[column][max(0, min(row, heatmapRows-1))]++
+	}
+	return grid, nil
+}
+
+// Colour of a cell holding the count out of the most replies in any cell of its column, so each
+// time bin shows where its RTTs concentrate, nil for an empty cell
+func heatmapColor(count, columnMax int) *color.RGBA {
+	if count == 0 {
+		return nil
+	}
+	position := float64(count) / float64(columnMax) * float64(len(heatmapPalette)-1)
+	lower := min(int(position), len(heatmapPalette)-2)
+	fraction := position - float64(lower)
+	from, to := heatmapPalette[lower], heatmapPalette[lower+1]
+	blend := func(a, b uint8) uint8 { return uint8(float64(a) + (float64(b)-float64(a))*fraction) }
+	return &color.RGBA{blend(from.R, to.R), blend(from.G, to.G), blend(from.B, to.B), 0xff}
+}
+
+// Render the heatmap of every reply so far to its file
+func (latency *heatmap) write() error {
+	latency.mutex.Lock()
+	grid, err := latency.grid()
+	latency.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(latency.path)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(latency.path), ".svg") {
+		err = grid.writeSVG(file)
+	} else {
+		err = png.Encode(file, grid.image())
+	}
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Draw the grid as an image, latest time on the right and highest RTT at the top
+func (grid heatmapGrid) image() image.Image {
+	picture := image.NewRGBA(image.Rect(0, 0, heatmapWidth, heatmapHeight))
+	for x := 0; x < heatmapWidth; x++ {
+		for y := 0; y < heatmapHeight; y++ {
+			picture.SetRGBA(x, y, color.RGBA{0xff, 0xff, 0xff, 0xff})
+		}
+	}
+	for column, counts := range grid.counts {
+		columnMax := 0
+		for _, count := range counts {
+			columnMax = max(columnMax, count)
+		}
+		x0, x1 := column*heatmapWidth/len(grid.counts), (column+1)*heatmapWidth/len(grid.counts)
+		for row, count := range counts {
+			cell := heatmapColor(count, columnMax)
+			if cell == nil {
+				continue
+			}
+			y0, y1 := heatmapHeight-(row+1)*heatmapHeight/heatmapRows, heatmapHeight-row*heatmapHeight/heatmapRows
+			for x := x0; x < x1; x++ {
+				for y := y0; y < y1; y++ {
+					picture.SetRGBA(x, y, *cell)
+				}
+			}
+		}
+	}
+	return picture
+}
+
+// Write the grid as an SVG with time and RTT axis labels
+func (grid heatmapGrid) writeSVG(file *os.File) error {
+	writer := bufio.NewWriter(file)
+	width, height := heatmapWidth+2*heatmapMargin, heatmapHeight+2*heatmapMargin
+	fmt.Fprintf(writer, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="12">`+"\n", width, height)
+	fmt.Fprintf(writer, `<rect width="%d" height="%d" fill="#fff"/>`+"\n", width, height)
+	cellWidth := float64(heatmapWidth) / float64(len(grid.counts))
+	cellHeight := float64(heatmapHeight) / float64(heatmapRows)
+	for column, counts := range grid.counts {
+		columnMax := 0
+		for _, count := range counts {
+			columnMax = max(columnMax, count)
+		}
+		for row, count := range counts {
+			cell := heatmapColor(count, columnMax)
+			if cell == nil {
+				continue
+			}
+			fmt.Fprintf(
+				writer,
+				`<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="#%02x%02x%02x"><title>%d</title></rect>`+"\n",
+				float64(heatmapMargin)+float64(column)*cellWidth,
+				float64(heatmapMargin+heatmapHeight)-float64(row+1)*cellHeight,
+				cellWidth+0.05,
+				cellHeight+0.05,
+				cell.R, cell.G, cell.B,
+				count)
+		}
+	}
+	// Label the RTT axis at each decade's bounds and the time axis at its ends and middle
+	decades := math.Log10(float64(grid.high) / float64(grid.low))
+	for tick := 0; tick <= 4; tick++ {
+		rtt := time.Duration(float64(grid.low) * math.Pow(10, decades*float64(tick)/4))
+		y := float64(heatmapMargin+heatmapHeight) - float64(tick)/4*float64(heatmapHeight)
+		fmt.Fprintf(writer, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`+"\n", heatmapMargin-4, y+4, display(rtt))
+	}
+	for tick := 0; tick <= 2; tick++ {
+		sent := grid.start.Add(time.Duration(float64(grid.end.Sub(grid.start)) * float64(tick) / 2))
+		x := float64(heatmapMargin) + float64(tick)/2*float64(heatmapWidth)
+		fmt.Fprintf(writer, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`+"\n", x, heatmapMargin+heatmapHeight+18, sent.Format(time.DateTime))
+	}
+	fmt.Fprintf(writer, `<text x="%d" y="%d" text-anchor="middle">RTT over time, colour by density per time slice</text>`+"\n", width/2, heatmapMargin/2)
+	fmt.Fprintln(writer, "</svg>")
+	return writer.Flush()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHistorySize int           = 1000            // Default number of recent probe results kept (-history)
+	defaultShowLast    int           = 20              // Results shown by show last and goping history without a count
+	historyTimeout     time.Duration = 5 * time.Second // Timeout fetching the history of a goping serve
+)
+
+var recentResults *resultHistory // Last probe results of all targets (-history), nil if none are kept
+
+// Ring buffer of the last probe results, so an operator can scroll back to a spike with show last
+// or GET /results without logging every result to disk
+type resultHistory struct {
+	mutex   sync.Mutex    // Guards the fields below
+	results []probeResult // Results kept, overwritten oldest first once full
+	next    int           // Index the next result is written to
+	full    bool          // Whether results has wrapped around
+}
+
+// Create a history keeping the last size results
+func newResultHistory(size int) *resultHistory {
+	return &resultHistory{results: make([]probeResult, size)}
+}
+
+// Keep a result, dropping the oldest if the history is full
+func (history *resultHistory) add(result probeResult) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	history.results[history.next] = result
+	history.next++
+	if history.next == len(history.results) {
+		history.next, history.full = 0, true
+	}
+}
+
+// Up to the last n results of the job, or of all jobs if the ID is empty, oldest first
+func (history *resultHistory) last(n int, job string) []probeResult {
+	return history.lastMatching(n, func(result probeResult) bool { return job == "" || result.Job == job })
+}
+
+// Up to the last n results matching, oldest first
+func (history *resultHistory) lastMatching(n int, match func(probeResult) bool) []probeResult {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	kept := history.results[:history.next]
+	if history.full {
+		kept = append(append([]probeResult(nil), history.results[history.next:]...), kept...)
+	}
+	var found []probeResult
+	for i := len(kept) - 1; i >= 0 && len(found) < n; i-- {
+		if match(kept[i]) {
+			found = append(found, kept[i])
+		}
+	}
+	// Collected newest first, so reverse them
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+	return found
+}
+
+// Read commands typed while pinging, printing the last results in the output format for show last N
+func readConsole() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 || fields[0] != "show" || fields[1] != "last" {
+			slog.Warn(fmt.Sprintf("Unknown command %q. Try show last N...", scanner.Text()))
+			continue
+		}
+		count := defaultShowLast
+		if len(fields) == 3 {
+			parsed, err := strconv.Atoi(fields[2])
+			if err != nil || parsed < 1 {
+				slog.Warn("Number of results to show must be a positive integer. Try show last N...")
+				continue
+			}
+			count = parsed
+		}
+		results := recentResults.last(count, "")
+		fmt.Printf("-------------------------| Last %d results |--------------------------\n", len(results))
+		output.writeAll(results)
+		fmt.Println("----------------------------------------------------------------------")
+		// Draw the table afresh below the results rather than over them
+		tableRedraw.Store(true)
+	}
+}
+
+// Print the last n results of a -record file, or of the history (-history) kept by a goping serve
+// at an http:// or https:// URL, in the output format (goping history)
+func printHistory(source string, n int, writer *resultWriter) error {
+	var results []probeResult
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		fetched, err := fetchHistory(source, n)
+		if err != nil {
+			return err
+		}
+		results = fetched
+	} else {
+		header, recorded, err := readRecording(source)
+		if err != nil {
+			return err
+		}
+		wantIPv6 = header.IPv6
+		results = recorded[max(len(recorded)-n, 0):]
+	}
+	writer.writeAll(results)
+	return nil
+}
+
+// Fetch the last n results kept by a goping serve from its HTTP API (GET /results)
+func fetchHistory(address string, n int) ([]probeResult, error) {
+	endpoint, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	endpoint = endpoint.JoinPath("results")
+	endpoint.RawQuery = url.Values{"last": {strconv.Itoa(n)}}.Encode()
+	request, err := http.NewRequest(http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if apiToken != "" {
+		request.Header.Set("Authorization", "Bearer "+apiToken)
+	}
+	client := http.Client{Timeout: historyTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(response.Body).Decode(&failure) == nil && failure.Error != "" {
+			return nil, fmt.Errorf("Could not fetch history from %s: %s", address, failure.Error)
+		}
+		return nil, fmt.Errorf("Could not fetch history from %s: %s", address, response.Status)
+	}
+	var reply resultsReply
+	if err := json.NewDecoder(response.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("Could not read history from %s: %w", address, err)
+	}
+	return reply.Results, nil
+}
+
+// Whether stdin is a terminal an operator can type commands into
+func interactive() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
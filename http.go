@@ -0,0 +1,204 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	keepAliveInterval  time.Duration = 15 * time.Second // How often idle /stream connections are kept alive
+	defaultHTTPAddress string        = "127.0.0.1:8080" // Address the HTTP API is served on, loopback unless -http exposes it
+)
+
+// Single page dashboard charting live results, served at /
+//
+//go:embed dashboard.html
+var dashboard []byte
+
+// Request to start pinging targets
+type startRequest struct {
+	Targets []string `json:"targets"` // Hostnames or IP addresses, each optionally labelled as host=label
+	Count   int      `json:"count"`   // Number of times to ping, -1 or 0 for infinite
+	Labels  labels   `json:"labels"`  // Labels attached to every result of the job
+}
+
+// Statuses of jobs
+type statsReply struct {
+	Jobs []jobStatus `json:"jobs"` // Status of each job
+}
+
+// Last results kept (-history), oldest first
+type resultsReply struct {
+	Results []probeResult `json:"results"` // Results of the job or jobs
+}
+
+// Routes of the HTTP API of the engine (goping serve -http)
+func httpHandler(server *engine) http.Handler {
+	mux := http.NewServeMux()
+	// Dashboard of live RTT, loss and jitter per target
+	mux.HandleFunc("GET /{$}", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writer.Write(dashboard)
+	})
+	// Start pinging targets at runtime, as one job
+	mux.HandleFunc("POST /targets", func(writer http.ResponseWriter, request *http.Request) {
+		var start startRequest
+		if err := json.NewDecoder(request.Body).Decode(&start); err != nil {
+			writeError(writer, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+		if start.Count == 0 {
+			start.Count = -1
+		}
+		started, err := server.start(start.Targets, start.Count, start.Labels)
+		if err != nil {
+			writeError(writer, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(writer, http.StatusCreated, started.status())
+	})
+	// Describe a job
+	mux.HandleFunc("GET /targets/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		found, err := server.job(request.PathValue("id"))
+		if err != nil {
+			writeError(writer, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSON(writer, http.StatusOK, found.status())
+	})
+	// Stop a job and forget it, returning its final statistics
+	mux.HandleFunc("DELETE /targets/{id}", func(writer http.ResponseWriter, request *http.Request) {
+		removed, err := server.remove(request.PathValue("id"))
+		if err != nil {
+			writeError(writer, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSON(writer, http.StatusOK, removed.status())
+	})
+	// Statistics of all jobs
+	mux.HandleFunc("GET /stats", func(writer http.ResponseWriter, request *http.Request) {
+		writeJSON(writer, http.StatusOK, statsReply{Jobs: server.statuses()})
+	})
+	// Pause all probing, keeping statistics, and resume it
+	mux.HandleFunc("POST /pause", func(writer http.ResponseWriter, request *http.Request) {
+		probing.pause()
+		writeJSON(writer, http.StatusOK, probing.status())
+	})
+	mux.HandleFunc("POST /resume", func(writer http.ResponseWriter, request *http.Request) {
+		probing.resume()
+		writeJSON(writer, http.StatusOK, probing.status())
+	})
+	mux.HandleFunc("GET /pause", func(writer http.ResponseWriter, request *http.Request) {
+		writeJSON(writer, http.StatusOK, probing.status())
+	})
+	// Reset the statistics of every job, replying with the ones of the ending epoch
+	mux.HandleFunc("POST /reset", func(writer http.ResponseWriter, request *http.Request) {
+		writeJSON(writer, http.StatusOK, statsReply{Jobs: server.statuses()})
+		resetStatistics(server.allStats())
+	})
+	// Last results of a job, or of all jobs, oldest first (-history)
+	mux.HandleFunc("GET /results", func(writer http.ResponseWriter, request *http.Request) {
+		count, id := defaultShowLast, request.URL.Query().Get("job")
+		if last := request.URL.Query().Get("last"); last != "" {
+			parsed, err := strconv.Atoi(last)
+			if err != nil || parsed < 1 {
+				writeError(writer, http.StatusBadRequest, "Number of results must be a positive integer")
+				return
+			}
+			count = parsed
+		}
+		results, err := server.last(count, id)
+		if err != nil {
+			writeError(writer, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSON(writer, http.StatusOK, resultsReply{Results: results})
+	})
+	// Push results of a job, or of all jobs, as Server-Sent Events
+	mux.HandleFunc("GET /stream", func(writer http.ResponseWriter, request *http.Request) {
+		streamResults(server, writer, request)
+	})
+	return mux
+}
+
+// Stream results to the client as Server-Sent Events until it disconnects,
+// following the job given with ?job=, or all jobs
+func streamResults(server *engine, writer http.ResponseWriter, request *http.Request) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		writeError(writer, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+	results, unsubscribe, err := server.subscribe(request.URL.Query().Get("job"))
+	if err != nil {
+		writeError(writer, http.StatusNotFound, err.Error())
+		return
+	}
+	defer unsubscribe()
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Comment every keepAliveInterval so proxies don't time out idle streams
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case result := <-results:
+			data, err := json.Marshal(result)
+			if err != nil {
+				slog.Debug("Could not encode result", "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(writer, "event: result\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// Write a value as a JSON response
+func writeJSON(writer http.ResponseWriter, code int, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(code)
+	if err := json.NewEncoder(writer).Encode(value); err != nil {
+		slog.Debug("Could not write response", "error", err)
+	}
+}
+
+// Write an error as a JSON response
+func writeError(writer http.ResponseWriter, code int, message string) {
+	writeJSON(writer, code, map[string]string{"error": message})
+}
+
+// Reject requests not presenting the -api-token, if one is required, as an authorization header or,
+// for the dashboard and EventSource which can't set headers, a ?token= parameter
+func requireToken(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !authorized(request.Header.Get("Authorization")) && !authorized("Bearer "+request.URL.Query().Get("token")) {
+			writer.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(writer, http.StatusUnauthorized, "Missing or invalid API token, expected Authorization: Bearer <token> or ?token=")
+			return
+		}
+		handler.ServeHTTP(writer, request)
+	})
+}
+
+// Serve the HTTP API of the engine on the address until it fails
+func serveHTTP(address string, server *engine) error {
+	return http.ListenAndServe(address, requireToken(httpHandler(server)))
+}
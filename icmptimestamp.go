@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+const (
+	timestampBodyLength  int    = 16      // ID, sequence and the three timestamps of an ICMP timestamp message
+	timestampNonStandard uint32 = 1 << 31 // High bit marking a timestamp not in milliseconds since midnight UT (RFC 792)
+)
+
+// Timestamps of an ICMP timestamp reply (-probe timestamp), with the estimated clock offset of the
+// target and one-way delays, each of which includes that offset unless corrected against -ntp
+type icmpTimestamps struct {
+	Originate uint32        `json:"originate"`           // When we sent the request, in ms since midnight UT
+	Receive   uint32        `json:"receive"`             // When the target received it, by its clock
+	Transmit  uint32        `json:"transmit"`            // When the target sent the reply, by its clock
+	Offset    time.Duration `json:"offset"`              // Estimated offset of the target's clock from ours
+	Forward   time.Duration `json:"forward"`             // Delay from us to the target
+	Return    time.Duration `json:"return"`              // Delay from the target back to us
+	Asymmetry time.Duration `json:"asymmetry,omitempty"` // Forward minus return delay with -ntp, taking the target's clock as synchronized
+}
+
+// Milliseconds since midnight UT, as carried by ICMP timestamp messages
+func millisecondsOfDay(t time.Time) uint32 {
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return uint32(t.Sub(midnight) / time.Millisecond)
+}
+
+// Create the body of an ICMP timestamp request originated now
+func timestampRequest(id int, seq int, originated time.Time) *icmp.RawBody {
+	data := make([]byte, timestampBodyLength)
+	binary.BigEndian.PutUint16(data[0:2], uint16(id))
+	binary.BigEndian.PutUint16(data[2:4], uint16(seq))
+	binary.BigEndian.PutUint32(data[4:8], millisecondsOfDay(originated))
+	return &icmp.RawBody{Data: data}
+}
+
+// Decode an ICMP timestamp reply received at the given time, estimating the clock offset
+// NTP style as ((receive - originate) + (transmit - received)) / 2
+func parseTimestampReply(data []byte, received time.Time) (*icmpTimestamps, error) {
+	if len(data) < timestampBodyLength {
+		return nil, fmt.Errorf("Timestamp reply too short: %d bytes", len(data))
+	}
+	timestamps := &icmpTimestamps{
+		Originate: binary.BigEndian.Uint32(data[4:8]),
+		Receive:   binary.BigEndian.Uint32(data[8:12]),
+		Transmit:  binary.BigEndian.Uint32(data[12:16]),
+	}
+	if timestamps.Receive&timestampNonStandard != 0 || timestamps.Transmit&timestampNonStandard != 0 {
+		return timestamps, fmt.Errorf("Target replied with non-standard timestamps")
+	}
+	timestamps.Forward = millisecondsBetween(timestamps.Originate, timestamps.Receive)
+	timestamps.Return = millisecondsBetween(timestamps.Transmit, millisecondsOfDay(received))
+	timestamps.Offset = (timestamps.Forward - timestamps.Return) / 2
+	// Take our clock's offset from the NTP server off both delays, leaving the path's asymmetry
+	if ntpReference {
+		timestamps.Forward -= localClockOffset
+		timestamps.Return += localClockOffset
+		timestamps.Asymmetry = timestamps.Forward - timestamps.Return
+	}
+	return timestamps, nil
+}
+
+// Duration from one ms-of-day timestamp to another, wrapping around midnight
+func millisecondsBetween(from uint32, to uint32) time.Duration {
+	const day = 24 * 60 * 60 * 1000
+	difference := (int64(to) - int64(from)) % day
+	// Take the shorter way around midnight
+	if difference > day/2 {
+		difference -= day
+	} else if difference < -day/2 {
+		difference += day
+	}
+	return time.Duration(difference) * time.Millisecond
+}
+
+// Describe the timestamps for output
+func (timestamps *icmpTimestamps) String() string {
+	description := fmt.Sprintf(
+		"Originate: %d\t\tReceive: %d\t\tTransmit: %d\t\tClock offset: %s\t\tOne-way: %s out, %s back",
+		timestamps.Originate,
+		timestamps.Receive,
+		timestamps.Transmit,
+		timestamps.Offset,
+		timestamps.Forward,
+		timestamps.Return)
+	if ntpReference {
+		description += fmt.Sprintf("\t\tAsymmetry: %s", timestamps.Asymmetry)
+	}
+	return description
+}
+
+// Print the target's clock offset, taken from the reply of least RTT as its queuing skews the
+// estimate least, and the median one-way delays. With -ntp, judge which way the path is longer.
+func (stats *statistic) showClock() {
+	best := stats.clockSamples[0]
+	offsets := make([]time.Duration, len(stats.clockSamples))
+	forwards := make([]time.Duration, len(stats.clockSamples))
+	returns := make([]time.Duration, len(stats.clockSamples))
+	for i, sample := range stats.clockSamples {
+		if sample.Forward+sample.Return < best.Forward+best.Return {
+			best = sample
+		}
+		offsets[i], forwards[i], returns[i] = sample.Offset, sample.Forward, sample.Return
+	}
+	fmt.Fprintf(
+		noticeOutput(),
+		"Clock offset: %s (at least RTT), median %s\t\tOne-way median: %s out, %s back\n",
+		best.Offset,
+		medianOf(offsets),
+		medianOf(forwards),
+		medianOf(returns))
+	if !ntpReference {
+		return
+	}
+	asymmetry := medianOf(forwards) - medianOf(returns)
+	switch {
+	case asymmetry > 0:
+		fmt.Fprintf(noticeOutput(), "Path asymmetry: forward longer by %s (NTP offset from our clock: %s)\n", asymmetry, localClockOffset)
+	case asymmetry < 0:
+		fmt.Fprintf(noticeOutput(), "Path asymmetry: return longer by %s (NTP offset from our clock: %s)\n", -asymmetry, localClockOffset)
+	default:
+		fmt.Fprintf(noticeOutput(), "Path asymmetry: none to the millisecond (NTP offset from our clock: %s)\n", localClockOffset)
+	}
+}
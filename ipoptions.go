@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	optionEnd          byte = 0   // End of option list
+	optionNoOperation  byte = 1   // Padding between options
+	optionRecordRoute  byte = 7   // Record route option (RFC 791)
+	optionTimestamp    byte = 68  // Internet timestamp option (RFC 791)
+	optionLooseRoute   byte = 131 // Loose source and record route option (RFC 791)
+	maxOptionsLength   int  = 40  // Space for options in an IPv4 header
+	timestampsOnly     byte = 0   // Timestamp option flag recording timestamps only (-T tsonly)
+	timestampsAndAddrs byte = 1   // Timestamp option flag recording address and timestamp pairs (-T tsandaddr)
+)
+
+var requestIPOptions []byte // IPv4 options set on outgoing probes (-R, -T or -g) flag, nil for none
+
+// Decoded record route or timestamp IPv4 option of a reply
+type ipOptions struct {
+	Route  []string      `json:"route,omitempty"`  // Addresses recorded with -R
+	Source []string      `json:"source,omitempty"` // Source route of -g, as recorded by the gateways passed
+	Stamps []optionStamp `json:"stamps,omitempty"` // Timestamps recorded with -T
+}
+
+// Timestamp recorded by a hop in the timestamp option
+type optionStamp struct {
+	Address      string `json:"address,omitempty"` // Address of the hop with -T tsandaddr
+	Milliseconds uint32 `json:"ms"`                // Milliseconds since midnight UT, or non-standard if the high bit is set
+}
+
+// Build a record route option with space for as many addresses as fit (-R)
+func recordRouteOption() []byte {
+	option := make([]byte, maxOptionsLength)
+	option[0] = optionRecordRoute
+	option[1] = byte(maxOptionsLength - 1) // 9 addresses, leaving a byte for the end of list
+	option[2] = 4                          // Pointer to the first free slot, counting from 1
+	return option
+}
+
+// Build a timestamp option with space for as many entries as fit (-T tsonly or tsandaddr)
+func timestampOption(flag byte) []byte {
+	option := make([]byte, maxOptionsLength)
+	option[0] = optionTimestamp
+	option[1] = byte(maxOptionsLength) // 9 timestamps
+	if flag == timestampsAndAddrs {
+		option[1] = byte(maxOptionsLength - 4) // 4 address and timestamp pairs
+	}
+	option[2] = 5 // Pointer to the first free slot, counting from 1
+	option[3] = flag
+	return option
+}
+
+// Build a loose source route option through the gateways (-g), 9 at most. The kernel sends the
+// probe to the first gateway and appends the target as the last hop of the route.
+func looseSourceRouteOption(gateways []net.IP) ([]byte, error) {
+	if len(gateways) > (maxOptionsLength-4)/4 {
+		return nil, fmt.Errorf("At most %d gateways fit in the loose source route option, got %d", (maxOptionsLength-4)/4, len(gateways))
+	}
+	option := make([]byte, 3, 3+4*len(gateways))
+	option[0] = optionLooseRoute
+	option[1] = byte(3 + 4*len(gateways))
+	option[2] = 4 // Pointer to the next gateway, counting from 1
+	for _, gateway := range gateways {
+		address := gateway.To4()
+		if address == nil {
+			return nil, fmt.Errorf("Gateway %s isn't an IPv4 address", gateway)
+		}
+		option = append(option, address...)
+	}
+	return option, nil
+}
+
+// Decode the record route, timestamp and loose source route options from the options of a reply's
+// IPv4 header, returning nil if it carried none
+func parseIPOptions(options []byte) *ipOptions {
+	var decoded *ipOptions
+	for len(options) > 0 {
+		kind := options[0]
+		if kind == optionEnd {
+			break
+		}
+		if kind == optionNoOperation {
+			options = options[1:]
+			continue
+		}
+		if len(options) < 2 || int(options[1]) < 2 || int(options[1]) > len(options) {
+			break
+		}
+		option := options[:options[1]]
+		options = options[options[1]:]
+		if len(option) < 3 {
+			continue
+		}
+		// The pointer is one past the last recorded byte, counting from 1
+		used := int(option[2]) - 1
+		if used > len(option) {
+			used = len(option)
+		}
+		switch kind {
+		case optionRecordRoute:
+			if decoded == nil {
+				decoded = new(ipOptions)
+			}
+			for i := 3; i+4 <= used; i += 4 {
+				decoded.Route = append(decoded.Route, net.IP(option[i:i+4]).String())
+			}
+		case optionLooseRoute:
+			if decoded == nil {
+				decoded = new(ipOptions)
+			}
+			for i := 3; i+4 <= len(option); i += 4 {
+				decoded.Source = append(decoded.Source, net.IP(option[i:i+4]).String())
+			}
+		case optionTimestamp:
+			if len(option) < 4 {
+				continue
+			}
+			if decoded == nil {
+				decoded = new(ipOptions)
+			}
+			withAddresses := option[3]&0x0f != timestampsOnly
+			for i := 4; i < used; {
+				var stamp optionStamp
+				if withAddresses {
+					if i+8 > used {
+						break
+					}
+					stamp.Address = net.IP(option[i : i+4]).String()
+					i += 4
+				} else if i+4 > used {
+					break
+				}
+				stamp.Milliseconds = binary.BigEndian.Uint32(option[i : i+4])
+				i += 4
+				decoded.Stamps = append(decoded.Stamps, stamp)
+			}
+		}
+	}
+	return decoded
+}
+
+// Describe the recorded route, timestamps and source route for output, like classic ping's RR: and TS: lines
+func (options *ipOptions) String() string {
+	var lines []string
+	if len(options.Route) > 0 {
+		lines = append(lines, "RR: "+strings.Join(options.Route, " "))
+	}
+	if len(options.Stamps) > 0 {
+		stamps := make([]string, len(options.Stamps))
+		for i, stamp := range options.Stamps {
+			stamps[i] = fmt.Sprintf("%d", stamp.Milliseconds)
+			if stamp.Address != "" {
+				stamps[i] = fmt.Sprintf("%s %d", stamp.Address, stamp.Milliseconds)
+			}
+		}
+		lines = append(lines, "TS: "+strings.Join(stamps, ", "))
+	}
+	if len(options.Source) > 0 {
+		lines = append(lines, "LSRR: "+strings.Join(options.Source, " "))
+	}
+	return strings.Join(lines, "\t\t")
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// IPv4 options are only implemented on Unix platforms
+func setIPOptions(rawConn syscall.RawConn, options []byte) error {
+	return errors.New("IP options are not supported on this platform")
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package main
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Set IPv4 options on every packet sent from the socket
+func setIPOptions(rawConn syscall.RawConn, options []byte) error {
+	var sockErr error
+	err := rawConn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptString(int(fd), unix.IPPROTO_IP, unix.IP_OPTIONS, string(options))
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
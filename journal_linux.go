@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const journalSocket string = "/run/systemd/journal/socket" // Socket of journald's native protocol
+
+// Handler sending records to the systemd journal over its native protocol (-journald), with every
+// attribute as a field, e.g. SEQ=, TARGET= and RTT_USEC=, so journalctl can filter on them
+type journalHandler struct {
+	level  slog.Leveler   // Minimum level sent
+	conn   *net.UnixConn  // Datagram socket of journald
+	mutex  *sync.Mutex    // Serializes sends, shared with derived handlers
+	fields []journalField // Fields added with WithAttrs
+	group  string         // Field name prefix added with WithGroup
+}
+
+// Field of a journal entry
+type journalField struct {
+	name  string // Upper-case field name
+	value string // Field value
+}
+
+// Connect to journald
+func newJournalHandler(level slog.Leveler) (slog.Handler, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journalHandler{level: level, conn: conn, mutex: new(sync.Mutex)}, nil
+}
+
+// Whether records of the level are sent
+func (handler *journalHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= handler.level.Level()
+}
+
+// Send a record as a journal entry of its message, priority and attributes
+func (handler *journalHandler) Handle(_ context.Context, record slog.Record) error {
+	var entry bytes.Buffer
+	priority := strings.Trim(syslogPriority(record.Level), "<>")
+	writeJournalField(&entry, "MESSAGE", strings.TrimSuffix(record.Message, "\n"))
+	writeJournalField(&entry, "PRIORITY", priority)
+	writeJournalField(&entry, "SYSLOG_IDENTIFIER", syslogTag)
+	for _, field := range handler.fields {
+		writeJournalField(&entry, field.name, field.value)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		for _, field := range journalFields(handler.group, attr) {
+			writeJournalField(&entry, field.name, field.value)
+		}
+		return true
+	})
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	_, err := handler.conn.Write(entry.Bytes())
+	return err
+}
+
+// Fields of an attribute, groups flattened with underscores and durations given in microseconds as NAME_USEC
+func journalFields(prefix string, attr slog.Attr) []journalField {
+	attr.Value = attr.Value.Resolve()
+	name := prefix + journalName(attr.Key)
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		// Inline the members of groups without a key
+		if attr.Key != "" {
+			prefix = name + "_"
+		}
+		var fields []journalField
+		for _, member := range attr.Value.Group() {
+			fields = append(fields, journalFields(prefix, member)...)
+		}
+		return fields
+	case slog.KindDuration:
+		return []journalField{{name: name + "_USEC", value: strconv.FormatInt(attr.Value.Duration().Microseconds(), 10)}}
+	case slog.KindTime:
+		return []journalField{{name: name, value: attr.Value.Time().Format(time.RFC3339Nano)}}
+	}
+	if attr.Key == "" {
+		return nil
+	}
+	return []journalField{{name: name, value: attr.Value.String()}}
+}
+
+// Journal field name of an attribute key: upper-case letters, digits and underscores
+func journalName(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, key)
+}
+
+// Append a field to an entry, values spanning lines in the protocol's length-prefixed form
+func writeJournalField(entry *bytes.Buffer, name string, value string) {
+	if !strings.Contains(value, "\n") {
+		entry.WriteString(name + "=" + value + "\n")
+		return
+	}
+	entry.WriteString(name + "\n")
+	binary.Write(entry, binary.LittleEndian, uint64(len(value)))
+	entry.WriteString(value + "\n")
+}
+
+// Derive a handler that adds the attributes to every entry
+func (handler *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	derived := *handler
+	derived.fields = append([]journalField(nil), handler.fields...)
+	for _, attr := range attrs {
+		derived.fields = append(derived.fields, journalFields(handler.group, attr)...)
+	}
+	return &derived
+}
+
+// Derive a handler that prefixes field names with the group name
+func (handler *journalHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return handler
+	}
+	derived := *handler
+	derived.group = handler.group + journalName(name) + "_"
+	return &derived
+}
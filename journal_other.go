@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// The systemd journal is only found on Linux
+func newJournalHandler(_ slog.Leveler) (slog.Handler, error) {
+	return nil, errors.New("The systemd journal is only available on Linux")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// Key under which a positional host=label target label is stored
+const targetLabelKey string = "label"
+
+// Labels attached to every output line of a target, as key=value pairs
+type labels map[string]string
+
+// Format labels as sorted key=value pairs for flag defaults and output
+func (tags labels) String() string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// Add a key=value label, allowing -label to be given repeatedly
+func (tags labels) Set(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("Label must be key=value, got %q", pair)
+	}
+	tags[key] = value
+	return nil
+}
+
+// Split a positional host=label?settings argument into its address, target labels
+// merged over the global -label tags, and probe settings overriding the global ones
+func parseTarget(argument string, global labels) (string, labels, targetOverrides) {
+	argument, overrides := splitOverrides(argument)
+	tags := make(labels, len(global)+1)
+	for key, value := range global {
+		tags[key] = value
+	}
+	address, label, ok := strings.Cut(argument, "=")
+	if ok && label != "" {
+		tags[targetLabelKey] = label
+	}
+	return address, tags, overrides
+}
+
+// Render the labels as a suffix for output lines, empty if there are none
+func (tags labels) suffix() string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "\t\tLabels: " + tags.String()
+}
+
+// Render the labels as a structured log attribute group
+func (tags labels) attr() slog.Attr {
+	attrs := make([]any, 0, len(tags))
+	for key, value := range tags {
+		attrs = append(attrs, slog.String(key, value))
+	}
+	return slog.Group("labels", attrs...)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+)
+
+const lateWindow int = 64 // Lost probes of a target remembered in case their replies arrive late
+
+var countLate bool // Whether late replies are taken off the loss figure (-count-late) flag
+
+// Probe declared lost when its timeout passed, whose reply may still arrive
+type lostProbe struct {
+	seq  int       // Sequence number of the probe
+	sent time.Time // When it was sent
+}
+
+// Replies of a target arriving after their probes were declared lost
+type lateReplies struct {
+	lost []lostProbe     // Recently lost probes, oldest first, up to lateWindow
+	rtts []time.Duration // RTT of each late reply
+}
+
+// Remember a probe that timed out, forgetting the oldest beyond the window
+func (late *lateReplies) expect(seq int, sent time.Time) {
+	if len(late.lost) == lateWindow {
+		late.lost = late.lost[1:]
+	}
+	late.lost = append(late.lost, lostProbe{seq: seq, sent: sent})
+}
+
+// Take the lost probe an echo reply with the 16 bit sequence number answers, returning it
+func (late *lateReplies) match(seq int) (lostProbe, bool) {
+	for i, probe := range late.lost {
+		if probe.seq&0xffff == seq {
+			late.lost = append(late.lost[:i], late.lost[i+1:]...)
+			return probe, true
+		}
+	}
+	return lostProbe{}, false
+}
+
+// Count an echo reply from the target to one of its lost probes as late, logging its RTT and, with
+// -count-late, taking the probe off the loss figure. Returns whether the reply was a late one.
+func (stats *statistic) observeLate(seq int, peer *net.IPAddr, ipAddress *net.IPAddr, received time.Time) bool {
+	if !peer.IP.Equal(ipAddress.IP) {
+		return false
+	}
+	probe, ok := stats.late.match(seq)
+	if !ok {
+		return false
+	}
+	rtt := received.Sub(probe.sent)
+	stats.mutex.Lock()
+	stats.late.rtts = append(stats.late.rtts, rtt)
+	if countLate && stats.lost > 0 {
+		stats.lost--
+		stats.loss = (float64(stats.lost) / float64(stats.count)) * 100.0
+	}
+	stats.mutex.Unlock()
+	slog.Info(fmt.Sprintf("Seq: %d\t\tLate reply from: %s\t\tRTT: %s%s", probe.seq+1, displayAddress(peer), display(rtt), stats.labels.suffix()),
+		"seq", probe.seq+1, "target", stats.target.address, "rtt", rtt, "late", true, stats.labels.attr())
+	return true
+}
+
+// Print the number of late replies and the range of their RTTs
+func (stats *statistic) showLate() {
+	rtts := stats.late.rtts
+	best, worst := rtts[0], rtts[0]
+	var total time.Duration
+	for _, rtt := range rtts {
+		best, worst = min(best, rtt), max(worst, rtt)
+		total += rtt
+	}
+	counted := "counted as lost"
+	if countLate {
+		counted = "not counted as lost"
+	}
+	fmt.Fprintf(
+		noticeOutput(),
+		"Late replies: %d (%s)\t\tRTT min/avg/max: %s/%s/%s\n",
+		len(rtts),
+		counted,
+		display(best),
+		display(total/time.Duration(len(rtts))),
+		display(worst))
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	levelTrace        slog.Level = slog.LevelDebug - 4 // Raw ICMP details, shown with -vv
+	logFileBackups    int        = 3                   // Number of rotated log files kept next to -log-file
+	defaultLogFileMiB int        = 10                  // Default size in MiB at which -log-file is rotated
+)
+
+// Set up the default logger for the given verbosity (0, 1 for -v, 2 for -vv),
+// teeing to a rotated log file if a path is given. Console lines are prefixed with
+// their syslog priority for journald if journal is set, or records are sent to the
+// journal natively with structured fields in place of the console if native is set.
+func setupLogging(verbosity int, logFile string, logFileMiB int, journal bool, native bool) error {
+	level := slog.LevelInfo
+	switch {
+	case verbosity >= 2:
+		level = levelTrace
+	case verbosity == 1:
+		level = slog.LevelDebug
+	}
+
+	var handler slog.Handler = &consoleHandler{level: level, writer: os.Stderr, mutex: new(sync.Mutex), priorities: journal}
+	if native {
+		var err error
+		if handler, err = newJournalHandler(level); err != nil {
+			return fmt.Errorf("Failed to connect to the systemd journal: %w", err)
+		}
+	}
+	if logFile != "" {
+		file, err := openRotatingFile(logFile, int64(logFileMiB)<<20, logFileBackups)
+		if err != nil {
+			return err
+		}
+		fileHandler := slog.NewTextHandler(file, &slog.HandlerOptions{Level: level, ReplaceAttr: nameTraceLevel})
+		handler = teeHandler{handler, fileHandler}
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Name levelTrace as TRACE rather than DEBUG-4 in structured output
+func nameTraceLevel(_ []string, attr slog.Attr) slog.Attr {
+	if level, ok := attr.Value.Any().(slog.Level); ok && attr.Key == slog.LevelKey && level == levelTrace {
+		attr.Value = slog.StringValue("TRACE")
+	}
+	return attr
+}
+
+// Whether trace level records are logged, to skip building costly attributes on the hot path otherwise
+func tracing() bool {
+	return slog.Default().Enabled(context.Background(), levelTrace)
+}
+
+// Log raw protocol details at trace level (-vv)
+func trace(msg string, args ...any) {
+	slog.Log(context.Background(), levelTrace, msg, args...)
+}
+
+// Handler printing records in goPing's plain console style: just the message, prefixed for
+// errors and debug levels, with attributes appended only to debug level records
+type consoleHandler struct {
+	level      slog.Leveler // Minimum level printed
+	writer     io.Writer    // Destination of the records
+	mutex      *sync.Mutex  // Serializes writes, shared with derived handlers
+	attrs      []slog.Attr  // Attributes added with WithAttrs
+	group      string       // Key prefix added with WithGroup
+	priorities bool         // Prefix lines with sd-daemon <N> syslog priorities, parsed by journald
+}
+
+// Whether records of the level are printed
+func (handler *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= handler.level.Level()
+}
+
+// Print a record as a single line
+func (handler *consoleHandler) Handle(_ context.Context, record slog.Record) error {
+	var line strings.Builder
+	if handler.priorities {
+		line.WriteString(syslogPriority(record.Level))
+	}
+	switch {
+	case record.Level >= slog.LevelError:
+		line.WriteString("ERROR: ")
+	case record.Level < slog.LevelDebug:
+		line.WriteString("TRACE: ")
+	case record.Level < slog.LevelInfo:
+		line.WriteString("DEBUG: ")
+	}
+	line.WriteString(strings.TrimSuffix(record.Message, "\n"))
+	if record.Level < slog.LevelInfo {
+		for _, attr := range handler.attrs {
+			fmt.Fprintf(&line, " %s=%s", attr.Key, attr.Value)
+		}
+		record.Attrs(func(attr slog.Attr) bool {
+			fmt.Fprintf(&line, " %s%s=%s", handler.group, attr.Key, attr.Value)
+			return true
+		})
+	}
+	line.WriteString("\n")
+
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	_, err := io.WriteString(handler.writer, line.String())
+	return err
+}
+
+// Syslog priority prefix of a level as understood by journald: <3> err, <4> warning, <6> info, <7> debug
+func syslogPriority(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "<3>"
+	case level >= slog.LevelWarn:
+		return "<4>"
+	case level >= slog.LevelInfo:
+		return "<6>"
+	default:
+		return "<7>"
+	}
+}
+
+// Derive a handler that adds the attributes to every record
+func (handler *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	derived := *handler
+	derived.attrs = append([]slog.Attr(nil), handler.attrs...)
+	for _, attr := range attrs {
+		attr.Key = handler.group + attr.Key
+		derived.attrs = append(derived.attrs, attr)
+	}
+	return &derived
+}
+
+// Derive a handler that prefixes attribute keys with the group name
+func (handler *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return handler
+	}
+	derived := *handler
+	derived.group = handler.group + name + "."
+	return &derived
+}
+
+// Handler passing every record on to several handlers
+type teeHandler []slog.Handler
+
+// Whether any of the handlers prints records of the level
+func (handlers teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pass the record to every handler enabled for its level, returning the first error
+func (handlers teeHandler) Handle(ctx context.Context, record slog.Record) error {
+	var firstErr error
+	for _, handler := range handlers {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, record.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Derive handlers that add the attributes to every record
+func (handlers teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	derived := make(teeHandler, len(handlers))
+	for i, handler := range handlers {
+		derived[i] = handler.WithAttrs(attrs)
+	}
+	return derived
+}
+
+// Derive handlers that group subsequent attributes
+func (handlers teeHandler) WithGroup(name string) slog.Handler {
+	derived := make(teeHandler, len(handlers))
+	for i, handler := range handlers {
+		derived[i] = handler.WithGroup(name)
+	}
+	return derived
+}
+
+// Log file that is rotated to path.1, path.2, ... once it grows past maxSize
+type rotatingFile struct {
+	path    string     // Path of the current log file
+	maxSize int64      // Size in bytes at which the file is rotated
+	backups int        // Number of rotated files kept
+	file    *os.File   // Current log file
+	size    int64      // Bytes written to the current log file
+	mutex   sync.Mutex // Serializes writes and rotation
+}
+
+// Open the log file for appending, creating it if needed
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	rotating := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := rotating.open(); err != nil {
+		return nil, err
+	}
+	return rotating, nil
+}
+
+// Open the current log file, picking up its existing size
+func (rotating *rotatingFile) open() error {
+	file, err := os.OpenFile(rotating.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rotating.file = file
+	rotating.size = info.Size()
+	return nil
+}
+
+// Write to the log file, rotating it first if the write would exceed maxSize
+func (rotating *rotatingFile) Write(p []byte) (int, error) {
+	rotating.mutex.Lock()
+	defer rotating.mutex.Unlock()
+	if rotating.size > 0 && rotating.size+int64(len(p)) > rotating.maxSize {
+		if err := rotating.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rotating.file.Write(p)
+	rotating.size += int64(n)
+	return n, err
+}
+
+// Shift path.N-1 to path.N down to path to path.1, dropping the oldest, and reopen path
+func (rotating *rotatingFile) rotate() error {
+	if err := rotating.file.Close(); err != nil {
+		return err
+	}
+	for i := rotating.backups; i > 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", rotating.path, i-1), fmt.Sprintf("%s.%d", rotating.path, i))
+	}
+	if err := os.Rename(rotating.path, rotating.path+".1"); err != nil {
+		return err
+	}
+	return rotating.open()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const metricsFileInterval time.Duration = 15 * time.Second // Interval between snapshots written to -metrics-file
+
+var (
+	metricsFile  string                                                  // File an OpenMetrics snapshot is written to (-metrics-file) flag, empty for none
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`) // Escapes of label values in the text format
+)
+
+// Metric families of the snapshot, in the order written, with the suffix of each of their samples
+var metricFamilies = []struct {
+	name     string // Name of the family
+	kind     string // OpenMetrics type
+	unit     string // Unit, empty for none
+	help     string // Description
+	suffixes string // Suffixes of its samples, space-separated
+}{
+	{"goping_rtt_seconds", "histogram", "seconds", "RTT of replies", "_bucket _sum _count"},
+	{"goping_probes_sent", "counter", "", "Probes sent", "_total"},
+	{"goping_probes_lost", "counter", "", "Probes lost", "_total"},
+}
+
+// Write the snapshot every metricsFileInterval, besides at every summary
+func writeMetricsPeriodically() {
+	for range time.Tick(metricsFileInterval) {
+		if currentStats != nil {
+			writeMetricsFile(currentStats())
+		}
+	}
+}
+
+// Write an OpenMetrics snapshot of the targets' RTT histograms and probe counters to -metrics-file,
+// through a temporary file renamed over it so the node_exporter textfile collector never reads it half written
+func writeMetricsFile(targets []*statistic) {
+	var series []remoteSeries
+	for _, stats := range targets {
+		series = append(series, stats.remoteSeries()...)
+	}
+	var snapshot bytes.Buffer
+	for _, family := range metricFamilies {
+		fmt.Fprintf(&snapshot, "# TYPE %s %s\n", family.name, family.kind)
+		if family.unit != "" {
+			fmt.Fprintf(&snapshot, "# UNIT %s %s\n", family.name, family.unit)
+		}
+		fmt.Fprintf(&snapshot, "# HELP %s %s\n", family.name, family.help)
+		for _, sample := range series {
+			var name string
+			pairs := make([]string, 0, len(sample.labels)-1)
+			for _, label := range sample.labels {
+				if label[0] == "__name__" {
+					name = label[1]
+				} else {
+					pairs = append(pairs, label[0]+`="`+labelEscaper.Replace(label[1])+`"`)
+				}
+			}
+			suffix, ok := strings.CutPrefix(name, family.name)
+			if !ok || !strings.Contains(" "+family.suffixes+" ", " "+suffix+" ") {
+				continue
+			}
+			fmt.Fprintf(&snapshot, "%s{%s} %s\n", name, strings.Join(pairs, ","), strconv.FormatFloat(sample.value, 'g', -1, 64))
+		}
+	}
+	snapshot.WriteString("# EOF\n")
+
+	temporary, err := os.CreateTemp(filepath.Dir(metricsFile), "."+filepath.Base(metricsFile)+".*")
+	if err == nil {
+		_, err = temporary.Write(snapshot.Bytes())
+		if closeErr := temporary.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(temporary.Name(), 0o644)
+		}
+		if err == nil {
+			err = os.Rename(temporary.Name(), metricsFile)
+		}
+		if err != nil {
+			os.Remove(temporary.Name())
+		}
+	}
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Could not write metrics to %s (%s)", metricsFile, err))
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const networkSettle time.Duration = time.Second // Quiet time after the last network event before acting on the change
+
+var networkEpoch atomic.Int64 // Number of network changes acted on, targets re-resolving when it moves past theirs
+
+// Watch for interface, address and route changes, such as a Wi-Fi roam or a VPN coming up or down,
+// logging each and having every target re-resolved at its next probe. Sockets are opened per probe,
+// so the next probe is sent from the new network without restarting.
+func watchNetwork() {
+	events := make(chan string, 16)
+	if err := subscribeNetworkChanges(events); err != nil {
+		slog.Debug("Could not watch for network changes", "error", err)
+		return
+	}
+	go func() {
+		for event := range events {
+			// Act once on a burst of events, as bringing up an interface changes its link, addresses and routes
+			kinds := []string{event}
+			settled := time.NewTimer(networkSettle)
+			for collecting := true; collecting; {
+				select {
+				case event := <-events:
+					if !slices.Contains(kinds, event) {
+						kinds = append(kinds, event)
+					}
+					settled.Reset(networkSettle)
+				case <-settled.C:
+					collecting = false
+				}
+			}
+			networkEpoch.Add(1)
+			slog.Warn(fmt.Sprintf("Network changed (%s). Re-resolving targets...", strings.Join(kinds, ", ")), "changes", kinds)
+		}
+	}()
+}
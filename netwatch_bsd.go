@@ -0,0 +1,44 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Subscribe to the kernel's routing socket messages of interfaces, addresses and routes,
+// sending the kind of each change to the channel
+func subscribeNetworkChanges(events chan<- string) error {
+	fd, err := syscall.Socket(syscall.AF_ROUTE, syscall.SOCK_RAW, syscall.AF_UNSPEC)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	syscall.CloseOnExec(fd)
+	go func() {
+		defer syscall.Close(fd)
+		buffer := make([]byte, os.Getpagesize())
+		for {
+			n, err := syscall.Read(fd, buffer)
+			if err == syscall.EINTR || err == syscall.ENOBUFS {
+				continue
+			}
+			if err != nil {
+				return
+			}
+			// Messages start with their length, version and type
+			if n < 4 {
+				continue
+			}
+			switch buffer[3] {
+			case syscall.RTM_IFINFO:
+				events <- "link"
+			case syscall.RTM_NEWADDR, syscall.RTM_DELADDR:
+				events <- "address"
+			case syscall.RTM_ADD, syscall.RTM_DELETE, syscall.RTM_CHANGE:
+				events <- "route"
+			}
+		}
+	}()
+	return nil
+}
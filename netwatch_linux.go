@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Subscribe to the kernel's rtnetlink notifications of links, addresses and routes,
+// sending the kind of each change to the channel
+func subscribeNetworkChanges(events chan<- string) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	groups := []uint{syscall.RTNLGRP_LINK, syscall.RTNLGRP_IPV4_IFADDR, syscall.RTNLGRP_IPV6_IFADDR, syscall.RTNLGRP_IPV4_ROUTE, syscall.RTNLGRP_IPV6_ROUTE}
+	var mask uint32
+	for _, group := range groups {
+		mask |= 1 << (group - 1)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: mask}); err != nil {
+		syscall.Close(fd)
+		return os.NewSyscallError("bind", err)
+	}
+	go func() {
+		defer syscall.Close(fd)
+		buffer := make([]byte, os.Getpagesize())
+		for {
+			n, _, err := syscall.Recvfrom(fd, buffer, 0)
+			if err == syscall.EINTR || err == syscall.ENOBUFS {
+				continue
+			}
+			if err != nil {
+				return
+			}
+			messages, err := syscall.ParseNetlinkMessage(buffer[:n])
+			if err != nil {
+				continue
+			}
+			for _, message := range messages {
+				switch message.Header.Type {
+				case syscall.RTM_NEWLINK, syscall.RTM_DELLINK:
+					events <- "link"
+				case syscall.RTM_NEWADDR, syscall.RTM_DELADDR:
+					events <- "address"
+				case syscall.RTM_NEWROUTE, syscall.RTM_DELROUTE:
+					events <- "route"
+				}
+			}
+		}
+	}()
+	return nil
+}
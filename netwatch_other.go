@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package main
+
+import "errors"
+
+// Watching for network changes is only implemented on Linux and the BSDs
+func subscribeNetworkChanges(events chan<- string) error {
+	return errors.New("Watching for network changes is not supported on this platform")
+}
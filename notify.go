@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+// Tell the service manager about a state change, e.g. READY=1, following the sd_notify
+// protocol over the datagram socket in $NOTIFY_SOCKET. Does nothing when not run by systemd.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	ntpPort         string        = "123"           // Port NTP servers listen on
+	ntpTimeout      time.Duration = 5 * time.Second // Deadline of the NTP server's reply
+	ntpPacketLength int           = 48              // Length of an NTP packet without extensions
+	ntpEpochOffset  int64         = 2208988800      // Seconds from the NTP epoch (1900) to the Unix epoch (1970)
+)
+
+var (
+	ntpReference     bool          // Whether one-way delays are corrected against an NTP server (-ntp) flag
+	localClockOffset time.Duration // Offset of the NTP server's clock from ours, with -ntp
+)
+
+// Query an NTP server once (SNTP, RFC 4330), returning the offset of its clock from ours
+// as ((T2 - T1) + (T3 - T4)) / 2, and the round trip to it
+func queryNTP(server string) (time.Duration, time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, ntpPort)
+	}
+	conn, err := net.DialTimeout("udp", server, ntpTimeout)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ntpTimeout))
+	request := make([]byte, ntpPacketLength)
+	request[0] = 0<<6 | 4<<3 | 3 // No leap warning, version 4, client mode
+	sent := time.Now()
+	putNTPTime(request[40:48], sent)
+	if _, err := conn.Write(request); err != nil {
+		return 0, 0, err
+	}
+	reply := make([]byte, ntpPacketLength)
+	read, err := conn.Read(reply)
+	received := time.Now()
+	if err != nil {
+		return 0, 0, err
+	}
+	switch {
+	case read < ntpPacketLength:
+		return 0, 0, fmt.Errorf("NTP reply too short: %d bytes", read)
+	case reply[0]&0x7 != 4:
+		return 0, 0, fmt.Errorf("NTP reply not in server mode")
+	case reply[1] == 0:
+		return 0, 0, fmt.Errorf("NTP server is unsynchronized or refused the query")
+	case binary.BigEndian.Uint64(reply[24:32]) != binary.BigEndian.Uint64(request[40:48]):
+		return 0, 0, fmt.Errorf("NTP reply doesn't answer our request")
+	}
+	serverReceived, serverSent := ntpTime(reply[32:40]), ntpTime(reply[40:48])
+	offset := (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2
+	roundTrip := received.Sub(sent) - serverSent.Sub(serverReceived)
+	return offset, roundTrip, nil
+}
+
+// Encode a time as a 64 bit NTP timestamp: seconds since 1900 and a binary fraction of a second
+func putNTPTime(data []byte, t time.Time) {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	binary.BigEndian.PutUint64(data, seconds<<32|fraction)
+}
+
+// Decode a 64 bit NTP timestamp
+func ntpTime(data []byte) time.Time {
+	timestamp := binary.BigEndian.Uint64(data)
+	seconds := int64(timestamp>>32) - ntpEpochOffset
+	nanoseconds := int64((timestamp & 0xffffffff) * uint64(time.Second) >> 32)
+	return time.Unix(seconds, nanoseconds)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Outcome of a single probe, as printed, recorded (-record) and replayed
+type probeResult struct {
+	Time       time.Time       `json:"time"`                 // When the probe was sent
+	Job        string          `json:"job,omitempty"`        // Server mode job that sent the probe, empty outside server mode
+	Target     string          `json:"target"`               // Target address as given
+	IP         string          `json:"ip"`                   // Address the target resolved to, empty if it didn't
+	Name       string          `json:"name"`                 // Display name of the address, with its reverse DNS name
+	Seq        int             `json:"seq"`                  // Sequence number of the probe
+	RTT        time.Duration   `json:"rtt"`                  // Round trip time, 0 if lost
+	Loss       float64         `json:"loss"`                 // Percent loss of the target so far
+	Error      string          `json:"error,omitempty"`      // Why the probe was lost, empty if it replied
+	Labels     labels          `json:"labels,omitempty"`     // Labels of the target
+	Mismatched bool            `json:"mismatched,omitempty"` // Whether the reply came from a source other than the target
+	Anomaly    bool            `json:"anomaly,omitempty"`    // Whether the RTT spiked above the recent baseline (-anomaly)
+	Truncated  bool            `json:"truncated,omitempty"`  // Whether the echo reply carried less payload than the request
+	Corrupted  bool            `json:"corrupted,omitempty"`  // Whether the echoed payload differed from the request's
+	LostAt     string          `json:"lost_at,omitempty"`    // Where the probe was lost with -split-path, local or upstream of the gateway
+	TTL        int             `json:"ttl,omitempty"`        // TTL or hop limit of the echo reply, 0 if unknown
+	Timestamps *icmpTimestamps `json:"timestamps,omitempty"` // Timestamps of the reply with -probe timestamp
+	Responders []responder     `json:"responders,omitempty"` // Hosts replying to a broadcast or multicast target (-b)
+	IPOptions  *ipOptions      `json:"ip_options,omitempty"` // Route or timestamps recorded in IPv4 options (-R or -T)
+	FlowLabel  uint32          `json:"flow_label,omitempty"` // IPv6 flow label of the probe (-flow-label), 0 for the kernel's choice
+	ECN        string          `json:"ecn,omitempty"`        // ECN codepoint of the probe when testing ECN (-ecn), e.g. ECT(0) or Not-ECT for controls
+	ReplyECN   string          `json:"reply_ecn,omitempty"`  // ECN field of the reply when testing ECN, empty if lost or unknown
+	Running    *runningStats   `json:"running,omitempty"`    // Cumulative RTTs of the target so far with -loop-stats
+	Clock      string          `json:"clock,omitempty"`      // Source of the RTT's timestamps: hardware, kernel, userspace or ebpf, empty if lost
+	GCPause    time.Duration   `json:"gc_pause,omitempty"`   // GC pause time overlapping the RTT, 0 if none did
+}
+
+// Cumulative RTTs of a target shown on every probe's line (-loop-stats)
+type runningStats struct {
+	MinRTT time.Duration `json:"min_rtt"` // Smallest RTT so far
+	AvgRTT time.Duration `json:"avg_rtt"` // Mean RTT so far
+	MaxRTT time.Duration `json:"max_rtt"` // Largest RTT so far
+}
+
+// Writer of probe results in the output format (-o)
+type resultWriter struct {
+	format string             // text, json or csv
+	line   *template.Template // Template of each result's line (-format), nil to print the output format
+	json   *json.Encoder
+	csv    *csv.Writer
+	header bool       // Whether the csv header has been written
+	mutex  sync.Mutex // Serializes results from concurrent targets
+}
+
+// Create a writer for the output format, printing machine readable formats to stdout
+func newResultWriter(format string) *resultWriter {
+	return &resultWriter{format: format, json: json.NewEncoder(os.Stdout), csv: csv.NewWriter(os.Stdout)}
+}
+
+// Print each result through the template instead of the output format, e.g. {{.Seq}} {{.Target}} {{.RTT}},
+// with every field of probeResult available and display rounding durations to the -precision
+func (writer *resultWriter) useTemplate(text string) error {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	line, err := template.New("format").Funcs(template.FuncMap{"display": display}).Parse(text)
+	if err == nil {
+		// Catch unknown fields before probing rather than on every line
+		err = line.Execute(io.Discard, probeResult{})
+	}
+	if err != nil {
+		return fmt.Errorf("Invalid format template: %w", err)
+	}
+	writer.line = line
+	return nil
+}
+
+// Print a probe result in the output format, or through the -format template if given
+func (writer *resultWriter) write(result probeResult) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	writer.print(result)
+}
+
+// Print results together, without those of other targets in between
+func (writer *resultWriter) writeAll(results []probeResult) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	for _, result := range results {
+		writer.print(result)
+	}
+}
+
+// Print a probe result, with the writer locked
+func (writer *resultWriter) print(result probeResult) {
+	if writer.line != nil {
+		if err := writer.line.Execute(os.Stdout, result); err != nil {
+			slog.Error(fmt.Sprintf("Could not format result: %s", err))
+		}
+		return
+	}
+	switch writer.format {
+	case "json":
+		writer.json.Encode(result)
+	case "csv":
+		if !writer.header {
+			writer.csv.Write([]string{"time", "target", "ip", "seq", "rtt_ns", "ttl", "loss", "anomaly", "error", "labels"})
+			writer.header = true
+		}
+		writer.csv.Write([]string{
+			result.Time.Format(time.RFC3339Nano),
+			result.Target,
+			result.IP,
+			strconv.Itoa(result.Seq),
+			strconv.FormatInt(int64(result.RTT), 10),
+			strconv.Itoa(result.TTL),
+			strconv.FormatFloat(result.Loss, 'f', 2, 64),
+			strconv.FormatBool(result.Anomaly),
+			result.Error,
+			result.Labels.String()})
+		writer.csv.Flush()
+	default:
+		result.log()
+	}
+}
+
+// Log a probe result as goPing's text line, preceded by the error if it was lost
+func (result probeResult) log() {
+	if result.Error != "" {
+		slog.Error(result.Error+result.Labels.suffix(), "seq", result.Seq, "target", result.Target, result.Labels.attr())
+	}
+	// Mark RTT spikes, and the flow label and ECN codepoints when set, on their line
+	anomaly := ""
+	if result.FlowLabel != 0 {
+		anomaly = fmt.Sprintf("\t\tFlow label: 0x%05x", result.FlowLabel)
+	}
+	if result.ECN != "" && result.ReplyECN != "" {
+		anomaly += fmt.Sprintf("\t\tECN: %s -> %s", result.ECN, result.ReplyECN)
+	} else if result.ECN != "" {
+		anomaly += fmt.Sprintf("\t\tECN: %s", result.ECN)
+	}
+	if result.Anomaly {
+		anomaly += "\t\tANOMALY"
+	}
+	if result.Truncated {
+		anomaly += "\t\tTRUNCATED"
+	}
+	if result.Corrupted {
+		anomaly += "\t\tCORRUPTED"
+	}
+	if result.LostAt != "" {
+		anomaly += "\t\tLost: " + result.LostAt
+	}
+	// Tell probes timed by the NIC from those it didn't time with -timestamping hardware
+	if hardwareTimestamping && result.Clock != "" {
+		anomaly += "\t\tClock: " + result.Clock
+	}
+	// Mark RTTs a GC pause may have inflated
+	if result.GCPause > 0 {
+		anomaly += "\t\tGC pause: " + display(result.GCPause).String()
+	}
+	// Follow the loss with the cumulative RTTs with -loop-stats
+	running := ""
+	if result.Running != nil {
+		running = fmt.Sprintf("\t\tmin/avg/max: %s/%s/%s", display(result.Running.MinRTT), display(result.Running.AvgRTT), display(result.Running.MaxRTT))
+	}
+	// Pring statistics every message
+	slog.Info(
+		fmt.Sprintf(
+			"Seq: %d\t\tPinging: %s\t\tRTT: %s\t\tLoss: %.2f%%%s%s%s",
+			result.Seq,
+			result.Name,
+			display(result.RTT),
+			result.Loss,
+			running,
+			anomaly,
+			result.Labels.suffix()),
+		"seq", result.Seq,
+		"target", result.Target,
+		"ip", result.IP,
+		"rtt", result.RTT,
+		"ttl", result.TTL,
+		"loss", result.Loss,
+		"anomaly", result.Anomaly,
+		result.Labels.attr())
+	// Follow with every host that replied to a broadcast or multicast target
+	for _, reply := range result.Responders {
+		slog.Info(fmt.Sprintf("  Reply from: %s\t\tRTT: %s%s", displayAddress(&net.IPAddr{IP: net.ParseIP(reply.IP)}), display(reply.RTT), result.Labels.suffix()), "seq", result.Seq, "target", result.Target, "responder", reply.IP, "rtt", reply.RTT, result.Labels.attr())
+	}
+	// Follow with the route or hop timestamps recorded in IPv4 options
+	if result.IPOptions != nil {
+		slog.Info("  "+result.IPOptions.String()+result.Labels.suffix(), "seq", result.Seq, "target", result.Target, "route", result.IPOptions.Route, result.Labels.attr())
+	}
+	// Follow with the target's timestamps with -probe timestamp
+	if result.Timestamps != nil {
+		slog.Info(result.Timestamps.String()+result.Labels.suffix(), "seq", result.Seq, "target", result.Target, result.Labels.attr())
+	}
+}
+
+// Cumulative RTTs of the target so far, for -loop-stats
+func (stats *statistic) running() *runningStats {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	running := new(runningStats)
+	for i, rtt := range stats.rttAll {
+		if i == 0 || rtt < running.MinRTT {
+			running.MinRTT = rtt
+		}
+		running.MaxRTT = max(running.MaxRTT, rtt)
+	}
+	if len(stats.rttAll) > 0 {
+		running.AvgRTT = stats.totalRTT / time.Duration(len(stats.rttAll))
+	}
+	return running
+}
+
+// Aggregated statistics of a target, as served in server mode
+type targetSummary struct {
+	Target       string         `json:"target"`                  // Target address as given
+	IP           string         `json:"ip,omitempty"`            // Address the target last resolved to
+	Labels       labels         `json:"labels,omitempty"`        // Labels of the target
+	Sent         int            `json:"sent"`                    // Number of probes sent
+	Lost         int            `json:"lost"`                    // Number of probes lost
+	Loss         float64        `json:"loss"`                    // Percent loss
+	MinRTT       time.Duration  `json:"min_rtt"`                 // Smallest RTT
+	AvgRTT       time.Duration  `json:"avg_rtt"`                 // Mean RTT
+	P95RTT       time.Duration  `json:"p95_rtt"`                 // 95th percentile RTT
+	MaxRTT       time.Duration  `json:"max_rtt"`                 // Largest RTT
+	Jitter       time.Duration  `json:"jitter"`                  // Mean difference between subsequent RTTs
+	State        string         `json:"state"`                   // Up/down state of the target
+	Anomalies    int            `json:"anomalies"`               // Number of RTTs flagged as anomalous
+	Late         int            `json:"late"`                    // Number of replies arriving after their probes were declared lost
+	Malformed    int            `json:"malformed"`               // Number of replies failing to parse or truncated
+	Corrupted    int            `json:"corrupted"`               // Number of echo replies with corrupted payloads
+	Errors       map[string]int `json:"errors,omitempty"`        // Number of lost probes of each cause, e.g. timeout
+	RateLimiting []string       `json:"rate_limiting,omitempty"` // Loss patterns pointing at ICMP rate limiting
+	Availability float64        `json:"availability"`            // Percent of the measured time the target was up, 0 before any was measured
+	Downtime     time.Duration  `json:"downtime"`                // Time the target was down
+	Geo          *geoLocation   `json:"geo,omitempty"`           // Location of the target's address (-geoip), nil if unknown
+}
+
+// Summarize the statistics of the target so far, safe to call while it is being probed
+func (stats *statistic) summary() targetSummary {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	summary := targetSummary{
+		Target:       stats.target.address,
+		IP:           stats.lastIP,
+		Labels:       stats.labels,
+		Sent:         stats.count,
+		Lost:         stats.lost,
+		Loss:         stats.loss,
+		State:        stats.state.String(),
+		Anomalies:    stats.anomalies,
+		Late:         len(stats.late.rtts),
+		Malformed:    stats.malformed,
+		Corrupted:    stats.corrupted,
+		Availability: stats.uptime.percent(),
+		Downtime:     stats.uptime.down,
+		Geo:          geoLookup(net.ParseIP(stats.lastIP)),
+		Errors:       maps.Clone(stats.lossCauses),
+		RateLimiting: stats.rateLimit.patterns(stats.count, stats.lost),
+	}
+	for i, rtt := range stats.rttAll {
+		if i == 0 || rtt < summary.MinRTT {
+			summary.MinRTT = rtt
+		}
+		if rtt > summary.MaxRTT {
+			summary.MaxRTT = rtt
+		}
+	}
+	if len(stats.rttAll) > 0 {
+		summary.AvgRTT = stats.totalRTT / time.Duration(len(stats.rttAll))
+		summary.P95RTT = percentile(stats.rttAll, 95)
+	}
+	jitter, _ := meanDeviation(successiveDifferences(stats.rttAll))
+	summary.Jitter = time.Duration(jitter)
+	return summary
+}
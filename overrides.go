@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Probe settings of a single target overriding the global ones, given as host?ttl=10&interval=200ms&timeout=1s
+type targetOverrides struct {
+	ttl      int           // TTL of the target's probes, 0 for -ttl
+	interval time.Duration // Interval between its probes, 0 for the default 1s
+	timeout  time.Duration // Deadline of its replies, 0 for the default
+}
+
+// Split the ?key=value&... settings off a target argument, returning the rest and the overrides.
+// Invalid or unknown settings are warned about and ignored, as the other flags' are.
+func splitOverrides(argument string) (string, targetOverrides) {
+	var overrides targetOverrides
+	rest, query, ok := strings.Cut(argument, "?")
+	if !ok {
+		return argument, overrides
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Invalid settings of target %s (%s). Ignoring them...", rest, err))
+		return rest, overrides
+	}
+	for key := range values {
+		value := values.Get(key)
+		switch key {
+		case "ttl":
+			ttl, err := strconv.Atoi(value)
+			if err != nil || ttl < 1 || ttl > 255 {
+				slog.Warn(fmt.Sprintf("TTL of target %s must be between 1 and 255. Ignoring it...", rest))
+				continue
+			}
+			overrides.ttl = ttl
+		case "interval":
+			interval, err := time.ParseDuration(value)
+			if err != nil || interval <= 0 {
+				slog.Warn(fmt.Sprintf("Interval of target %s must be a positive duration. Ignoring it...", rest))
+				continue
+			}
+			overrides.interval = interval
+		case "timeout":
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout <= 0 {
+				slog.Warn(fmt.Sprintf("Timeout of target %s must be a positive duration. Ignoring it...", rest))
+				continue
+			}
+			overrides.timeout = timeout
+		default:
+			slog.Warn(fmt.Sprintf("Unknown setting %q of target %s, expected ttl, interval or timeout. Ignoring it...", key, rest))
+		}
+	}
+	return rest, overrides
+}
+
+// Interval between probes of the target, its own if overridden
+func (stats *statistic) probeEvery() time.Duration {
+	if stats.interval > 0 {
+		return stats.interval
+	}
+	// Set with + and - in the interactive console (-tui)
+	if adjusted := runtimeInterval.Load(); adjusted > 0 {
+		return time.Duration(adjusted)
+	}
+	return probeInterval
+}
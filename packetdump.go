@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Print a hex dump of an ICMP message along with its decoded header fields (-debug-packets)
+func dumpPacket(sent bool, peer net.Addr, packet []byte, matched bool) {
+	var dump strings.Builder
+	if sent {
+		fmt.Fprintf(&dump, "Sent %d bytes to %s", len(packet), peer)
+	} else {
+		fmt.Fprintf(&dump, "Received %d bytes from %s", len(packet), peer)
+	}
+	if !matched {
+		dump.WriteString(" (not a reply to this request)")
+	}
+	dump.WriteString("\n")
+
+	if len(packet) < 4 {
+		dump.WriteString("  Truncated ICMP header\n")
+	} else {
+		// Decode the fixed header
+		var typeName string
+		if wantIPv6 {
+			typeName = ipv6.ICMPType(packet[0]).String()
+		} else {
+			typeName = ipv4.ICMPType(packet[0]).String()
+		}
+		checksum := binary.BigEndian.Uint16(packet[2:4])
+		fmt.Fprintf(&dump, "  Type: %d (%s)  Code: %d  Checksum: 0x%04x", packet[0], typeName, packet[1], checksum)
+		// ICMPv6 checksums cover a pseudo-header the socket doesn't show us, so only verify ICMPv4
+		if wantIPv6 {
+			dump.WriteString(" (not verified for ICMPv6)")
+		} else if computed := icmpChecksum(packet); computed == checksum {
+			dump.WriteString(" (valid)")
+		} else {
+			fmt.Fprintf(&dump, " (INVALID, expected 0x%04x)", computed)
+		}
+		dump.WriteString("\n")
+		// Echo requests and replies carry an identifier and sequence
+		if len(packet) >= 8 && isEchoType(packet[0]) {
+			fmt.Fprintf(&dump, "  ID: %d  Seq: %d  Payload: %d bytes\n", binary.BigEndian.Uint16(packet[4:6]), binary.BigEndian.Uint16(packet[6:8]), len(packet)-8)
+		}
+	}
+	dump.WriteString(hex.Dump(packet))
+	slog.Info(dump.String())
+}
+
+// Whether the ICMP type byte is an echo or timestamp request or reply of the IP version in use,
+// all of which carry an identifier and sequence
+func isEchoType(icmpType byte) bool {
+	if wantIPv6 {
+		return ipv6.ICMPType(icmpType) == ipv6.ICMPTypeEchoRequest || ipv6.ICMPType(icmpType) == ipv6.ICMPTypeEchoReply
+	}
+	switch ipv4.ICMPType(icmpType) {
+	case ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply, ipv4.ICMPTypeTimestamp, ipv4.ICMPTypeTimestampReply:
+		return true
+	}
+	return false
+}
+
+// Compute the ICMPv4 checksum of the message (RFC 792), treating its checksum field as zero
+func icmpChecksum(packet []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(packet); i += 2 {
+		if i == 2 {
+			continue
+		}
+		sum += uint32(binary.BigEndian.Uint16(packet[i : i+2]))
+	}
+	if len(packet)%2 == 1 {
+		sum += uint32(packet[len(packet)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
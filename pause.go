@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Pausing of all probing (SIGUSR1 or the HTTP API), keeping statistics and leaving paused spans
+// out of them since no probes are sent meanwhile
+type pauser struct {
+	mutex   sync.Mutex    // Guards the fields below
+	paused  bool          // Whether probing is paused
+	resumed chan struct{} // Closed when probing resumes, replaced on each pause
+	spans   []pauseSpan   // Spans probing was paused for, the last still open while paused
+}
+
+// Span of time probing was paused for
+type pauseSpan struct {
+	Start time.Time `json:"start"`         // When probing was paused
+	End   time.Time `json:"end,omitempty"` // When probing resumed, zero while still paused
+}
+
+// Whether probing is paused and for which spans so far
+type pauseStatus struct {
+	Paused bool          `json:"paused"` // Whether probing is paused
+	Spans  []pauseSpan   `json:"spans"`  // Spans probing was paused for
+	Total  time.Duration `json:"total"`  // Time spent paused in total
+}
+
+var probing = &pauser{} // Pause state of all probing
+
+// Pause probing, returning false if it already was
+func (probing *pauser) pause() bool {
+	probing.mutex.Lock()
+	defer probing.mutex.Unlock()
+	if probing.paused {
+		return false
+	}
+	probing.paused = true
+	probing.resumed = make(chan struct{})
+	probing.spans = append(probing.spans, pauseSpan{Start: time.Now()})
+	slog.Info("Pausing probes...")
+	return true
+}
+
+// Resume probing, returning false if it wasn't paused
+func (probing *pauser) resume() bool {
+	probing.mutex.Lock()
+	defer probing.mutex.Unlock()
+	if !probing.paused {
+		return false
+	}
+	probing.paused = false
+	close(probing.resumed)
+	span := &probing.spans[len(probing.spans)-1]
+	span.End = time.Now()
+	slog.Info(fmt.Sprintf("Resuming probes after a pause of %s...", display(span.End.Sub(span.Start))))
+	return true
+}
+
+// Block while probing is paused, returning false if stopped meanwhile
+func (probing *pauser) wait(stop <-chan struct{}) bool {
+	probing.mutex.Lock()
+	paused, resumed := probing.paused, probing.resumed
+	probing.mutex.Unlock()
+	if !paused {
+		return true
+	}
+	select {
+	case <-stop:
+		return false
+	case <-resumed:
+		return true
+	}
+}
+
+// Pause state with the spans paused so far
+func (probing *pauser) status() pauseStatus {
+	probing.mutex.Lock()
+	defer probing.mutex.Unlock()
+	status := pauseStatus{Paused: probing.paused, Spans: append([]pauseSpan{}, probing.spans...)}
+	for _, span := range probing.spans {
+		end := span.End
+		if end.IsZero() {
+			end = time.Now()
+		}
+		status.Total += end.Sub(span.Start)
+	}
+	return status
+}
+
+// Time probing was paused for between the two times
+func (probing *pauser) pausedBetween(from time.Time, to time.Time) time.Duration {
+	probing.mutex.Lock()
+	defer probing.mutex.Unlock()
+	var paused time.Duration
+	for _, span := range probing.spans {
+		end := span.End
+		if end.IsZero() {
+			end = to
+		}
+		// Clip the span to the interval
+		start := span.Start
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			paused += end.Sub(start)
+		}
+	}
+	return paused
+}
+
+// Toggle pausing on every SIGUSR1, where the platform has it
+func listenForPause() {
+	toggles := make(chan os.Signal, 1)
+	if !notifyPauseSignal(toggles) {
+		return
+	}
+	for range toggles {
+		if !probing.pause() {
+			probing.resume()
+		}
+	}
+}
+
+// Print how often and how long probing was paused, if it ever was
+func showPauses() {
+	status := probing.status()
+	if len(status.Spans) > 0 {
+		fmt.Fprintf(noticeOutput(), "Paused: %d times\t\tTotal: %s (excluded from statistics)\n", len(status.Spans), display(status.Total))
+	}
+}
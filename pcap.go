@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+const pcapSnapLength uint32 = 65535 // Maximum packet length recorded in the -pcap file
+
+// Capture file of all sent and received ICMP packets (-pcap)
+type capture struct {
+	file   *os.File       // Underlying pcap file, written unbuffered so it survives ctrl-c
+	writer *pcapgo.Writer // Writer of pcap records
+	mutex  sync.Mutex     // Serializes records from concurrent targets
+}
+
+// Create the pcap file and write its header, using raw IP link type since the
+// socket only hands us ICMP messages and the IP header is rebuilt for each record
+func openCapture(path string) (*capture, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer := pcapgo.NewWriterNanos(file)
+	if err := writer.WriteFileHeader(pcapSnapLength, layers.LinkTypeRaw); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &capture{file: file, writer: writer}, nil
+}
+
+// Record an ICMP message wrapped in a synthesized IP header between the two addresses
+func (packetCapture *capture) write(timestamp time.Time, source net.IP, destination net.IP, hopLimit int, message []byte) error {
+	if source == nil {
+		source = unspecifiedAddress()
+	}
+	var network gopacket.SerializableLayer
+	if wantIPv6 {
+		network = &layers.IPv6{
+			Version:    6,
+			NextHeader: layers.IPProtocolICMPv6,
+			HopLimit:   uint8(hopLimit),
+			SrcIP:      source,
+			DstIP:      destination,
+		}
+	} else {
+		network = &layers.IPv4{
+			Version:  4,
+			IHL:      5,
+			TTL:      uint8(hopLimit),
+			Protocol: layers.IPProtocolICMPv4,
+			SrcIP:    source.To4(),
+			DstIP:    destination.To4(),
+		}
+	}
+	buffer := gopacket.NewSerializeBuffer()
+	options := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, options, network, gopacket.Payload(message)); err != nil {
+		return err
+	}
+
+	packetCapture.mutex.Lock()
+	defer packetCapture.mutex.Unlock()
+	return packetCapture.writer.WritePacket(gopacket.CaptureInfo{
+		Timestamp:     timestamp,
+		CaptureLength: len(buffer.Bytes()),
+		Length:        len(buffer.Bytes()),
+	}, buffer.Bytes())
+}
+
+// Close the pcap file
+func (packetCapture *capture) close() error {
+	packetCapture.mutex.Lock()
+	defer packetCapture.mutex.Unlock()
+	return packetCapture.file.Close()
+}
+
+// Find the local address the kernel would use to reach the IP, for the synthesized IP headers
+func routeSource(ip net.IP) net.IP {
+	network := "udp4"
+	if wantIPv6 {
+		network = "udp6"
+	}
+	// Connecting a UDP socket sends nothing but selects a route and source address
+	conn, err := net.DialUDP(network, nil, &net.UDPAddr{IP: ip, Port: 9})
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP
+}
+
+// Find the interface the kernel would use to reach the IP, by its source address, empty if unknown
+func routeDevice(ip net.IP) string {
+	source := routeSource(ip)
+	interfaces, err := net.Interfaces()
+	if source == nil || err != nil {
+		return ""
+	}
+	for _, device := range interfaces {
+		addresses, err := device.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, address := range addresses {
+			if network, ok := address.(*net.IPNet); ok && network.IP.Equal(source) {
+				return device.Name
+			}
+		}
+	}
+	return ""
+}
+
+// Unspecified address of the IP version in use
+func unspecifiedAddress() net.IP {
+	if wantIPv6 {
+		return net.IPv6unspecified
+	}
+	return net.IPv4zero
+}
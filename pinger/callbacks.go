@@ -0,0 +1,71 @@
+package pinger
+
+const eventBuffer int = 1024 // Events queued for callbacks before probing waits on them
+
+// Callbacks registered on a pinger, run in order by its dispatch goroutine. They are registered
+// before Run, those registered while it runs taking effect on the next run.
+type callbacks struct {
+	onSend    []func(ProbeResult) // Called as each probe is sent
+	onRecv    []func(ProbeResult) // Called as each reply arrives
+	onTimeout []func(ProbeResult) // Called as each probe is lost
+	onFinish  []func(Statistics)  // Called once Run is done
+}
+
+// Call the function as each probe is sent, with the probe's sequence number, address and send time
+func (pinger *Pinger) OnSend(callback func(ProbeResult)) {
+	pinger.mutex.Lock()
+	defer pinger.mutex.Unlock()
+	pinger.callbacks.onSend = append(pinger.callbacks.onSend, callback)
+}
+
+// Call the function as each reply arrives, with the probe's RTT and reply TTL
+func (pinger *Pinger) OnRecv(callback func(ProbeResult)) {
+	pinger.mutex.Lock()
+	defer pinger.mutex.Unlock()
+	pinger.callbacks.onRecv = append(pinger.callbacks.onRecv, callback)
+}
+
+// Call the function as each probe is lost, with Err saying why: ErrTimeout, or the error sending it
+func (pinger *Pinger) OnTimeout(callback func(ProbeResult)) {
+	pinger.mutex.Lock()
+	defer pinger.mutex.Unlock()
+	pinger.callbacks.onTimeout = append(pinger.callbacks.onTimeout, callback)
+}
+
+// Call the function with the final statistics once Run is done, after every other callback
+func (pinger *Pinger) OnFinish(callback func(Statistics)) {
+	pinger.mutex.Lock()
+	defer pinger.mutex.Unlock()
+	pinger.callbacks.onFinish = append(pinger.callbacks.onFinish, callback)
+}
+
+// Start the goroutine running callbacks so slow ones don't delay probes, returning a function
+// that queues the final statistics for OnFinish and waits for every queued callback to run
+func (pinger *Pinger) startDispatch() func() {
+	pinger.mutex.Lock()
+	pinger.active = pinger.callbacks
+	pinger.mutex.Unlock()
+	pinger.events = make(chan func(), eventBuffer)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range pinger.events {
+			event()
+		}
+	}()
+	return func() {
+		statistics := pinger.Statistics()
+		for _, callback := range pinger.active.onFinish {
+			pinger.events <- func() { callback(statistics) }
+		}
+		close(pinger.events)
+		<-done
+	}
+}
+
+// Queue the callbacks of an event for the dispatch goroutine, waiting if it is eventBuffer behind
+func (pinger *Pinger) dispatch(registered []func(ProbeResult), result ProbeResult) {
+	for _, callback := range registered {
+		pinger.events <- func() { callback(result) }
+	}
+}
@@ -0,0 +1,94 @@
+package pinger
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Option configures a Pinger created with New
+type Option func(*Pinger) error
+
+// Send a probe every interval, 1s by default
+func WithInterval(interval time.Duration) Option {
+	return func(pinger *Pinger) error {
+		if interval <= 0 {
+			return errors.New("Interval must be positive")
+		}
+		pinger.interval = interval
+		return nil
+	}
+}
+
+// Wait this long for the reply to each probe before counting it lost, 10s by default
+func WithTimeout(timeout time.Duration) Option {
+	return func(pinger *Pinger) error {
+		if timeout <= 0 {
+			return errors.New("Timeout must be positive")
+		}
+		pinger.timeout = timeout
+		return nil
+	}
+}
+
+// Send probes with this TTL (IPv4) or hop limit (IPv6), 64 by default
+func WithTTL(ttl int) Option {
+	return func(pinger *Pinger) error {
+		if ttl < 1 || ttl > 255 {
+			return fmt.Errorf("TTL must be between 1 and 255, got %d", ttl)
+		}
+		pinger.ttl = ttl
+		return nil
+	}
+}
+
+// Carry this many bytes of payload in each probe, 18 by default
+func WithPayloadSize(size int) Option {
+	return func(pinger *Pinger) error {
+		if size < 0 || size > MaxPayloadSize {
+			return fmt.Errorf("Payload size must be between 0 and %d, got %d", MaxPayloadSize, size)
+		}
+		pinger.payloadSize = size
+		return nil
+	}
+}
+
+// Send probes over a raw ICMP socket, which needs root or CAP_NET_RAW, rather than an unprivileged
+// ICMP datagram socket (net.ipv4.ping_group_range on Linux). Probes are privileged by default, as goPing's are.
+func WithPrivileged(privileged bool) Option {
+	return func(pinger *Pinger) error {
+		pinger.privileged = privileged
+		return nil
+	}
+}
+
+// Resolve the target with this resolver rather than net.DefaultResolver
+func WithResolver(resolver *net.Resolver) Option {
+	return func(pinger *Pinger) error {
+		if resolver == nil {
+			return errors.New("Resolver must not be nil")
+		}
+		pinger.resolver = resolver
+		return nil
+	}
+}
+
+// Stop after this many probes, -1 to probe until the context given to Run is done (the default)
+func WithCount(count int) Option {
+	return func(pinger *Pinger) error {
+		if count < -1 || count == 0 {
+			return fmt.Errorf("Count must be positive, or -1 for no limit, got %d", count)
+		}
+		pinger.count = count
+		return nil
+	}
+}
+
+// Probe the target's IPv6 address rather than its IPv4 address
+func WithIPv6(ipv6 bool) Option {
+	return func(pinger *Pinger) error {
+		pinger.ipv6 = ipv6
+		return nil
+	}
+}
@@ -0,0 +1,410 @@
+// Package pinger probes a target with ICMP echo requests and gathers statistics of the replies,
+// for embedding goPing's probing in other programs:
+//
+//	p, err := pinger.New("example.com", pinger.WithCount(5), pinger.WithInterval(500*time.Millisecond))
+//	if err != nil {
+//		return err
+//	}
+//	if err := p.Run(ctx); err != nil {
+//		return err
+//	}
+//	fmt.Println(p.Statistics())
+package pinger
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	DefaultInterval    time.Duration = time.Second          // Interval between probes unless WithInterval is given
+	DefaultTimeout     time.Duration = 10 * time.Second     // Deadline of each probe's reply unless WithTimeout is given
+	DefaultTTL         int           = 64                   // TTL of probes unless WithTTL is given
+	DefaultPayloadSize int           = len(payloadPattern)  // Payload bytes of probes unless WithPayloadSize is given
+	MaxPayloadSize     int           = 65535 - 20 - 8       // Largest payload of an IPv4 echo request
+	payloadPattern     string        = "PLS-GIB-INTERNSHIP" // Repeated to fill payloads, as in goPing's own probes
+	maxReplySize       int           = MaxPayloadSize + 8   // Buffer size for replies, fitting the largest echo
+)
+
+// Returned as the error of probes whose reply didn't arrive within the timeout
+var ErrTimeout = errors.New("Request timed out")
+
+var echoIDs atomic.Uint32 // Pingers created so far, offsetting each one's echo identifier
+
+// Pinger probes a single target every interval, created with New and started with Run
+type Pinger struct {
+	target      string           // Hostname or IP address to probe
+	interval    time.Duration    // Interval between probes
+	timeout     time.Duration    // Deadline of each probe's reply
+	ttl         int              // TTL or hop limit of probes
+	payloadSize int              // Payload bytes of each probe
+	privileged  bool             // Whether to use a raw socket rather than an unprivileged datagram one
+	resolver    *net.Resolver    // Resolver of the target
+	count       int              // Probes to send, -1 for no limit
+	ipv6        bool             // Whether to probe the target's IPv6 address
+	id          int              // ICMP echo identifier of this pinger's probes
+	active      callbacks        // Callbacks of the current run, only read by the probing goroutine
+	events      chan func()      // Callbacks queued for the dispatch goroutine while running
+	mutex       sync.Mutex       // Guards the fields below, read while probing
+	callbacks   callbacks        // Callbacks registered for the next run
+	results     chan ProbeResult // Channel of the next run's results, nil unless Results was called
+	ip          net.IP           // Address the target resolved to, nil before Run
+	sent        int              // Probes sent
+	received    int              // Probes replied to
+	minRTT      time.Duration    // Smallest RTT
+	maxRTT      time.Duration    // Largest RTT
+	totalRTT    time.Duration    // Sum of RTTs for averaging
+	squaredRTT  float64          // Sum of squared RTTs in nanoseconds for the standard deviation
+}
+
+// Outcome of a single probe
+type ProbeResult struct {
+	Seq  int           // Sequence number of the probe, from 0
+	IP   net.IP        // Address probed
+	Time time.Time     // When the probe was sent
+	RTT  time.Duration // Round trip time, 0 if lost
+	TTL  int           // TTL or hop limit of the reply, 0 if lost or unknown
+	Err  error         // Why the probe was lost, nil if it was replied to
+}
+
+// Statistics of the probes sent so far
+type Statistics struct {
+	Target    string        // Hostname or IP address as given
+	IP        net.IP        // Address the target resolved to
+	Sent      int           // Probes sent
+	Received  int           // Probes replied to
+	Loss      float64       // Percent loss
+	MinRTT    time.Duration // Smallest RTT
+	AvgRTT    time.Duration // Mean RTT
+	MaxRTT    time.Duration // Largest RTT
+	StdDevRTT time.Duration // Standard deviation of RTTs
+}
+
+// Create a pinger for the target, configured by the options and defaulting to goPing's own settings
+func New(target string, options ...Option) (*Pinger, error) {
+	if target == "" {
+		return nil, errors.New("Please enter a target to ping")
+	}
+	pinger := &Pinger{
+		target:      target,
+		interval:    DefaultInterval,
+		timeout:     DefaultTimeout,
+		ttl:         DefaultTTL,
+		payloadSize: DefaultPayloadSize,
+		privileged:  true,
+		resolver:    net.DefaultResolver,
+		count:       -1,
+		id:          (os.Getpid() + int(echoIDs.Add(1)) - 1) & 0xffff,
+	}
+	for _, option := range options {
+		if err := option(pinger); err != nil {
+			return nil, err
+		}
+	}
+	return pinger, nil
+}
+
+// Resolve the target and probe it until the count is reached or the context is done, returning
+// an error only if probing couldn't start. Lost probes are counted in the statistics instead.
+// Probes are sent every interval even while earlier ones await their replies, so the timeout may
+// exceed the interval, and each is reported as it finishes, a lost probe after later replies.
+func (pinger *Pinger) Run(ctx context.Context) error {
+	finish := pinger.startDispatch()
+	defer finish()
+	results := pinger.takeResults()
+	if results != nil {
+		defer close(results)
+	}
+	ip, err := pinger.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	pinger.mutex.Lock()
+	pinger.ip = ip
+	pinger.mutex.Unlock()
+
+	receiving, err := acquireSocket(pinger.privileged, receiverKey{ipv6: pinger.ipv6, ttl: pinger.ttl})
+	if err != nil {
+		return err
+	}
+	defer receiving.release()
+	probing := pinger.newProber(receiving, ip)
+	defer probing.stop()
+
+	// Send a probe every interval while earlier ones still await their replies, so the timeout
+	// may exceed the interval, finishing each probe as its reply arrives or its deadline passes
+	for pinger.count == -1 || probing.next < pinger.count || probing.oldest < probing.next {
+		select {
+		case <-ctx.Done():
+			// Probes cut short by the context were neither replied to nor lost
+			probing.forgetAll(pinger.id)
+			return nil
+		case <-probing.timer.C:
+			pinger.send(ctx, probing, results)
+			if pinger.count == -1 || probing.next < pinger.count {
+				probing.timer.Reset(pinger.interval)
+			}
+		case reply := <-probing.replies:
+			if flight := probing.flight(reply.seq); flight != nil {
+				flight.result.RTT, flight.result.TTL = reply.received.Sub(flight.result.Time), reply.ttl
+				pinger.finish(ctx, probing, flight, results)
+			}
+		case <-probing.deadline.C:
+			// Time out every probe past its deadline, oldest first
+			now := time.Now()
+			for oldest := probing.flight(probing.oldest); oldest != nil && !now.Before(oldest.result.Time.Add(pinger.timeout)); oldest = probing.flight(probing.oldest) {
+				oldest.result.Err = ErrTimeout
+				pinger.finish(ctx, probing, oldest, results)
+			}
+		}
+		pinger.resetDeadline(probing)
+	}
+	return nil
+}
+
+// Statistics of the probes sent so far, safe to call while probing
+func (pinger *Pinger) Statistics() Statistics {
+	pinger.mutex.Lock()
+	defer pinger.mutex.Unlock()
+	statistics := Statistics{
+		Target:   pinger.target,
+		IP:       pinger.ip,
+		Sent:     pinger.sent,
+		Received: pinger.received,
+		MinRTT:   pinger.minRTT,
+		MaxRTT:   pinger.maxRTT,
+	}
+	if pinger.sent > 0 {
+		statistics.Loss = float64(pinger.sent-pinger.received) / float64(pinger.sent) * 100
+	}
+	if pinger.received > 0 {
+		mean := float64(pinger.totalRTT) / float64(pinger.received)
+		statistics.AvgRTT = time.Duration(mean)
+		statistics.StdDevRTT = time.Duration(math.Sqrt(max(pinger.squaredRTT/float64(pinger.received)-mean*mean, 0)))
+	}
+	return statistics
+}
+
+// Describe the statistics in goPing's summary style
+func (statistics Statistics) String() string {
+	return fmt.Sprintf(
+		"%s (%s): %d sent, %d received, %.2f%% loss, RTT min/avg/max/stddev %s/%s/%s/%s",
+		statistics.Target,
+		statistics.IP,
+		statistics.Sent,
+		statistics.Received,
+		statistics.Loss,
+		statistics.MinRTT,
+		statistics.AvgRTT,
+		statistics.MaxRTT,
+		statistics.StdDevRTT)
+}
+
+// Resolve the target to an address of the IP version in use
+func (pinger *Pinger) resolve(ctx context.Context) (net.IP, error) {
+	network := "ip4"
+	if pinger.ipv6 {
+		network = "ip6"
+	}
+	ips, err := pinger.resolver.LookupIP(ctx, network, pinger.target)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("%s has no %s address", pinger.target, network)
+	}
+	return ips[0], nil
+}
+
+// State reused by every probe of a run, so steady probing doesn't allocate per probe
+type prober struct {
+	receiving   socket       // Socket probes are sent and replies read on
+	ip          net.IP       // Address probed
+	destination net.Addr     // Address probed as the socket addresses it
+	requestType byte         // ICMP type of echo requests of the IP version
+	request     []byte       // Buffer probes are encoded in
+	payload     []byte       // Payload of every probe
+	flights     []flight     // Probes that may be in flight by sequence number modulo their number
+	replies     chan arrival // Receives the replies of every probe in flight
+	next        int          // Sequence number of the next probe
+	oldest      int          // Sequence number of the oldest probe that may still be in flight
+	outgoing    outgoing     // Probe being sent, queued for a batched send
+	timer       *time.Timer  // Interval until the next probe
+	deadline    *time.Timer  // Deadline of the oldest probe in flight
+}
+
+// Probe sent and awaiting its reply
+type flight struct {
+	result  ProbeResult  // Outcome of the probe, with when it was sent
+	pending pendingReply // Reply awaited, registered with the receiver
+	waiting bool         // Whether the probe is still in flight
+}
+
+// Set up the state of a run probing the address on the receiver, with room for as many probes in
+// flight as are sent within the timeout
+func (pinger *Pinger) newProber(receiving socket, ip net.IP) *prober {
+	inFlight := int(pinger.timeout/pinger.interval) + 2
+	probing := &prober{
+		receiving:   receiving,
+		ip:          ip,
+		destination: receiving.destination(ip),
+		requestType: byte(ipv4.ICMPTypeEcho),
+		request:     make([]byte, 0, 8+pinger.payloadSize),
+		payload:     payload(pinger.payloadSize),
+		flights:     make([]flight, inFlight),
+		// Room for a reply to every probe in flight and a late one to each after its deadline,
+		// so the read loop never waits
+		replies:  make(chan arrival, 2*inFlight),
+		timer:    time.NewTimer(0),
+		deadline: time.NewTimer(pinger.timeout),
+	}
+	probing.deadline.Stop()
+	for i := range probing.flights {
+		probing.flights[i].pending = pendingReply{ip: ip, replies: probing.replies}
+	}
+	if pinger.ipv6 {
+		probing.requestType = byte(ipv6.ICMPTypeEchoRequest)
+	}
+	return probing
+}
+
+// Probe with the sequence number if it is still in flight, nil if it isn't
+func (probing *prober) flight(seq int) *flight {
+	flight := &probing.flights[seq%len(probing.flights)]
+	if !flight.waiting || flight.result.Seq != seq {
+		return nil
+	}
+	return flight
+}
+
+// Send the next probe, making room for it by timing out the oldest in flight if the interval
+// fell so far behind that every slot is taken
+func (pinger *Pinger) send(ctx context.Context, probing *prober, results chan<- ProbeResult) {
+	seq := probing.next
+	probing.next++
+	sending := &probing.flights[seq%len(probing.flights)]
+	if sending.waiting {
+		sending.result.Err = ErrTimeout
+		pinger.finish(ctx, probing, sending, results)
+	}
+	sending.result = ProbeResult{Seq: seq, IP: probing.ip}
+	sending.pending.seq = seq
+	sending.waiting = true
+	probing.request = encodeEcho(probing.request, probing.requestType, pinger.id, seq, probing.payload, !pinger.ipv6)
+
+	// Expect the reply before sending so a fast one isn't missed
+	probing.receiving.expect(&sending.pending, pinger.id, seq&0xffff)
+	var err error
+	if sending.result.Time, err = probing.receiving.send(&probing.outgoing, probing.request, probing.destination); err != nil {
+		sending.result.Err = err
+		pinger.finish(ctx, probing, sending, results)
+		return
+	}
+	pinger.dispatch(pinger.active.onSend, sending.result)
+}
+
+// Count the probe replied to or lost and report it, no longer expecting its reply
+func (pinger *Pinger) finish(ctx context.Context, probing *prober, finished *flight, results chan<- ProbeResult) {
+	finished.waiting = false
+	probing.receiving.forget(pinger.id, finished.result.Seq&0xffff)
+	pinger.record(finished.result)
+	if finished.result.Err == nil {
+		pinger.dispatch(pinger.active.onRecv, finished.result)
+	} else {
+		pinger.dispatch(pinger.active.onTimeout, finished.result)
+	}
+	deliver(ctx, results, finished.result)
+	// Move past probes finished ahead of older ones
+	for probing.oldest < probing.next && probing.flight(probing.oldest) == nil {
+		probing.oldest++
+	}
+}
+
+// Wake at the deadline of the oldest probe in flight, if any is
+func (pinger *Pinger) resetDeadline(probing *prober) {
+	probing.deadline.Stop()
+	if oldest := probing.flight(probing.oldest); oldest != nil {
+		probing.deadline.Reset(time.Until(oldest.result.Time.Add(pinger.timeout)))
+	}
+}
+
+// Stop expecting the replies of every probe in flight
+func (probing *prober) forgetAll(id int) {
+	for i := range probing.flights {
+		if probing.flights[i].waiting {
+			probing.flights[i].waiting = false
+			probing.receiving.forget(id, probing.flights[i].result.Seq&0xffff)
+		}
+	}
+}
+
+// Stop the timers of the run
+func (probing *prober) stop() {
+	probing.timer.Stop()
+	probing.deadline.Stop()
+}
+
+// Encode an echo request into the buffer, reusing its capacity. IPv4 requests are checksummed,
+// while the kernel fills in the checksum of IPv6 ones, which covers a pseudo-header.
+func encodeEcho(buffer []byte, requestType byte, id int, seq int, data []byte, checksum bool) []byte {
+	buffer = append(buffer[:0], requestType, 0, 0, 0)
+	buffer = binary.BigEndian.AppendUint16(buffer, uint16(id))
+	buffer = binary.BigEndian.AppendUint16(buffer, uint16(seq))
+	buffer = append(buffer, data...)
+	if checksum {
+		binary.BigEndian.PutUint16(buffer[2:4], internetChecksum(buffer))
+	}
+	return buffer
+}
+
+// RFC 1071 Internet checksum of a message whose checksum field is zero
+func internetChecksum(message []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(message); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(message[i : i+2]))
+	}
+	if len(message)%2 == 1 {
+		sum += uint32(message[len(message)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+// Add the probe's outcome to the statistics
+func (pinger *Pinger) record(result ProbeResult) {
+	pinger.mutex.Lock()
+	defer pinger.mutex.Unlock()
+	pinger.sent++
+	if result.Err != nil {
+		return
+	}
+	if pinger.received == 0 || result.RTT < pinger.minRTT {
+		pinger.minRTT = result.RTT
+	}
+	pinger.maxRTT = max(pinger.maxRTT, result.RTT)
+	pinger.received++
+	pinger.totalRTT += result.RTT
+	pinger.squaredRTT += float64(result.RTT) * float64(result.RTT)
+}
+
+// Payload of the given size repeating goPing's pattern
+func payload(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = payloadPattern[i%len(payloadPattern)]
+	}
+	return data
+}
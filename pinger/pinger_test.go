@@ -0,0 +1,175 @@
+package pinger
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+// Socket replying to each probe in memory after the delay of its sequence number, never for a negative
+// one, its replies demultiplexed by a receiver without a socket as read ones are
+type fakeSocket struct {
+	*receiver
+	replyID int                         // Echo identifier of replies, as the kernel replaces it on unprivileged sockets, 0 for the probe's
+	delay   func(seq int) time.Duration // Delay of the reply to each probe
+}
+
+// Reply to the probe once its delay passes, as testPeer would
+func (fake *fakeSocket) send(_ *outgoing, encoded []byte, _ net.Addr) (time.Time, error) {
+	sent := time.Now()
+	id, seq := int(binary.BigEndian.Uint16(encoded[4:6])), int(binary.BigEndian.Uint16(encoded[6:8]))
+	if fake.replyID != 0 {
+		id = fake.replyID
+	}
+	if delay := fake.delay(seq); delay >= 0 {
+		reply := encodeEcho(nil, byte(ipv4.ICMPTypeEchoReply), id, seq, encoded[8:], true)
+		time.AfterFunc(delay, func() { fake.deliver(reply, DefaultTTL, testPeer, time.Now()) })
+	}
+	return sent, nil
+}
+
+// Nothing to release without a socket
+func (fake *fakeSocket) release() {}
+
+// Run a pinger of testPeer on the fake socket, returning its results in the order reported
+func runFake(t *testing.T, fake *fakeSocket, options ...Option) ([]ProbeResult, Statistics) {
+	t.Helper()
+	acquire := acquireSocket
+	acquireSocket = func(bool, receiverKey) (socket, error) { return fake, nil }
+	t.Cleanup(func() { acquireSocket = acquire })
+	pinger, err := New(testPeer.String(), append(options, WithPrivileged(fake.privileged))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := pinger.Results()
+	done := make(chan error, 1)
+	go func() { done <- pinger.Run(context.Background()) }()
+	var reported []ProbeResult
+	for result := range results {
+		reported = append(reported, result)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	return reported, pinger.Statistics()
+}
+
+// Sequence numbers of the results in the order reported
+func reportedSeqs(results []ProbeResult) []int {
+	seqs := make([]int, len(results))
+	for i, result := range results {
+		seqs[i] = result.Seq
+	}
+	return seqs
+}
+
+// Replies to probes all in flight at once arrive newest first, each matched to its own probe
+func TestRunProbesInFlight(t *testing.T) {
+	const count, interval, step = 5, 20 * time.Millisecond, 60 * time.Millisecond
+	delay := func(seq int) time.Duration { return time.Duration(count-seq) * step }
+	tests := []struct {
+		name       string
+		privileged bool
+		replyID    int
+	}{
+		{"privileged", true, 0},
+		{"unprivileged with the kernel's identifier", false, 0xbeef},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := &fakeSocket{receiver: newTestReceiver(test.privileged, false), replyID: test.replyID, delay: delay}
+			results, statistics := runFake(t, fake, WithCount(count), WithInterval(interval), WithTimeout(time.Second))
+			if got, want := reportedSeqs(results), []int{4, 3, 2, 1, 0}; !slices.Equal(got, want) {
+				t.Fatalf("Results reported for seqs %v, want %v", got, want)
+			}
+			for _, result := range results {
+				if result.Err != nil || result.RTT < delay(result.Seq) || result.TTL != DefaultTTL {
+					t.Errorf("Seq %d got RTT %s, TTL %d and error %v, want RTT of at least %s, TTL %d and no error",
+						result.Seq, result.RTT, result.TTL, result.Err, delay(result.Seq), DefaultTTL)
+				}
+			}
+			if statistics.Sent != count || statistics.Received != count {
+				t.Errorf("%d sent and %d received, want %d of each", statistics.Sent, statistics.Received, count)
+			}
+		})
+	}
+}
+
+// Probes unanswered or answered past their deadline time out after later replies, in the order sent
+func TestRunTimesOutProbesInFlight(t *testing.T) {
+	const count, interval, timeout = 6, 20 * time.Millisecond, 200 * time.Millisecond
+	fake := &fakeSocket{receiver: newTestReceiver(true, false), delay: func(seq int) time.Duration {
+		switch seq {
+		case 0:
+			return -1
+		case 2, 4:
+			return 2 * timeout
+		}
+		return 5 * time.Millisecond
+	}}
+	results, statistics := runFake(t, fake, WithCount(count), WithInterval(interval), WithTimeout(timeout))
+	if got, want := reportedSeqs(results), []int{1, 3, 5, 0, 2, 4}; !slices.Equal(got, want) {
+		t.Fatalf("Results reported for seqs %v, want %v", got, want)
+	}
+	for _, result := range results {
+		lost := result.Seq%2 == 0
+		if lost && (!errors.Is(result.Err, ErrTimeout) || result.RTT != 0) {
+			t.Errorf("Seq %d got RTT %s and error %v, want it timed out", result.Seq, result.RTT, result.Err)
+		}
+		if !lost && result.Err != nil {
+			t.Errorf("Seq %d got error %v, want a reply", result.Seq, result.Err)
+		}
+	}
+	if statistics.Sent != count || statistics.Received != count/2 {
+		t.Errorf("%d sent and %d received, want %d and %d", statistics.Sent, statistics.Received, count, count/2)
+	}
+	// The late replies arrive once the run is done, finding no probe still awaiting them
+	time.Sleep(2 * timeout)
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	if len(fake.waiting) != 0 {
+		t.Errorf("%d probes still awaited after the run, want none", len(fake.waiting))
+	}
+}
+
+func BenchmarkEncodeEcho(b *testing.B) {
+	buffer := make([]byte, 0, 8+DefaultPayloadSize)
+	data := payload(DefaultPayloadSize)
+	b.ReportAllocs()
+	for seq := 0; b.Loop(); seq++ {
+		buffer = encodeEcho(buffer, 8, 0x1234, seq, data, true)
+	}
+}
+
+// Send a probe to localhost and finish it once its reply is delivered, as Run does for every probe
+func BenchmarkSendReceive(b *testing.B) {
+	receiving, err := acquireReceiver(true, receiverKey{ttl: DefaultTTL})
+	if err != nil {
+		b.Skip(err)
+	}
+	defer receiving.release()
+	pinger, err := New("127.0.0.1")
+	if err != nil {
+		b.Fatal(err)
+	}
+	probing := pinger.newProber(receiving, net.IPv4(127, 0, 0, 1))
+	defer probing.stop()
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		pinger.send(ctx, probing, nil)
+		reply := <-probing.replies
+		finished := probing.flight(reply.seq)
+		if finished == nil {
+			b.Fatalf("Reply to seq %d matched no probe in flight", reply.seq)
+		}
+		finished.result.RTT, finished.result.TTL = reply.received.Sub(finished.result.Time), reply.ttl
+		pinger.finish(ctx, probing, finished, nil)
+	}
+}
@@ -0,0 +1,213 @@
+package pinger
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Sockets shared by privileged pingers of the same IP version and TTL, each opened by the first
+// pinger running and closed once the last one is done, so hundreds of targets share one raw socket
+var (
+	sharedReceivers = make(map[receiverKey]*receiver) // Open shared receivers
+	receiversMutex  sync.Mutex                        // Guards sharedReceivers and their users
+)
+
+const sharedReadBuffer int = 4 << 20 // Receive buffer size requested for shared sockets, capped by the kernel
+
+// Socket settings pingers must agree on to share a receiver, TTL being set per socket
+type receiverKey struct {
+	ipv6 bool // Whether the socket is IPv6
+	ttl  int  // TTL or hop limit of probes sent on the socket
+}
+
+// Socket sending probes and demultiplexing replies to the probes waiting for them by echo identifier
+// and sequence number, shared by privileged pingers. Unprivileged pingers each have their own, as the
+// kernel picks their echo identifier per socket.
+type receiver struct {
+	key        receiverKey               // Settings of the socket
+	privileged bool                      // Whether the socket is raw rather than an unprivileged datagram socket
+	conn       *icmp.PacketConn          // Socket probes are sent and replies read on
+	users      int                       // Pingers running on a shared receiver
+	mutex      sync.Mutex                // Guards waiting
+	waiting    map[echoKey]*pendingReply // Probes awaiting replies
+	queue      chan *outgoing            // Probes waiting to be sent in a batch, where sends are batched
+	closed     chan struct{}             // Closed once the socket is released for good
+}
+
+// Socket a run sends its probes on and awaits their replies from, a receiver unless faked in tests
+type socket interface {
+	expect(pending *pendingReply, id int, seq int)                                 // Expect a reply to a probe
+	forget(id int, seq int)                                                        // Stop expecting it
+	destination(ip net.IP) net.Addr                                                // Address probes to the IP
+	send(probe *outgoing, encoded []byte, destination net.Addr) (time.Time, error) // Send a probe
+	release()                                                                      // Leave the socket once the run is done
+}
+
+// Take the socket of a run for the pinger's settings, replaced in tests to probe without one
+var acquireSocket = func(privileged bool, key receiverKey) (socket, error) {
+	receiving, err := acquireReceiver(privileged, key)
+	if err != nil {
+		return nil, err
+	}
+	return receiving, nil
+}
+
+// Echo identifier and sequence number matching a reply to its probe, the identifier 0 on unprivileged sockets
+type echoKey struct {
+	id  int // Echo identifier
+	seq int // Sequence number
+}
+
+// Probe awaiting its reply
+type pendingReply struct {
+	ip      net.IP       // Address probed, which the reply must come from
+	seq     int          // Sequence number of the probe, before wrapping to 16 bits
+	replies chan arrival // Receives the reply, shared by the pinger's probes and buffered so the read loop never waits
+}
+
+// Reply read for a waiting probe
+type arrival struct {
+	seq      int       // Sequence number of the probe replied to
+	received time.Time // When the reply was read
+	ttl      int       // TTL or hop limit of the reply, 0 if unknown
+}
+
+// Take the receiver for the pinger's settings, joining a shared one if privileged or opening its own
+func acquireReceiver(privileged bool, key receiverKey) (*receiver, error) {
+	if !privileged {
+		return openReceiver(false, key)
+	}
+	receiversMutex.Lock()
+	defer receiversMutex.Unlock()
+	shared, ok := sharedReceivers[key]
+	if !ok {
+		var err error
+		if shared, err = openReceiver(true, key); err != nil {
+			return nil, err
+		}
+		sharedReceivers[key] = shared
+	}
+	shared.users++
+	return shared, nil
+}
+
+// Leave the receiver, closing its socket once no pinger uses it
+func (receiving *receiver) release() {
+	if !receiving.privileged {
+		close(receiving.closed)
+		receiving.conn.Close()
+		return
+	}
+	receiversMutex.Lock()
+	defer receiversMutex.Unlock()
+	receiving.users--
+	if receiving.users == 0 {
+		delete(sharedReceivers, receiving.key)
+		close(receiving.closed)
+		receiving.conn.Close()
+	}
+}
+
+// Open the ICMP socket, raw if privileged, and start reading replies from it
+func openReceiver(privileged bool, key receiverKey) (*receiver, error) {
+	network, address := "udp4", "0.0.0.0"
+	switch {
+	case privileged && key.ipv6:
+		network, address = "ip6:ipv6-icmp", "::"
+	case privileged:
+		network = "ip4:icmp"
+	case key.ipv6:
+		network, address = "udp6", "::"
+	}
+	conn, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+	// Set the TTL of probes and have the TTL of replies delivered, where the platform allows
+	if key.ipv6 {
+		conn.IPv6PacketConn().SetHopLimit(key.ttl)
+		conn.IPv6PacketConn().SetControlMessage(ipv6.FlagHopLimit, true)
+	} else {
+		conn.IPv4PacketConn().SetTTL(key.ttl)
+		conn.IPv4PacketConn().SetControlMessage(ipv4.FlagTTL, true)
+	}
+	// Filter raw sockets to echo replies and make room for bursts of them from many pingers
+	if privileged {
+		var socket net.PacketConn
+		if key.ipv6 {
+			var filter ipv6.ICMPFilter
+			filter.SetAll(true)
+			filter.Accept(ipv6.ICMPTypeEchoReply)
+			conn.IPv6PacketConn().SetICMPFilter(&filter)
+			socket = conn.IPv6PacketConn().PacketConn
+		} else {
+			var filter ipv4.ICMPFilter
+			filter.SetAll(true)
+			filter.Accept(ipv4.ICMPTypeEchoReply)
+			conn.IPv4PacketConn().SetICMPFilter(&filter)
+			socket = conn.IPv4PacketConn().PacketConn
+		}
+		if buffered, ok := socket.(interface{ SetReadBuffer(int) error }); ok {
+			buffered.SetReadBuffer(sharedReadBuffer)
+		}
+	}
+	receiving := &receiver{key: key, privileged: privileged, conn: conn, waiting: make(map[echoKey]*pendingReply), closed: make(chan struct{})}
+	receiving.start()
+	return receiving, nil
+}
+
+// Expect a reply to the probe, to be delivered on the pending reply's channel tagged with its sequence
+// number, so a late reply to a probe already timed out can be told apart and discarded
+func (receiving *receiver) expect(pending *pendingReply, id int, seq int) {
+	receiving.mutex.Lock()
+	defer receiving.mutex.Unlock()
+	receiving.waiting[receiving.echoKey(id, seq)] = pending
+}
+
+// Stop expecting a reply to the probe, once it arrived or timed out
+func (receiving *receiver) forget(id int, seq int) {
+	receiving.mutex.Lock()
+	defer receiving.mutex.Unlock()
+	delete(receiving.waiting, receiving.echoKey(id, seq))
+}
+
+// Key of a probe, ignoring the echo identifier the kernel replaces on unprivileged sockets
+func (receiving *receiver) echoKey(id int, seq int) echoKey {
+	if !receiving.privileged {
+		id = 0
+	}
+	return echoKey{id: id, seq: seq}
+}
+
+// Address an encoded probe to the IP, by UDP address on unprivileged datagram sockets
+func (receiving *receiver) destination(ip net.IP) net.Addr {
+	if !receiving.privileged {
+		return &net.UDPAddr{IP: ip}
+	}
+	return &net.IPAddr{IP: ip}
+}
+
+// Hand a packet read at the given time to the probe waiting for it, if it is an echo reply to one.
+// Only the echo header is decoded, rather than parsing the whole message, to keep reading allocation free.
+func (receiving *receiver) deliver(packet []byte, ttl int, peer net.IP, received time.Time) {
+	replyType := byte(ipv4.ICMPTypeEchoReply)
+	if receiving.key.ipv6 {
+		replyType = byte(ipv6.ICMPTypeEchoReply)
+	}
+	if len(packet) < 8 || packet[0] != replyType || packet[1] != 0 {
+		return
+	}
+	key := receiving.echoKey(int(binary.BigEndian.Uint16(packet[4:6])), int(binary.BigEndian.Uint16(packet[6:8])))
+	receiving.mutex.Lock()
+	defer receiving.mutex.Unlock()
+	if pending, ok := receiving.waiting[key]; ok && pending.ip.Equal(peer) {
+		delete(receiving.waiting, key)
+		pending.replies <- arrival{seq: pending.seq, received: received, ttl: ttl}
+	}
+}
@@ -0,0 +1,252 @@
+package pinger
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	batchSize      int = 64   // Most probes sent with one sendmmsg, or replies read with one recvmmsg
+	batchReplySize int = 1500 // Buffer size of each reply read in a batch, enough for the echo header of any reply
+	batchOOBSize   int = 64   // Buffer size of each reply's control messages, enough for its TTL or hop limit
+)
+
+// Raised to probes still queued when their socket is released
+var errReceiverClosed = errors.New("Socket closed")
+
+// Probe queued for the next batched send
+type outgoing struct {
+	encoded     []byte           // Encoded probe
+	destination net.Addr         // Address probed
+	done        chan sendOutcome // Receives when the probe was sent, buffered so the sender never waits
+}
+
+// Outcome of a batched send
+type sendOutcome struct {
+	sent time.Time // When the batch was handed to the kernel
+	err  error     // Why the probe couldn't be sent, nil if it was
+}
+
+// Start reading replies and sending queued probes in batches
+func (receiving *receiver) start() {
+	receiving.queue = make(chan *outgoing, batchSize)
+	go receiving.readLoop()
+	go receiving.writeLoop()
+}
+
+// Queue an encoded probe to the address for the next batch, returning when it was sent.
+// The probe's queue entry is reused by each of its pinger's probes, only one being in flight.
+func (receiving *receiver) send(probe *outgoing, encoded []byte, destination net.Addr) (time.Time, error) {
+	if probe.done == nil {
+		probe.done = make(chan sendOutcome, 1)
+	}
+	probe.encoded, probe.destination = encoded, destination
+	select {
+	case receiving.queue <- probe:
+	case <-receiving.closed:
+		return time.Time{}, errReceiverClosed
+	}
+	outcome := <-probe.done
+	return outcome.sent, outcome.err
+}
+
+// Send queued probes until the socket is released, every probe queued meanwhile going out with one
+// sendmmsg, so many pingers probing at once cost few syscalls without delaying a lone probe
+func (receiving *receiver) writeLoop() {
+	messages := make([]ipv4.Message, batchSize)
+	for i := range messages {
+		messages[i].Buffers = make([][]byte, 1)
+	}
+	batch := make([]*outgoing, 0, batchSize)
+	for {
+		batch = batch[:0]
+		select {
+		case probe := <-receiving.queue:
+			batch = append(batch, probe)
+		case <-receiving.closed:
+			return
+		}
+		// Take every other probe already waiting
+	draining:
+		for len(batch) < batchSize {
+			select {
+			case probe := <-receiving.queue:
+				batch = append(batch, probe)
+			default:
+				break draining
+			}
+		}
+		for i, probe := range batch {
+			messages[i].Buffers[0], messages[i].Addr = probe.encoded, probe.destination
+		}
+		sent := time.Now()
+		// sendmmsg may send only some of the batch, so send the rest until all are sent or one fails
+		written := 0
+		var err error
+		for written < len(batch) && err == nil {
+			var n int
+			n, err = receiving.writeBatch(messages[written:len(batch)])
+			written += n
+		}
+		for i, probe := range batch {
+			if i < written {
+				probe.done <- sendOutcome{sent: sent}
+			} else {
+				probe.done <- sendOutcome{sent: sent, err: err}
+			}
+		}
+	}
+}
+
+// Send the messages with sendmmsg, returning how many were sent
+func (receiving *receiver) writeBatch(messages []ipv4.Message) (int, error) {
+	if receiving.key.ipv6 {
+		return receiving.conn.IPv6PacketConn().WriteBatch(messages, 0)
+	}
+	return receiving.conn.IPv4PacketConn().WriteBatch(messages, 0)
+}
+
+// Read replies in batches with recvmmsg until the socket is closed
+func (receiving *receiver) readLoop() {
+	var socket net.PacketConn
+	if receiving.key.ipv6 {
+		socket = receiving.conn.IPv6PacketConn().PacketConn
+	} else {
+		socket = receiving.conn.IPv4PacketConn().PacketConn
+	}
+	conn, ok := socket.(syscall.Conn)
+	if !ok {
+		return
+	}
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return
+	}
+	batch := newReplyBatch()
+	for {
+		err := rawConn.Read(batch.recv)
+		received := time.Now()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			return
+		}
+		if batch.err != nil {
+			return
+		}
+		for i := range batch.n {
+			packet, oob, peer := batch.reply(i)
+			packet, ttl := receiving.payload(packet, oob)
+			receiving.deliver(packet, ttl, peer, received)
+		}
+	}
+}
+
+// struct mmsghdr of recvmmsg, missing from x/sys/unix
+type mmsghdr struct {
+	header unix.Msghdr // Message read
+	length uint32      // Length of the message read
+}
+
+// Replies read with one recvmmsg into buffers reused by every batch. recvmmsg is called directly,
+// as ReadBatch allocates the source address of every reply.
+type replyBatch struct {
+	headers [batchSize]mmsghdr               // Messages read
+	iovecs  [batchSize]unix.Iovec            // Buffers of the messages
+	names   [batchSize]unix.RawSockaddrInet6 // Sources of the messages, large enough for either IP version
+	buffers [batchSize][batchReplySize]byte  // Replies read
+	oobs    [batchSize][batchOOBSize]byte    // Control messages of the replies
+	recv    func(uintptr) bool               // Call recvmmsg, kept so passing it doesn't allocate
+	n       int                              // Replies read by the last call
+	err     error                            // Error of the last call
+}
+
+// Point the messages of a batch at its buffers
+func newReplyBatch() *replyBatch {
+	batch := &replyBatch{}
+	for i := range batch.headers {
+		batch.iovecs[i].Base = &batch.buffers[i][0]
+		batch.iovecs[i].SetLen(batchReplySize)
+		header := &batch.headers[i].header
+		header.Name = (*byte)(unsafe.Pointer(&batch.names[i]))
+		header.Iov = &batch.iovecs[i]
+		header.SetIovlen(1)
+		header.Control = &batch.oobs[i][0]
+	}
+	batch.recv = batch.recvmmsg
+	return batch
+}
+
+// Call recvmmsg once the socket is readable
+func (batch *replyBatch) recvmmsg(fd uintptr) bool {
+	for i := range batch.headers {
+		batch.headers[i].header.Namelen = unix.SizeofSockaddrInet6
+		batch.headers[i].header.SetControllen(batchOOBSize)
+	}
+	for {
+		n, _, errno := unix.Syscall6(unix.SYS_RECVMMSG, fd, uintptr(unsafe.Pointer(&batch.headers[0])), uintptr(batchSize), 0, 0, 0)
+		switch errno {
+		case unix.EINTR:
+			continue
+		case unix.EAGAIN:
+			return false
+		case 0:
+			batch.n, batch.err = int(n), nil
+		default:
+			batch.n, batch.err = 0, errno
+		}
+		return true
+	}
+}
+
+// Reply of the last call with its control messages and source, valid until the next call
+func (batch *replyBatch) reply(i int) ([]byte, []byte, net.IP) {
+	packet := batch.buffers[i][:batch.headers[i].length]
+	oob := batch.oobs[i][:batch.headers[i].header.Controllen]
+	name := &batch.names[i]
+	if name.Family == unix.AF_INET {
+		return packet, oob, (*unix.RawSockaddrInet4)(unsafe.Pointer(name)).Addr[:]
+	}
+	return packet, oob, name.Addr[:]
+}
+
+// ICMP message of a packet read in a batch with its TTL or hop limit, 0 if unknown. Raw IPv4 sockets
+// deliver the IP header with recvmmsg, unlike single reads, so it is stripped and its TTL taken.
+func (receiving *receiver) payload(packet []byte, oob []byte) ([]byte, int) {
+	if !receiving.key.ipv6 && receiving.privileged && len(packet) >= ipv4.HeaderLen && packet[0]>>4 == ipv4.Version {
+		headerLength := int(packet[0]&0x0f) << 2
+		if headerLength >= ipv4.HeaderLen && headerLength <= len(packet) {
+			return packet[headerLength:], int(packet[8])
+		}
+	}
+	return packet, controlTTL(oob, receiving.key.ipv6)
+}
+
+// TTL or hop limit of a packet from its control messages, 0 if missing, walked in place as
+// ipv4.ControlMessage.Parse allocates
+func controlTTL(oob []byte, ipv6 bool) int {
+	level, kind := int32(unix.IPPROTO_IP), int32(unix.IP_TTL)
+	if ipv6 {
+		level, kind = unix.IPPROTO_IPV6, unix.IPV6_HOPLIMIT
+	}
+	for len(oob) >= unix.SizeofCmsghdr {
+		header := (*unix.Cmsghdr)(unsafe.Pointer(&oob[0]))
+		length := int(header.Len)
+		if length < unix.CmsgLen(0) || length > len(oob) {
+			return 0
+		}
+		if header.Level == level && header.Type == kind && length >= unix.CmsgLen(4) {
+			return int(int32(binary.NativeEndian.Uint32(oob[unix.CmsgLen(0):])))
+		}
+		oob = oob[min(unix.CmsgSpace(length-unix.CmsgLen(0)), len(oob)):]
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+//go:build !linux
+
+package pinger
+
+import (
+	"net"
+	"time"
+)
+
+// Probe queued for a batched send, only batched on Linux
+type outgoing struct{}
+
+// Start reading replies
+func (receiving *receiver) start() {
+	go receiving.readLoop()
+}
+
+// Send an encoded probe to the address, returning when it was sent
+func (receiving *receiver) send(_ *outgoing, encoded []byte, destination net.Addr) (time.Time, error) {
+	sent := time.Now()
+	_, err := receiving.conn.WriteTo(encoded, destination)
+	return sent, err
+}
+
+// Read replies one at a time until the socket is closed
+func (receiving *receiver) readLoop() {
+	buffer := make([]byte, maxReplySize)
+	for {
+		n, ttl, peer, err := receiving.read(buffer)
+		received := time.Now()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			return
+		}
+		receiving.deliver(buffer[:n], ttl, peerIP(peer), received)
+	}
+}
+
+// Address of a reply's source, a raw socket's IP address or a datagram socket's UDP address
+func peerIP(peer net.Addr) net.IP {
+	switch address := peer.(type) {
+	case *net.IPAddr:
+		return address.IP
+	case *net.UDPAddr:
+		return address.IP
+	}
+	return nil
+}
+
+// Read a packet into the buffer, returning its length, TTL or hop limit (0 if unknown) and source
+func (receiving *receiver) read(buffer []byte) (int, int, net.Addr, error) {
+	if receiving.key.ipv6 {
+		n, controlMessage, peer, err := receiving.conn.IPv6PacketConn().ReadFrom(buffer)
+		if controlMessage == nil {
+			return n, 0, peer, err
+		}
+		return n, controlMessage.HopLimit, peer, err
+	}
+	n, controlMessage, peer, err := receiving.conn.IPv4PacketConn().ReadFrom(buffer)
+	if controlMessage == nil {
+		return n, 0, peer, err
+	}
+	return n, controlMessage.TTL, peer, err
+}
@@ -0,0 +1,161 @@
+package pinger
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+var testPeer = net.IPv4(127, 0, 0, 1) // Address probed by the tests
+
+// Receiver demultiplexing replies handed to it, without a socket of its own
+func newTestReceiver(privileged bool, ipv6 bool) *receiver {
+	return &receiver{
+		key:        receiverKey{ipv6: ipv6, ttl: DefaultTTL},
+		privileged: privileged,
+		waiting:    make(map[echoKey]*pendingReply),
+		closed:     make(chan struct{}),
+	}
+}
+
+// Echo reply with the identifier and sequence number
+func echoReply(id int, seq int) []byte {
+	return encodeEcho(nil, byte(ipv4.ICMPTypeEchoReply), id, seq, payload(DefaultPayloadSize), true)
+}
+
+// Probe awaiting its reply from testPeer on a channel of its own
+func newTestPending(seq int) *pendingReply {
+	return &pendingReply{ip: testPeer, seq: seq, replies: make(chan arrival, 2)}
+}
+
+// Fail unless the probe got exactly one reply, tagged with its sequence number
+func expectArrival(t *testing.T, name string, pending *pendingReply) {
+	t.Helper()
+	select {
+	case got := <-pending.replies:
+		if got.seq != pending.seq || got.ttl != DefaultTTL {
+			t.Errorf("%s got reply to seq %d with TTL %d, want seq %d with TTL %d", name, got.seq, got.ttl, pending.seq, DefaultTTL)
+		}
+	default:
+		t.Errorf("%s got no reply", name)
+	}
+	expectNoArrival(t, name, pending)
+}
+
+// Fail if the probe got a reply
+func expectNoArrival(t *testing.T, name string, pending *pendingReply) {
+	t.Helper()
+	select {
+	case got := <-pending.replies:
+		t.Errorf("%s got a reply to seq %d, want none", name, got.seq)
+	default:
+	}
+}
+
+func TestDeliverMatchesIDAndSeq(t *testing.T) {
+	receiving := newTestReceiver(true, false)
+	first, second, third := newTestPending(7), newTestPending(7), newTestPending(8)
+	receiving.expect(first, 1, 7)
+	receiving.expect(second, 2, 7)
+	receiving.expect(third, 1, 8)
+
+	receiving.deliver(echoReply(2, 7), DefaultTTL, testPeer, time.Now())
+	expectArrival(t, "id 2 seq 7", second)
+	expectNoArrival(t, "id 1 seq 7", first)
+	expectNoArrival(t, "id 1 seq 8", third)
+
+	receiving.deliver(echoReply(1, 8), DefaultTTL, testPeer, time.Now())
+	expectArrival(t, "id 1 seq 8", third)
+	expectNoArrival(t, "id 1 seq 7", first)
+
+	// Neither another pinger's reply nor a duplicate of one delivered reaches a probe
+	receiving.deliver(echoReply(3, 7), DefaultTTL, testPeer, time.Now())
+	receiving.deliver(echoReply(2, 7), DefaultTTL, testPeer, time.Now())
+	expectNoArrival(t, "id 1 seq 7", first)
+	expectNoArrival(t, "id 2 seq 7", second)
+	if len(receiving.waiting) != 1 {
+		t.Errorf("%d probes still awaited, want 1", len(receiving.waiting))
+	}
+}
+
+func TestDeliverIgnoresOtherPackets(t *testing.T) {
+	request := encodeEcho(nil, byte(ipv4.ICMPTypeEcho), 1, 1, nil, true)
+	coded := echoReply(1, 1)
+	coded[1] = 1
+	tests := []struct {
+		name   string
+		ipv6   bool
+		packet []byte
+		peer   net.IP
+	}{
+		{"reply from another address", false, echoReply(1, 1), net.IPv4(192, 0, 2, 1)},
+		{"echo request", false, request, testPeer},
+		{"nonzero code", false, coded, testPeer},
+		{"truncated header", false, echoReply(1, 1)[:7], testPeer},
+		{"IPv4 reply on IPv6", true, echoReply(1, 1), testPeer},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			receiving := newTestReceiver(true, test.ipv6)
+			pending := newTestPending(1)
+			receiving.expect(pending, 1, 1)
+			receiving.deliver(test.packet, DefaultTTL, test.peer, time.Now())
+			expectNoArrival(t, test.name, pending)
+		})
+	}
+}
+
+func TestDeliverIPv6(t *testing.T) {
+	receiving := newTestReceiver(true, true)
+	pending := &pendingReply{ip: net.IPv6loopback, seq: 3, replies: make(chan arrival, 1)}
+	receiving.expect(pending, 1, 3)
+	receiving.deliver(encodeEcho(nil, byte(ipv6.ICMPTypeEchoReply), 1, 3, nil, false), DefaultTTL, net.IPv6loopback, time.Now())
+	expectArrival(t, "IPv6 reply", pending)
+}
+
+// Unprivileged sockets have the kernel replace the echo identifier, so replies match by sequence
+// number alone, while a shared raw socket tells pingers apart by it
+func TestDeliverUnprivilegedIgnoresID(t *testing.T) {
+	tests := []struct {
+		privileged bool
+		matched    bool
+	}{
+		{privileged: false, matched: true},
+		{privileged: true, matched: false},
+	}
+	for _, test := range tests {
+		receiving := newTestReceiver(test.privileged, false)
+		pending := newTestPending(5)
+		receiving.expect(pending, 0x1234, 5)
+		receiving.deliver(echoReply(0xbeef, 5), DefaultTTL, testPeer, time.Now())
+		name := "unprivileged reply with the kernel's identifier"
+		if test.privileged {
+			name = "privileged reply with another identifier"
+		}
+		if test.matched {
+			expectArrival(t, name, pending)
+		} else {
+			expectNoArrival(t, name, pending)
+		}
+	}
+
+	// Forgetting a probe by the pinger's own identifier stops its replies
+	receiving := newTestReceiver(false, false)
+	pending := newTestPending(6)
+	receiving.expect(pending, 0x1234, 6)
+	receiving.forget(0x1234, 6)
+	receiving.deliver(echoReply(0xbeef, 6), DefaultTTL, testPeer, time.Now())
+	expectNoArrival(t, "forgotten probe", pending)
+}
+
+// Replies carry 16 bit sequence numbers, and are tagged with the probe's own before wrapping
+func TestDeliverWrappedSeq(t *testing.T) {
+	receiving := newTestReceiver(true, false)
+	pending := newTestPending(70000)
+	receiving.expect(pending, 1, 70000&0xffff)
+	receiving.deliver(echoReply(1, 70000), DefaultTTL, testPeer, time.Now())
+	expectArrival(t, "wrapped seq", pending)
+}
@@ -0,0 +1,35 @@
+package pinger
+
+import "context"
+
+// Channel receiving the outcome of every probe of the next run, replied to or lost, closed once Run
+// returns. Results are buffered, then Run waits for them to be received unless its context is done,
+// so range over the channel or stop receiving only by cancelling the context. Each run needs its own call.
+func (pinger *Pinger) Results() <-chan ProbeResult {
+	pinger.mutex.Lock()
+	defer pinger.mutex.Unlock()
+	if pinger.results == nil {
+		pinger.results = make(chan ProbeResult, eventBuffer)
+	}
+	return pinger.results
+}
+
+// Take the results channel requested for this run, nil if Results wasn't called
+func (pinger *Pinger) takeResults() chan ProbeResult {
+	pinger.mutex.Lock()
+	defer pinger.mutex.Unlock()
+	results := pinger.results
+	pinger.results = nil
+	return results
+}
+
+// Send the result to the results channel of the run if there is one, giving up once the context is done
+func deliver(ctx context.Context, results chan<- ProbeResult, result ProbeResult) {
+	if results == nil {
+		return
+	}
+	select {
+	case results <- result:
+	case <-ctx.Done():
+	}
+}
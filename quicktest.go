@@ -0,0 +1,32 @@
+package main
+
+const defaultQuicktestCount int = 10 // Probes of each target of goping quicktest unless -c is given
+
+// Well-known target probed by the quicktest and benchmark commands
+type wellKnownTarget struct {
+	ipv4  string // IPv4 address, or hostname resolved with the IP version in use
+	ipv6  string // IPv6 address, or hostname resolved with the IP version in use
+	label string // Operator the target is labelled with
+}
+
+// Well-known anycast resolvers probed by goping quicktest, answering from a nearby site almost
+// anywhere so they gauge the local connection
+var quicktestTargets = []wellKnownTarget{
+	{"1.1.1.1", "2606:4700:4700::1111", "cloudflare"},
+	{"8.8.8.8", "2001:4860:4860::8888", "google"},
+	{"9.9.9.9", "2620:fe::fe", "quad9"},
+	{"208.67.222.222", "2620:119:35::35", "opendns"},
+}
+
+// Arguments, as address=label, pinging the targets with the IP version in use
+func wellKnownArguments(targets []wellKnownTarget) []string {
+	arguments := make([]string, len(targets))
+	for i, target := range targets {
+		address := target.ipv4
+		if wantIPv6 {
+			address = target.ipv6
+		}
+		arguments[i] = address + "=" + target.label
+	}
+	return arguments
+}
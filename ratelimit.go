@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	rateLimitBursts     int     = 4    // Loss bursts needed before their spacing is judged periodic
+	rateLimitBurstsKept int     = 256  // Start times of the most recent loss bursts remembered
+	rateLimitGapsKept   int     = 1024 // Gaps of the most recent probes remembered, with whether each was lost
+	rateLimitPeriodCV   float64 = 0.2  // Coefficient of variation below which bursts are periodic
+	rateLimitGapProbes  int     = 10   // Probes needed at both short and long gaps to compare their loss
+	rateLimitLongGap    float64 = 1.5  // Gap to the previous probe, relative to the median, counting as long
+	rateLimitMinLoss    float64 = 0.01 // Loss below which periodic bursts are too rare to matter
+)
+
+// Loss patterns of a target consistent with ICMP rate limiting by the target or a router on the way:
+// bursts of loss at a steady period as a token bucket empties and refills, and loss that vanishes
+// when probes are sent further apart
+type rateLimitEvidence struct {
+	burstStarts []time.Time     // Send times of the first probe of recent loss bursts
+	lastReplied bool            // Whether the previous probe replied, to find where bursts start
+	lastSent    time.Time       // When the previous probe was sent
+	gaps        []time.Duration // Gap to the previous probe of recent probes after the first
+	gapLost     []bool          // Whether the probe after each gap was lost
+}
+
+// Track the outcome of a probe sent at the given time. Must be called with stats.mutex held.
+func (evidence *rateLimitEvidence) observe(replied bool, sent time.Time) {
+	if !evidence.lastSent.IsZero() {
+		if len(evidence.gaps) == rateLimitGapsKept {
+			evidence.gaps, evidence.gapLost = evidence.gaps[1:], evidence.gapLost[1:]
+		}
+		evidence.gaps = append(evidence.gaps, sent.Sub(evidence.lastSent))
+		evidence.gapLost = append(evidence.gapLost, !replied)
+	}
+	if !replied && (evidence.lastReplied || evidence.lastSent.IsZero()) {
+		if len(evidence.burstStarts) == rateLimitBurstsKept {
+			evidence.burstStarts = evidence.burstStarts[1:]
+		}
+		evidence.burstStarts = append(evidence.burstStarts, sent)
+	}
+	evidence.lastReplied, evidence.lastSent = replied, sent
+}
+
+// Describe the patterns of a target's losses pointing at ICMP rate limiting, given its numbers of
+// probes sent and lost, none if there are none. Must be called with stats.mutex held.
+func (evidence *rateLimitEvidence) patterns(sent int, lost int) []string {
+	var found []string
+
+	// Bursts starting at a steady period, as when a limiter's bucket drains and refills
+	if len(evidence.burstStarts) >= rateLimitBursts && float64(lost) >= rateLimitMinLoss*float64(sent) {
+		periods := make([]time.Duration, len(evidence.burstStarts)-1)
+		for i := range periods {
+			periods[i] = evidence.burstStarts[i+1].Sub(evidence.burstStarts[i])
+		}
+		mean, deviation := meanDeviation(periods)
+		if mean > 0 && deviation/mean < rateLimitPeriodCV {
+			found = append(found, fmt.Sprintf("%d loss bursts began every %s (±%s)",
+				len(evidence.burstStarts), display(time.Duration(mean)), display(time.Duration(deviation))))
+		}
+	}
+
+	// Loss significantly higher among probes sent soon after the previous one than long after it
+	if len(evidence.gaps) >= 2*rateLimitGapProbes {
+		median := medianOf(evidence.gaps)
+		var shortSent, shortLost, longSent, longLost int
+		for i, gap := range evidence.gaps {
+			switch {
+			case gap <= median:
+				shortSent++
+				if evidence.gapLost[i] {
+					shortLost++
+				}
+			case float64(gap) >= rateLimitLongGap*float64(median):
+				longSent++
+				if evidence.gapLost[i] {
+					longLost++
+				}
+			}
+		}
+		if shortSent >= rateLimitGapProbes && longSent >= rateLimitGapProbes && shortLost > 0 {
+			shortLoss, longLoss := float64(shortLost)/float64(shortSent), float64(longLost)/float64(longSent)
+			pooled := float64(shortLost+longLost) / float64(shortSent+longSent)
+			z := zScore(shortLoss-longLoss, math.Sqrt(pooled*(1-pooled)*(1/float64(shortSent)+1/float64(longSent))))
+			if z > significanceZ && shortLoss >= 2*longLoss {
+				found = append(found, fmt.Sprintf("%.2f%% loss of probes sent %s or less after the previous one, %.2f%% of those %s or more after",
+					shortLoss*100, display(median), longLoss*100, display(time.Duration(rateLimitLongGap*float64(median)))))
+			}
+		}
+	}
+	return found
+}
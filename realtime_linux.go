@@ -0,0 +1,22 @@
+//go:build linux
+
+package main
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Schedule the calling thread SCHED_FIFO at the priority, from 1 to 99
+func setThreadPriority(priority int) error {
+	attributes := unix.SchedAttr{Size: uint32(unsafe.Sizeof(unix.SchedAttr{})), Policy: unix.SCHED_FIFO, Priority: uint32(priority)}
+	return unix.SchedSetAttr(0, &attributes, 0)
+}
+
+// Pin the calling thread to the CPU
+func setThreadAffinity(cpu int) error {
+	var set unix.CPUSet
+	set.Set(cpu)
+	return unix.SchedSetaffinity(0, &set)
+}
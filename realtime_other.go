@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// Real-time scheduling is only implemented on Linux
+func setThreadPriority(priority int) error {
+	return errors.New("Real-time priority is not supported on this platform")
+}
+
+// CPU pinning is only implemented on Linux
+func setThreadAffinity(cpu int) error {
+	return errors.New("CPU pinning is not supported on this platform")
+}
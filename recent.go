@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const recentTargetsKept int = 50 // Targets remembered for shell completion, the oldest dropped beyond
+
+// File the targets recently pinged are remembered in, one per line newest first, in the user's cache directory
+func recentTargetsPath() (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cache, "goping", "targets"), nil
+}
+
+// Targets recently pinged, newest first, completed by the shell scripts (goping completion --targets)
+func recentTargets() []string {
+	path, err := recentTargetsPath()
+	if err != nil {
+		return nil
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(contents))
+}
+
+// Remember the addresses of the targets given, without labels or settings, so the shell completes them
+// later. Failing to is only logged, as completion is a convenience.
+func rememberTargets(arguments []string) {
+	path, err := recentTargetsPath()
+	if err != nil {
+		slog.Debug("Could not remember targets", "error", err)
+		return
+	}
+	var remembered []string
+	for _, argument := range arguments {
+		address, _, _ := parseTarget(argument, nil)
+		if address != "" && !slices.Contains(remembered, address) {
+			remembered = append(remembered, address)
+		}
+	}
+	for _, address := range recentTargets() {
+		if !slices.Contains(remembered, address) {
+			remembered = append(remembered, address)
+		}
+	}
+	remembered = remembered[:min(len(remembered), recentTargetsKept)]
+
+	// Replace the file whole, so a concurrent goping never reads it half written
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		slog.Debug("Could not remember targets", "error", err)
+		return
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "targets-*")
+	if err != nil {
+		slog.Debug("Could not remember targets", "error", err)
+		return
+	}
+	_, err = fmt.Fprintln(file, strings.Join(remembered, "\n"))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		slog.Debug("Could not remember targets", "error", err)
+	}
+}
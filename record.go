@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Header written at the start of a -record file, followed by one probeResult per probe
+type session struct {
+	Start  time.Time // When recording started
+	IPv6   bool      // Whether the session pinged over IPv6
+	Format int       // Version of the recording format
+}
+
+// Version of the recording format, bumped on incompatible changes to probeResult
+const recordFormat int = 1
+
+// Recording of every probe result to a gob file (-record)
+type recorder struct {
+	file    *os.File     // Underlying file, written unbuffered so it survives ctrl-c
+	encoder *gob.Encoder // Encoder of the session header and probe results
+	mutex   sync.Mutex   // Serializes results from concurrent targets
+}
+
+// Create the recording file and write the session header
+func openRecorder(path string) (*recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	encoder := gob.NewEncoder(file)
+	if err := encoder.Encode(session{Start: time.Now(), IPv6: wantIPv6, Format: recordFormat}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &recorder{file: file, encoder: encoder}, nil
+}
+
+// Record a probe result
+func (sessionRecorder *recorder) write(result probeResult) error {
+	sessionRecorder.mutex.Lock()
+	defer sessionRecorder.mutex.Unlock()
+	return sessionRecorder.encoder.Encode(result)
+}
+
+// Close the recording file
+func (sessionRecorder *recorder) close() error {
+	return sessionRecorder.file.Close()
+}
+
+// Read a recording made with -record, returning its session header and probe results
+func readRecording(path string) (session, []probeResult, error) {
+	var header session
+	file, err := os.Open(path)
+	if err != nil {
+		return header, nil, err
+	}
+	defer file.Close()
+	decoder := gob.NewDecoder(file)
+	if err := decoder.Decode(&header); err != nil {
+		return header, nil, fmt.Errorf("Could not read recording %s: %w", path, err)
+	}
+	if header.Format != recordFormat {
+		return header, nil, fmt.Errorf("Unsupported recording format %d in %s", header.Format, path)
+	}
+	var results []probeResult
+	for {
+		var result probeResult
+		err := decoder.Decode(&result)
+		// A recording cut short by ctrl-c may end mid-result
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return header, results, nil
+		}
+		if err != nil {
+			return header, results, fmt.Errorf("Could not read recording %s: %w", path, err)
+		}
+		results = append(results, result)
+	}
+}
+
+// Statistics of each target of a recording, in order of first appearance
+func replayStatistics(results []probeResult) []*statistic {
+	var allStats []*statistic
+	byTarget := make(map[string]*statistic)
+	for _, result := range results {
+		stats, ok := byTarget[result.Target]
+		if !ok {
+			stats = &statistic{target: &resolution{address: result.Target}, labels: result.Labels}
+			byTarget[result.Target] = stats
+			allStats = append(allStats, stats)
+		}
+		if result.Mismatched {
+			stats.mismatched++
+		}
+		stats.rtt = result.RTT
+		stats.tally(result.Error == "", result.Time)
+		stats.responders = result.Responders
+		stats.tallyResponders()
+	}
+	return allStats
+}
+
+// Re-render a recording made with -record in the output format (-o) and show its summary (goping replay),
+// leaving the summary out of machine readable formats so conversions stay parseable
+func replay(path string, writer *resultWriter) error {
+	header, results, err := readRecording(path)
+	if err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Replaying %d probes recorded %s...", len(results), header.Start.Format(time.RFC1123)))
+	wantIPv6 = header.IPv6
+	for _, result := range results {
+		writer.write(result)
+		if latencyHeatmap != nil {
+			latencyHeatmap.add(result)
+		}
+	}
+	if writer.format == "text" {
+		showSummary(replayStatistics(results))
+	}
+	return nil
+}
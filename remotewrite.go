@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const remoteWriteInterval time.Duration = 15 * time.Second // Interval between pushes to -remote-write-url
+
+var (
+	remoteWriteURL *url.URL // Prometheus remote write endpoint metrics are pushed to (-remote-write-url) flag, nil for none
+
+	// Upper bounds in seconds of the RTT histogram buckets, +Inf implied
+	rttBuckets = [...]float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+	invalidLabelName = regexp.MustCompile(`[^a-zA-Z0-9_]`) // Characters Prometheus label names can't hold
+)
+
+// Sample of a time series, named and labelled as Prometheus would scrape it
+type remoteSeries struct {
+	labels [][2]string // Name and value of each label, __name__ included, sorted by name
+	value  float64     // Value at the time of the push
+}
+
+// Cumulative histogram of a target's RTTs over rttBuckets, counted as replies are tallied so
+// pushing it doesn't walk every RTT kept
+type rttHistogram struct {
+	buckets [len(rttBuckets)]uint64 // Replies with an RTT at or below each bound of rttBuckets
+	sum     time.Duration           // Sum of their RTTs
+	count   uint64                  // Number of replies, the +Inf bucket
+}
+
+// Count a reply's RTT into every bucket whose bound it is within
+func (histogram *rttHistogram) observe(rtt time.Duration) {
+	seconds := rtt.Seconds()
+	for i := len(rttBuckets) - 1; i >= 0 && seconds <= rttBuckets[i]; i-- {
+		histogram.buckets[i]++
+	}
+	histogram.sum += rtt
+	histogram.count++
+}
+
+// Push every target's RTT histogram and probe counters to the -remote-write-url endpoint, e.g. of
+// Mimir, Thanos or VictoriaMetrics, every remoteWriteInterval, as no local scrape target is served
+func pushRemoteWrite() {
+	client := http.Client{Timeout: notifyTimeout}
+	for now := range time.Tick(remoteWriteInterval) {
+		if currentStats == nil {
+			continue
+		}
+		var series []remoteSeries
+		for _, stats := range currentStats() {
+			series = append(series, stats.remoteSeries()...)
+		}
+		request, err := http.NewRequest(http.MethodPost, remoteWriteURL.String(), bytes.NewReader(s2.EncodeSnappy(nil, encodeWriteRequest(series, now))))
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Could not push metrics to %s (%s)", remoteWriteURL.Redacted(), err))
+			continue
+		}
+		request.Header.Set("Content-Encoding", "snappy")
+		request.Header.Set("Content-Type", "application/x-protobuf")
+		request.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+		response, err := client.Do(request)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Could not push metrics to %s (%s)", remoteWriteURL.Redacted(), err))
+			continue
+		}
+		response.Body.Close()
+		if response.StatusCode/100 != 2 {
+			slog.Warn(fmt.Sprintf("Pushing metrics to %s failed: %s", remoteWriteURL.Redacted(), response.Status))
+		}
+	}
+}
+
+// Series of the target: goping_rtt_seconds as a cumulative histogram of its RTTs, and
+// goping_probes_sent_total and goping_probes_lost_total counters, labelled with its address and labels
+func (stats *statistic) remoteSeries() []remoteSeries {
+	stats.mutex.Lock()
+	sent, lost, histogram := stats.count, stats.lost, stats.histogram
+	stats.mutex.Unlock()
+
+	base := [][2]string{{"target", stats.target.address}}
+	for key, value := range stats.labels {
+		base = append(base, [2]string{remoteLabelName(key), value})
+	}
+	named := func(name string, extra ...[2]string) [][2]string {
+		tags := append(append([][2]string{{"__name__", name}}, base...), extra...)
+		sort.Slice(tags, func(i, j int) bool { return tags[i][0] < tags[j][0] })
+		return tags
+	}
+
+	var series []remoteSeries
+	for i, bound := range rttBuckets {
+		le := [2]string{"le", fmt.Sprint(bound)}
+		series = append(series, remoteSeries{named("goping_rtt_seconds_bucket", le), float64(histogram.buckets[i])})
+	}
+	return append(series,
+		remoteSeries{named("goping_rtt_seconds_bucket", [2]string{"le", "+Inf"}), float64(histogram.count)},
+		remoteSeries{named("goping_rtt_seconds_sum"), histogram.sum.Seconds()},
+		remoteSeries{named("goping_rtt_seconds_count"), float64(histogram.count)},
+		remoteSeries{named("goping_probes_sent_total"), float64(sent)},
+		remoteSeries{named("goping_probes_lost_total"), float64(lost)},
+	)
+}
+
+// Prometheus label name of a target label, prefixed with label_ where it would clash with
+// goPing's own labels or be invalid
+func remoteLabelName(key string) string {
+	name := invalidLabelName.ReplaceAllString(key, "_")
+	if name == "target" || name == "le" || strings.HasPrefix(name, "__") || name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "label_" + name
+	}
+	return name
+}
+
+// Encode a remote write WriteRequest protobuf of the series, each with one sample at the given time
+func encodeWriteRequest(series []remoteSeries, at time.Time) []byte {
+	var request []byte
+	for _, sample := range series {
+		var timeSeries []byte
+		for _, label := range sample.labels {
+			var encoded []byte
+			encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label[0])
+			encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label[1])
+			timeSeries = protowire.AppendTag(timeSeries, 1, protowire.BytesType)
+			timeSeries = protowire.AppendBytes(timeSeries, encoded)
+		}
+		var encoded []byte
+		encoded = protowire.AppendTag(encoded, 1, protowire.Fixed64Type)
+		encoded = protowire.AppendFixed64(encoded, math.Float64bits(sample.value))
+		encoded = protowire.AppendTag(encoded, 2, protowire.VarintType)
+		encoded = protowire.AppendVarint(encoded, uint64(at.UnixMilli()))
+		timeSeries = protowire.AppendTag(timeSeries, 2, protowire.BytesType)
+		timeSeries = protowire.AppendBytes(timeSeries, encoded)
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, timeSeries)
+	}
+	return request
+}
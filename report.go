@@ -0,0 +1,188 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	chartWidth       float64 = 800 // Width of report charts in SVG units
+	chartHeight      float64 = 200 // Height of the RTT chart and histogram in SVG units
+	histogramBuckets int     = 20  // Number of RTT histogram bars
+)
+
+//go:embed report.html
+var reportTemplate string
+
+// Report rendered as a self-contained HTML file at termination (-report), collecting every probe result
+type htmlReport struct {
+	path    string                       // File the report is written to
+	start   time.Time                    // When the run started
+	end     time.Time                    // When the run ended, zero for when the report is written
+	mutex   sync.Mutex                   // Guards the fields below
+	results map[*statistic][]probeResult // Results of each target
+	order   []*statistic                 // Targets in the order they were first probed
+}
+
+// Report section of a target, with its charts precomputed as SVG coordinates
+type reportTarget struct {
+	Summary   targetSummary // Statistics of the whole run
+	RTTPoints string        // Polyline points of RTT over time
+	Losses    []float64     // X coordinates of lost probes on the loss timeline
+	Bars      []reportBar   // RTT histogram
+	Start     string        // Time of the first probe
+	End       string        // Time of the last probe
+	PeakRTT   time.Duration // RTT at the top of the RTT chart and right of the histogram
+}
+
+// Bar of the RTT histogram
+type reportBar struct {
+	X, Y, Width, Height float64 // Rectangle of the bar in SVG coordinates
+	Label               string  // Range of RTTs and count shown on hover
+}
+
+// Create a report written to the path at termination
+func newReport(path string) *htmlReport {
+	return &htmlReport{path: path, start: time.Now(), results: make(map[*statistic][]probeResult)}
+}
+
+// Collect a probe result of the target
+func (report *htmlReport) add(stats *statistic, result probeResult) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+	if _, ok := report.results[stats]; !ok {
+		report.order = append(report.order, stats)
+	}
+	report.results[stats] = append(report.results[stats], result)
+}
+
+// Render the report of every target probed so far to its file
+func (report *htmlReport) write() error {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+	page, err := template.New("report").Funcs(template.FuncMap{"display": display}).Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+	end := report.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	data := struct {
+		Start, End string
+		Targets    []reportTarget
+		Width      float64
+		Height     float64
+	}{
+		Start:  report.start.Format(time.RFC1123),
+		End:    end.Format(time.RFC1123),
+		Width:  chartWidth,
+		Height: chartHeight,
+	}
+	for _, stats := range report.order {
+		data.Targets = append(data.Targets, chartTarget(stats.summary(), report.results[stats]))
+	}
+	file, err := os.Create(report.path)
+	if err != nil {
+		return err
+	}
+	if err := page.Execute(file, data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Render the HTML report of a recording made with -record to the path (goping report)
+func reportRecording(recordPath string, reportPath string) error {
+	header, results, err := readRecording(recordPath)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("No probes recorded in %s", recordPath)
+	}
+	wantIPv6 = header.IPv6
+	report := newReport(reportPath)
+	report.start, report.end = header.Start, results[len(results)-1].Time
+	byTarget := make(map[string]*statistic)
+	for _, stats := range replayStatistics(results) {
+		byTarget[stats.target.address] = stats
+	}
+	for _, result := range results {
+		report.add(byTarget[result.Target], result)
+	}
+	if err := report.write(); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Wrote report of %d probes recorded %s to %s...", len(results), header.Start.Format(time.RFC1123), reportPath))
+	return nil
+}
+
+// Lay out the charts of a target's results
+func chartTarget(summary targetSummary, results []probeResult) reportTarget {
+	section := reportTarget{Summary: summary}
+	if len(results) == 0 {
+		return section
+	}
+	first, last := results[0].Time, results[len(results)-1].Time
+	section.Start, section.End = first.Format(time.TimeOnly), last.Format(time.TimeOnly)
+	rtts, _ := repliedRTTs(results)
+	for _, rtt := range rtts {
+		section.PeakRTT = max(section.PeakRTT, rtt)
+	}
+	// Place each probe along the run, the only probe in the middle
+	x := func(sent time.Time) float64 {
+		if !last.After(first) {
+			return chartWidth / 2
+		}
+		return float64(sent.Sub(first)) / float64(last.Sub(first)) * chartWidth
+	}
+
+	var points strings.Builder
+	for _, result := range results {
+		if result.Error != "" {
+			section.Losses = append(section.Losses, x(result.Time))
+			continue
+		}
+		y := chartHeight
+		if section.PeakRTT > 0 {
+			y -= float64(result.RTT) / float64(section.PeakRTT) * chartHeight
+		}
+		fmt.Fprintf(&points, "%.1f,%.1f ", x(result.Time), y)
+	}
+	section.RTTPoints = strings.TrimSpace(points.String())
+
+	// Count RTTs into equal-width buckets up to the peak
+	if len(rtts) == 0 || section.PeakRTT == 0 {
+		return section
+	}
+	counts := make([]int, histogramBuckets)
+	for _, rtt := range rtts {
+		bucket := min(int(float64(rtt)/float64(section.PeakRTT)*float64(histogramBuckets)), histogramBuckets-1)
+		counts[bucket]++
+	}
+	highest := 0
+	for _, count := range counts {
+		highest = max(highest, count)
+	}
+	width := chartWidth / float64(histogramBuckets)
+	bucketRTT := section.PeakRTT / time.Duration(histogramBuckets)
+	for i, count := range counts {
+		height := float64(count) / float64(highest) * chartHeight
+		section.Bars = append(section.Bars, reportBar{
+			X:      float64(i) * width,
+			Y:      chartHeight - height,
+			Width:  width - 1,
+			Height: height,
+			Label:  fmt.Sprintf("%s - %s: %d", display(bucketRTT*time.Duration(i)), display(bucketRTT*time.Duration(i+1)), count),
+		})
+	}
+	return section
+}
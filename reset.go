@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	currentStats func() []*statistic // Statistics clients of the running mode, for SIGUSR2 resets, nil until running
+	epoch        int                 // Number of the current measurement epoch, counting resets
+	resetMutex   sync.Mutex          // Serializes resets, guarding epoch
+)
+
+// Reset requested of a target's statistics, carried out by its probing goroutine before its next probe
+// so no probe is in flight while counters and sequence numbers restart
+type resetRequest struct {
+	pending atomic.Bool // Whether a reset is waiting for the next probe
+}
+
+// Print the statistics of every target as the summary of the ending epoch, then have each target's
+// counters reset to zero so a new measurement epoch starts, e.g. after a network change
+func resetStatistics(allStats []*statistic) {
+	resetMutex.Lock()
+	defer resetMutex.Unlock()
+	epoch++
+	fmt.Printf("\nStatistics of epoch %d, resetting...", epoch)
+	showSummary(allStats)
+	fmt.Println()
+	for _, stats := range allStats {
+		stats.resetting.pending.Store(true)
+	}
+}
+
+// Have each target's counters reset without printing the summary of the ending epoch, which
+// would draw over the interactive console (-tui), returning the number of the ending epoch
+func resetQuietly(allStats []*statistic) int {
+	resetMutex.Lock()
+	defer resetMutex.Unlock()
+	epoch++
+	for _, stats := range allStats {
+		stats.resetting.pending.Store(true)
+	}
+	return epoch
+}
+
+// Number of resets so far, the current epoch being the next
+func currentEpoch() int {
+	resetMutex.Lock()
+	defer resetMutex.Unlock()
+	return epoch
+}
+
+// Carry out a requested reset before the next probe. Only called by the target's probing goroutine.
+func (stats *statistic) resetIfRequested() {
+	if !stats.resetting.pending.Swap(false) {
+		return
+	}
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	stats.count, stats.lost, stats.loss, stats.mismatched = 0, 0, 0, 0
+	stats.malformed, stats.truncated = 0, 0
+	stats.corrupted, stats.corruptedBits = 0, 0
+	stats.lastRTT, stats.totalRTT, stats.rttAll, stats.totalDifferencesRTT, stats.jitter = 0, 0, nil, 0, 0
+	stats.histogram = rttHistogram{}
+	stats.anomalies = 0
+	stats.bursts = lossBursts{}
+	stats.route.changes = 0
+	stats.uptime = availability{}
+	stats.responderStats, stats.responderOrder = nil, nil
+	stats.flowStats, stats.flowOrder = nil, nil
+	stats.ecnStats = ecnStatistic{}
+	stats.late = lateReplies{}
+	stats.clockSamples = nil
+	stats.lossCauses = nil
+	stats.rateLimit = rateLimitEvidence{}
+	stats.gcProbes, stats.gcPauseTotal = 0, 0
+}
+
+// Reset statistics on every SIGUSR2, where the platform has it
+func listenForReset() {
+	resets := make(chan os.Signal, 1)
+	if !notifyResetSignal(resets) {
+		return
+	}
+	for range resets {
+		if currentStats != nil {
+			resetStatistics(currentStats())
+		}
+	}
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	resolvConf        string        = "/etc/resolv.conf"        // System resolver configuration for -resolve ttl
+	defaultRecordTTL  time.Duration = time.Minute               // Re-resolve interval when the DNS TTL can't be determined
+	dnsQueryTimeout   time.Duration = 5 * time.Second           // Timeout for a single DNS query
+	dnsMaxMessageSize int           = 4096                      // Receive buffer size for DNS replies
+	neverExpires      time.Duration = 1<<63 - 1                 // TTL given to IP literals, which never need re-resolving
+	dnsMessageType    string        = "application/dns-message" // Media type of DNS-over-HTTPS messages
+	mdnsPort          int           = 5353                      // Multicast DNS port
+	mdnsQueryTimeout  time.Duration = 2 * time.Second           // Time to wait for a multicast DNS response
+	resolveRetryMin   time.Duration = time.Second               // First wait before retrying a failed re-resolution
+	resolveRetryMax   time.Duration = 30 * time.Second          // Longest wait between retries of a failed re-resolution
+)
+
+var mdnsGroup4 = net.IPv4(224, 0, 0, 251) // Multicast DNS group for IPv4
+
+// Resolution state of the target, kept across probes according to the -resolve policy
+type resolution struct {
+	address   string      // Hostname or IP address given by the user
+	ipAddress *net.IPAddr // Currently resolved IP address, nil until first resolved
+	probes    int         // Probes sent since the last resolution
+	epoch     int64       // Network changes seen (networkEpoch) when it was last resolved
+	expires   time.Time   // When the DNS TTL of ipAddress runs out (-resolve ttl)
+
+	retrying bool            // Whether a failed re-resolution is retried in the background, ipAddress being probed meanwhile
+	retry    sync.Mutex      // Guards retried
+	retried  *resolvedEntry  // Result of the background retry, adopted by the next probe, nil until it succeeds
+	stop     <-chan struct{} // Closed once the target is no longer probed, ending the background retry, nil for never
+}
+
+// Address resolved for a target, with what is needed to adopt it
+type resolvedEntry struct {
+	ipAddress *net.IPAddr   // Address resolved
+	recordTTL time.Duration // DNS TTL of the record with -resolve ttl
+	epoch     int64         // Network changes seen when it was resolved
+	took      time.Duration // How long resolving took
+}
+
+// Return the target's IP address, re-resolving it first if the -resolve policy calls for it.
+// Should re-resolving fail, the last address is returned while resolution is retried in the
+// background, so a DNS blip doesn't count as loss.
+func (target *resolution) current() (*net.IPAddr, error) {
+	if target.retrying {
+		target.retry.Lock()
+		retried := target.retried
+		target.retried = nil
+		target.retry.Unlock()
+		if retried == nil {
+			target.probes++
+			return target.ipAddress, nil
+		}
+		target.retrying = false
+		slog.Info(fmt.Sprintf("Resolved %s again, to %s...", target.address, retried.ipAddress))
+		target.adopt(retried)
+		return target.ipAddress, nil
+	}
+	if target.ipAddress != nil && !target.due() {
+		target.probes++
+		return target.ipAddress, nil
+	}
+
+	resolved, err := target.lookup()
+	if err != nil && target.ipAddress != nil {
+		slog.Warn(fmt.Sprintf("Could not re-resolve %s (%s). Probing its last address %s while retrying...", target.address, err, target.ipAddress))
+		target.retrying = true
+		go target.retryLookup()
+		target.probes++
+		return target.ipAddress, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	target.adopt(resolved)
+	return target.ipAddress, nil
+}
+
+// Resolve the target before its first probe, so a hostname that can't resolve fails at startup
+// rather than losing every probe. The first probe uses the address without counting as re-resolved.
+func (target *resolution) resolveFirst() error {
+	resolved, err := target.lookup()
+	if err != nil {
+		return fmt.Errorf("Could not resolve %s: %w", target.address, err)
+	}
+	target.adopt(resolved)
+	target.probes = 0
+	return nil
+}
+
+// Resolve every target before its first probe at once, returning each one's error, nil if it resolved
+func resolveTargets(allStats []*statistic) []error {
+	resolveErrs := make([]error, len(allStats))
+	var resolving sync.WaitGroup
+	for i, stats := range allStats {
+		resolving.Add(1)
+		go func(i int, stats *statistic) {
+			defer resolving.Done()
+			resolveErrs[i] = stats.target.resolveFirst()
+		}(i, stats)
+	}
+	resolving.Wait()
+	return resolveErrs
+}
+
+// Resolve the target's hostname to an IP address, with its DNS TTL if needed
+func (target *resolution) lookup() (*resolvedEntry, error) {
+	resolveNetwork := resolveNetwork4
+	if wantIPv6 {
+		resolveNetwork = resolveNetwork6
+	}
+	resolved := &resolvedEntry{epoch: networkEpoch.Load()}
+	slog.Debug("Resolving target", "address", target.address, "network", resolveNetwork, "resolver", resolverName(target.address))
+	timeResolve := time.Now()
+	var err error
+	if resolveTTL {
+		resolved.ipAddress, resolved.recordTTL, err = lookupTTL(resolveNetwork, target.address)
+	} else {
+		resolved.ipAddress, err = resolve(resolveNetwork, target.address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resolved.took = time.Since(timeResolve)
+	slog.Debug("Resolved target", "address", target.address, "ip", resolved.ipAddress, "took", resolved.took, "recordTTL", resolved.recordTTL)
+	return resolved, nil
+}
+
+// Retry resolving the target until it succeeds or the target is stopped, waiting longer after
+// each failure, leaving the result for the next probe to adopt
+func (target *resolution) retryLookup() {
+	wait := resolveRetryMin
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-target.stop:
+			slog.Debug("Stopped re-resolving target", "address", target.address)
+			return
+		case <-timer.C:
+		}
+		resolved, err := target.lookup()
+		if err == nil {
+			target.retry.Lock()
+			target.retried = resolved
+			target.retry.Unlock()
+			return
+		}
+		slog.Debug("Could not re-resolve target", "address", target.address, "error", err, "retry", wait)
+		wait = min(2*wait, resolveRetryMax)
+		timer.Reset(wait)
+	}
+}
+
+// Make a resolved address the target's, logging the first resolution and any change of
+// address, skipping IP literals
+func (target *resolution) adopt(resolved *resolvedEntry) {
+	if net.ParseIP(target.address) == nil {
+		if target.ipAddress == nil {
+			slog.Info(fmt.Sprintf("Resolved %s to %s in %s via %s", target.address, resolved.ipAddress, display(resolved.took), resolverName(target.address)))
+		} else if !target.ipAddress.IP.Equal(resolved.ipAddress.IP) {
+			slog.Info(fmt.Sprintf("Resolved address of %s changed from %s to %s", target.address, target.ipAddress, resolved.ipAddress))
+		}
+	}
+	target.ipAddress = resolved.ipAddress
+	target.probes = 1
+	target.epoch = resolved.epoch
+	target.expires = time.Now().Add(resolved.recordTTL)
+}
+
+// Whether the target is due to be re-resolved under the -resolve policy, or after a network change
+func (target *resolution) due() bool {
+	switch {
+	case target.epoch != networkEpoch.Load():
+		return true
+	case resolveTTL:
+		return !time.Now().Before(target.expires)
+	case resolveEvery > 0:
+		return target.probes >= resolveEvery
+	default:
+		return false
+	}
+}
+
+// Resolve the address within the given IP network, using the custom or encrypted resolver if set
+func resolve(resolveNetwork string, address string) (*net.IPAddr, error) {
+	if encryptedDNS() || multicastName(address) {
+		ipAddresses, _, err := queryDNS(resolveNetwork, address)
+		if err != nil {
+			return nil, err
+		}
+		return ipAddresses[0], nil
+	}
+	if resolver == nil {
+		return net.ResolveIPAddr(resolveNetwork, address)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dnsQueryTimeout)
+	defer cancel()
+	ipAddresses, err := resolver.LookupIPAddr(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	// Pick the first address matching the IP version
+	for _, ipAddress := range ipAddresses {
+		if (ipAddress.IP.To4() == nil) == (resolveNetwork == resolveNetwork6) {
+			return &ipAddress, nil
+		}
+	}
+	return nil, fmt.Errorf("No %s address found for %s via %s", resolveNetwork, address, resolverName(address))
+}
+
+// Resolve every address of the hostname within the IP version in use
+func resolveAll(address string) ([]*net.IPAddr, error) {
+	resolveNetwork := resolveNetwork4
+	if wantIPv6 {
+		resolveNetwork = resolveNetwork6
+	}
+	if encryptedDNS() || multicastName(address) {
+		ipAddresses, _, err := queryDNS(resolveNetwork, address)
+		return ipAddresses, err
+	}
+	lookup := resolver
+	if lookup == nil {
+		lookup = net.DefaultResolver
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dnsQueryTimeout)
+	defer cancel()
+	ipAddresses, err := lookup.LookupIPAddr(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	var matching []*net.IPAddr
+	for _, ipAddress := range ipAddresses {
+		if (ipAddress.IP.To4() == nil) == (resolveNetwork == resolveNetwork6) {
+			matching = append(matching, &ipAddress)
+		}
+	}
+	if len(matching) == 0 {
+		return nil, fmt.Errorf("No %s address found for %s", resolveNetwork, address)
+	}
+	return matching, nil
+}
+
+// Resolve the address by querying the DNS server directly, so the record's TTL is known.
+// Falls back to a regular resolution with defaultRecordTTL if the query can't be made.
+func lookupTTL(resolveNetwork string, address string) (*net.IPAddr, time.Duration, error) {
+	ipAddresses, recordTTL, err := queryDNS(resolveNetwork, address)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Could not determine DNS TTL of %s (%s). Re-resolving every %s...", address, err, defaultRecordTTL))
+		ipAddress, err := resolve(resolveNetwork, address)
+		return ipAddress, defaultRecordTTL, err
+	}
+	return ipAddresses[0], recordTTL, nil
+}
+
+// Send a single A/AAAA query for the address over dnsExchange, or mDNS for .local names,
+// returning all answers and the lowest TTL in the answer chain
+func queryDNS(resolveNetwork string, address string) ([]*net.IPAddr, time.Duration, error) {
+	// IP literals never need resolving
+	if net.ParseIP(address) != nil {
+		ipAddress, err := net.ResolveIPAddr(resolveNetwork, address)
+		if err != nil {
+			return nil, 0, err
+		}
+		return []*net.IPAddr{ipAddress}, neverExpires, nil
+	}
+
+	// Build the query
+	name, err := dnsmessage.NewName(strings.TrimSuffix(address, ".") + ".")
+	if err != nil {
+		return nil, 0, err
+	}
+	queryType := dnsmessage.TypeA
+	if resolveNetwork == resolveNetwork6 {
+		queryType = dnsmessage.TypeAAAA
+	}
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: uint16(rand.Intn(1 << 16)), RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: queryType, Class: dnsmessage.ClassINET}},
+	}
+	queryEncoded, err := query.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Exchange it with the server
+	slog.Debug("Sending DNS query", "name", name, "type", queryType, "id", query.Header.ID, "resolver", resolverName(address))
+	exchange := dnsExchange
+	if multicastName(address) {
+		exchange = mdnsExchange
+	}
+	replyEncoded, err := exchange(queryEncoded)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Parse the answers
+	var parser dnsmessage.Parser
+	header, err := parser.Start(replyEncoded)
+	if err != nil {
+		return nil, 0, err
+	}
+	if header.ID != query.Header.ID {
+		return nil, 0, fmt.Errorf("DNS reply ID mismatch")
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, fmt.Errorf("DNS query for %s failed: %s", address, header.RCode)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, 0, err
+	}
+	var (
+		ipAddresses []*net.IPAddr
+		minTTL      uint32 = 1<<32 - 1
+	)
+	for {
+		answer, err := parser.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if answer.TTL < minTTL {
+			minTTL = answer.TTL
+		}
+		switch {
+		case answer.Type == dnsmessage.TypeA && queryType == dnsmessage.TypeA:
+			record, err := parser.AResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			ipAddresses = append(ipAddresses, &net.IPAddr{IP: net.IP(record.A[:])})
+		case answer.Type == dnsmessage.TypeAAAA && queryType == dnsmessage.TypeAAAA:
+			record, err := parser.AAAAResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			ipAddresses = append(ipAddresses, &net.IPAddr{IP: net.IP(record.AAAA[:])})
+		default:
+			if err := parser.SkipAnswer(); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	slog.Debug("Received DNS reply", "name", name, "id", header.ID, "answers", len(ipAddresses), "minTTL", minTTL)
+	if len(ipAddresses) == 0 {
+		return nil, 0, fmt.Errorf("No %s address found for %s via %s", resolveNetwork, address, resolverName(address))
+	}
+	return ipAddresses, time.Duration(minTTL) * time.Second, nil
+}
+
+// Exchange a DNS message over plain UDP with the custom or system resolver
+func udpExchange(queryEncoded []byte) ([]byte, error) {
+	server := resolverAddress
+	if server == "" {
+		var err error
+		if server, err = systemNameserver(); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := net.DialTimeout("udp", server, dnsQueryTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(dnsQueryTimeout)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(queryEncoded); err != nil {
+		return nil, err
+	}
+	replyEncoded := make([]byte, dnsMaxMessageSize)
+	replyRead, err := conn.Read(replyEncoded)
+	if err != nil {
+		return nil, err
+	}
+	return replyEncoded[:replyRead], nil
+}
+
+// Exchange a DNS message over TLS (RFC 7858), framed with a two byte length prefix
+func tlsExchange(queryEncoded []byte) ([]byte, error) {
+	host, _, err := net.SplitHostPort(dotAddress)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: dnsQueryTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", dotAddress, &tls.Config{ServerName: host})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(dnsQueryTimeout)); err != nil {
+		return nil, err
+	}
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(queryEncoded)))
+	if _, err := conn.Write(append(framed, queryEncoded...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	replyEncoded := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, replyEncoded); err != nil {
+		return nil, err
+	}
+	return replyEncoded, nil
+}
+
+// Exchange a DNS message over HTTPS (RFC 8484) with a POST request
+func httpsExchange(queryEncoded []byte) ([]byte, error) {
+	request, err := http.NewRequest(http.MethodPost, dohURL, bytes.NewReader(queryEncoded))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", dnsMessageType)
+	request.Header.Set("Accept", dnsMessageType)
+	client := &http.Client{Timeout: dnsQueryTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS-over-HTTPS query to %s failed: %s", dohURL, response.Status)
+	}
+	return io.ReadAll(io.LimitReader(response.Body, 1<<16))
+}
+
+// Exchange a DNS message over multicast DNS (RFC 6762) as a one-shot legacy query,
+// which responders answer by unicast to our port with the query ID echoed
+func mdnsExchange(queryEncoded []byte) ([]byte, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(mdnsQueryTimeout)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteTo(queryEncoded, &net.UDPAddr{IP: mdnsGroup4, Port: mdnsPort}); err != nil {
+		return nil, err
+	}
+	// Skip unrelated multicast traffic until a response to this query arrives
+	replyEncoded := make([]byte, dnsMaxMessageSize)
+	for {
+		replyRead, _, err := conn.ReadFrom(replyEncoded)
+		if err != nil {
+			return nil, err
+		}
+		if replyRead >= 2 && bytes.Equal(replyEncoded[:2], queryEncoded[:2]) {
+			return replyEncoded[:replyRead], nil
+		}
+	}
+}
+
+// Whether the hostname belongs to the multicast DNS .local domain
+func multicastName(address string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSuffix(address, ".")), ".local")
+}
+
+// Whether target resolution goes over encrypted DNS (-doh or -dot)
+func encryptedDNS() bool {
+	return dohURL != "" || dotAddress != ""
+}
+
+// Describe the resolver used for the address in log messages
+func resolverName(address string) string {
+	switch {
+	case multicastName(address):
+		return "mDNS"
+	case dohURL != "":
+		return dohURL
+	case dotAddress != "":
+		return "tls://" + dotAddress
+	case resolverAddress != "":
+		return resolverAddress
+	default:
+		return "system resolver"
+	}
+}
+
+// Read the first nameserver from the system resolver configuration
+func systemNameserver() (string, error) {
+	file, err := os.Open(resolvConf)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53"), nil
+		}
+	}
+	return "", fmt.Errorf("No nameserver found in %s", resolvConf)
+}
+
+// Format an address as "hostname (ip)" using a cached reverse DNS lookup,
+// falling back to the bare IP if -n was given or no PTR record exists
+func displayAddress(address *net.IPAddr) string {
+	if address == nil || numeric {
+		return address.String()
+	}
+	name := reverseName(address)
+	if name == "" {
+		return address.String()
+	}
+	return fmt.Sprintf("%s (%s)", name, address)
+}
+
+// Hostname of an address from a cached reverse DNS lookup, empty if no PTR record exists
+func reverseName(address *net.IPAddr) string {
+	ip := address.String()
+	reverseNamesMutex.Lock()
+	name, cached := reverseNames[ip]
+	reverseNamesMutex.Unlock()
+	if !cached {
+		if names, err := net.LookupAddr(address.IP.String()); err == nil && len(names) > 0 {
+			name = strings.TrimSuffix(names[0], ".")
+		}
+		reverseNamesMutex.Lock()
+		reverseNames[ip] = name
+		reverseNamesMutex.Unlock()
+	}
+	return name
+}
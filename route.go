@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// Evidence of the path to a target, watched for shifts that suggest it was rerouted
+type routeTracker struct {
+	replyTTL  int    // TTL of the last echo reply from the target, 0 before the first
+	responder net.IP // Source of the last time exceeded message, nil before the first
+	changes   int    // Number of possible route changes logged
+}
+
+// Log a possible route change if the reply TTL of an echo reply, or the responder of a time exceeded
+// message, differs from the last one seen. The TTL is 0 and the responder nil when a probe had neither.
+func (stats *statistic) trackRoute(replyTTL int, responder net.IP, sent time.Time) {
+	route := &stats.route
+	if replyTTL > 0 {
+		if route.replyTTL > 0 && replyTTL != route.replyTTL {
+			route.changes++
+			slog.Warn(
+				fmt.Sprintf(
+					"Possible route change at %s: reply TTL %d -> %d (%+d hops)%s",
+					sent.Format(time.RFC3339),
+					route.replyTTL,
+					replyTTL,
+					route.replyTTL-replyTTL,
+					stats.labels.suffix()),
+				"target", stats.target.address,
+				"seq", stats.count,
+				"from", route.replyTTL,
+				"to", replyTTL,
+				stats.labels.attr())
+		}
+		route.replyTTL = replyTTL
+	}
+	if responder != nil {
+		if route.responder != nil && !responder.Equal(route.responder) {
+			route.changes++
+			slog.Warn(
+				fmt.Sprintf(
+					"Possible route change at %s: time exceeded from %s instead of %s%s",
+					sent.Format(time.RFC3339),
+					displayAddress(&net.IPAddr{IP: responder}),
+					displayAddress(&net.IPAddr{IP: route.responder}),
+					stats.labels.suffix()),
+				"target", stats.target.address,
+				"seq", stats.count,
+				"from", route.responder.String(),
+				"to", responder.String(),
+				stats.labels.attr())
+		}
+		route.responder = responder
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import "time"
+
+const (
+	defaultRTOMin time.Duration = 100 * time.Millisecond // Default lower bound of adaptive timeouts (-rto-min)
+	defaultRTOMax time.Duration = 10 * time.Second       // Default upper bound of adaptive timeouts (-rto-max)
+)
+
+var (
+	adaptiveTimeout bool          // Derive each probe's timeout from the RTTs so far (-adaptive-timeout) flag
+	rtoMin          time.Duration // Lower bound of adaptive timeouts (-rto-min) flag
+	rtoMax          time.Duration // Upper bound of adaptive timeouts (-rto-max) flag
+)
+
+// Retransmission timeout estimator of RFC 6298 as TCP uses it, turning smoothed RTT and RTT variance
+// into the time to wait for a reply before counting a probe lost
+type rtoEstimator struct {
+	srtt    time.Duration // Smoothed RTT
+	rttvar  time.Duration // RTT variance
+	sampled bool          // Whether an RTT has been measured yet
+	backoff uint          // Timeouts in a row since the last reply, each doubling the timeout
+}
+
+// Timeout of the next probe, the upper bound until an RTT has been measured
+func (estimator *rtoEstimator) timeout() time.Duration {
+	if !estimator.sampled {
+		return rtoMax
+	}
+	rto := estimator.srtt + 4*estimator.rttvar
+	rto = min(rto<<min(estimator.backoff, 16), rtoMax)
+	return max(rto, rtoMin)
+}
+
+// Update the estimate with the outcome of a probe: the RTT of a reply, or a timeout backing off
+func (estimator *rtoEstimator) observe(timedOut bool, rtt time.Duration) {
+	if timedOut {
+		estimator.backoff++
+		return
+	}
+	estimator.backoff = 0
+	if !estimator.sampled {
+		estimator.srtt, estimator.rttvar, estimator.sampled = rtt, rtt/2, true
+		return
+	}
+	difference := estimator.srtt - rtt
+	if difference < 0 {
+		difference = -difference
+	}
+	estimator.rttvar = (3*estimator.rttvar + difference) / 4
+	estimator.srtt = (7*estimator.srtt + rtt) / 8
+}
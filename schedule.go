@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Days of the week as written in schedules, indexed by time.Weekday
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Window of the day probing is allowed in, as written in the config file,
+// e.g. {"days": "mon-fri", "from": "09:00", "to": "18:00"}
+type scheduleWindow struct {
+	Days string `json:"days"` // Days the window opens on: mon-fri, sat,sun, or * or empty for every day
+	From string `json:"from"` // Local time the window opens, HH:MM
+	To   string `json:"to"`   // Local time the window closes, HH:MM, before From to run past midnight
+}
+
+// Parsed window of a probing schedule
+type probeWindow struct {
+	days     [7]bool // Days of the week the window opens on
+	from, to int     // Minutes of the day the window opens and closes
+}
+
+// Windows of local time probes may be sent in, probing paused outside them
+type schedule []probeWindow
+
+var probeSchedule schedule // Probing schedule of the config file, nil to probe at any time
+
+// Parse the schedule of the config file
+func parseSchedule(windows []scheduleWindow) (schedule, error) {
+	var plan schedule
+	for _, window := range windows {
+		var parsed probeWindow
+		var err error
+		if parsed.days, err = parseDays(window.Days); err != nil {
+			return nil, err
+		}
+		if parsed.from, err = minuteOfDay(window.From); err != nil {
+			return nil, err
+		}
+		if parsed.to, err = minuteOfDay(window.To); err != nil {
+			return nil, err
+		}
+		if parsed.from == parsed.to {
+			return nil, fmt.Errorf("Schedule window from %s to %s is empty", window.From, window.To)
+		}
+		plan = append(plan, parsed)
+	}
+	return plan, nil
+}
+
+// Parse days such as mon-fri or sat,sun, * or empty for every day
+func parseDays(days string) ([7]bool, error) {
+	var set [7]bool
+	days = strings.ToLower(strings.TrimSpace(days))
+	if days == "" || days == "*" {
+		return [7]bool{true, true, true, true, true, true, true}, nil
+	}
+	for _, part := range strings.Split(days, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		if !isRange {
+			last = first
+		}
+		start, end := weekdayIndex(first), weekdayIndex(last)
+		if start < 0 || end < 0 {
+			return set, fmt.Errorf("Invalid schedule days %q, expected e.g. mon-fri or sat,sun", days)
+		}
+		// Ranges may wrap around the week, e.g. fri-mon
+		for day := start; ; day = (day + 1) % 7 {
+			set[day] = true
+			if day == end {
+				break
+			}
+		}
+	}
+	return set, nil
+}
+
+// Index of a day name in weekdayNames, -1 if unknown
+func weekdayIndex(name string) int {
+	for i, weekday := range weekdayNames {
+		if name == weekday {
+			return i
+		}
+	}
+	return -1
+}
+
+// Parse a HH:MM time into minutes since midnight
+func minuteOfDay(clock string) (int, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid schedule time %q, expected HH:MM", clock)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Whether the schedule allows probing at the time
+func (plan schedule) allows(now time.Time) bool {
+	minute := now.Hour()*60 + now.Minute()
+	today := int(now.Weekday())
+	yesterday := (today + 6) % 7
+	for _, window := range plan {
+		if window.from < window.to {
+			if window.days[today] && minute >= window.from && minute < window.to {
+				return true
+			}
+			continue
+		}
+		// Windows past midnight belong to the day they open on
+		if (window.days[today] && minute >= window.from) || (window.days[yesterday] && minute < window.to) {
+			return true
+		}
+	}
+	return false
+}
+
+// Start of the next minute the schedule allows probing in, within the coming week
+func (plan schedule) next(now time.Time) time.Time {
+	minute := now.Truncate(time.Minute)
+	for i := 0; i <= 7*24*60; i++ {
+		minute = minute.Add(time.Minute)
+		if plan.allows(minute) {
+			return minute
+		}
+	}
+	return minute
+}
+
+// Sleep until the schedule allows probing, returning false if stopped meanwhile.
+// Only called by the target's probing goroutine.
+func (stats *statistic) waitForSchedule(stop <-chan struct{}) bool {
+	if probeSchedule == nil || probeSchedule.allows(time.Now()) {
+		return true
+	}
+	resume := probeSchedule.next(time.Now())
+	slog.Info(fmt.Sprintf("Outside the probing schedule. Pausing %s until %s...", stats.target.address, resume.Format("Mon 15:04")))
+	// Time outside the schedule isn't measured, so it counts as neither up nor down
+	stats.skipAvailability()
+	select {
+	case <-stop:
+		return false
+	case <-time.After(time.Until(resume)):
+		return true
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const subscriberBuffer int = 256 // Results buffered per stream subscriber before they are dropped for it
+
+var apiToken string // Bearer token clients of goping serve must present (-api-token) flag, empty for none
+
+// Ping jobs started and stopped remotely in server mode (goping serve), publishing every probe
+// result to subscribed streams
+type engine struct {
+	mutex       sync.Mutex                  // Guards the fields below
+	jobs        map[string]*job             // Jobs by ID
+	order       []string                    // Job IDs in the order they were started
+	lastJob     int                         // Number of the last job started
+	subscribers map[chan probeResult]string // Streams of results, each with the job it follows, empty for all
+	echo        func(probeResult)           // Also passed every result if set, e.g. to log it in daemon mode
+}
+
+// Targets pinged together, started by one request
+type job struct {
+	id      string        // ID of the job
+	stats   []*statistic  // Statistics client per target
+	stop    chan struct{} // Closed to stop the job
+	stopped sync.Once     // Closes stop once
+	done    chan struct{} // Closed once all targets have finished
+}
+
+// Status of a job with its targets' statistics
+type jobStatus struct {
+	ID      string          `json:"id"`      // ID of the job
+	Running bool            `json:"running"` // Whether the job is still probing
+	Targets []targetSummary `json:"targets"` // Statistics of each target
+}
+
+// Create an engine with no jobs
+func newEngine() *engine {
+	return &engine{jobs: make(map[string]*job), subscribers: make(map[chan probeResult]string)}
+}
+
+// Start pinging the targets, each optionally labelled as host=label, count times or forever if -1.
+// Every target must resolve, as on the command line, so a mistyped hostname is rejected rather
+// than losing every probe.
+func (server *engine) start(targets []string, count int, tags labels) (*job, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("Please enter at least one IP/hostname to ping")
+	}
+	if count == 0 || count < -1 {
+		return nil, errors.New("Times to ping must be positive int, or -1 for infinite")
+	}
+	var allStats []*statistic
+	for _, target := range targets {
+		address, targetLabels, overrides := parseTarget(target, tags)
+		allStats = append(allStats, &statistic{
+			target:   &resolution{address: address},
+			labels:   targetLabels,
+			id:       nextEchoID(),
+			hopLimit: overrides.ttl,
+			interval: overrides.interval,
+			timeout:  overrides.timeout,
+		})
+	}
+	if err := errors.Join(resolveTargets(allStats)...); err != nil {
+		return nil, err
+	}
+
+	server.mutex.Lock()
+	server.lastJob++
+	newJob := &job{id: strconv.Itoa(server.lastJob), stats: allStats, stop: make(chan struct{}), done: make(chan struct{})}
+	server.jobs[newJob.id] = newJob
+	server.order = append(server.order, newJob.id)
+	server.mutex.Unlock()
+	var wg sync.WaitGroup
+	for _, stats := range newJob.stats {
+		wg.Add(1)
+		go func(stats *statistic) {
+			defer wg.Done()
+			stats.run(count, newJob.stop, func(result probeResult) {
+				result.Job = newJob.id
+				server.publish(result)
+			})
+		}(stats)
+	}
+	go func() {
+		wg.Wait()
+		close(newJob.done)
+		slog.Info(fmt.Sprintf("Job %s finished...", newJob.id))
+	}()
+	slog.Info(fmt.Sprintf("Started job %s pinging %d targets...", newJob.id, len(targets)))
+	return newJob, nil
+}
+
+// Whether an authorization header presents the -api-token as a bearer token, or no token is required
+func authorized(authorization string) bool {
+	if apiToken == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(apiToken)) == 1
+}
+
+// Warn that an API served on the address without an -api-token lets anyone reaching it start probes
+func warnIfExposed(api string, address string) {
+	host, _, err := net.SplitHostPort(address)
+	if apiToken != "" || err != nil {
+		return
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return
+	}
+	slog.Warn(fmt.Sprintf("Serving the %s API on %s without -api-token, so anyone reaching it can start probes...", api, address))
+}
+
+// Stop a job after its current probes, keeping its statistics
+func (server *engine) stop(id string) (*job, error) {
+	stopping, err := server.job(id)
+	if err != nil {
+		return nil, err
+	}
+	stopping.stopped.Do(func() {
+		close(stopping.stop)
+		slog.Info(fmt.Sprintf("Stopping job %s...", id))
+	})
+	return stopping, nil
+}
+
+// Stop a job and remove it from the engine once its current probes finish,
+// so it no longer appears in statistics
+func (server *engine) remove(id string) (*job, error) {
+	removed, err := server.stop(id)
+	if err != nil {
+		return nil, err
+	}
+	<-removed.done
+	server.mutex.Lock()
+	delete(server.jobs, id)
+	for i, listed := range server.order {
+		if listed == id {
+			server.order = append(server.order[:i], server.order[i+1:]...)
+			break
+		}
+	}
+	server.mutex.Unlock()
+	slog.Info(fmt.Sprintf("Removed job %s...", id))
+	return removed, nil
+}
+
+// Job with the ID
+func (server *engine) job(id string) (*job, error) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	found, ok := server.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("No job %q", id)
+	}
+	return found, nil
+}
+
+// Status of all jobs in the order they were started
+func (server *engine) statuses() []jobStatus {
+	server.mutex.Lock()
+	jobs := make([]*job, len(server.order))
+	for i, id := range server.order {
+		jobs[i] = server.jobs[id]
+	}
+	server.mutex.Unlock()
+	statuses := make([]jobStatus, len(jobs))
+	for i, listed := range jobs {
+		statuses[i] = listed.status()
+	}
+	return statuses
+}
+
+// Statistics clients of all jobs, for the summary at termination
+func (server *engine) allStats() []*statistic {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	var allStats []*statistic
+	for _, id := range server.order {
+		allStats = append(allStats, server.jobs[id].stats...)
+	}
+	return allStats
+}
+
+// Status of the job with its targets' statistics so far
+func (listed *job) status() jobStatus {
+	status := jobStatus{ID: listed.id, Running: true, Targets: make([]targetSummary, len(listed.stats))}
+	select {
+	case <-listed.done:
+		status.Running = false
+	default:
+	}
+	for i, stats := range listed.stats {
+		status.Targets[i] = stats.summary()
+	}
+	return status
+}
+
+// Up to the last n results of a job, or of all jobs if the ID is empty, oldest first
+func (server *engine) last(n int, id string) ([]probeResult, error) {
+	if id != "" {
+		if _, err := server.job(id); err != nil {
+			return nil, err
+		}
+	}
+	if recentResults == nil {
+		return nil, errors.New("No results are kept. Please serve with -history above 0")
+	}
+	return recentResults.last(n, id), nil
+}
+
+// Subscribe to the results of a job, or of all jobs if the ID is empty,
+// returning the stream and a function to unsubscribe
+func (server *engine) subscribe(id string) (<-chan probeResult, func(), error) {
+	if id != "" {
+		if _, err := server.job(id); err != nil {
+			return nil, nil, err
+		}
+	}
+	results := make(chan probeResult, subscriberBuffer)
+	server.mutex.Lock()
+	server.subscribers[results] = id
+	server.mutex.Unlock()
+	return results, func() {
+		server.mutex.Lock()
+		delete(server.subscribers, results)
+		server.mutex.Unlock()
+	}, nil
+}
+
+// Pass a result to every subscriber following its job, dropping it for subscribers too slow
+// to keep up rather than delaying probes
+func (server *engine) publish(result probeResult) {
+	if recentResults != nil {
+		recentResults.add(result)
+	}
+	if server.echo != nil {
+		server.echo(result)
+	}
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	for results, id := range server.subscribers {
+		if id != "" && id != result.Job {
+			continue
+		}
+		select {
+		case results <- result:
+		default:
+			slog.Debug("Dropped result for slow subscriber", "job", result.Job, "seq", result.Seq)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+// A job whose targets don't all resolve is rejected before any is probed
+func TestStartUnresolvedTarget(t *testing.T) {
+	failLookups(t)
+	server := newEngine()
+	if _, err := server.start([]string{"127.0.0.1", "nonexistent.invalid"}, 1, nil); err == nil {
+		t.Fatal("Started a job with a target that doesn't resolve")
+	}
+	if len(server.jobs) != 0 || server.lastJob != 0 {
+		t.Errorf("%d jobs registered and %d started, want none", len(server.jobs), server.lastJob)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// Windows services are only found on Windows, elsewhere -daemon runs under systemd or another supervisor
+func controlService(_ string, _ []string) error {
+	return errors.New("Windows services are only available on Windows, run -daemon under systemd instead")
+}
+
+// Run the daemon until terminated
+func runDaemon(service *daemon) error {
+	return service.run()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	windowsServiceName        string = "goPing"                                                  // Name of the service in the service manager
+	windowsServiceDescription string = "Pings targets and monitors their availability (-daemon)" // Description shown in services.msc
+)
+
+// Install, uninstall, start or stop goPing as a Windows service (goping service), installed to run
+// the daemon (-daemon) at boot with the arguments following install
+func controlService(action string, arguments []string) error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("Failed to connect to the service manager: %w", err)
+	}
+	defer manager.Disconnect()
+
+	if action == "install" {
+		executable, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		if existing, err := manager.OpenService(windowsServiceName); err == nil {
+			existing.Close()
+			return fmt.Errorf("Service %s is already installed", windowsServiceName)
+		}
+		service, err := manager.CreateService(windowsServiceName, executable, mgr.Config{
+			DisplayName: windowsServiceName,
+			Description: windowsServiceDescription,
+			StartType:   mgr.StartAutomatic,
+		}, append([]string{"-daemon"}, arguments...)...)
+		if err != nil {
+			return fmt.Errorf("Failed to install service %s: %w", windowsServiceName, err)
+		}
+		service.Close()
+		slog.Info(fmt.Sprintf("Installed service %s running %s -daemon...", windowsServiceName, executable))
+		return nil
+	}
+
+	service, err := manager.OpenService(windowsServiceName)
+	if err != nil {
+		return fmt.Errorf("Service %s isn't installed: %w", windowsServiceName, err)
+	}
+	defer service.Close()
+	switch action {
+	case "uninstall":
+		err = service.Delete()
+	case "start":
+		err = service.Start()
+	case "stop":
+		_, err = service.Control(svc.Stop)
+	default:
+		return fmt.Errorf("Unknown service action %q, expected install, uninstall, start or stop", action)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to %s service %s: %w", action, windowsServiceName, err)
+	}
+	slog.Info(fmt.Sprintf("Service %s %s...", windowsServiceName, serviceActionDone[action]))
+	return nil
+}
+
+// Outcome of each service action, as logged
+var serviceActionDone = map[string]string{"uninstall": "uninstalled", "start": "started", "stop": "stopping"}
+
+// Run the daemon until terminated, under the service manager if started as a Windows service
+func runDaemon(service *daemon) error {
+	if isService, err := svc.IsWindowsService(); err != nil || !isService {
+		return service.run()
+	}
+	return svc.Run(windowsServiceName, &windowsService{service: service})
+}
+
+// Handler of service manager requests to the daemon run as a Windows service
+type windowsService struct {
+	service *daemon // Daemon run by the service
+}
+
+// Run the daemon, reporting its state to the service manager, until it is stopped or the system shuts down
+func (running *windowsService) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	failed := make(chan error, 1)
+	go func() { failed <- running.service.run() }()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case err := <-failed:
+			slog.Error(err.Error())
+			return false, 1
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				showSummary(running.service.server.allStats())
+				return false, 0
+			}
+		}
+	}
+}
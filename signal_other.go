@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// SIGUSR1 doesn't exist here, so probing is only paused through the HTTP API
+func notifyPauseSignal(c chan<- os.Signal) bool {
+	return false
+}
+
+// SIGUSR2 doesn't exist here, so statistics are only reset through the HTTP API
+func notifyResetSignal(c chan<- os.Signal) bool {
+	return false
+}
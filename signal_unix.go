@@ -0,0 +1,21 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Deliver SIGUSR1 to the channel to toggle pausing
+func notifyPauseSignal(c chan<- os.Signal) bool {
+	signal.Notify(c, syscall.SIGUSR1)
+	return true
+}
+
+// Deliver SIGUSR2 to the channel to reset statistics
+func notifyResetSignal(c chan<- os.Signal) bool {
+	signal.Notify(c, syscall.SIGUSR2)
+	return true
+}
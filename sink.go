@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	defaultNATSSubject string        = "goping.results" // Subject published to when a nats:// sink names none
+	defaultKafkaTopic  string        = "goping"         // Topic produced to when a kafka:// sink names none
+	defaultKafkaPort   string        = "9092"           // Port of Kafka brokers given without one
+	sinkFlushTimeout   time.Duration = 5 * time.Second  // Longest wait for buffered events at termination
+)
+
+// Destination every probe result is published to as a JSON event (-sink), for feeding
+// existing event pipelines
+type eventSink interface {
+	publish(result probeResult) error // Queue a result for delivery, without waiting on the network
+	close() error                     // Deliver queued results and disconnect
+}
+
+// Event sink (-sink) if given, nil otherwise
+var sink eventSink
+
+// Open the event sink named by a URL: nats://[user:password@]host[:port][/subject] or
+// kafka://broker[:port][,broker...][/topic]
+func openSink(sinkURL string) (eventSink, error) {
+	parsed, err := url.Parse(sinkURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("Invalid sink %q, expected nats://host/subject or kafka://brokers/topic", sinkURL)
+	}
+	name := strings.TrimPrefix(parsed.Path, "/")
+	switch parsed.Scheme {
+	case "nats":
+		if name == "" {
+			name = defaultNATSSubject
+		}
+		return openNATSSink(parsed, name)
+	case "kafka":
+		if name == "" {
+			name = defaultKafkaTopic
+		}
+		return openKafkaSink(strings.Split(parsed.Host, ","), name), nil
+	default:
+		return nil, fmt.Errorf("Unsupported sink %q, expected nats:// or kafka://", parsed.Scheme)
+	}
+}
+
+// Sink publishing results to a NATS subject
+type natsSink struct {
+	conn    *nats.Conn // Connection to the NATS server, reconnecting as needed
+	subject string     // Subject results are published to
+}
+
+// Connect to the NATS server, with the subject stripped from the URL
+func openNATSSink(server *url.URL, subject string) (*natsSink, error) {
+	address := *server
+	address.Path = ""
+	conn, err := nats.Connect(address.String(), nats.Name("goPing"))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to NATS at %s: %w", server.Host, err)
+	}
+	return &natsSink{conn: conn, subject: subject}, nil
+}
+
+// Publish a result, buffered by the client while disconnected
+func (events *natsSink) publish(result probeResult) error {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return events.conn.Publish(events.subject, payload)
+}
+
+// Flush buffered results and disconnect
+func (events *natsSink) close() error {
+	defer events.conn.Close()
+	return events.conn.FlushTimeout(sinkFlushTimeout)
+}
+
+// Sink producing results to a Kafka topic, keyed by target so each target's results stay in order
+type kafkaSink struct {
+	writer *kafka.Writer // Asynchronous producer batching results in the background
+}
+
+// Create a producer for the brokers, which connects on the first result
+func openKafkaSink(brokers []string, topic string) *kafkaSink {
+	for i, broker := range brokers {
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			brokers[i] = net.JoinHostPort(broker, defaultKafkaPort)
+		}
+	}
+	return &kafkaSink{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		BatchTimeout: time.Second,
+		Async:        true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				slog.Warn(fmt.Sprintf("Failed to deliver %d results to Kafka: %v", len(messages), err))
+			}
+		},
+	}}
+}
+
+// Queue a result for the producer
+func (events *kafkaSink) publish(result probeResult) error {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return events.writer.WriteMessages(context.Background(), kafka.Message{Key: []byte(result.Target), Value: payload})
+}
+
+// Deliver queued results and close the producer
+func (events *kafkaSink) close() error {
+	return events.writer.Close()
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSLOWindow   time.Duration = 24 * time.Hour // Default span SLO compliance is measured over
+	defaultSLOReport   time.Duration = time.Hour      // Default interval of SLO reports
+	defaultBurnRate    float64       = 10             // Default error budget burn rate alerted on
+	defaultPercentile  float64       = 95             // Default percentile of RTT objectives
+	burnWindowFraction int           = 24             // Burn rate is measured over this fraction of the SLO window
+)
+
+// Tracks compliance of every target with the SLOs of the config file over rolling windows, reporting
+// periodically and alerting when an error budget burns too fast
+type sloTracker struct {
+	settings sloConfig                // Objectives, report interval and burn rate
+	alerts   *alerter                 // Notifiers burn alerts are sent to
+	mutex    sync.Mutex               // Guards the fields below
+	targets  map[*statistic]*sloState // Compliance state of each target seen
+	order    []*statistic             // Targets in the order they were seen, for reports
+}
+
+// Compliance state of a target with each objective
+type sloState struct {
+	target     string           // Hostname or IP address as given
+	labels     labels           // Labels of the target
+	objectives []objectiveState // State per objective, in config order
+}
+
+// Probe outcomes of a target against one objective
+type objectiveState struct {
+	window  rollingWindow // Outcomes over the SLO window
+	recent  rollingWindow // Outcomes over the burn rate window
+	burning bool          // Whether a burn alert is raised
+}
+
+// Create a tracker of the SLOs, validating them and sending burn alerts to the alerter's notifiers
+func newSLOTracker(settings sloConfig, alerts *alerter) (*sloTracker, error) {
+	if len(settings.Objectives) == 0 {
+		return nil, errors.New("Please declare at least one SLO objective")
+	}
+	for i := range settings.Objectives {
+		objective := &settings.Objectives[i]
+		if (objective.RTT > 0) == (objective.Loss > 0) {
+			return nil, fmt.Errorf("SLO %q must set exactly one of rtt and loss", objective.Name)
+		}
+		if objective.Percentile == 0 {
+			objective.Percentile = defaultPercentile
+		}
+		if objective.Percentile <= 0 || objective.Percentile >= 100 {
+			return nil, fmt.Errorf("SLO %q percentile must be between 0 and 100", objective.Name)
+		}
+		if objective.Window <= 0 {
+			objective.Window = duration(defaultSLOWindow)
+		}
+		if objective.Name == "" {
+			objective.Name = "loss"
+			if objective.RTT > 0 {
+				objective.Name = "rtt"
+			}
+		}
+	}
+	if settings.Report <= 0 {
+		settings.Report = duration(defaultSLOReport)
+	}
+	if settings.BurnRate <= 0 {
+		settings.BurnRate = defaultBurnRate
+	}
+	return &sloTracker{settings: settings, alerts: alerts, targets: make(map[*statistic]*sloState)}, nil
+}
+
+// Describe the objective, e.g. "p95 RTT < 80ms over 24h"
+func (objective objectiveConfig) String() string {
+	if objective.RTT > 0 {
+		return fmt.Sprintf("p%g RTT < %s over %s", objective.Percentile, time.Duration(objective.RTT), time.Duration(objective.Window))
+	}
+	return fmt.Sprintf("loss < %g%% over %s", objective.Loss, time.Duration(objective.Window))
+}
+
+// Fraction of probes allowed to be bad: lost for loss objectives, slower than the RTT for RTT objectives
+func (objective objectiveConfig) budget() float64 {
+	if objective.RTT > 0 {
+		return (100 - objective.Percentile) / 100
+	}
+	return objective.Loss / 100
+}
+
+// Fraction of the probes in the window that are bad against the objective, and how many counted.
+// Lost probes only count against loss objectives.
+func (objective objectiveConfig) badFraction(window *rollingWindow) (float64, int) {
+	bad, counted := 0, 0
+	for _, sample := range window.samples {
+		switch {
+		case objective.Loss > 0:
+			counted++
+			if !sample.replied {
+				bad++
+			}
+		case sample.replied:
+			counted++
+			if sample.rtt >= time.Duration(objective.RTT) {
+				bad++
+			}
+		}
+	}
+	if counted == 0 {
+		return 0, 0
+	}
+	return float64(bad) / float64(counted), counted
+}
+
+// Value of the objective's measure over the window, e.g. "p95 12ms" or "loss 0.10%"
+func (objective objectiveConfig) measure(window *rollingWindow) string {
+	if objective.Loss > 0 {
+		return fmt.Sprintf("loss %.2f%%", window.stats().Loss)
+	}
+	var rtts []time.Duration
+	for _, sample := range window.samples {
+		if sample.replied {
+			rtts = append(rtts, sample.rtt)
+		}
+	}
+	return fmt.Sprintf("p%g %s", objective.Percentile, display(percentile(rtts, objective.Percentile)))
+}
+
+// Add a probe result of the target to its objectives' windows, alerting when the error budget of an
+// objective starts or stops burning at the configured rate
+func (slos *sloTracker) observe(stats *statistic, result probeResult) {
+	slos.mutex.Lock()
+	tracked, ok := slos.targets[stats]
+	if !ok {
+		tracked = &sloState{target: result.Target, labels: result.Labels}
+		for _, objective := range slos.settings.Objectives {
+			tracked.objectives = append(tracked.objectives, objectiveState{
+				window: rollingWindow{span: time.Duration(objective.Window)},
+				recent: rollingWindow{span: time.Duration(objective.Window) / time.Duration(burnWindowFraction)},
+			})
+		}
+		slos.targets[stats] = tracked
+		slos.order = append(slos.order, stats)
+	}
+	var events []alertEvent
+	for i, objective := range slos.settings.Objectives {
+		state := &tracked.objectives[i]
+		state.window.add(result)
+		state.recent.add(result)
+		bad, counted := objective.badFraction(&state.recent)
+		if counted < minAlertSamples {
+			continue
+		}
+		rate := bad / objective.budget()
+		burning := rate >= slos.settings.BurnRate
+		if burning == state.burning {
+			continue
+		}
+		state.burning = burning
+		event := alertEvent{
+			Time:   result.Time,
+			Kind:   alertBudgetBurn,
+			Target: result.Target,
+			IP:     result.IP,
+			Labels: result.Labels,
+			Reason: fmt.Sprintf("SLO %s (%s) error budget burning at %.1fx, %s left", objective.Name, objective, rate, slos.budgetLeft(objective, &state.window)),
+			Stats:  state.recent.stats(),
+		}
+		if !burning {
+			event.Kind = alertBudgetRecovery
+			event.Reason = fmt.Sprintf("SLO %s (%s) error budget burning at %.1fx", objective.Name, objective, rate)
+		}
+		events = append(events, event)
+	}
+	slos.mutex.Unlock()
+
+	for _, event := range events {
+		go slos.alerts.send(event)
+	}
+}
+
+// Share of the error budget of the objective left over its window
+func (slos *sloTracker) budgetLeft(objective objectiveConfig, window *rollingWindow) string {
+	bad, _ := objective.badFraction(window)
+	return fmt.Sprintf("%.1f%%", max(0, 1-bad/objective.budget())*100)
+}
+
+// Log an SLO report every report interval
+func (slos *sloTracker) reportPeriodically() {
+	ticker := time.NewTicker(time.Duration(slos.settings.Report))
+	defer ticker.Stop()
+	for range ticker.C {
+		slos.report()
+	}
+}
+
+// Log the compliance of every target with every objective so far
+func (slos *sloTracker) report() {
+	slos.mutex.Lock()
+	defer slos.mutex.Unlock()
+	slog.Info("----------------------------| SLO report |----------------------------")
+	for _, stats := range slos.order {
+		tracked := slos.targets[stats]
+		for i, objective := range slos.settings.Objectives {
+			state := &tracked.objectives[i]
+			bad, _ := objective.badFraction(&state.window)
+			verdict := "MET"
+			if bad > objective.budget() {
+				verdict = "MISSED"
+			}
+			slog.Info(
+				fmt.Sprintf(
+					"SLO: %s (%s)\t\tTarget: %s\t\t%s\t\t%s\t\tBudget left: %s%s",
+					objective.Name,
+					objective,
+					tracked.target,
+					objective.measure(&state.window),
+					verdict,
+					slos.budgetLeft(objective, &state.window),
+					tracked.labels.suffix()),
+				"slo", objective.Name,
+				"target", tracked.target,
+				"met", verdict == "MET",
+				tracked.labels.attr())
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSNMPCommunity  string = "public"                     // Community of traps unless configured
+	defaultSNMPEnterprise string = "1.3.6.1.4.1.8072.9999.9999" // Enterprise OID of traps unless configured, NET-SNMP's experimental arc
+	snmpTrapPort          string = "162"                        // Port SNMP managers receive traps on
+)
+
+// Well-known OIDs of every SNMPv2 trap (RFC 3416)
+var (
+	oidSysUpTime   = []uint64{1, 3, 6, 1, 2, 1, 1, 3, 0}       // sysUpTime.0, time since goPing started
+	oidSNMPTrapOID = []uint64{1, 3, 6, 1, 6, 3, 1, 1, 4, 1, 0} // snmpTrapOID.0, which trap this is
+)
+
+// ASN.1 BER tags of the SNMP types traps carry
+const (
+	berInteger     byte = 0x02
+	berOctetString byte = 0x04
+	berOID         byte = 0x06
+	berSequence    byte = 0x30
+	berGauge32     byte = 0x42
+	berTimeTicks   byte = 0x43
+	berTrapV2      byte = 0xa7
+)
+
+// Number of the trap under <enterprise>.0 of each kind of alert
+var snmpTrapNumbers = map[string]uint64{
+	alertDown:           1,
+	alertUp:             2,
+	alertSLABreach:      3,
+	alertSLARecovery:    4,
+	alertBudgetBurn:     5,
+	alertBudgetRecovery: 6,
+}
+
+// Notifier sending alerts as SNMPv2c traps to a manager, for NOCs alerting over SNMP
+type snmpNotifier struct {
+	settings   snmpConfig // Manager, community and enterprise OID
+	enterprise []uint64   // Arcs of the enterprise OID
+	started    time.Time  // When goPing started, for sysUpTime
+}
+
+// Create an SNMP trap notifier, checking its settings
+func newSNMPNotifier(settings snmpConfig) (*snmpNotifier, error) {
+	if settings.Manager == "" {
+		return nil, errors.New("SNMP traps need a manager to send them to")
+	}
+	if _, _, err := net.SplitHostPort(settings.Manager); err != nil {
+		settings.Manager = net.JoinHostPort(settings.Manager, snmpTrapPort)
+	}
+	if settings.Community == "" {
+		settings.Community = defaultSNMPCommunity
+	}
+	if settings.Enterprise == "" {
+		settings.Enterprise = defaultSNMPEnterprise
+	}
+	enterprise, err := parseOID(strings.TrimPrefix(settings.Enterprise, "."))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid SNMP enterprise OID %q: %w", settings.Enterprise, err)
+	}
+	return &snmpNotifier{settings: settings, enterprise: enterprise, started: time.Now()}, nil
+}
+
+// Name of the destination
+func (snmp *snmpNotifier) name() string {
+	return "SNMP"
+}
+
+// Send the alert as the trap <enterprise>.0.<kind> with its target and window statistics bound
+// to <enterprise>.1.<field>
+func (snmp *snmpNotifier) notify(event alertEvent) error {
+	under := func(arcs ...uint64) []uint64 {
+		return append(append([]uint64(nil), snmp.enterprise...), arcs...)
+	}
+	field := func(number uint64) []uint64 { return under(1, number) }
+	bindings := [][]byte{
+		varBind(oidSysUpTime, berValue(berTimeTicks, encodeUnsigned(uint64(time.Since(snmp.started)/(10*time.Millisecond))))),
+		varBind(oidSNMPTrapOID, berValue(berOID, encodeOID(under(0, snmpTrapNumbers[event.Kind])))),
+		varBind(field(1), berValue(berOctetString, []byte(event.Target))),
+		varBind(field(2), berValue(berOctetString, []byte(event.IP))),
+		varBind(field(3), berValue(berOctetString, []byte(event.Labels.String()))),
+		varBind(field(4), berValue(berOctetString, []byte(event.Reason))),
+		varBind(field(5), berValue(berGauge32, encodeUnsigned(uint64(event.Stats.Loss*100)))),
+		varBind(field(6), berValue(berGauge32, encodeUnsigned(uint64(event.Stats.AvgRTT/time.Microsecond)))),
+		varBind(field(7), berValue(berGauge32, encodeUnsigned(uint64(event.Stats.P95RTT/time.Microsecond)))),
+		varBind(field(8), berValue(berOctetString, []byte(event.subject()))),
+	}
+	pdu := berValue(berTrapV2, concat(
+		berValue(berInteger, encodeUnsigned(uint64(rand.Int32()))), // Request ID
+		berValue(berInteger, encodeUnsigned(0)),                    // Error status
+		berValue(berInteger, encodeUnsigned(0)),                    // Error index
+		berValue(berSequence, concat(bindings...)),
+	))
+	message := berValue(berSequence, concat(
+		berValue(berInteger, encodeUnsigned(1)), // SNMPv2c
+		berValue(berOctetString, []byte(snmp.settings.Community)),
+		pdu,
+	))
+
+	conn, err := net.DialTimeout("udp", snmp.settings.Manager, notifyTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(message)
+	return err
+}
+
+// Encode a variable binding of an OID to a BER encoded value
+func varBind(oid []uint64, value []byte) []byte {
+	return berValue(berSequence, concat(berValue(berOID, encodeOID(oid)), value))
+}
+
+// Encode a BER tag, length and contents
+func berValue(tag byte, contents []byte) []byte {
+	encoded := []byte{tag}
+	if length := len(contents); length < 0x80 {
+		encoded = append(encoded, byte(length))
+	} else {
+		var lengthBytes []byte
+		for ; length > 0; length >>= 8 {
+			lengthBytes = append([]byte{byte(length)}, lengthBytes...)
+		}
+		encoded = append(encoded, 0x80|byte(len(lengthBytes)))
+		encoded = append(encoded, lengthBytes...)
+	}
+	return append(encoded, contents...)
+}
+
+// Encode the contents of a non-negative INTEGER, Gauge32 or TimeTicks in as few bytes as
+// possible, with a leading zero byte where the high bit would make it negative
+func encodeUnsigned(value uint64) []byte {
+	encoded := []byte{byte(value)}
+	for value >>= 8; value > 0; value >>= 8 {
+		encoded = append([]byte{byte(value)}, encoded...)
+	}
+	if encoded[0]&0x80 != 0 {
+		encoded = append([]byte{0}, encoded...)
+	}
+	return encoded
+}
+
+// Parse the arcs of a dotted OID such as 1.3.6.1.4.1.8072
+func parseOID(oid string) ([]uint64, error) {
+	parts := strings.Split(oid, ".")
+	if len(parts) < 2 {
+		return nil, errors.New("An OID needs at least two arcs")
+	}
+	arcs := make([]uint64, len(parts))
+	for i, part := range parts {
+		arc, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		arcs[i] = arc
+	}
+	if arcs[0] > 2 || (arcs[0] < 2 && arcs[1] > 39) {
+		return nil, errors.New("An OID must start with 0, 1 or 2 and a second arc below 40")
+	}
+	return arcs, nil
+}
+
+// Encode the contents of an OID, its first two arcs in one byte and the rest base 128
+func encodeOID(oid []uint64) []byte {
+	arcs := append([]uint64{oid[0]*40 + oid[1]}, oid[2:]...)
+	var encoded []byte
+	for _, arc := range arcs {
+		chunk := []byte{byte(arc & 0x7f)}
+		for arc >>= 7; arc > 0; arc >>= 7 {
+			chunk = append([]byte{byte(arc&0x7f) | 0x80}, chunk...)
+		}
+		encoded = append(encoded, chunk...)
+	}
+	return encoded
+}
+
+// Join byte slices
+func concat(parts ...[]byte) []byte {
+	var joined []byte
+	for _, part := range parts {
+		joined = append(joined, part...)
+	}
+	return joined
+}
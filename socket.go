@@ -0,0 +1,484 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const controlMessageSize int = 512 // Buffer size for control messages such as kernel timestamps
+
+// Raw ICMP socket used to send a target's requests and read replies
+type icmpSocket struct {
+	conn             *net.IPConn       // Raw ICMP connection
+	ipv4Conn         *ipv4.PacketConn  // IPv4 socket options, nil for IPv6
+	ipv6Conn         *ipv6.PacketConn  // IPv6 socket options, nil for IPv4
+	kernelTimestamps bool              // Whether replies carry kernel receive timestamps
+	sentTimestamps   bool              // Whether requests are timestamped into the error queue (-timestamping hardware)
+	oob              []byte            // Buffer for control messages of each reply, such as timestamps and hop limits
+	flowOOB          []byte            // Control message setting the IPv6 flow label of requests, nil for the kernel's choice
+	flowLabels       map[uint32][]byte // Control messages of the flow labels leased on the socket, by label
+	sentTraffic      int               // TOS or traffic class set for requests, -1 before any is set
+	destination      *net.IPAddr       // Address the socket was configured to probe
+	source           net.IP            // Our address towards it, for the synthesized IP headers of captured packets (-pcap)
+	closed           chan struct{}     // Closed once the socket is
+	calls            socketCalls       // State of its system calls, reused so reads and writes don't allocate
+}
+
+// Packet read from a socket, with what the kernel reported of it
+type packet struct {
+	buffer       []byte       // Buffer the packet is read into
+	n            int          // Length of the ICMP message at the front of the buffer
+	peer         *net.IPAddr  // Source of the packet, only valid until the next packet is read into the buffer
+	address      net.IPAddr   // Storage of the source on Linux, reused by every packet read into the buffer
+	addressIP    [16]byte     // Storage of its IP
+	message      icmp.Message // Storage of an echo message decoded in place, reused by every packet read into the buffer
+	echo         icmp.Echo    // Storage of its body
+	received     time.Time    // When it was received, by the kernel's clock if it timestamped it
+	kernel       bool         // Whether it was timestamped by the kernel
+	hardware     time.Time    // When the NIC received it, zero if it didn't timestamp it
+	hopLimit     int          // TTL or hop limit of the packet, 0 if unknown
+	options      []byte       // IPv4 options of the packet, nil if it had none
+	trafficClass int          // TOS (IPv4) or traffic class (IPv6) of the packet, -1 if unknown
+}
+
+const listenerPackets int = 16 // Packets a listener reads ahead of their handling
+
+// Goroutine reading a target's socket as packets arrive, so replies to several probes in flight
+// are each read when they arrive and late ones are seen between probes
+type listener struct {
+	sock    *icmpSocket  // Socket read
+	packets chan *packet // Packets read, closed once the socket is
+	free    chan *packet // Packets handled, to be read into again
+}
+
+// Start reading the socket into buffers of the size, until it is closed
+func listen(sock *icmpSocket, size int) *listener {
+	reading := &listener{sock: sock, packets: make(chan *packet, listenerPackets), free: make(chan *packet, listenerPackets)}
+	for range listenerPackets {
+		reading.free <- &packet{buffer: make([]byte, size)}
+	}
+	go func() {
+		defer close(reading.packets)
+		for {
+			var read *packet
+			select {
+			case read = <-reading.free:
+			case <-sock.closed:
+				return
+			}
+			if err := sock.read(read); err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				slog.Debug("Could not read reply", "error", err)
+				reading.free <- read
+				continue
+			}
+			reading.packets <- read
+		}
+	}()
+	return reading
+}
+
+// Check that a raw ICMP socket can be opened before probing, so missing privileges fail once
+// at startup rather than on every probe
+func checkSocket() error {
+	sock, err := openSocket()
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("Could not open an ICMP socket (%w). Please run goPing as root or with raw socket capabilities", err)
+	}
+	if err != nil {
+		return fmt.Errorf("Could not open an ICMP socket: %w", err)
+	}
+	sock.close()
+	return nil
+}
+
+// Take the target's socket for probing the address, opening and configuring it on the first probe
+// so every later one only sends and reads. It is reopened if the target re-resolved to another
+// address, which timestamping's device and the flow label leases follow.
+func (stats *statistic) socket(ipAddress *net.IPAddr) (*icmpSocket, error) {
+	if stats.sock != nil && !stats.reopens(ipAddress) {
+		return stats.sock, nil
+	}
+	stats.closeSocket()
+	sock, err := openSocket()
+	if err != nil {
+		return nil, err
+	}
+	if err := stats.configureSocket(sock, ipAddress); err != nil {
+		sock.close()
+		return nil, err
+	}
+	stats.sock = sock
+	return sock, nil
+}
+
+// Whether probing the address needs the target's open socket replaced, the target having re-resolved
+func (stats *statistic) reopens(ipAddress *net.IPAddr) bool {
+	return stats.sock != nil && ipAddress != nil && !stats.sock.destination.IP.Equal(ipAddress.IP)
+}
+
+// Close the target's socket, if it has one open
+func (stats *statistic) closeSocket() {
+	if stats.sock != nil {
+		stats.sock.close()
+		stats.sock = nil
+	}
+}
+
+// TTL of the target's probes, a traced hop's own TTL overriding -ttl
+func (stats *statistic) probeTTL() int {
+	if stats.hopLimit > 0 {
+		return stats.hopLimit
+	}
+	return ttl
+}
+
+// Configure a new socket with every setting probes of the address share
+func (stats *statistic) configureSocket(sock *icmpSocket, ipAddress *net.IPAddr) error {
+	// Refuse broadcast and multicast targets unless -b is given, as classic ping does
+	if !broadcast && isBroadcast(ipAddress.IP) {
+		return fmt.Errorf("%s is a broadcast or multicast address, use -b to ping it", ipAddress)
+	}
+	sock.destination = ipAddress
+	if packetCapture != nil {
+		sock.source = routeSource(ipAddress.IP)
+	}
+	// Pass only replies to this target's probes, unless crafted probes may be answered by any type
+	if !craftedProbe() {
+		if err := sock.filterReplies(stats.id); err != nil {
+			replyFilterFallback.Do(func() {
+				slog.Debug("Could not filter replies in the kernel", "error", err)
+			})
+		}
+	}
+	// Send from this side's interface of goping compare -via
+	if stats.device != "" {
+		if err := sock.bindToDevice(stats.device); err != nil {
+			return fmt.Errorf("Could not bind to %s: %w", stats.device, err)
+		}
+	}
+
+	// Set TTL deadlines
+	if err := sock.setTTL(stats.probeTTL()); err != nil {
+		slog.Debug("Could not set TTL", "ttl", stats.probeTTL(), "error", err)
+	}
+
+	// Record the route or timestamps of hops, or source route probes, in IPv4 options (-R, -T or -g)
+	if requestIPOptions != nil {
+		if err := sock.setIPOptions(requestIPOptions); err != nil {
+			ipOptionsFallback.Do(func() {
+				slog.Warn(fmt.Sprintf("Could not set IP options (%s). Sending without them...", err))
+			})
+		}
+	}
+
+	// Allow sending to broadcast addresses (-b)
+	if broadcast && !wantIPv6 {
+		if err := sock.enableBroadcast(); err != nil {
+			slog.Debug("Could not enable broadcast", "error", err)
+		}
+	}
+
+	// Timestamp requests and replies by the NIC if requested (-timestamping hardware), falling back to kernel
+	if hardwareTimestamping {
+		device := stats.device
+		if device == "" {
+			device = routeDevice(ipAddress.IP)
+		}
+		if err := sock.enableHardwareTimestamps(device); err != nil {
+			hardwareFallback.Do(func() {
+				slog.Warn(fmt.Sprintf("Could not enable hardware timestamps (%s). Falling back to kernel...", err))
+			})
+		}
+	}
+
+	// Timestamp replies in the kernel if requested (-timestamping kernel), falling back to userspace
+	if kernelTimestamping && !sock.kernelTimestamps {
+		if err := sock.enableKernelTimestamps(); err != nil {
+			timestampFallback.Do(func() {
+				slog.Warn(fmt.Sprintf("Could not enable kernel timestamps (%s). Falling back to userspace...", err))
+			})
+		}
+	}
+	return nil
+}
+
+// Open a raw ICMP socket for the IP version in use
+func openSocket() (*icmpSocket, error) {
+	listenNetwork, listenAddress := listenNetwork4, listenAddress4
+	if wantIPv6 {
+		listenNetwork, listenAddress = listenNetwork6, listenAddress6
+	}
+	packetConn, err := net.ListenPacket(listenNetwork, listenAddress)
+	if err != nil {
+		return nil, err
+	}
+	sock := &icmpSocket{conn: packetConn.(*net.IPConn), oob: make([]byte, controlMessageSize), sentTraffic: -1, closed: make(chan struct{})}
+	if wantIPv6 {
+		sock.ipv6Conn = ipv6.NewPacketConn(sock.conn)
+		// Have the hop limit of replies delivered, as IPv6 raw sockets don't see the IP header
+		if err := sock.ipv6Conn.SetControlMessage(ipv6.FlagHopLimit, true); err != nil {
+			slog.Debug("Could not receive hop limits", "error", err)
+		}
+		// Have the traffic class of replies delivered too, for the ECN field (-ecn)
+		if ecnProbe != ecnNotECT {
+			if err := sock.ipv6Conn.SetControlMessage(ipv6.FlagTrafficClass, true); err != nil {
+				slog.Debug("Could not receive traffic classes", "error", err)
+			}
+		}
+	} else {
+		sock.ipv4Conn = ipv4.NewPacketConn(sock.conn)
+	}
+	if err := sock.prepareCalls(); err != nil {
+		sock.close()
+		return nil, err
+	}
+	// Probe inside a VRF (-vrf)
+	if vrfDevice != "" {
+		if err := sock.bindToDevice(vrfDevice); err != nil {
+			sock.close()
+			return nil, fmt.Errorf("Could not bind to VRF %s: %w", vrfDevice, err)
+		}
+	}
+	// Steer probes with policy routing (-mark)
+	if socketMark != 0 {
+		if err := sock.setMark(socketMark); err != nil {
+			sock.close()
+			return nil, fmt.Errorf("Could not set mark %d: %w", socketMark, err)
+		}
+	}
+	slog.Debug("Opened ICMP socket", "network", listenNetwork, "address", listenAddress, "local", sock.conn.LocalAddr())
+	return sock, nil
+}
+
+// Close the socket
+func (sock *icmpSocket) close() error {
+	close(sock.closed)
+	return sock.conn.Close()
+}
+
+// Set the TTL (IPv4) or hop limit (IPv6) of outgoing requests
+func (sock *icmpSocket) setTTL(ttl int) error {
+	if sock.ipv6Conn != nil {
+		return sock.ipv6Conn.SetHopLimit(ttl)
+	}
+	return sock.ipv4Conn.SetTTL(ttl)
+}
+
+// Set the TOS (IPv4) or traffic class (IPv6) of outgoing requests, such as their ECN codepoint (-ecn),
+// unless it is already set
+func (sock *icmpSocket) setTrafficClass(trafficClass int) error {
+	if trafficClass == sock.sentTraffic {
+		return nil
+	}
+	var err error
+	if sock.ipv6Conn != nil {
+		err = sock.ipv6Conn.SetTrafficClass(trafficClass)
+	} else {
+		err = sock.ipv4Conn.SetTOS(trafficClass)
+	}
+	if err != nil {
+		return err
+	}
+	sock.sentTraffic = trafficClass
+	return nil
+}
+
+// Bind the socket to a VRF or other device (-vrf)
+func (sock *icmpSocket) bindToDevice(device string) error {
+	rawConn, err := sock.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return bindToDevice(rawConn, device)
+}
+
+// Set the firewall mark of requests (-mark)
+func (sock *icmpSocket) setMark(mark int) error {
+	rawConn, err := sock.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return setMark(rawConn, mark)
+}
+
+// Allow requests to broadcast addresses (-b)
+func (sock *icmpSocket) enableBroadcast() error {
+	rawConn, err := sock.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return enableBroadcast(rawConn)
+}
+
+// Set IPv4 options, such as record route, on outgoing requests
+func (sock *icmpSocket) setIPOptions(options []byte) error {
+	rawConn, err := sock.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return setIPOptions(rawConn, options)
+}
+
+// Have the kernel timestamp replies on receipt, so RTT excludes userspace scheduling delay
+func (sock *icmpSocket) enableKernelTimestamps() error {
+	rawConn, err := sock.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	if err := enableTimestamps(rawConn); err != nil {
+		return err
+	}
+	sock.kernelTimestamps = true
+	return nil
+}
+
+// Have the NIC of the device timestamp requests and replies, with software timestamps of both
+// taken by the kernel, which remain where the NIC timestamps neither
+func (sock *icmpSocket) enableHardwareTimestamps(device string) error {
+	rawConn, err := sock.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	if err := enableTransmitTimestamps(rawConn); err != nil {
+		return err
+	}
+	sock.kernelTimestamps, sock.sentTimestamps = true, true
+	return enableHardwareTimestamping(rawConn, device)
+}
+
+// Software and hardware timestamps of the requests sent, each zero if not taken
+func (sock *icmpSocket) sent() (time.Time, time.Time) {
+	rawConn, err := sock.conn.SyscallConn()
+	if err != nil {
+		return time.Time{}, time.Time{}
+	}
+	return readTransmitTimestamps(rawConn)
+}
+
+// Send requests to the address with the IPv6 flow label, leasing it from the kernel the first time
+func (sock *icmpSocket) setFlowLabel(ipAddress *net.IPAddr, label uint32) error {
+	if oob, ok := sock.flowLabels[label]; ok {
+		sock.flowOOB = oob
+		return nil
+	}
+	rawConn, err := sock.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	if err := leaseFlowLabel(rawConn, ipAddress.IP, label); err != nil {
+		return err
+	}
+	if sock.flowLabels == nil {
+		sock.flowLabels = make(map[uint32][]byte)
+	}
+	sock.flowOOB = flowLabelControl(label)
+	sock.flowLabels[label] = sock.flowOOB
+	return nil
+}
+
+// Send an ICMP message to the address
+func (sock *icmpSocket) writeTo(message []byte, ipAddress *net.IPAddr) error {
+	return sock.writeMsg(message, sock.flowOOB, ipAddress)
+}
+
+// Set the deadline for reading replies
+func (sock *icmpSocket) setReadDeadline(deadline time.Time) error {
+	return sock.conn.SetReadDeadline(deadline)
+}
+
+// Read the next ICMP message into the packet's buffer, with its source, when it was received, by
+// the kernel's clock if kernel timestamps are enabled, its TTL or hop limit, IPv4 options and traffic class
+func (sock *icmpSocket) read(read *packet) error {
+	buffer := read.buffer
+	n, oobn, err := sock.readMsg(read)
+	read.n, read.received = n, time.Now()
+	if err != nil {
+		return err
+	}
+	read.kernel, read.hardware = false, time.Time{}
+	if sock.kernelTimestamps {
+		if timestamp, ok := parseTimestamp(sock.oob[:oobn]); ok {
+			read.received, read.kernel = timestamp, true
+		}
+	}
+	if sock.sentTimestamps {
+		read.hardware, _ = parseHardwareTimestamp(sock.oob[:oobn])
+	}
+	// Raw IPv4 sockets deliver the IP header with ReadMsgIP, unlike ReadFrom
+	read.hopLimit, read.options, read.trafficClass = 0, nil, -1
+	if sock.ipv4Conn != nil {
+		read.options = ipv4HeaderOptions(buffer[:n])
+		if n >= ipv4.HeaderLen && buffer[0]>>4 == ipv4.Version {
+			read.trafficClass = int(buffer[1])
+		}
+		read.n, read.hopLimit = stripIPv4Header(buffer, n)
+	} else {
+		hopLimit, trafficClass := parseIPv6Control(sock.oob[:oobn])
+		read.hopLimit = hopLimit
+		if ecnProbe != ecnNotECT {
+			read.trafficClass = trafficClass
+		}
+	}
+	return nil
+}
+
+// Source of the packet as an IP that outlives it, the expected address itself if the packet came
+// from it, so replies of the target don't allocate
+func (read *packet) source(expected net.IP) net.IP {
+	if read.peer.IP.Equal(expected) {
+		return expected
+	}
+	return slices.Clone(read.peer.IP)
+}
+
+// Error of a read timing out on the socket, which a probe lost without one reports
+func (sock *icmpSocket) timeoutError() error {
+	return &net.OpError{Op: "read", Net: sock.network(), Source: sock.conn.LocalAddr(), Err: os.ErrDeadlineExceeded}
+}
+
+// Network of the socket as the net package names it
+func (sock *icmpSocket) network() string {
+	if sock.ipv6Conn != nil {
+		return "ip6"
+	}
+	return "ip4"
+}
+
+// Copy the options of the IPv4 header at the front of a packet, nil if it has none
+func ipv4HeaderOptions(packet []byte) []byte {
+	if len(packet) < ipv4.HeaderLen || packet[0]>>4 != ipv4.Version {
+		return nil
+	}
+	headerLength := int(packet[0]&0x0f) << 2
+	if headerLength <= ipv4.HeaderLen || headerLength > len(packet) {
+		return nil
+	}
+	return append([]byte(nil), packet[ipv4.HeaderLen:headerLength]...)
+}
+
+// Remove the IPv4 header from the front of a packet read from a raw socket,
+// returning the new length and the TTL from the header, 0 if there was none
+func stripIPv4Header(buffer []byte, n int) (int, int) {
+	if n < ipv4.HeaderLen {
+		return n, 0
+	}
+	headerLength := int(buffer[0]&0x0f) << 2
+	if buffer[0]>>4 != ipv4.Version || headerLength < ipv4.HeaderLen || headerLength > n {
+		return n, 0
+	}
+	hopLimit := int(buffer[8])
+	copy(buffer, buffer[headerLength:n])
+	return n - headerLength, hopLimit
+}
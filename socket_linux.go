@@ -0,0 +1,197 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// State of a socket's recvmsg and sendmsg calls, reused by every read and write so neither allocates,
+// as ReadMsgIP and WriteToIP allocate the address of every packet. Reads come from one goroutine at a
+// time, as do writes.
+type socketCalls struct {
+	rawConn    syscall.RawConn       // File descriptor of the connection
+	recv       func(uintptr) bool    // Call recvmsg with the fields below, kept so passing it doesn't allocate
+	readHeader unix.Msghdr           // Header of the packet being read
+	readIovec  unix.Iovec            // Buffer of the packet being read
+	name       unix.RawSockaddrInet6 // Source of the packet read, large enough for either IP version
+	n          int                   // Length of the packet read
+	recvErr    error                 // Error of the last recvmsg
+	zoneIndex  uint32                // Interface index of the last IPv6 zone read
+	zone       string                // Its name
+	send       func(uintptr) bool    // Call sendmsg with the fields below, kept so passing it doesn't allocate
+	message    []byte                // Message being sent
+	sendOOB    []byte                // Its control messages, nil for none
+	to         unix.Sockaddr         // Destination, pointing at one of the two below
+	to4        unix.SockaddrInet4    // Destination of IPv4 requests
+	to6        unix.SockaddrInet6    // Destination of IPv6 requests
+	sendZone   string                // Name of the last IPv6 zone sent to
+	sendErr    error                 // Error of the last sendmsg
+}
+
+// Prepare the socket's reads and writes, which go through its file descriptor
+func (sock *icmpSocket) prepareCalls() error {
+	calls := &sock.calls
+	rawConn, err := sock.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	calls.rawConn = rawConn
+	calls.recv, calls.send = calls.recvmsg, calls.sendmsg
+	calls.readHeader.Name = (*byte)(unsafe.Pointer(&calls.name))
+	calls.readHeader.Iov = &calls.readIovec
+	calls.readHeader.SetIovlen(1)
+	calls.readHeader.Control = &sock.oob[0]
+	calls.to = &calls.to4
+	if sock.ipv6Conn != nil {
+		calls.to = &calls.to6
+	}
+	return nil
+}
+
+// Read the next packet into its buffer, and its control messages into the socket's, returning
+// their lengths. The source is written into the packet's own address.
+func (sock *icmpSocket) readMsg(read *packet) (int, int, error) {
+	calls := &sock.calls
+	calls.readIovec.Base = &read.buffer[0]
+	calls.readIovec.SetLen(len(read.buffer))
+	calls.readHeader.Namelen = unix.SizeofSockaddrInet6
+	calls.readHeader.SetControllen(len(sock.oob))
+	if err := calls.rawConn.Read(calls.recv); err != nil {
+		return 0, 0, err
+	}
+	if calls.recvErr != nil {
+		return 0, 0, sock.callError("read", "recvmsg", nil, calls.recvErr)
+	}
+	read.peer = &read.address
+	if calls.name.Family == unix.AF_INET {
+		inet4 := (*unix.RawSockaddrInet4)(unsafe.Pointer(&calls.name))
+		read.address.IP, read.address.Zone = read.addressIP[:copy(read.addressIP[:], inet4.Addr[:])], ""
+	} else {
+		read.address.IP, read.address.Zone = read.addressIP[:copy(read.addressIP[:], calls.name.Addr[:])], calls.zoneName(calls.name.Scope_id)
+	}
+	return calls.n, int(calls.readHeader.Controllen), nil
+}
+
+// Call recvmsg once the socket is readable
+func (calls *socketCalls) recvmsg(fd uintptr) bool {
+	for {
+		n, _, errno := unix.Syscall(unix.SYS_RECVMSG, fd, uintptr(unsafe.Pointer(&calls.readHeader)), 0)
+		switch errno {
+		case unix.EINTR:
+			continue
+		case unix.EAGAIN:
+			return false
+		case 0:
+			calls.n, calls.recvErr = int(n), nil
+		default:
+			calls.n, calls.recvErr = 0, errno
+		}
+		return true
+	}
+}
+
+// Name of the interface of an IPv6 zone, looked up only when it changes, "" for none
+func (calls *socketCalls) zoneName(index uint32) string {
+	if index == 0 {
+		return ""
+	}
+	if index != calls.zoneIndex {
+		calls.zoneIndex, calls.zone = index, strconv.Itoa(int(index))
+		if device, err := net.InterfaceByIndex(int(index)); err == nil {
+			calls.zone = device.Name
+		}
+	}
+	return calls.zone
+}
+
+// Send the message with its control messages, nil for none, to the address
+func (sock *icmpSocket) writeMsg(message []byte, oob []byte, ipAddress *net.IPAddr) error {
+	calls := &sock.calls
+	if sock.ipv6Conn != nil {
+		copy(calls.to6.Addr[:], ipAddress.IP.To16())
+		if ipAddress.Zone != calls.sendZone {
+			calls.to6.ZoneId, calls.sendZone = 0, ipAddress.Zone
+			if device, err := net.InterfaceByName(ipAddress.Zone); err == nil {
+				calls.to6.ZoneId = uint32(device.Index)
+			} else if index, err := strconv.Atoi(ipAddress.Zone); err == nil {
+				calls.to6.ZoneId = uint32(index)
+			}
+		}
+	} else if ip := ipAddress.IP.To4(); ip != nil {
+		copy(calls.to4.Addr[:], ip)
+	} else {
+		return sock.callError("write", "sendmsg", ipAddress, unix.EAFNOSUPPORT)
+	}
+	calls.message, calls.sendOOB = message, oob
+	if err := calls.rawConn.Write(calls.send); err != nil {
+		return err
+	}
+	if calls.sendErr != nil {
+		return sock.callError("write", "sendmsg", ipAddress, calls.sendErr)
+	}
+	return nil
+}
+
+// Call sendmsg once the socket is writable
+func (calls *socketCalls) sendmsg(fd uintptr) bool {
+	for {
+		_, err := unix.SendmsgN(int(fd), calls.message, calls.sendOOB, calls.to, 0)
+		switch err {
+		case unix.EINTR:
+			continue
+		case unix.EAGAIN:
+			return false
+		}
+		calls.sendErr = err
+		return true
+	}
+}
+
+// Error of a failed system call on the socket, as the net package reports them
+func (sock *icmpSocket) callError(op string, call string, ipAddress *net.IPAddr, err error) error {
+	opErr := &net.OpError{Op: op, Net: sock.network(), Source: sock.conn.LocalAddr(), Err: os.NewSyscallError(call, err)}
+	if ipAddress != nil {
+		opErr.Addr = ipAddress
+	}
+	return opErr
+}
+
+// Hop limit and traffic class of an IPv6 packet from its control messages, 0 and -1 if missing,
+// walked in place as ipv6.ControlMessage.Parse allocates
+func parseIPv6Control(oob []byte) (int, int) {
+	hopLimit, trafficClass := 0, -1
+	controlMessages(oob, func(header *unix.Cmsghdr, data []byte) {
+		if header.Level != unix.IPPROTO_IPV6 || len(data) < 4 {
+			return
+		}
+		switch header.Type {
+		case unix.IPV6_HOPLIMIT:
+			hopLimit = int(int32(binary.NativeEndian.Uint32(data)))
+		case unix.IPV6_TCLASS:
+			trafficClass = int(int32(binary.NativeEndian.Uint32(data)))
+		}
+	})
+	return hopLimit, trafficClass
+}
+
+// Visit each control message of a packet with its data, without allocating as
+// unix.ParseSocketControlMessage does
+func controlMessages(oob []byte, visit func(header *unix.Cmsghdr, data []byte)) {
+	for len(oob) >= unix.SizeofCmsghdr {
+		header := (*unix.Cmsghdr)(unsafe.Pointer(&oob[0]))
+		length := int(header.Len)
+		if length < unix.CmsgLen(0) || length > len(oob) {
+			return
+		}
+		visit(header, oob[unix.CmsgLen(0):length])
+		oob = oob[min(unix.CmsgSpace(length-unix.CmsgLen(0)), len(oob)):]
+	}
+}
@@ -0,0 +1,45 @@
+//go:build !linux
+
+package main
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv6"
+)
+
+// Reads and writes go through the net package, allocating the address of every packet, as only
+// Linux calls recvmsg and sendmsg directly
+type socketCalls struct{}
+
+// Nothing to prepare where reads and writes go through the net package
+func (sock *icmpSocket) prepareCalls() error {
+	return nil
+}
+
+// Read the next packet into its buffer, and its control messages into the socket's, returning
+// their lengths, with its source
+func (sock *icmpSocket) readMsg(read *packet) (int, int, error) {
+	n, oobn, _, peer, err := sock.conn.ReadMsgIP(read.buffer, sock.oob)
+	read.peer = peer
+	return n, oobn, err
+}
+
+// Send the message with its control messages, nil for none, to the address
+func (sock *icmpSocket) writeMsg(message []byte, oob []byte, ipAddress *net.IPAddr) error {
+	if oob != nil {
+		_, _, err := sock.conn.WriteMsgIP(message, oob, ipAddress)
+		return err
+	}
+	_, err := sock.conn.WriteToIP(message, ipAddress)
+	return err
+}
+
+// Hop limit and traffic class of an IPv6 packet from its control messages, 0 and -1 if missing
+func parseIPv6Control(oob []byte) (int, int) {
+	var controlMessage ipv6.ControlMessage
+	if err := controlMessage.Parse(oob); err != nil {
+		return 0, -1
+	}
+	return controlMessage.HopLimit, controlMessage.TrafficClass
+}
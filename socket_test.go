@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// Send an echo request to localhost and read its reply on the target's socket, as every probe does
+func BenchmarkProbeRoundTrip(b *testing.B) {
+	if err := checkSocket(); err != nil {
+		b.Skip(err)
+	}
+	stats := &statistic{id: nextEchoID(), timeout: time.Second}
+	defer stats.closeSocket()
+	ipAddress := &net.IPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	probe := &flight{}
+	b.ReportAllocs()
+	for seq := 0; b.Loop(); seq++ {
+		*probe = flight{probeState: probeState{seq: seq, replyECN: -1}, ip: ipAddress, resolved: true}
+		stats.await(probe)
+		if probe.err != nil {
+			b.Fatal(probe.err)
+		}
+	}
+}
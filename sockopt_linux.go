@@ -0,0 +1,34 @@
+//go:build linux
+
+package main
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Set SO_MARK on the socket, so policy routing rules matching the fwmark steer its packets
+func setMark(rawConn syscall.RawConn, mark int) error {
+	var sockErr error
+	err := rawConn.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, mark)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// Bind the socket to a device with SO_BINDTODEVICE, which for a VRF device
+// confines its packets to that VRF's routing table
+func bindToDevice(rawConn syscall.RawConn, device string) error {
+	var sockErr error
+	err := rawConn.Control(func(fd uintptr) {
+		sockErr = unix.BindToDevice(int(fd), device)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Firewall marks are only implemented on Linux
+func setMark(rawConn syscall.RawConn, mark int) error {
+	return errors.New("Socket marks are not supported on this platform")
+}
+
+// Binding to a VRF device is only implemented on Linux
+func bindToDevice(rawConn syscall.RawConn, device string) error {
+	return errors.New("VRF binding is not supported on this platform")
+}
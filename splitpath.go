@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+var (
+	splitPath    bool       // Whether the default gateway is pinged alongside the targets (-split-path) flag
+	splitGateway *statistic // Statistics of the gateway with -split-path, nil otherwise
+)
+
+// Where the loss of a target's probe happened with -split-path: local if the gateway's last probe
+// was lost too, upstream if it replied, empty for replies, the gateway itself or before it was probed
+func (stats *statistic) lossSide(replied bool) string {
+	if replied || splitGateway == nil || stats == splitGateway {
+		return ""
+	}
+	splitGateway.mutex.Lock()
+	defer splitGateway.mutex.Unlock()
+	switch {
+	case splitGateway.count == 0:
+		return ""
+	case splitGateway.lastRTT == 0:
+		return "local"
+	default:
+		return "upstream"
+	}
+}
+
+// Print each target's loss and median RTT beside the gateway's, judging whether they arise on the
+// local network up to the first hop or upstream of it, as when telling the LAN and ISP apart
+func showSplitPath(targets []*statistic) {
+	gateway := splitGateway.summary()
+	gatewayMedian := splitGateway.medianRTT()
+	fmt.Fprintln(noticeOutput(), "\n----------------------------| First hop vs destination |----------------------------")
+	fmt.Fprintf(noticeOutput(), "Gateway %s:\t\tLoss: %.2f%%\t\tMedian RTT: %s\n", gateway.IP, gateway.Loss, display(gatewayMedian))
+	for _, stats := range targets {
+		if stats == splitGateway {
+			continue
+		}
+		summary := stats.summary()
+		median := stats.medianRTT()
+		fmt.Fprintf(noticeOutput(), "%s:\t\tLoss: %.2f%%\t\tMedian RTT: %s\n", stats.target.address, summary.Loss, display(median))
+		switch {
+		case summary.Sent == 0:
+			continue
+		case summary.Loss == 0 && gateway.Loss == 0:
+			fmt.Fprintln(noticeOutput(), "  Loss: none on either")
+		case summary.Loss == 0:
+			fmt.Fprintln(noticeOutput(), "  Loss: only of probes to the gateway, which deprioritizes ICMP to itself while forwarding")
+		case gateway.Loss >= summary.Loss/2:
+			fmt.Fprintln(noticeOutput(), "  Loss: local, the first hop loses probes as well")
+		default:
+			fmt.Fprintln(noticeOutput(), "  Loss: upstream, beyond the first hop")
+		}
+		if median > 0 && gatewayMedian > 0 {
+			side := "upstream"
+			if 2*gatewayMedian >= median {
+				side = "local"
+			}
+			fmt.Fprintf(noticeOutput(), "  Latency: %s, %s to the first hop and %s beyond\n", side, display(gatewayMedian), display(max(median-gatewayMedian, 0)))
+		}
+	}
+}
+
+// Median RTT of the target's replies so far, 0 if none
+func (stats *statistic) medianRTT() time.Duration {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	if len(stats.rttAll) == 0 {
+		return 0
+	}
+	return medianOf(stats.rttAll)
+}
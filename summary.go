@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+var (
+	summaryOnly bool      // Whether per-probe output is suppressed, leaving only the summary at exit (-summary-only) flag
+	runStarted  time.Time // When probing started, for the summary document
+)
+
+// Summary of a run printed as one JSON document at exit with -summary-only -o json
+type runSummary struct {
+	Start            time.Time       `json:"start"`                       // When probing started
+	End              time.Time       `json:"end"`                         // When the summary was taken
+	Targets          []targetSummary `json:"targets"`                     // Statistics of every target
+	AssertionsFailed bool            `json:"assertions_failed,omitempty"` // Whether an -assert-* threshold was exceeded
+}
+
+// ICMP message received from the target, or from another host, in place of its reply
+type replyError struct {
+	kind icmp.Type   // Type of the message, e.g. destination unreachable
+	from *net.IPAddr // Host the message came from, nil if the target
+	want *net.IPAddr // Target probed
+}
+
+func (err *replyError) Error() string {
+	if err.from == nil {
+		return fmt.Sprintf("Received %s instead of echo reply", err.kind)
+	}
+	return fmt.Sprintf("Received %s from %s instead of %s", err.kind, displayAddress(err.from), displayAddress(err.want))
+}
+
+// Cause of a lost probe for the per-cause breakdown: unresolved, timeout, the ICMP type
+// received in place of the reply, or other for socket errors
+func lossCause(err error, resolved bool) string {
+	var netErr net.Error
+	var received *replyError
+	switch {
+	case !resolved:
+		return "unresolved"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &received):
+		return strings.ToLower(fmt.Sprint(received.kind))
+	default:
+		return "other"
+	}
+}
+
+// Count a lost probe under its cause
+func (stats *statistic) tallyLossCause(cause string) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	if stats.lossCauses == nil {
+		stats.lossCauses = make(map[string]int)
+	}
+	stats.lossCauses[cause]++
+}
+
+// Describe the causes of lost probes, most frequent first, e.g. "timeout 3, destination unreachable 1"
+func describeLossCauses(causes map[string]int) string {
+	names := make([]string, 0, len(causes))
+	for cause := range causes {
+		names = append(names, cause)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if causes[names[i]] != causes[names[j]] {
+			return causes[names[i]] > causes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, cause := range names {
+		parts[i] = fmt.Sprintf("%s %d", cause, causes[cause])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Whether the summary is printed as a single JSON document (-summary-only -o json), so nothing
+// else may be written to stdout
+func jsonSummary() bool {
+	return summaryOnly && output.format == "json"
+}
+
+// Where the text summary and notices printed around it go: stderr while stdout carries JSON or CSV
+// results or the JSON summary, so the stream stays parseable as replay leaves it
+func noticeOutput() io.Writer {
+	if output != nil && output.format != "text" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// Print the summary of a ping run at its end and judge its assertions, as one JSON document
+// with -summary-only -o json and as statistics otherwise
+func showFinalSummary(allStats []*statistic) {
+	if !jsonSummary() {
+		showSummary(allStats)
+		checkAssertions(allStats)
+		return
+	}
+	checkAssertions(allStats)
+	document := runSummary{Start: runStarted, End: time.Now(), AssertionsFailed: assertionsFailed}
+	for _, stats := range allStats {
+		document.Targets = append(document.Targets, stats.summary())
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(document)
+	// Snapshot the statistics for the textfile collector, as showSummary would have
+	if metricsFile != "" {
+		writeMetricsFile(allStats)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Run the function with stdout and stderr captured, returning what each was written
+func captureOutput(t *testing.T, run func()) (string, string) {
+	t.Helper()
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	captured := make([]bytes.Buffer, 2)
+	done := make(chan struct{}, 2)
+	for i, file := range []**os.File{&os.Stdout, &os.Stderr} {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		*file = writer
+		defer writer.Close()
+		go func() {
+			io.Copy(&captured[i], reader)
+			done <- struct{}{}
+		}()
+	}
+	run()
+	os.Stdout.Close()
+	os.Stderr.Close()
+	<-done
+	<-done
+	return captured[0].String(), captured[1].String()
+}
+
+// Machine readable results stay parseable to the end, the text summary going to stderr
+func TestSummaryLeavesResultsParseable(t *testing.T) {
+	defer func(restored *resultWriter) { output = restored }(output)
+	stats := &statistic{target: &resolution{address: "192.0.2.1"}, count: 2, lost: 1, loss: 50}
+	results := []probeResult{
+		{Time: time.Now(), Target: "192.0.2.1", IP: "192.0.2.1", Seq: 1, RTT: time.Millisecond, Loss: 0},
+		{Time: time.Now(), Target: "192.0.2.1", IP: "192.0.2.1", Seq: 2, Loss: 50, Error: "Request timed out"},
+	}
+	tests := []struct {
+		format string
+		parse  func(string) (int, error)
+	}{
+		{"json", func(stdout string) (int, error) {
+			decoder := json.NewDecoder(strings.NewReader(stdout))
+			parsed := 0
+			for {
+				var result probeResult
+				if err := decoder.Decode(&result); errors.Is(err, io.EOF) {
+					return parsed, nil
+				} else if err != nil {
+					return parsed, err
+				}
+				parsed++
+			}
+		}},
+		{"csv", func(stdout string) (int, error) {
+			records, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+			return max(len(records)-1, 0), err
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			stdout, stderr := captureOutput(t, func() {
+				output = newResultWriter(test.format)
+				output.writeAll(results)
+				showFinalSummary([]*statistic{stats})
+			})
+			parsed, err := test.parse(stdout)
+			if err != nil || parsed != len(results) {
+				t.Errorf("Parsed %d results of stdout with error %v, want %d:\n%s", parsed, err, len(results), stdout)
+			}
+			if !strings.Contains(stderr, "Statistics") {
+				t.Errorf("Summary missing from stderr:\n%s", stderr)
+			}
+		})
+	}
+}
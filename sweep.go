@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	defaultSweepMin   int           = 64                     // Smallest payload size of goping sweep-size unless -min is given
+	defaultSweepMax   int           = 1500                   // Largest payload size unless -max is given
+	defaultSweepStep  int           = 16                     // Increment between payload sizes unless -step is given
+	defaultSweepCount int           = 3                      // Probes of each size unless -c is given
+	maxSweepSize      int           = 65507                  // Largest echo payload fitting an IPv4 packet
+	sweepInterval     time.Duration = 200 * time.Millisecond // Interval between probes, short so a sweep finishes quickly
+	sweepTimeout      time.Duration = 2 * time.Second        // Deadline of each reply
+)
+
+// Probes of one payload size of a sweep
+type sweepPoint struct {
+	size  int        // Payload size in bytes
+	stats *statistic // Probes of the size
+}
+
+// Probe the target with every payload size from minSize to maxSize, step apart, count times each
+// (goping sweep-size), printing each size's loss and RTT and judging whether large probes
+// vanish, as at an MTU black hole, or are lost more often, as with size-dependent rate limiting
+func runSizeSweep(target *resolution, minSize int, maxSize int, step int, count int) error {
+	ip, err := target.current()
+	if err != nil {
+		return err
+	}
+	var points []*sweepPoint
+	for size := minSize; size <= maxSize; size += step {
+		payload := bytes.Repeat(echoPayload, size/len(echoPayload)+1)[:size]
+		points = append(points, &sweepPoint{
+			size:  size,
+			stats: &statistic{target: target, id: nextEchoID(), payload: payload, timeout: sweepTimeout},
+		})
+	}
+	swept := 0 // Sizes probed so far, for the summary on ctrl-c
+	closeHandler(func() { showSweep(ip, points[:swept]) })
+	slog.Info(fmt.Sprintf("Sweeping %s with payloads of %d to %d bytes, %d probes each...", displayAddress(ip), minSize, maxSize, count))
+	for _, point := range points {
+		for i := 0; i < count; i++ {
+			probing.wait(nil)
+			sent := time.Now()
+			err := point.stats.ping(ip)
+			if err != nil {
+				slog.Debug("Probe lost", "size", point.size, "seq", point.stats.count, "error", err)
+			}
+			point.stats.tally(err == nil, sent)
+			time.Sleep(sweepInterval)
+		}
+		point.stats.closeSocket()
+		swept++
+		summary := point.stats.summary()
+		slog.Info(
+			fmt.Sprintf("Size: %d\t\tSent: %d\t\tLoss: %.2f%%\t\tAvg RTT: %s", point.size, summary.Sent, summary.Loss, display(summary.AvgRTT)),
+			"size", point.size,
+			"sent", summary.Sent,
+			"loss", summary.Loss,
+			"rtt", summary.AvgRTT)
+	}
+	showSweep(ip, points)
+	return nil
+}
+
+// Print a table of every size probed, followed by the verdict of the sweep
+func showSweep(ip *net.IPAddr, points []*sweepPoint) {
+	fmt.Printf("\n----------------------------| Sizes to %s |----------------------------\n", displayAddress(ip))
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "SIZE\tPACKET\tSENT\tLOSS\tBEST\tAVG\tWORST")
+	headers := 28 // IPv4 and ICMP headers
+	if wantIPv6 {
+		headers = 48
+	}
+	largestAnswered := -1 // Index of the largest size any probe of which replied
+	for i, point := range points {
+		summary := point.stats.summary()
+		if summary.Sent > summary.Lost {
+			largestAnswered = i
+			fmt.Fprintf(writer, "%d\t%d\t%d\t%.2f%%\t%s\t%s\t%s\n", point.size, point.size+headers, summary.Sent, summary.Loss, display(summary.MinRTT), display(summary.AvgRTT), display(summary.MaxRTT))
+		} else {
+			fmt.Fprintf(writer, "%d\t%d\t%d\t%.2f%%\t\t\t\n", point.size, point.size+headers, summary.Sent, summary.Loss)
+		}
+	}
+	writer.Flush()
+
+	switch {
+	case len(points) == 0:
+		return
+	case largestAnswered == -1:
+		fmt.Println("No size replied")
+		return
+	case largestAnswered < len(points)-1:
+		// Every larger size lost entirely points at a hop dropping them without a fragmentation needed message
+		largest := points[largestAnswered].size
+		fmt.Printf("Sizes above %d bytes were all lost: possible MTU black hole, the largest packet passing being %d bytes\n", largest, largest+headers)
+	}
+
+	// Loss: two proportion z-test of the smaller against the larger half of the sizes that got through
+	answered := points[:largestAnswered+1]
+	if len(answered) < 2 {
+		return
+	}
+	var sent, lost [2]int
+	for i, point := range answered {
+		half := 0
+		if i >= len(answered)/2 {
+			half = 1
+		}
+		sent[half] += point.stats.count
+		lost[half] += point.stats.lost
+	}
+	smallerLoss := float64(lost[0]) / float64(sent[0])
+	largerLoss := float64(lost[1]) / float64(sent[1])
+	pooled := float64(lost[0]+lost[1]) / float64(sent[0]+sent[1])
+	standardError := math.Sqrt(pooled * (1 - pooled) * (1/float64(sent[0]) + 1/float64(sent[1])))
+	if largerLoss > smallerLoss && zScore(largerLoss-smallerLoss, standardError) > significanceZ {
+		fmt.Printf("Loss rises with size, %.2f%% of smaller probes vs %.2f%% of larger ones: possible size-dependent rate limiting\n", 100*smallerLoss, 100*largerLoss)
+	} else {
+		fmt.Println("No size-dependent loss among the sizes that replied")
+	}
+}
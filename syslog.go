@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSyslogPort string = "514"    // Port of syslog servers given without one
+	syslogTag         string = "goping" // Application name of messages
+	syslogWarning     int    = 4        // Severity of targets going down
+	syslogNotice      int    = 5        // Severity of targets coming back up
+)
+
+// Sockets of the local syslog daemon, tried in order
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// Facilities of -syslog-facility by name
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11, "local0": 16, "local1": 17, "local2": 18,
+	"local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Severities of -syslog-severity by name
+var syslogSeverities = map[string]int{
+	"emerg": 0, "alert": 1, "crit": 2, "err": 3, "warning": 4, "notice": 5, "info": 6, "debug": 7,
+}
+
+// Syslog destination of probe results and state changes (-syslog) if given, nil otherwise
+var syslogOutput *syslogSink
+
+// Sender of probe results and up/down state changes to local or remote syslog
+type syslogSink struct {
+	network  string                     // Network messages are sent over: unixgram or unix locally, udp or tcp remotely
+	address  string                     // Socket path or host:port
+	local    bool                       // Whether messages go to the local daemon, in its RFC 3164 format, rather than as RFC 5424
+	facility int                        // Facility of every message
+	severity int                        // Severity of probe results
+	hostname string                     // Host name sent to remote servers
+	mutex    sync.Mutex                 // Guards conn and states
+	conn     net.Conn                   // Connection to syslog, redialed if a send fails
+	states   map[*statistic]targetState // Last state of each target, to send changes
+}
+
+// Connect to syslog at the address: local for the local daemon, udp://host[:port], tcp://host[:port],
+// or host[:port] for UDP, with the facility and severity of probe results given by name
+func openSyslog(address string, facility string, severity string) (*syslogSink, error) {
+	sink := &syslogSink{states: make(map[*statistic]targetState)}
+	var ok bool
+	if sink.facility, ok = syslogFacilities[facility]; !ok {
+		return nil, fmt.Errorf("Invalid syslog facility %q, expected e.g. daemon, user or local0", facility)
+	}
+	if sink.severity, ok = syslogSeverities[severity]; !ok {
+		return nil, fmt.Errorf("Invalid syslog severity %q, expected e.g. info, notice or warning", severity)
+	}
+	if address == "local" {
+		sink.local = true
+	} else {
+		sink.network = "udp"
+		if scheme, rest, found := strings.Cut(address, "://"); found {
+			if scheme != "udp" && scheme != "tcp" {
+				return nil, fmt.Errorf("Unsupported syslog address %q, expected local, udp:// or tcp://", address)
+			}
+			sink.network, address = scheme, rest
+		}
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(address, defaultSyslogPort)
+		}
+		sink.address = address
+		sink.hostname, _ = os.Hostname()
+	}
+	if err := sink.dial(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// Connect to syslog, trying each local socket as a datagram then a stream socket for the local daemon
+func (sink *syslogSink) dial() error {
+	if !sink.local {
+		conn, err := net.Dial(sink.network, sink.address)
+		if err != nil {
+			return fmt.Errorf("Failed to connect to syslog at %s: %w", sink.address, err)
+		}
+		sink.conn = conn
+		return nil
+	}
+	for _, path := range localSyslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, path); err == nil {
+				sink.conn, sink.network, sink.address = conn, network, path
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("No local syslog daemon found at %s", strings.Join(localSyslogSockets, ", "))
+}
+
+// Send a message at the severity, reconnecting once if syslog went away. Must be called with sink.mutex held.
+func (sink *syslogSink) send(severity int, message string) error {
+	priority := sink.facility<<3 | severity
+	var line string
+	if sink.local {
+		line = fmt.Sprintf("<%d>%s %s[%d]: %s", priority, time.Now().Format(time.Stamp), syslogTag, os.Getpid(), message)
+	} else {
+		line = fmt.Sprintf("<%d>1 %s %s %s %d - - %s", priority, time.Now().Format(time.RFC3339Nano), sink.hostname, syslogTag, os.Getpid(), message)
+	}
+	// Stream sockets carry newline-terminated messages (RFC 6587), datagrams one message each
+	if sink.network == "tcp" || sink.network == "unix" {
+		line += "\n"
+	}
+	if sink.conn != nil {
+		if _, err := sink.conn.Write([]byte(line)); err == nil {
+			return nil
+		}
+		sink.conn.Close()
+		sink.conn = nil
+	}
+	if err := sink.dial(); err != nil {
+		return err
+	}
+	_, err := sink.conn.Write([]byte(line))
+	return err
+}
+
+// Send the probe result, preceded by the target's state change if it went down or came back up
+func (sink *syslogSink) observe(stats *statistic, result probeResult) error {
+	stats.mutex.Lock()
+	state := stats.state
+	stats.mutex.Unlock()
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	previous := sink.states[stats]
+	sink.states[stats] = state
+	// Send going down, including from the start, and coming back up
+	if state != previous && state != stateUnknown && (previous != stateUnknown || state == stateDown) {
+		severity := syslogNotice
+		if state == stateDown {
+			severity = syslogWarning
+		}
+		if err := sink.send(severity, fmt.Sprintf("target=%s ip=%s state=%s%s", result.Target, result.IP, state, syslogLabels(result.Labels))); err != nil {
+			return err
+		}
+	}
+	message := fmt.Sprintf("seq=%d target=%s ip=%s rtt=%s ttl=%d loss=%.2f%%", result.Seq, result.Target, result.IP, display(result.RTT), result.TTL, result.Loss)
+	if result.Error != "" {
+		message += fmt.Sprintf(" error=%q", result.Error)
+	}
+	return sink.send(sink.severity, message+syslogLabels(result.Labels))
+}
+
+// Labels of a message as trailing key=value pairs
+func syslogLabels(tags labels) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " " + tags.String()
+}
+
+// Disconnect from syslog
+func (sink *syslogSink) close() error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	if sink.conn == nil {
+		return nil
+	}
+	return sink.conn.Close()
+}
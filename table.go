@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	downThreshold   int           = 3           // Consecutive losses before an up target is considered down
+	upThreshold     int           = 2           // Consecutive replies before a down target is considered up
+	refreshInterval time.Duration = time.Second // How often the -table display is redrawn
+)
+
+var tableRedraw atomic.Bool // Set once lines were printed below the table, so it's drawn afresh beneath them
+
+// Up/down state of a target, changed with hysteresis so a single loss or reply doesn't flap it
+type targetState int
+
+const (
+	stateUnknown targetState = iota // No probe has completed yet
+	stateUp                         // Target is replying
+	stateDown                       // Target has stopped replying
+)
+
+// Name the state for display
+func (state targetState) String() string {
+	switch state {
+	case stateUp:
+		return "UP"
+	case stateDown:
+		return "DOWN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Move the target's state according to the outcome of the last probe, requiring
+// downThreshold consecutive losses to go down and upThreshold consecutive replies to come back up.
+// The first probe decides the state straight away. Must be called with stats.mutex held.
+func (stats *statistic) updateState(replied bool) {
+	switch {
+	case stats.state == stateUnknown:
+		stats.state = stateFor(replied)
+		stats.consecutive = 0
+	case (stats.state == stateUp) == replied:
+		stats.consecutive = 0
+	default:
+		stats.consecutive++
+		threshold := downThreshold
+		if stats.state == stateDown {
+			threshold = upThreshold
+		}
+		if stats.consecutive >= threshold {
+			stats.state = stateFor(replied)
+			stats.consecutive = 0
+		}
+	}
+}
+
+// State matching the outcome of a single probe
+func stateFor(replied bool) targetState {
+	if replied {
+		return stateUp
+	}
+	return stateDown
+}
+
+// Redraw the table of all targets in place every refreshInterval until done is closed,
+// then draw it a final time
+func refreshTable(allStats []*statistic, done <-chan struct{}) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	drawnLines := 0
+	for {
+		if tableRedraw.Swap(false) {
+			drawnLines = 0
+		}
+		drawnLines = drawTable(allStats, drawnLines)
+		select {
+		case <-done:
+			drawTable(allStats, drawnLines)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Draw one row per target over the previously drawn table, returning the number of lines drawn
+func drawTable(allStats []*statistic, drawnLines int) int {
+	var table bytes.Buffer
+	writer := tabwriter.NewWriter(&table, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "TARGET\tSENT\tLAST RTT\tAVG RTT\tLOSS\tSTATE\tLABELS")
+	for _, stats := range allStats {
+		stats.mutex.Lock()
+		var averageRTT time.Duration
+		if len(stats.rttAll) > 0 {
+			averageRTT = stats.totalRTT / time.Duration(len(stats.rttAll))
+		}
+		fmt.Fprintf(
+			writer,
+			"%s\t%d\t%s\t%s\t%.2f%%\t%s\t%s\n",
+			stats.target.address,
+			stats.count,
+			display(stats.lastRTT),
+			display(averageRTT),
+			stats.loss,
+			stats.state,
+			stats.labels)
+		stats.mutex.Unlock()
+	}
+	writer.Flush()
+
+	// Move the cursor back over the last table and clear it before redrawing
+	if drawnLines > 0 {
+		fmt.Fprintf(os.Stdout, "\033[%dA\033[J", drawnLines)
+	}
+	os.Stdout.Write(table.Bytes())
+	return len(allStats) + 1
+}
@@ -0,0 +1,199 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Enable software receive timestamps with SO_TIMESTAMPING, falling back to SO_TIMESTAMPNS
+func enableTimestamps(rawConn syscall.RawConn) error {
+	var sockErr error
+	err := rawConn.Control(func(fd uintptr) {
+		flags := unix.SOF_TIMESTAMPING_RX_SOFTWARE | unix.SOF_TIMESTAMPING_SOFTWARE
+		if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMPING, flags); sockErr == nil {
+			return
+		}
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMPNS, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// Enable software and hardware timestamps of both requests sent and replies received with
+// SO_TIMESTAMPING, collecting those of requests from the error queue without the packet
+func enableTransmitTimestamps(rawConn syscall.RawConn) error {
+	var sockErr error
+	err := rawConn.Control(func(fd uintptr) {
+		flags := unix.SOF_TIMESTAMPING_TX_SOFTWARE | unix.SOF_TIMESTAMPING_RX_SOFTWARE | unix.SOF_TIMESTAMPING_SOFTWARE |
+			unix.SOF_TIMESTAMPING_TX_HARDWARE | unix.SOF_TIMESTAMPING_RX_HARDWARE | unix.SOF_TIMESTAMPING_RAW_HARDWARE |
+			unix.SOF_TIMESTAMPING_OPT_TSONLY
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMPING, flags)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// Devices set to timestamp in hardware, each once however many sockets probe through it
+var (
+	timestampingDevices = make(map[string]*deviceTimestamping) // Devices set so far, by name
+	timestampingMutex   sync.Mutex                             // Guards timestampingDevices
+)
+
+// Hardware timestamping of a device, as goPing found and left it
+type deviceTimestamping struct {
+	saved *unix.HwTstampConfig // Configuration before goPing changed it, restored at exit, nil if unchanged
+	err   error                // Why the device doesn't timestamp in hardware, nil if it does
+}
+
+// Have the NIC of the device timestamp every packet sent and received with SIOCSHWTSTAMP, the
+// first time a socket probes through it. Its configuration is saved with SIOCGHWTSTAMP first, so
+// restoreHardwareTimestamping can put it back, and the device is left alone if it can't be.
+func enableHardwareTimestamping(rawConn syscall.RawConn, device string) error {
+	timestampingMutex.Lock()
+	defer timestampingMutex.Unlock()
+	if configured, ok := timestampingDevices[device]; ok {
+		return configured.err
+	}
+	configured := &deviceTimestamping{}
+	err := rawConn.Control(func(fd uintptr) {
+		saved, err := unix.IoctlGetHwTstamp(int(fd), device)
+		if err != nil {
+			configured.err = fmt.Errorf("%s doesn't timestamp in hardware: %w", device, err)
+			return
+		}
+		config := unix.HwTstampConfig{Tx_type: unix.HWTSTAMP_TX_ON, Rx_filter: unix.HWTSTAMP_FILTER_ALL}
+		if *saved == config {
+			return
+		}
+		if err := unix.IoctlSetHwTstamp(int(fd), device, &config); err != nil {
+			configured.err = fmt.Errorf("%s doesn't timestamp in hardware: %w", device, err)
+			return
+		}
+		configured.saved = saved
+		slog.Debug("Enabled hardware timestamping", "device", device, "tx", saved.Tx_type, "rx", saved.Rx_filter)
+	})
+	if err != nil {
+		return err
+	}
+	timestampingDevices[device] = configured
+	return configured.err
+}
+
+// Put back the hardware timestamping configuration of every device goPing changed, as PTP
+// daemons or other programs on the host may rely on it
+func restoreHardwareTimestamping() {
+	timestampingMutex.Lock()
+	defer timestampingMutex.Unlock()
+	if len(timestampingDevices) == 0 {
+		return
+	}
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Could not restore hardware timestamping (%s). Leaving it enabled...", err))
+		return
+	}
+	defer unix.Close(fd)
+	for device, configured := range timestampingDevices {
+		if configured.saved == nil {
+			continue
+		}
+		if err := unix.IoctlSetHwTstamp(fd, device, configured.saved); err != nil {
+			slog.Warn(fmt.Sprintf("Could not restore hardware timestamping of %s (%s). Leaving it enabled...", device, err))
+		}
+	}
+	clear(timestampingDevices)
+}
+
+// Collect the software and hardware timestamps of the requests sent from the error queue, each
+// zero if there is none
+func readTransmitTimestamps(rawConn syscall.RawConn) (time.Time, time.Time) {
+	var software, hardware time.Time
+	oob := make([]byte, controlMessageSize)
+	rawConn.Control(func(fd uintptr) {
+		for {
+			_, oobn, _, _, err := unix.Recvmsg(int(fd), nil, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+			if err != nil {
+				return
+			}
+			if sent, ok := timestampingTimes(oob[:oobn]); ok {
+				if !sent[0].IsZero() {
+					software = sent[0]
+				}
+				if !sent[2].IsZero() {
+					hardware = sent[2]
+				}
+			}
+		}
+	})
+	return software, hardware
+}
+
+// Extract the hardware receive timestamp from a reply's control messages, taken by the NIC's clock
+func parseHardwareTimestamp(oob []byte) (time.Time, bool) {
+	received, ok := timestampingTimes(oob)
+	if !ok || received[2].IsZero() {
+		return time.Time{}, false
+	}
+	return received[2], true
+}
+
+// Software, deprecated, and hardware timestamps of a packet's SCM_TIMESTAMPING control message,
+// each zero if not taken, false if there is no such message
+func timestampingTimes(oob []byte) ([3]time.Time, bool) {
+	var times [3]time.Time
+	found := false
+	controlMessages(oob, func(header *unix.Cmsghdr, data []byte) {
+		if found || header.Level != unix.SOL_SOCKET || header.Type != unix.SCM_TIMESTAMPING || len(data) < int(unsafe.Sizeof([3]unix.Timespec{})) {
+			return
+		}
+		timestamps := (*[3]unix.Timespec)(unsafe.Pointer(&data[0]))
+		for i, timestamp := range timestamps {
+			if timestamp.Sec != 0 || timestamp.Nsec != 0 {
+				times[i] = time.Unix(timestamp.Unix())
+			}
+		}
+		found = true
+	})
+	return times, found
+}
+
+// Extract the kernel receive timestamp from a reply's control messages
+func parseTimestamp(oob []byte) (time.Time, bool) {
+	var received time.Time
+	found := false
+	controlMessages(oob, func(header *unix.Cmsghdr, data []byte) {
+		if found || header.Level != unix.SOL_SOCKET {
+			return
+		}
+		switch header.Type {
+		case unix.SCM_TIMESTAMPING:
+			// Software, deprecated, and hardware timestamps, in that order
+			if len(data) < int(unsafe.Sizeof([3]unix.Timespec{})) {
+				return
+			}
+			timestamps := (*[3]unix.Timespec)(unsafe.Pointer(&data[0]))
+			if timestamps[0].Sec != 0 || timestamps[0].Nsec != 0 {
+				received, found = time.Unix(timestamps[0].Unix()), true
+			}
+		case unix.SCM_TIMESTAMPNS:
+			if len(data) < int(unsafe.Sizeof(unix.Timespec{})) {
+				return
+			}
+			timestamp := (*unix.Timespec)(unsafe.Pointer(&data[0]))
+			received, found = time.Unix(timestamp.Unix()), true
+		}
+	})
+	return received, found
+}
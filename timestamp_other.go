@@ -0,0 +1,42 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// Kernel receive timestamps are only implemented on Linux
+func enableTimestamps(rawConn syscall.RawConn) error {
+	return errors.New("Kernel timestamps are not supported on this platform")
+}
+
+// Transmit timestamps are only implemented on Linux
+func enableTransmitTimestamps(rawConn syscall.RawConn) error {
+	return errors.New("Transmit timestamps are not supported on this platform")
+}
+
+// Hardware timestamps are only implemented on Linux
+func enableHardwareTimestamping(rawConn syscall.RawConn, device string) error {
+	return errors.New("Hardware timestamps are not supported on this platform")
+}
+
+// No device is set to timestamp in hardware on this platform
+func restoreHardwareTimestamping() {}
+
+// No requests are timestamped on this platform
+func readTransmitTimestamps(rawConn syscall.RawConn) (time.Time, time.Time) {
+	return time.Time{}, time.Time{}
+}
+
+// No control messages carry hardware timestamps on this platform
+func parseHardwareTimestamp(oob []byte) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// No control messages carry timestamps on this platform
+func parseTimestamp(oob []byte) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	defaultMaxHops int           = 30              // Default highest TTL probed by goping trace
+	traceTimeout   time.Duration = 2 * time.Second // Deadline of replies to each hop's probe
+	traceSource    string        = "goPing"        // Node of the hop graph probes start from
+)
+
+// Path to a target discovered by probing every TTL up to it each round (goping trace), mtr style
+type tracePath struct {
+	target    *resolution       // Target as given
+	ip        *net.IPAddr       // Address of the target probed
+	hops      []*traceHop       // Hops by TTL, from 1
+	mutex     sync.Mutex        // Guards the fields below
+	reached   int               // Lowest TTL the target itself replied at, 0 until it has
+	rounds    int               // Rounds probed
+	edges     map[[2]string]int // Rounds each pair of consecutive responders was seen in
+	edgeOrder [][2]string       // Keys of edges in the order first seen
+}
+
+// Hop of a traced path: probes sent with its TTL and the routers answering them
+type traceHop struct {
+	number     int                      // TTL of the hop
+	stats      *statistic               // Probes of the hop, counting ones nobody answered as lost
+	responders map[string]*hopResponder // Routers that answered by IP, several if the path changes or balances
+	order      []string                 // IPs of responders in the order they first answered
+}
+
+// Router answering probes of a hop, or the target at the last hop
+type hopResponder struct {
+	ip         net.IP          // Address of the router
+	rtts       []time.Duration // RTT of each answer
+	extensions []string        // ICMP extensions of its last answer carrying any (RFC 4884), e.g. MPLS labels
+	ecn        string          // ECN field of the last marked probe it answered (-ecn), as it arrived or as echoed by the target
+}
+
+// Create a path tracer for the target probing TTLs up to maxHops
+func newTracePath(target *resolution, ip *net.IPAddr, maxHops int) *tracePath {
+	path := &tracePath{target: target, ip: ip, edges: make(map[[2]string]int)}
+	for number := 1; number <= maxHops; number++ {
+		path.hops = append(path.hops, &traceHop{
+			number:     number,
+			stats:      &statistic{target: target, id: nextEchoID(), hopLimit: number, timeout: traceTimeout},
+			responders: make(map[string]*hopResponder),
+		})
+	}
+	return path
+}
+
+// Close the sockets of every hop
+func (path *tracePath) close() {
+	for _, hop := range path.hops {
+		hop.stats.closeSocket()
+	}
+}
+
+// Probe the path count rounds, or forever if -1, an interval apart
+func (path *tracePath) run(count int) {
+	for round := 0; count == -1 || round < count; round++ {
+		if round > 0 {
+			time.Sleep(nextInterval(probeInterval))
+		}
+		probing.wait(nil)
+		path.round()
+	}
+}
+
+// Probe every hop up to the target at once, recording who answered each
+func (path *tracePath) round() {
+	path.mutex.Lock()
+	hops := path.hops
+	if path.reached > 0 {
+		hops = path.hops[:path.reached]
+	}
+	path.mutex.Unlock()
+
+	answered := make([]net.IP, len(hops)) // Responder of each hop this round, nil if none
+	var wg sync.WaitGroup
+	for i, hop := range hops {
+		wg.Add(1)
+		go func(i int, hop *traceHop) {
+			defer wg.Done()
+			answered[i] = hop.probe(path.ip)
+		}(i, hop)
+	}
+	wg.Wait()
+
+	path.mutex.Lock()
+	defer path.mutex.Unlock()
+	path.rounds++
+	previous := traceSource
+	for i, ip := range answered {
+		if ip == nil {
+			continue
+		}
+		if ip.Equal(path.ip.IP) && (path.reached == 0 || hops[i].number < path.reached) {
+			path.reached = hops[i].number
+		}
+		// Link consecutive responders, bridging hops that didn't answer
+		edge := [2]string{previous, ip.String()}
+		if _, ok := path.edges[edge]; !ok {
+			path.edgeOrder = append(path.edgeOrder, edge)
+		}
+		path.edges[edge]++
+		previous = ip.String()
+		if ip.Equal(path.ip.IP) {
+			break
+		}
+	}
+}
+
+// Send one probe with the hop's TTL, returning the router or target that answered, nil if none did
+func (hop *traceHop) probe(ip *net.IPAddr) net.IP {
+	sent := time.Now()
+	err := hop.stats.ping(ip)
+	answeredBy := hop.stats.responder
+	if err == nil {
+		answeredBy = ip.IP
+	}
+	hop.stats.tally(answeredBy != nil, sent)
+	if answeredBy == nil {
+		trace("Hop did not answer", "hop", hop.number, "error", err)
+		return nil
+	}
+	hop.stats.mutex.Lock()
+	defer hop.stats.mutex.Unlock()
+	key := answeredBy.String()
+	answering, ok := hop.responders[key]
+	if !ok {
+		answering = &hopResponder{ip: answeredBy}
+		hop.responders[key] = answering
+		hop.order = append(hop.order, key)
+	}
+	answering.rtts = append(answering.rtts, hop.stats.rtt)
+	if hop.stats.extensions != nil {
+		answering.extensions = hop.stats.extensions
+	}
+	if hop.stats.ecn != ecnNotECT && hop.stats.replyECN >= 0 {
+		answering.ecn = ecnNames[hop.stats.replyECN]
+	}
+	return answeredBy
+}
+
+// Copy of the routers answering the hop, safe to call while it is being probed
+func (hop *traceHop) answering() []hopResponder {
+	hop.stats.mutex.Lock()
+	defer hop.stats.mutex.Unlock()
+	answering := make([]hopResponder, len(hop.order))
+	for i, key := range hop.order {
+		answering[i] = hopResponder{ip: hop.responders[key].ip, rtts: append([]time.Duration(nil), hop.responders[key].rtts...), extensions: hop.responders[key].extensions, ecn: hop.responders[key].ecn}
+	}
+	return answering
+}
+
+// Hops up to the target, or every hop probed if it hasn't replied
+func (path *tracePath) visibleHops() []*traceHop {
+	if path.reached > 0 {
+		return path.hops[:path.reached]
+	}
+	return path.hops
+}
+
+// Print a table of every hop with its loss and the RTTs of each router answering it
+func (path *tracePath) show() {
+	path.mutex.Lock()
+	defer path.mutex.Unlock()
+	fmt.Printf("\n----------------------------| Path to %s |----------------------------\n", displayAddress(path.ip))
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	header := "HOP\tADDRESS\tSENT\tLOSS\tBEST\tAVG\tWORST"
+	// Show where ECN markings are bleached or rewritten along the path (-ecn)
+	if ecnProbe != ecnNotECT {
+		header += "\tECN"
+	}
+	// Locate each router in the -geoip databases
+	if len(geoDatabases) > 0 {
+		header += "\tLOCATION"
+	}
+	// Name the owner of each router's network over RDAP (-whois)
+	if whoisHops {
+		header += "\tOWNER"
+	}
+	fmt.Fprintln(writer, header)
+	for _, hop := range path.visibleHops() {
+		summary := hop.stats.summary()
+		answering := hop.answering()
+		if len(answering) == 0 {
+			fmt.Fprintf(writer, "%d\t???\t%d\t%.2f%%\t\t\t\n", hop.number, summary.Sent, summary.Loss)
+			continue
+		}
+		for i, responder := range answering {
+			best, average, worst := responder.rttRange()
+			number, sent, loss := fmt.Sprint(hop.number), fmt.Sprint(summary.Sent), fmt.Sprintf("%.2f%%", summary.Loss)
+			// Routers after the first answering a hop share its probes
+			if i > 0 {
+				number, sent, loss = "", "", ""
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s", number, displayAddress(&net.IPAddr{IP: responder.ip}), sent, loss, display(best), display(average), display(worst))
+			if ecnProbe != ecnNotECT {
+				fmt.Fprintf(writer, "\t%s", responder.ecn)
+			}
+			if len(geoDatabases) > 0 {
+				fmt.Fprintf(writer, "\t%s", geoLookup(responder.ip))
+			}
+			if whoisHops {
+				fmt.Fprintf(writer, "\t%s", hopOwner(responder.ip))
+			}
+			fmt.Fprintln(writer)
+		}
+	}
+	writer.Flush()
+	path.showExtensions()
+	if path.reached == 0 {
+		fmt.Printf("%s not reached within %d hops\n", path.ip, len(path.hops))
+	}
+}
+
+// Print the ICMP extensions routers attached to their answers, such as the MPLS labels of the LSP
+// probes took and the interfaces they arrived on. Must be called with path.mutex held.
+func (path *tracePath) showExtensions() {
+	header := false
+	for _, hop := range path.visibleHops() {
+		for _, responder := range hop.answering() {
+			if len(responder.extensions) == 0 {
+				continue
+			}
+			if !header {
+				fmt.Println("\nICMP extensions:")
+				header = true
+			}
+			fmt.Printf("%d  %s\n", hop.number, displayAddress(&net.IPAddr{IP: responder.ip}))
+			for _, extension := range responder.extensions {
+				fmt.Printf("    %s\n", extension)
+			}
+		}
+	}
+}
+
+// Lowest, mean and highest RTT of the router's answers
+func (answering *hopResponder) rttRange() (time.Duration, time.Duration, time.Duration) {
+	best, worst := answering.rtts[0], answering.rtts[0]
+	var total time.Duration
+	for _, rtt := range answering.rtts {
+		best, worst = min(best, rtt), max(worst, rtt)
+		total += rtt
+	}
+	return best, total / time.Duration(len(answering.rtts)), worst
+}
+
+// Discovered hop graph of a traced path (-export json)
+type hopGraph struct {
+	Target string      `json:"target"` // Target as given
+	IP     string      `json:"ip"`     // Address of the target probed
+	Rounds int         `json:"rounds"` // Rounds probed
+	Hops   []graphHop  `json:"hops"`   // Hops up to the target
+	Edges  []graphEdge `json:"edges"`  // Links between consecutive responders
+}
+
+// Hop of the graph with its loss and answering routers
+type graphHop struct {
+	Hop        int         `json:"hop"`        // TTL of the hop
+	Sent       int         `json:"sent"`       // Probes sent
+	Lost       int         `json:"lost"`       // Probes nobody answered
+	Loss       float64     `json:"loss"`       // Percent loss
+	Responders []graphNode `json:"responders"` // Routers answering the hop
+}
+
+// Router of the graph with the RTTs of its answers
+type graphNode struct {
+	IP         string        `json:"ip"`                   // Address of the router
+	Name       string        `json:"name,omitempty"`       // Reverse DNS name, unless -n is given
+	Replies    int           `json:"replies"`              // Probes it answered
+	MinRTT     time.Duration `json:"min_rtt"`              // Lowest RTT
+	AvgRTT     time.Duration `json:"avg_rtt"`              // Mean RTT
+	MaxRTT     time.Duration `json:"max_rtt"`              // Highest RTT
+	Extensions []string      `json:"extensions,omitempty"` // ICMP extensions of its last answer carrying any, e.g. MPLS labels
+	Geo        *geoLocation  `json:"geo,omitempty"`        // Location of the router (-geoip), nil if unknown
+	Owner      string        `json:"owner,omitempty"`      // Owner of the router's network (-whois), empty if not looked up
+}
+
+// Link of the graph between responders of consecutive hops, from goPing itself for the first
+type graphEdge struct {
+	From   string `json:"from"`   // Responder of the earlier hop, or goPing itself
+	To     string `json:"to"`     // Responder of the later hop
+	Rounds int    `json:"rounds"` // Rounds the link was seen in
+}
+
+// Build the hop graph discovered so far
+func (path *tracePath) graph() hopGraph {
+	path.mutex.Lock()
+	defer path.mutex.Unlock()
+	graph := hopGraph{Target: path.target.address, IP: path.ip.IP.String(), Rounds: path.rounds}
+	for _, hop := range path.visibleHops() {
+		summary := hop.stats.summary()
+		node := graphHop{Hop: hop.number, Sent: summary.Sent, Lost: summary.Lost, Loss: summary.Loss, Responders: []graphNode{}}
+		for _, answering := range hop.answering() {
+			best, average, worst := answering.rttRange()
+			name := ""
+			if !numeric {
+				name = reverseName(&net.IPAddr{IP: answering.ip})
+			}
+			owner := ""
+			if whoisHops {
+				owner = hopOwner(answering.ip)
+			}
+			node.Responders = append(node.Responders, graphNode{IP: answering.ip.String(), Name: name, Replies: len(answering.rtts), MinRTT: best, AvgRTT: average, MaxRTT: worst, Extensions: answering.extensions, Geo: geoLookup(answering.ip), Owner: owner})
+		}
+		graph.Hops = append(graph.Hops, node)
+	}
+	for _, edge := range path.edgeOrder {
+		graph.Edges = append(graph.Edges, graphEdge{From: edge[0], To: edge[1], Rounds: path.edges[edge]})
+	}
+	return graph
+}
+
+// Write the hop graph to stdout as JSON or Graphviz DOT (-export)
+func (path *tracePath) export(format string) error {
+	graph := path.graph()
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(graph)
+	}
+	var dot strings.Builder
+	fmt.Fprintf(&dot, "digraph %q {\n\trankdir=LR;\n\tnode [shape=box, fontname=monospace];\n", "goping "+graph.Target)
+	fmt.Fprintf(&dot, "\t%q [shape=ellipse];\n", traceSource)
+	for _, hop := range graph.Hops {
+		for _, node := range hop.Responders {
+			label := node.IP
+			if node.Name != "" {
+				label = node.Name + "\n" + node.IP
+			}
+			label = fmt.Sprintf("%s\nhop %d, loss %.2f%%\nRTT %s / %s / %s", label, hop.Hop, hop.Loss, display(node.MinRTT), display(node.AvgRTT), display(node.MaxRTT))
+			if node.Geo != nil {
+				label += "\n" + node.Geo.String()
+			}
+			if node.Owner != "" {
+				label += "\n" + node.Owner
+			}
+			// Follow with the router's ICMP extensions, e.g. its MPLS labels
+			for _, extension := range node.Extensions {
+				label += "\n" + extension
+			}
+			fmt.Fprintf(&dot, "\t%q [label=%q];\n", node.IP, label)
+		}
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(&dot, "\t%q -> %q [label=%q];\n", edge.From, edge.To, fmt.Sprintf("%d/%d", edge.Rounds, graph.Rounds))
+	}
+	dot.WriteString("}\n")
+	_, err := os.Stdout.WriteString(dot.String())
+	return err
+}
+
+// Trace the path to the target (goping trace), printing the hop table, or the hop graph
+// in the export format if given, once done or interrupted
+func runTrace(target *resolution, maxHops int, count int, exportFormat string) error {
+	ip, err := target.current()
+	if err != nil {
+		return err
+	}
+	path := newTracePath(target, ip, maxHops)
+	defer path.close()
+	finish := func() {
+		if exportFormat == "" {
+			path.show()
+			return
+		}
+		if err := path.export(exportFormat); err != nil {
+			slog.Error(err.Error())
+		}
+	}
+	closeHandler(finish)
+	slog.Info(fmt.Sprintf("Tracing the path to %s over up to %d hops...", displayAddress(ip), maxHops))
+	path.run(count)
+	finish()
+	return nil
+}
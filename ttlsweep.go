@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const defaultTTLSweepCount int = 3 // Probes of each TTL of goping sweep-ttl unless -c is given
+
+// Answers to the probes of one TTL of a TTL sweep
+type ttlPoint struct {
+	ttl        int        // TTL of the probes
+	stats      *statistic // Probes of the TTL, counting ones nobody answered as lost
+	exceeded   int        // Time exceeded messages from routers
+	echoed     int        // Echo replies from the target
+	other      int        // Other ICMP messages, such as destination unreachable
+	otherType  icmp.Type  // Type of the last of those
+	responders []string   // Addresses that answered, in the order they first did
+}
+
+// Probe the target with every TTL from firstTTL to maxTTL, count rounds, one probe at a time
+// (goping sweep-ttl), tabulating which TTLs drew time exceeded messages and which echo replies,
+// to find hops that filter probes or rate limit their ICMP without a full trace
+func runTTLSweep(target *resolution, firstTTL int, maxTTL int, count int) error {
+	ip, err := target.current()
+	if err != nil {
+		return err
+	}
+	var points []*ttlPoint
+	for ttl := firstTTL; ttl <= maxTTL; ttl++ {
+		points = append(points, &ttlPoint{
+			ttl:   ttl,
+			stats: &statistic{target: target, id: nextEchoID(), hopLimit: ttl, timeout: sweepTimeout},
+		})
+	}
+	defer func() {
+		for _, point := range points {
+			point.stats.closeSocket()
+		}
+	}()
+	probed := 0 // TTLs probed so far, for the summary on ctrl-c
+	closeHandler(func() { showTTLSweep(ip, points[:probed]) })
+	slog.Info(fmt.Sprintf("Sweeping TTLs %d to %d towards %s, %d probes each...", firstTTL, maxTTL, displayAddress(ip), count))
+	reached := len(points) // TTLs up to the lowest the target replied at, beyond which probes only repeat its reply
+	for round := 0; round < count; round++ {
+		for i, point := range points[:reached] {
+			probing.wait(nil)
+			if point.probe(ip) {
+				reached = i + 1
+			}
+			probed = max(probed, i+1)
+			time.Sleep(sweepInterval)
+			if i+1 == reached {
+				break
+			}
+		}
+	}
+	showTTLSweep(ip, points[:probed])
+	return nil
+}
+
+// Send one probe with the TTL and count what answered it, returning whether the target replied
+func (point *ttlPoint) probe(ip *net.IPAddr) bool {
+	sent := time.Now()
+	err := point.stats.ping(ip)
+	answer, answerer := point.stats.answer, point.stats.answerer
+	point.stats.tally(answer != nil, sent)
+	seq := point.stats.count
+	if answer == nil {
+		slog.Info(fmt.Sprintf("TTL: %d\t\tSeq: %d\t\tNo answer", point.ttl, seq), "ttl", point.ttl, "seq", seq, "error", err)
+		return false
+	}
+	if address := answerer.String(); !slices.Contains(point.responders, address) {
+		point.responders = append(point.responders, address)
+	}
+	echoed := false
+	switch answer {
+	case ipv4.ICMPTypeEchoReply, ipv6.ICMPTypeEchoReply:
+		point.echoed++
+		echoed = true
+	case ipv4.ICMPTypeTimeExceeded, ipv6.ICMPTypeTimeExceeded:
+		point.exceeded++
+	default:
+		point.other++
+		point.otherType = answer
+	}
+	slog.Info(
+		fmt.Sprintf("TTL: %d\t\tSeq: %d\t\t%s from %s\t\tRTT: %s", point.ttl, seq, answer, displayAddress(&net.IPAddr{IP: answerer}), display(point.stats.rtt)),
+		"ttl", point.ttl,
+		"seq", seq,
+		"answer", answer,
+		"from", answerer,
+		"rtt", point.stats.rtt)
+	return echoed
+}
+
+// Print a table of what answered each TTL, followed by the hops that filter or rate limit
+func showTTLSweep(ip *net.IPAddr, points []*ttlPoint) {
+	fmt.Printf("\n----------------------------| TTLs to %s |----------------------------\n", displayAddress(ip))
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "TTL\tADDRESS\tSENT\tEXCEEDED\tECHO\tOTHER\tNONE")
+	for _, point := range points {
+		addresses := "???"
+		if len(point.responders) > 0 {
+			addresses = strings.Join(point.responders, " ")
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%d\t%d\t%d\t%d\t%d\n", point.ttl, addresses, point.stats.count, point.exceeded, point.echoed, point.other, point.stats.lost)
+	}
+	writer.Flush()
+
+	// The target's share of replies is the baseline that hops answering less of the time fall short of
+	var target *ttlPoint
+	for _, point := range points {
+		if point.echoed > 0 {
+			target = point
+			break
+		}
+	}
+	if target == nil {
+		if len(points) > 0 {
+			fmt.Printf("%s not reached by TTL %d\n", ip, points[len(points)-1].ttl)
+		}
+	} else {
+		fmt.Printf("%s reached at TTL %d, replying to %d of %d probes\n", ip, target.ttl, target.echoed, target.stats.count)
+	}
+	for _, point := range points {
+		if point == target {
+			break
+		}
+		switch {
+		case point.other > 0:
+			fmt.Printf("TTL %d: %s, probes are filtered there\n", point.ttl, point.otherType)
+		case point.exceeded == 0 && target != nil:
+			fmt.Printf("TTL %d: no time exceeded while later TTLs answered, the hop filters or doesn't send them\n", point.ttl)
+		case target != nil && point.exceeded*target.stats.count < target.echoed*point.stats.count:
+			fmt.Printf("TTL %d: answered %d of %d probes while the target answered %d of %d, ICMP rate limiting at the hop rather than loss on the path\n",
+				point.ttl, point.exceeded, point.stats.count, target.echoed, target.stats.count)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const tuiResults int = 10 // Last results of the selected target shown in its view
+
+var (
+	interactiveTUI  bool         // Interactive console with keyboard controls (-tui) flag
+	runtimeInterval atomic.Int64 // Interval between probes set with + and - in the interactive console, 0 for the default
+	// Intervals + and - step through
+	intervalSteps = []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond,
+		time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second, time.Minute,
+	}
+)
+
+// State of the interactive console (-tui), drawn by bubbletea
+type tuiModel struct {
+	allStats   []*statistic    // Statistics clients of the targets
+	done       <-chan struct{} // Closed once all targets are done
+	selected   int             // Index of the target highlighted
+	detail     bool            // Whether the selected target's own view is shown in place of the table
+	confirming bool            // Whether quitting awaits confirmation
+	quit       bool            // Whether the operator quit before the targets were done
+	status     string          // Outcome of the last key pressed, shown below the table
+}
+
+type tuiTick struct{} // Time to redraw the console
+type tuiDone struct{} // All targets are done
+
+// Run the interactive console until the operator quits or all targets are done, returning whether
+// the operator quit
+func runTUI(allStats []*statistic, done <-chan struct{}) (bool, error) {
+	model, err := tea.NewProgram(tuiModel{allStats: allStats, done: done}, tea.WithAltScreen()).Run()
+	if err != nil {
+		return false, err
+	}
+	return model.(tuiModel).quit, nil
+}
+
+// Redraw every refreshInterval
+func tuiRefresh() tea.Cmd {
+	return tea.Tick(refreshInterval, func(time.Time) tea.Msg { return tuiTick{} })
+}
+
+func (model tuiModel) Init() tea.Cmd {
+	done := model.done
+	return tea.Batch(tuiRefresh(), func() tea.Msg {
+		<-done
+		return tuiDone{}
+	})
+}
+
+func (model tuiModel) Update(message tea.Msg) (tea.Model, tea.Cmd) {
+	switch message := message.(type) {
+	case tuiTick:
+		return model, tuiRefresh()
+	case tuiDone:
+		return model, tea.Quit
+	case tea.KeyMsg:
+		return model.press(message.String())
+	}
+	return model, nil
+}
+
+// Act on a key pressed
+func (model tuiModel) press(key string) (tea.Model, tea.Cmd) {
+	// Quit only on y, any other key cancelling
+	if model.confirming {
+		model.confirming = false
+		if key == "y" || key == "Y" {
+			model.quit = true
+			return model, tea.Quit
+		}
+		model.status = "Kept probing"
+		return model, nil
+	}
+	switch key {
+	case "q", "ctrl+c", "esc":
+		model.confirming = true
+	case " ":
+		if probing.pause() {
+			model.status = "Paused probes"
+		} else {
+			probing.resume()
+			model.status = "Resumed probes"
+		}
+	case "+", "=":
+		model.status = fmt.Sprintf("Probing every %s", stepInterval(1))
+	case "-", "_":
+		model.status = fmt.Sprintf("Probing every %s", stepInterval(-1))
+	case "up", "k":
+		model.selected = max(model.selected-1, 0)
+	case "down", "j":
+		model.selected = min(model.selected+1, len(model.allStats)-1)
+	case "enter", "tab":
+		model.detail = !model.detail
+	case "r":
+		model.status = fmt.Sprintf("Reset statistics, starting epoch %d", resetQuietly(model.allStats)+1)
+	}
+	return model, nil
+}
+
+// Move the default interval between probes one step longer or shorter, returning the new one.
+// Targets with their own ?interval= keep it.
+func stepInterval(direction int) time.Duration {
+	current := probeInterval
+	if adjusted := runtimeInterval.Load(); adjusted > 0 {
+		current = time.Duration(adjusted)
+	}
+	next := current
+	if direction > 0 {
+		if i := slices.IndexFunc(intervalSteps, func(step time.Duration) bool { return step > current }); i >= 0 {
+			next = intervalSteps[i]
+		}
+	} else {
+		for _, step := range intervalSteps {
+			if step < current {
+				next = step
+			}
+		}
+	}
+	runtimeInterval.Store(int64(next))
+	return next
+}
+
+func (model tuiModel) View() string {
+	var view strings.Builder
+	state := "Probing"
+	if probing.status().Paused {
+		state = "Paused"
+	}
+	interval := probeInterval
+	if adjusted := runtimeInterval.Load(); adjusted > 0 {
+		interval = time.Duration(adjusted)
+	}
+	fmt.Fprintf(&view, "goPing  %s every %s  Epoch %d\n\n", state, interval, currentEpoch()+1)
+	if model.detail {
+		view.WriteString(model.targetView(model.allStats[model.selected]))
+	} else {
+		view.WriteString(model.tableView())
+	}
+	view.WriteString("\n")
+	if model.confirming {
+		view.WriteString("Quit and print the summary? (y/N)\n")
+		return view.String()
+	}
+	if model.status != "" {
+		view.WriteString(model.status + "\n")
+	}
+	view.WriteString("space pause/resume  +/- interval  ↑/↓ select  enter target view  r reset  q quit\n")
+	return view.String()
+}
+
+// Table of all targets with the selected one marked
+func (model tuiModel) tableView() string {
+	var table bytes.Buffer
+	writer := tabwriter.NewWriter(&table, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "  TARGET\tSENT\tLAST RTT\tAVG RTT\tLOSS\tSTATE\tLABELS")
+	for i, stats := range model.allStats {
+		marker := "  "
+		if i == model.selected {
+			marker = "> "
+		}
+		stats.mutex.Lock()
+		var averageRTT time.Duration
+		if len(stats.rttAll) > 0 {
+			averageRTT = stats.totalRTT / time.Duration(len(stats.rttAll))
+		}
+		fmt.Fprintf(
+			writer,
+			"%s%s\t%d\t%s\t%s\t%.2f%%\t%s\t%s\n",
+			marker,
+			stats.target.address,
+			stats.count,
+			display(stats.lastRTT),
+			display(averageRTT),
+			stats.loss,
+			stats.state,
+			stats.labels)
+		stats.mutex.Unlock()
+	}
+	writer.Flush()
+	return table.String()
+}
+
+// Statistics of one target with its last results kept (-history)
+func (model tuiModel) targetView(stats *statistic) string {
+	var view strings.Builder
+	summary := stats.summary()
+	fmt.Fprintf(&view, "%s%s  %s\n", summary.Target, summary.Labels.suffix(), summary.IP)
+	fmt.Fprintf(&view, "Sent: %d   Lost: %d   Loss: %.2f%%   State: %s\n", summary.Sent, summary.Lost, summary.Loss, summary.State)
+	fmt.Fprintf(&view, "RTT min/avg/p95/max: %s/%s/%s/%s   Jitter: %s\n",
+		display(summary.MinRTT), display(summary.AvgRTT), display(summary.P95RTT), display(summary.MaxRTT), display(summary.Jitter))
+	if recentResults == nil {
+		return view.String()
+	}
+	view.WriteString("\n")
+	for _, result := range recentResults.lastMatching(tuiResults, func(result probeResult) bool {
+		return result.Target == stats.target.address && result.Labels.String() == stats.labels.String()
+	}) {
+		if result.Error != "" {
+			fmt.Fprintf(&view, "Seq: %d   %s\n", result.Seq, result.Error)
+			continue
+		}
+		fmt.Fprintf(&view, "Seq: %d   RTT: %s   TTL: %d\n", result.Seq, display(result.RTT), result.TTL)
+	}
+	return view.String()
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	rdapTimeout   time.Duration = 10 * time.Second        // Deadline of each RDAP lookup, redirects included
+	rdapMediaType string        = "application/rdap+json" // Media type of RDAP responses (RFC 7480)
+	rdapRedirect  string        = "https://rdap.org"      // Redirector to the registry's RDAP server for the address or AS number
+)
+
+var (
+	whoisHops      bool                                       // Whether goping trace looks up the owner of each hop over RDAP (-whois) flag
+	rdapOwnerRoles = []string{"registrant", "administrative"} // Roles of contacts taken as the owner, most telling first
+
+	rdapCache      = make(map[string]string) // Owner of each address looked up by trace, or why the lookup failed
+	rdapCacheMutex sync.Mutex                // Guards rdapCache across rounds of the trace
+)
+
+// Network or autonomous system record of an RDAP response (RFC 9083), with the fields goPing shows
+type rdapRecord struct {
+	Handle       string       `json:"handle"`       // Registry's identifier, e.g. NET-8-8-8-0-2
+	Name         string       `json:"name"`         // Name of the network or autonomous system
+	Country      string       `json:"country"`      // Country code of the registration
+	StartAddress string       `json:"startAddress"` // First address of the network
+	EndAddress   string       `json:"endAddress"`   // Last address of the network
+	StartAutnum  int          `json:"startAutnum"`  // First AS number of the registration
+	EndAutnum    int          `json:"endAutnum"`    // Last AS number of the registration
+	Entities     []rdapEntity `json:"entities"`     // Contacts of the registration
+	CIDRs        []struct {
+		V4Prefix string `json:"v4prefix"`
+		V6Prefix string `json:"v6prefix"`
+		Length   int    `json:"length"`
+	} `json:"cidr0_cidrs"` // Prefixes of the network (RDAP cidr0 extension)
+}
+
+// Contact of an RDAP record, with its jCard (RFC 7095) and nested contacts
+type rdapEntity struct {
+	Handle   string          `json:"handle"`     // Registry's identifier of the contact
+	Roles    []string        `json:"roles"`      // Roles such as registrant or abuse
+	VCard    json.RawMessage `json:"vcardArray"` // ["vcard", [[name, params, type, value], ...]]
+	Entities []rdapEntity    `json:"entities"`   // Contacts of the contact, such as its abuse desk
+}
+
+// Look up the owner of an IP address or AS number (e.g. AS15169) over RDAP, through the redirector
+// to the regional registry holding it, returning the record and the URL that answered
+func lookupRDAP(query string) (*rdapRecord, string, error) {
+	var path string
+	if number, ok := strings.CutPrefix(strings.ToUpper(query), "AS"); ok {
+		if _, err := strconv.ParseUint(number, 10, 32); err != nil {
+			return nil, "", fmt.Errorf("Invalid AS number %q", query)
+		}
+		path = "/autnum/" + number
+	} else if ip := net.ParseIP(query); ip != nil {
+		path = "/ip/" + ip.String()
+	} else {
+		return nil, "", fmt.Errorf("Invalid IP address or AS number %q", query)
+	}
+	client := http.Client{Timeout: rdapTimeout}
+	request, err := http.NewRequest(http.MethodGet, rdapRedirect+path, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	request.Header.Set("Accept", rdapMediaType)
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+	source := response.Request.URL.String()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, source, fmt.Errorf("No RDAP record of %s", query)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, source, fmt.Errorf("RDAP lookup of %s failed: %s", query, response.Status)
+	}
+	record := new(rdapRecord)
+	if err := json.NewDecoder(response.Body).Decode(record); err != nil {
+		return nil, source, fmt.Errorf("Invalid RDAP response for %s: %w", query, err)
+	}
+	return record, source, nil
+}
+
+// Prefixes of the network, or its address range if the registry doesn't give them
+func (record *rdapRecord) network() string {
+	var prefixes []string
+	for _, cidr := range record.CIDRs {
+		prefix := cidr.V4Prefix
+		if prefix == "" {
+			prefix = cidr.V6Prefix
+		}
+		prefixes = append(prefixes, fmt.Sprintf("%s/%d", prefix, cidr.Length))
+	}
+	switch {
+	case len(prefixes) > 0:
+		return strings.Join(prefixes, ", ")
+	case record.StartAddress != "":
+		return record.StartAddress + " - " + record.EndAddress
+	case record.StartAutnum != 0 && record.StartAutnum != record.EndAutnum:
+		return fmt.Sprintf("AS%d - AS%d", record.StartAutnum, record.EndAutnum)
+	case record.StartAutnum != 0:
+		return fmt.Sprintf("AS%d", record.StartAutnum)
+	default:
+		return ""
+	}
+}
+
+// Name of the organization owning the record, from its registrant or else administrative contact
+func (record *rdapRecord) owner() string {
+	for _, role := range rdapOwnerRoles {
+		if entity := findEntity(record.Entities, role); entity != nil {
+			if name := entity.card("fn"); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// Email address of the abuse desk of the record, empty if it has none
+func (record *rdapRecord) abuse() string {
+	if entity := findEntity(record.Entities, "abuse"); entity != nil {
+		return entity.card("email")
+	}
+	return ""
+}
+
+// First contact with the role, searching nested contacts too
+func findEntity(entities []rdapEntity, role string) *rdapEntity {
+	for i := range entities {
+		for _, candidate := range entities[i].Roles {
+			if candidate == role {
+				return &entities[i]
+			}
+		}
+	}
+	for i := range entities {
+		if entity := findEntity(entities[i].Entities, role); entity != nil {
+			return entity
+		}
+	}
+	return nil
+}
+
+// Text value of a property of the contact's jCard, such as fn or email, empty if it has none
+func (entity *rdapEntity) card(property string) string {
+	var card []json.RawMessage
+	if json.Unmarshal(entity.VCard, &card) != nil || len(card) < 2 {
+		return ""
+	}
+	var properties [][]json.RawMessage
+	if json.Unmarshal(card[1], &properties) != nil {
+		return ""
+	}
+	for _, fields := range properties {
+		var name, value string
+		if len(fields) < 4 || json.Unmarshal(fields[0], &name) != nil || name != property {
+			continue
+		}
+		if json.Unmarshal(fields[3], &value) == nil {
+			return value
+		}
+	}
+	return ""
+}
+
+// Look up and print the owner of each IP address or AS number (goping whois),
+// returning whether every lookup succeeded
+func runWhois(queries []string) bool {
+	succeeded := true
+	for i, query := range queries {
+		if i > 0 {
+			fmt.Println()
+		}
+		record, source, err := lookupRDAP(query)
+		if err != nil {
+			slog.Error(err.Error())
+			succeeded = false
+			continue
+		}
+		owner := record.owner()
+		if owner == "" {
+			owner = "(no registrant)"
+		}
+		fmt.Printf("Query: %s\n", query)
+		fmt.Printf("Name: %s (%s)\n", record.Name, record.Handle)
+		if network := record.network(); network != "" {
+			fmt.Printf("Network: %s\n", network)
+		}
+		fmt.Printf("Owner: %s\n", owner)
+		if record.Country != "" {
+			fmt.Printf("Country: %s\n", record.Country)
+		}
+		if abuse := record.abuse(); abuse != "" {
+			fmt.Printf("Abuse: %s\n", abuse)
+		}
+		fmt.Printf("Source: %s\n", source)
+	}
+	return succeeded
+}
+
+// Owner of a traced hop's address with -whois, looked up once per address, empty for private
+// addresses that no registry holds
+func hopOwner(ip net.IP) string {
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return ""
+	}
+	address := ip.String()
+	rdapCacheMutex.Lock()
+	owner, cached := rdapCache[address]
+	rdapCacheMutex.Unlock()
+	if cached {
+		return owner
+	}
+	record, _, err := lookupRDAP(address)
+	switch {
+	case err != nil:
+		slog.Debug("Could not look up hop owner", "ip", address, "error", err)
+		owner = "(lookup failed)"
+	case record.owner() != "":
+		owner = fmt.Sprintf("%s (%s)", record.owner(), record.Name)
+	default:
+		owner = record.Name
+	}
+	rdapCacheMutex.Lock()
+	rdapCache[address] = owner
+	rdapCacheMutex.Unlock()
+	return owner
+}
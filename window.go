@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Probe outcomes over a sliding span of time, for statistics of recent behaviour rather than
+// of the whole run
+type rollingWindow struct {
+	span    time.Duration  // How far back samples are kept
+	samples []windowSample // Samples within the span, oldest first
+}
+
+// Outcome of one probe in a rolling window
+type windowSample struct {
+	sent    time.Time     // When the probe was sent
+	rtt     time.Duration // RTT of the reply, 0 if lost
+	replied bool          // Whether the probe was answered
+}
+
+// Statistics of the probes in a rolling window
+type windowStats struct {
+	Span   time.Duration `json:"span"`    // Span of the window
+	Sent   int           `json:"sent"`    // Probes sent within the span
+	Lost   int           `json:"lost"`    // Probes lost within the span
+	Loss   float64       `json:"loss"`    // Percentage of probes lost
+	MinRTT time.Duration `json:"min_rtt"` // Lowest RTT
+	AvgRTT time.Duration `json:"avg_rtt"` // Mean RTT
+	P95RTT time.Duration `json:"p95_rtt"` // 95th percentile RTT
+	MaxRTT time.Duration `json:"max_rtt"` // Highest RTT
+	Jitter time.Duration `json:"jitter"`  // Mean difference between successive RTTs
+}
+
+// Add the outcome of a probe, dropping samples that have fallen out of the span
+func (window *rollingWindow) add(result probeResult) {
+	window.samples = append(window.samples, windowSample{sent: result.Time, rtt: result.RTT, replied: result.Error == ""})
+	expired := 0
+	for expired < len(window.samples) && result.Time.Sub(window.samples[expired].sent) > window.span {
+		expired++
+	}
+	window.samples = append(window.samples[:0], window.samples[expired:]...)
+}
+
+// Statistics of the samples in the window
+func (window *rollingWindow) stats() windowStats {
+	summary := windowStats{Span: window.span, Sent: len(window.samples)}
+	var rtts []time.Duration
+	var totalRTT time.Duration
+	for _, sample := range window.samples {
+		if !sample.replied {
+			summary.Lost++
+			continue
+		}
+		rtts = append(rtts, sample.rtt)
+		totalRTT += sample.rtt
+		if summary.MinRTT == 0 || sample.rtt < summary.MinRTT {
+			summary.MinRTT = sample.rtt
+		}
+		summary.MaxRTT = max(summary.MaxRTT, sample.rtt)
+	}
+	if summary.Sent > 0 {
+		summary.Loss = float64(summary.Lost) / float64(summary.Sent) * 100
+	}
+	if len(rtts) > 0 {
+		summary.AvgRTT = totalRTT / time.Duration(len(rtts))
+		summary.P95RTT = percentile(rtts, 95)
+	}
+	if differences := successiveDifferences(rtts); len(differences) > 0 {
+		var totalDifferences time.Duration
+		for _, difference := range differences {
+			totalDifferences += difference
+		}
+		summary.Jitter = totalDifferences / time.Duration(len(differences))
+	}
+	return summary
+}
+
+// Describe the window statistics in a line
+func (summary windowStats) String() string {
+	return fmt.Sprintf(
+		"Last %s: %d sent, %d lost (%.2f%%), RTT min/avg/p95/max %s/%s/%s/%s, jitter %s",
+		summary.Span,
+		summary.Sent,
+		summary.Lost,
+		summary.Loss,
+		display(summary.MinRTT),
+		display(summary.AvgRTT),
+		display(summary.P95RTT),
+		display(summary.MaxRTT),
+		display(summary.Jitter))
+}
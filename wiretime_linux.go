@@ -0,0 +1,345 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	wireTimerEntries uint32 = 4096 // Requests and replies each remembered by the timestamp maps, the least recent evicted first
+	helperMapUpdate  int32  = 2    // bpf_map_update_elem
+	helperKtime      int32  = 5    // bpf_ktime_get_ns
+	helperLoadBytes  int32  = 26   // bpf_skb_load_bytes
+	tcxNext          int32  = -1   // TCX_NEXT, leaving the packet to the next program or the stack
+)
+
+// Round trip timer of echo requests and replies timestamped by eBPF programs at the tc hooks of every
+// interface (-timestamping ebpf), as they leave and arrive on the wire rather than as goPing reads them
+type wireTimer struct {
+	egress  int   // Map of echo identifiers and sequences to when requests left
+	ingress int   // Map of echo identifiers and sequences to when replies arrived
+	links   []int // tcx links of the programs, detached as goPing exits
+}
+
+// Instruction of an eBPF program, jumping to a label if it names one
+type bpfInstruction struct {
+	code   uint8  // Opcode
+	dst    uint8  // Destination register
+	src    uint8  // Source register
+	offset int16  // Offset of memory accesses and jumps
+	imm    int32  // Immediate operand
+	jumpTo string // Label jumped to, empty if none
+	label  string // Label of the instruction, empty if none
+	wide   bool   // Whether the instruction takes two slots, loading a 64-bit immediate
+}
+
+// Load the timestamping programs and attach them to the egress and ingress of every interface up,
+// failing where the kernel lacks eBPF, tcx links (Linux 6.6) or the privileges to use them
+func openWireTimer() (*wireTimer, error) {
+	timer := &wireTimer{egress: -1, ingress: -1}
+	var err error
+	if timer.egress, err = bpfMapCreate(); err == nil {
+		timer.ingress, err = bpfMapCreate()
+	}
+	if err != nil {
+		timer.close()
+		return nil, fmt.Errorf("Could not create eBPF maps: %w", err)
+	}
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		timer.close()
+		return nil, err
+	}
+	var attachErr error
+	for _, device := range interfaces {
+		if device.Flags&net.FlagUp == 0 {
+			continue
+		}
+		// Ethernet and loopback frames lead with a 14 byte link header, tunnels with none
+		var linkHeader int32
+		switch linkType(device.Name) {
+		case "1", "772":
+			linkHeader = 14
+		case "65534":
+		default:
+			slog.Debug("Not timestamping interface with unknown link type", "interface", device.Name)
+			continue
+		}
+		for _, direction := range []struct {
+			attachType uint32
+			timestamps int
+			request    bool
+		}{{unix.BPF_TCX_EGRESS, timer.egress, true}, {unix.BPF_TCX_INGRESS, timer.ingress, false}} {
+			program, err := bpfProgramLoad(wireTimestamper(linkHeader, direction.request, direction.timestamps))
+			if err == nil {
+				var link int
+				link, err = bpfLinkCreate(program, device.Index, direction.attachType)
+				unix.Close(program)
+				if err == nil {
+					timer.links = append(timer.links, link)
+				}
+			}
+			if err != nil {
+				attachErr = fmt.Errorf("Could not attach eBPF program to %s: %w", device.Name, err)
+				slog.Debug(attachErr.Error())
+			}
+		}
+	}
+	if len(timer.links) == 0 {
+		timer.close()
+		if attachErr == nil {
+			attachErr = errors.New("No interface to attach eBPF programs to")
+		}
+		return nil, attachErr
+	}
+	return timer, nil
+}
+
+// Round trip time of the request of the echo identifier and sequence between leaving and its reply
+// arriving, false if either wasn't timestamped, e.g. over an interface that came up since
+func (timer *wireTimer) rtt(id int, seq int) (time.Duration, bool) {
+	key := [4]byte{byte(id >> 8), byte(id), byte(seq >> 8), byte(seq)}
+	sent, sentOK := bpfMapTake(timer.egress, key)
+	received, receivedOK := bpfMapTake(timer.ingress, key)
+	if !sentOK || !receivedOK || received < sent {
+		return 0, false
+	}
+	return time.Duration(received - sent), true
+}
+
+// Detach the programs and free the maps
+func (timer *wireTimer) close() {
+	for _, link := range timer.links {
+		unix.Close(link)
+	}
+	for _, timestamps := range []int{timer.egress, timer.ingress} {
+		if timestamps >= 0 {
+			unix.Close(timestamps)
+		}
+	}
+}
+
+// ARPHRD link type of the interface, e.g. 1 for Ethernet, empty if unknown
+func linkType(device string) string {
+	contents, err := os.ReadFile("/sys/class/net/" + device + "/type")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(contents))
+}
+
+// Program timestamping ICMP or ICMPv6 echo requests (egress) or replies (ingress) into the map by
+// their identifier and sequence, with the network header following a link header of the given length.
+// r6 holds the packet, r7 the offset of the ICMP header and r9 the type timestamped.
+func wireTimestamper(linkHeader int32, request bool, timestamps int) []bpfInstruction {
+	wantType4, wantType6 := int32(0), int32(129)
+	if request {
+		wantType4, wantType6 = 8, 128
+	}
+	// Load bytes of the packet at the offset in r2 onto the stack at r10+stack, leaving if they can't be
+	loadBytes := func(stack int16, size int32) []bpfInstruction {
+		return []bpfInstruction{
+			{code: 0xbf, dst: 1, src: 6},            // r1 = r6
+			{code: 0xbf, dst: 3, src: 10},           // r3 = r10
+			{code: 0x07, dst: 3, imm: int32(stack)}, // r3 += stack
+			{code: 0xb7, dst: 4, imm: size},         // r4 = size
+			{code: 0x85, imm: helperLoadBytes},      // call bpf_skb_load_bytes
+			{code: 0x55, dst: 0, jumpTo: "out"},     // if r0 != 0 goto out
+		}
+	}
+	var program []bpfInstruction
+	program = append(program, bpfInstruction{code: 0xbf, dst: 6, src: 1})          // r6 = r1
+	program = append(program, bpfInstruction{code: 0xb7, dst: 2, imm: linkHeader}) // r2 = offset of the network header
+	program = append(program, loadBytes(-8, 1)...)
+	program = append(program,
+		bpfInstruction{code: 0x71, dst: 2, src: 10, offset: -8},                 // r2 = version and IHL
+		bpfInstruction{code: 0xbf, dst: 3, src: 2},                              // r3 = r2
+		bpfInstruction{code: 0x77, dst: 3, imm: 4},                              // r3 >>= 4, the IP version
+		bpfInstruction{code: 0x15, dst: 3, imm: 6, jumpTo: "ipv6"},              // if r3 == 6 goto ipv6
+		bpfInstruction{code: 0x55, dst: 3, imm: 4, jumpTo: "out"},               // if r3 != 4 goto out
+		bpfInstruction{code: 0xbf, dst: 7, src: 2},                              // r7 = r2
+		bpfInstruction{code: 0x57, dst: 7, imm: 0x0f},                           // r7 &= 0x0f
+		bpfInstruction{code: 0x67, dst: 7, imm: 2},                              // r7 <<= 2, the IPv4 header length
+		bpfInstruction{code: 0x07, dst: 7, imm: linkHeader},                     // r7 += link header length
+		bpfInstruction{code: 0xb7, dst: 9, imm: wantType4},                      // r9 = ICMP type timestamped
+		bpfInstruction{code: 0xb7, dst: 2, imm: linkHeader + 9},                 // r2 = offset of the protocol
+		bpfInstruction{code: 0xb7, dst: 8, imm: 1},                              // r8 = ICMP
+		bpfInstruction{code: 0x05, jumpTo: "protocol"},                          // goto protocol
+		bpfInstruction{code: 0xb7, dst: 7, imm: linkHeader + 40, label: "ipv6"}, // r7 = offset of the ICMPv6 header
+		bpfInstruction{code: 0xb7, dst: 9, imm: wantType6},                      // r9 = ICMPv6 type timestamped
+		bpfInstruction{code: 0xb7, dst: 2, imm: linkHeader + 6},                 // r2 = offset of the next header
+		bpfInstruction{code: 0xb7, dst: 8, imm: 58},                             // r8 = ICMPv6
+	)
+	protocol := loadBytes(-8, 1)
+	protocol[0].label = "protocol"
+	program = append(program, protocol...)
+	program = append(program,
+		bpfInstruction{code: 0x71, dst: 2, src: 10, offset: -8},   // r2 = protocol
+		bpfInstruction{code: 0x5d, dst: 2, src: 8, jumpTo: "out"}, // if r2 != r8 goto out
+		bpfInstruction{code: 0xbf, dst: 2, src: 7},                // r2 = r7
+	)
+	program = append(program, loadBytes(-16, 8)...)
+	program = append(program,
+		bpfInstruction{code: 0x71, dst: 2, src: 10, offset: -16},                       // r2 = type
+		bpfInstruction{code: 0x5d, dst: 2, src: 9, jumpTo: "out"},                      // if r2 != r9 goto out
+		bpfInstruction{code: 0x61, dst: 2, src: 10, offset: -12},                       // r2 = identifier and sequence
+		bpfInstruction{code: 0x63, dst: 10, src: 2, offset: -20},                       // key = r2
+		bpfInstruction{code: 0x85, imm: helperKtime},                                   // call bpf_ktime_get_ns
+		bpfInstruction{code: 0x7b, dst: 10, src: 0, offset: -32},                       // value = r0
+		bpfInstruction{code: 0x18, dst: 1, src: 1, imm: int32(timestamps), wide: true}, // r1 = map file descriptor
+		bpfInstruction{code: 0xbf, dst: 2, src: 10},                                    // r2 = r10
+		bpfInstruction{code: 0x07, dst: 2, imm: -20},                                   // r2 = &key
+		bpfInstruction{code: 0xbf, dst: 3, src: 10},                                    // r3 = r10
+		bpfInstruction{code: 0x07, dst: 3, imm: -32},                                   // r3 = &value
+		bpfInstruction{code: 0xb7, dst: 4, imm: 0},                                     // r4 = BPF_ANY
+		bpfInstruction{code: 0x85, imm: helperMapUpdate},                               // call bpf_map_update_elem
+		bpfInstruction{code: 0xb7, dst: 0, imm: tcxNext, label: "out"},                 // r0 = TCX_NEXT
+		bpfInstruction{code: 0x95},                                                     // exit
+	)
+	return program
+}
+
+// Encode the program's instructions, resolving jumps to their labels
+func encodeProgram(program []bpfInstruction) []byte {
+	slots := make(map[string]int)
+	slot := 0
+	for _, instruction := range program {
+		if instruction.label != "" {
+			slots[instruction.label] = slot
+		}
+		slot++
+		if instruction.wide {
+			slot++
+		}
+	}
+	lowDst := binary.NativeEndian.Uint16([]byte{1, 0}) == 1 // Register nibbles follow the bitfield order of the byte order
+	var encoded []byte
+	slot = 0
+	for _, instruction := range program {
+		slot++
+		if instruction.wide {
+			slot++
+		}
+		offset := instruction.offset
+		if instruction.jumpTo != "" {
+			offset = int16(slots[instruction.jumpTo] - slot)
+		}
+		registers := instruction.dst | instruction.src<<4
+		if !lowDst {
+			registers = instruction.dst<<4 | instruction.src
+		}
+		encoded = append(encoded, instruction.code, registers)
+		encoded = binary.NativeEndian.AppendUint16(encoded, uint16(offset))
+		encoded = binary.NativeEndian.AppendUint32(encoded, uint32(instruction.imm))
+		if instruction.wide {
+			encoded = append(encoded, make([]byte, 8)...)
+		}
+	}
+	return encoded
+}
+
+// Issue the bpf system call with the attributes
+func bpfCall(command uintptr, attributes unsafe.Pointer, size uintptr) (int, error) {
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, command, uintptr(attributes), size)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// Create a least recently used hash map of 4 byte echo identifiers and sequences to 8 byte timestamps
+func bpfMapCreate() (int, error) {
+	attributes := struct {
+		mapType    uint32
+		keySize    uint32
+		valueSize  uint32
+		maxEntries uint32
+		flags      uint32
+	}{unix.BPF_MAP_TYPE_LRU_HASH, 4, 8, wireTimerEntries, 0}
+	return bpfCall(unix.BPF_MAP_CREATE, unsafe.Pointer(&attributes), unsafe.Sizeof(attributes))
+}
+
+// Load a tc classifier program, including the verifier's log in the error if it is rejected
+func bpfProgramLoad(program []bpfInstruction) (int, error) {
+	instructions := encodeProgram(program)
+	license := []byte("GPL\x00")
+	log := make([]byte, 1<<16)
+	attributes := struct {
+		progType     uint32
+		insnCount    uint32
+		insns        uint64
+		license      uint64
+		logLevel     uint32
+		logSize      uint32
+		logBuf       uint64
+		kernVersion  uint32
+		progFlags    uint32
+		progName     [16]byte
+		progIfindex  uint32
+		expectedType uint32
+	}{
+		progType:  unix.BPF_PROG_TYPE_SCHED_CLS,
+		insnCount: uint32(len(instructions) / 8),
+		insns:     uint64(uintptr(unsafe.Pointer(&instructions[0]))),
+		license:   uint64(uintptr(unsafe.Pointer(&license[0]))),
+		logLevel:  1,
+		logSize:   uint32(len(log)),
+		logBuf:    uint64(uintptr(unsafe.Pointer(&log[0]))),
+	}
+	copy(attributes.progName[:], "goping_wire")
+	fd, err := bpfCall(unix.BPF_PROG_LOAD, unsafe.Pointer(&attributes), unsafe.Sizeof(attributes))
+	runtime.KeepAlive(instructions)
+	runtime.KeepAlive(license)
+	runtime.KeepAlive(log)
+	if err != nil {
+		if verifier := strings.TrimSpace(string(log[:max(0, strings.IndexByte(string(log), 0))])); verifier != "" {
+			return -1, fmt.Errorf("%w: %s", err, verifier)
+		}
+		return -1, err
+	}
+	return fd, nil
+}
+
+// Attach the program to the hook of the interface as a tcx link, after any programs already there
+func bpfLinkCreate(program int, ifindex int, attachType uint32) (int, error) {
+	attributes := struct {
+		progFD           uint32
+		targetIfindex    uint32
+		attachType       uint32
+		flags            uint32
+		relative         uint32
+		_                uint32
+		expectedRevision uint64
+	}{progFD: uint32(program), targetIfindex: uint32(ifindex), attachType: attachType}
+	return bpfCall(unix.BPF_LINK_CREATE, unsafe.Pointer(&attributes), unsafe.Sizeof(attributes))
+}
+
+// Look up and delete the timestamp of the key, false if there is none
+func bpfMapTake(timestamps int, key [4]byte) (uint64, bool) {
+	var value uint64
+	attributes := struct {
+		mapFD uint32
+		_     uint32
+		key   uint64
+		value uint64
+		flags uint64
+	}{mapFD: uint32(timestamps), key: uint64(uintptr(unsafe.Pointer(&key[0]))), value: uint64(uintptr(unsafe.Pointer(&value)))}
+	if _, err := bpfCall(unix.BPF_MAP_LOOKUP_ELEM, unsafe.Pointer(&attributes), unsafe.Sizeof(attributes)); err != nil {
+		return 0, false
+	}
+	bpfCall(unix.BPF_MAP_DELETE_ELEM, unsafe.Pointer(&attributes), unsafe.Sizeof(attributes))
+	runtime.KeepAlive(&key)
+	return value, true
+}
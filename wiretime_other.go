@@ -0,0 +1,24 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// Round trip timer of requests and replies on the wire, only implemented on Linux
+type wireTimer struct{}
+
+// Timestamping on the wire with eBPF is only implemented on Linux
+func openWireTimer() (*wireTimer, error) {
+	return nil, errors.New("eBPF timestamping is not supported on this platform")
+}
+
+// Round trip time of the request of the echo identifier and sequence, never known on this platform
+func (timer *wireTimer) rtt(id int, seq int) (time.Duration, bool) {
+	return 0, false
+}
+
+// Detach the programs and free the maps, none on this platform
+func (timer *wireTimer) close() {}